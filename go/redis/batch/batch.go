@@ -0,0 +1,301 @@
+// Package batch provides concurrency-safe writer which batches redis commands into pipelines.
+//
+// The commands added from any goroutine are queued, and flushed in a single pipeline
+// when `MaxCommands` commands are queued or after `FlushInterval`, whichever comes first.
+// The batches are flushed one at a time, so the commands are executed in the order they are added.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/defaults"
+	"github.com/boxofimagination/bxdk/go/log"
+	"github.com/boxofimagination/bxdk/go/redis"
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+var (
+	// ErrClosed returned when the command is added after the writer is closed
+	ErrClosed = errors.New("batch writer closed")
+
+	// ErrQueueFull returned when the command is added while the queue is full, e.g. redis is too slow
+	ErrQueueFull = errors.New("batch writer queue full")
+)
+
+// FlushError is returned by `Flush` & `Close` when some of the flushed commands without callback failed.
+// Only the first failure is kept, the args of the commands are never logged nor returned
+// as they might contain the cached values.
+type FlushError struct {
+	// Failed is number of the failed commands
+	Failed int
+
+	// Cmd & Key are name & key of the first failed command, the key is empty if the command has no key
+	Cmd, Key string
+
+	// Err is error of the first failed command
+	Err error
+}
+
+func (e *FlushError) Error() string {
+	return fmt.Sprintf("batch: %d commands failed, the first one is %v %v: %v", e.Failed, e.Cmd, e.Key, e.Err)
+}
+
+// Unwrap returns error of the first failed command
+func (e *FlushError) Unwrap() error {
+	return e.Err
+}
+
+// add records the failed command
+func (e *FlushError) add(cmd command, err error) {
+	e.Failed++
+	if e.Failed > 1 {
+		return
+	}
+	e.Cmd, e.Err = cmd.cmd, err
+	if idx := engine.KeyIndexes(cmd.cmd, cmd.args); len(idx) > 0 {
+		e.Key = fmt.Sprint(cmd.args[idx[0]])
+	}
+}
+
+// Callback receives reply & error of a flushed command.
+// The error is either the error replied by the server, or the error of the whole pipeline execution.
+// Nil reply is returned as ErrNil, check it using `IsErrNil` of the client.
+//
+// The callbacks are called from the flushing goroutine, they must not block.
+type Callback func(reply interface{}, err error)
+
+// Config defines configuration of the batch writer
+type Config struct {
+	// MaxCommands is number of the queued commands which triggers the flush,
+	// it is also the maximum number of commands in a pipeline
+	MaxCommands int `yaml:"max_commands" default:"100"`
+
+	// FlushInterval is the maximum duration the command is queued before it is flushed
+	FlushInterval time.Duration `yaml:"flush_interval" default:"100ms"`
+
+	// FlushTimeout bounds each pipeline execution of the flush
+	FlushTimeout time.Duration `yaml:"flush_timeout" default:"5s"`
+
+	// QueueSize is the maximum number of the queued commands,
+	// the commands added beyond it are rejected with ErrQueueFull
+	QueueSize int `yaml:"queue_size" default:"10000"`
+
+	// Retry is number of retry of the failed pipeline execution
+	Retry int `yaml:"retry" default:"1"`
+}
+
+// command is a queued command
+type command struct {
+	cmd      string
+	args     []interface{}
+	callback Callback
+}
+
+// Writer batches the commands into pipelines, it is safe for concurrent use
+type Writer struct {
+	cli redis.Redis
+	cfg Config
+
+	mux     sync.Mutex
+	queue   []command
+	closed  bool
+	closeCh chan struct{}
+
+	// fullCh signals the flusher that MaxCommands commands are queued
+	fullCh chan struct{}
+
+	// flushCh receives the explicit flush requests, the error of the flush is sent to the given channel
+	flushCh chan chan error
+
+	// doneCh is closed after the final flush
+	doneCh chan struct{}
+
+	// closeErr is error of the final flush, it is set before doneCh is closed
+	closeErr error
+}
+
+// New creates batch writer from the given config, and starts its flusher.
+// Call Close to flush the remaining commands and stop the flusher.
+func New(cli redis.Redis, cfg Config) (*Writer, error) {
+	if err := defaults.SetDefault(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.QueueSize < cfg.MaxCommands {
+		cfg.QueueSize = cfg.MaxCommands
+	}
+
+	w := &Writer{
+		cli:     cli,
+		cfg:     cfg,
+		queue:   make([]command, 0, cfg.MaxCommands),
+		closeCh: make(chan struct{}),
+		fullCh:  make(chan struct{}, 1),
+		flushCh: make(chan chan error),
+		doneCh:  make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Add queues the command, its error is reported by the flush, see FlushError.
+// It returns ErrClosed or ErrQueueFull if the command is rejected.
+func (w *Writer) Add(cmd string, args ...interface{}) error {
+	return w.AddFunc(nil, cmd, args...)
+}
+
+// AddFunc queues the command, the callback is called after the command is flushed.
+// It returns ErrClosed or ErrQueueFull if the command is rejected, the callback is not called in that case.
+func (w *Writer) AddFunc(callback Callback, cmd string, args ...interface{}) error {
+	w.mux.Lock()
+	if w.closed {
+		w.mux.Unlock()
+		return ErrClosed
+	}
+	if len(w.queue) >= w.cfg.QueueSize {
+		w.mux.Unlock()
+		return ErrQueueFull
+	}
+
+	w.queue = append(w.queue, command{
+		cmd:      cmd,
+		args:     args,
+		callback: callback,
+	})
+	full := len(w.queue) >= w.cfg.MaxCommands
+	w.mux.Unlock()
+
+	if full {
+		select {
+		case w.fullCh <- struct{}{}:
+		default: // the flusher is already signaled
+		}
+	}
+	return nil
+}
+
+// Flush flushes the queued commands and waits until they are executed, or until the ctx is done.
+// It returns *FlushError if some of the commands without callback failed.
+func (w *Writer) Flush(ctx context.Context) error {
+	flushedCh := make(chan error, 1)
+	select {
+	case w.flushCh <- flushedCh:
+	case <-w.doneCh:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-flushedCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting the commands, flushes the queued commands,
+// and waits until they are executed or until the ctx is done.
+// It returns *FlushError if some of the commands without callback failed in the final flush.
+// Its signature matches the handler of `grace.WaitTermSig`.
+func (w *Writer) Close(ctx context.Context) error {
+	w.mux.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.closeCh)
+	}
+	w.mux.Unlock()
+
+	select {
+	case <-w.doneCh:
+		return w.closeErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run flushes the queue until the writer is closed
+func (w *Writer) run() {
+	defer close(w.doneCh)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.logFlush()
+		case <-w.fullCh:
+			w.logFlush()
+		case flushedCh := <-w.flushCh:
+			flushedCh <- w.flush()
+		case <-w.closeCh:
+			// no more command could be added
+			w.closeErr = w.flush()
+			return
+		}
+	}
+}
+
+// logFlush flushes the queue, the error is logged as nobody waits for it
+func (w *Writer) logFlush() {
+	if err := w.flush(); err != nil {
+		log.Errorf("%v", err)
+	}
+}
+
+// flush executes all of the queued commands, in pipelines of at most MaxCommands commands.
+// It returns *FlushError if some of the commands without callback failed.
+func (w *Writer) flush() error {
+	w.mux.Lock()
+	queue := w.queue
+	w.queue = make([]command, 0, w.cfg.MaxCommands)
+	w.mux.Unlock()
+
+	flushErr := &FlushError{}
+	for len(queue) > 0 {
+		n := len(queue)
+		if n > w.cfg.MaxCommands {
+			n = w.cfg.MaxCommands
+		}
+		w.exec(queue[:n], flushErr)
+		queue = queue[n:]
+	}
+
+	if flushErr.Failed > 0 {
+		return flushErr
+	}
+	return nil
+}
+
+// exec executes the commands in a pipeline, and reports their replies.
+// The failed commands without callback are added to the flushErr.
+func (w *Writer) exec(cmds []command, flushErr *FlushError) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.cfg.FlushTimeout)
+	defer cancel()
+
+	p := w.cli.PipelineContext(ctx, w.cfg.Retry, len(cmds))
+	defer p.Close()
+
+	results := make([]*redis.Result, len(cmds))
+	for i, cmd := range cmds {
+		results[i] = p.AddRawCmd(cmd.cmd, cmd.args...)
+	}
+
+	// the errors are reported through the results
+	p.Exec()
+
+	for i, cmd := range cmds {
+		reply, err := results[i].Result()
+		if cmd.callback != nil {
+			cmd.callback(reply, err)
+			continue
+		}
+		if err != nil && !w.cli.IsErrNil(err) {
+			flushErr.add(cmd, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis"
+)
+
+func newTestWriter(t *testing.T, cfg Config) (*Writer, *redis.Client, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	cli, err := redis.New(redis.Config{Address: mr.Addr()})
+	require.NoError(t, err)
+
+	w, err := New(cli, cfg)
+	require.NoError(t, err)
+	return w, cli, mr
+}
+
+// replies collects the replies of the callbacks
+type replies struct {
+	mux  sync.Mutex
+	vals []interface{}
+	errs []error
+	wg   sync.WaitGroup
+}
+
+func (r *replies) callback() Callback {
+	r.wg.Add(1)
+	return func(reply interface{}, err error) {
+		r.mux.Lock()
+		r.vals = append(r.vals, reply)
+		r.errs = append(r.errs, err)
+		r.mux.Unlock()
+		r.wg.Done()
+	}
+}
+
+func TestFlushOnMaxCommands(t *testing.T) {
+	w, _, mr := newTestWriter(t, Config{MaxCommands: 3, FlushInterval: time.Hour})
+	defer mr.Close()
+	defer w.Close(context.Background())
+
+	var r replies
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.AddFunc(r.callback(), "INCR", "counter"))
+	}
+	r.wg.Wait()
+
+	require.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, r.vals)
+	require.Equal(t, []error{nil, nil, nil}, r.errs)
+}
+
+func TestFlushOnInterval(t *testing.T) {
+	w, _, mr := newTestWriter(t, Config{MaxCommands: 100, FlushInterval: 10 * time.Millisecond})
+	defer mr.Close()
+	defer w.Close(context.Background())
+
+	var r replies
+	require.NoError(t, w.AddFunc(r.callback(), "SET", "key", "value"))
+	r.wg.Wait()
+
+	require.Equal(t, []interface{}{"OK"}, r.vals)
+	mr.CheckGet(t, "key", "value")
+}
+
+func TestCallbackErrors(t *testing.T) {
+	w, cli, mr := newTestWriter(t, Config{FlushInterval: time.Hour})
+	defer mr.Close()
+	defer w.Close(context.Background())
+
+	require.NoError(t, mr.Set("str", "v"))
+
+	var r replies
+	require.NoError(t, w.AddFunc(r.callback(), "INCR", "str"))
+	require.NoError(t, w.AddFunc(r.callback(), "GET", "not-exist"))
+	require.NoError(t, w.Add("INCR", "str")) // reported by the flush
+	require.NoError(t, w.Add("GET", "not-exist"))
+	require.NoError(t, w.Add("HSET", "str", "field", "secret"))
+	require.NoError(t, w.AddFunc(r.callback(), "INCR", "counter"))
+
+	err := w.Flush(context.Background())
+	var flushErr *FlushError
+	require.True(t, errors.As(err, &flushErr), err)
+	require.Equal(t, 2, flushErr.Failed)
+	require.Equal(t, "INCR", flushErr.Cmd)
+	require.Equal(t, "str", flushErr.Key)
+	require.Error(t, flushErr.Err)
+	require.NotContains(t, err.Error(), "secret")
+	r.wg.Wait()
+
+	// the errors of the previous flush are not reported again
+	require.NoError(t, w.Add("INCR", "counter"))
+	require.NoError(t, w.Flush(context.Background()))
+
+	require.Error(t, r.errs[0])
+	require.True(t, cli.IsErrNil(r.errs[1]))
+	require.NoError(t, r.errs[2])
+	require.Equal(t, int64(1), r.vals[2])
+
+	// the final flush error is returned by Close
+	require.NoError(t, w.Add("INCR", "str"))
+	require.True(t, errors.As(w.Close(context.Background()), &flushErr))
+	require.Equal(t, 1, flushErr.Failed)
+}
+
+func TestClose(t *testing.T) {
+	w, _, mr := newTestWriter(t, Config{MaxCommands: 2, FlushInterval: time.Hour})
+	defer mr.Close()
+
+	// the commands are flushed in pipelines of MaxCommands commands, in order
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.Add("RPUSH", "list", i))
+	}
+	require.NoError(t, w.Close(context.Background()))
+
+	vals, err := mr.List("list")
+	require.NoError(t, err)
+	require.Equal(t, []string{"0", "1", "2", "3", "4"}, vals)
+
+	require.Equal(t, ErrClosed, w.Add("INCR", "counter"))
+	require.Equal(t, ErrClosed, w.Flush(context.Background()))
+	require.NoError(t, w.Close(context.Background()))
+}
+
+func TestQueueFull(t *testing.T) {
+	w, _, mr := newTestWriter(t, Config{MaxCommands: 1, QueueSize: 2, FlushInterval: time.Hour})
+	defer mr.Close()
+
+	// block the flusher in the callback
+	blockedCh, unblockCh := make(chan struct{}), make(chan struct{})
+	require.NoError(t, w.AddFunc(func(interface{}, error) {
+		close(blockedCh)
+		<-unblockCh
+	}, "INCR", "counter"))
+	<-blockedCh
+
+	require.NoError(t, w.Add("INCR", "counter"))
+	require.NoError(t, w.Add("INCR", "counter"))
+	require.Equal(t, ErrQueueFull, w.Add("INCR", "counter"))
+
+	close(unblockCh)
+	require.NoError(t, w.Close(context.Background()))
+	mr.CheckGet(t, "counter", "3")
+}
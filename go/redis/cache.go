@@ -0,0 +1,189 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/log"
+)
+
+// ErrNotFound returned by the Filler when the value doesn't exist, it could be wrapped.
+// `GetWithSingleFiller` caches it, so the next calls don't call the filler until it is expired.
+var ErrNotFound = errors.New("not found")
+
+// ErrFillerPanic returned by `GetWithSingleFiller` when the filler panics, it is wrapped with the panic value
+var ErrFillerPanic = errors.New("filler panicked")
+
+// defaultFillerTimeout is the filler timeout when `FillerTimeoutMs` is not set
+const defaultFillerTimeout = 10 * time.Second
+
+// notFoundValue is the cached value of ErrNotFound.
+// Version 0 is reserved for it, it is not a valid codec version.
+const notFoundValue = "\x00"
+
+// Filler gets the value from the source of truth (e.g. database) when it is not cached yet.
+// It returns ErrNotFound if the value doesn't exist.
+type Filler func(ctx context.Context) (interface{}, error)
+
+// GetWithSingleFiller gets the cached value of the key, and decodes it into the value pointed by v.
+//
+// If the key is not cached yet, it calls the filler and caches the returned value for `expire` seconds.
+// Only one filler is called at a time for the same key, the concurrent callers wait for it and get its result.
+//
+// If the filler returns ErrNotFound, it is cached for `notFoundExpire` seconds,
+// so the subsequent calls return ErrNotFound without calling the filler.
+// Set `notFoundExpire` to 0 to disable it.
+// The filler's other errors are not cached.
+func (c *Client) GetWithSingleFiller(key string, v interface{}, expire, notFoundExpire int, filler Filler) error {
+	return c.GetWithSingleFillerContext(context.Background(), key, v, expire, notFoundExpire, filler)
+}
+
+// GetWithSingleFillerContext is GetWithSingleFiller with context.
+// The ctx bounds only the waiting of this caller. The filler is shared by the concurrent callers,
+// it is called with context which has the values of the first caller's ctx (e.g. the tracing span)
+// but is not canceled with it, and is timed out after `FillerTimeoutMs`.
+func (c *Client) GetWithSingleFillerContext(ctx context.Context, key string, v interface{},
+	expire, notFoundExpire int, filler Filler) error {
+	if filler == nil {
+		return ErrNilFiltre
+	}
+
+	data, err := c.GetContext(ctx, key)
+	if err != nil && !c.IsErrNil(err) {
+		return err
+	}
+	if err != nil {
+		data, err = c.fillers.do(ctx, key, func() (string, error) {
+			fillCtx, cancel := c.fillerContext(ctx)
+			defer cancel()
+			return c.fill(fillCtx, key, expire, notFoundExpire, filler)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if data == notFoundValue {
+		return ErrNotFound
+	}
+	return decode([]byte(data), v)
+}
+
+// fillerContext returns context of the filler, detached from the caller's ctx cancellation
+func (c *Client) fillerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := c.fillerTimeout
+	if timeout <= 0 {
+		timeout = defaultFillerTimeout
+	}
+	return context.WithTimeout(detachedContext{ctx}, timeout)
+}
+
+// detachedContext has the values of the parent context, but not its deadline & cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// fill calls the filler and caches the returned value
+func (c *Client) fill(ctx context.Context, key string, expire, notFoundExpire int, filler Filler) (string, error) {
+	val, err := filler(ctx)
+	if errors.Is(err, ErrNotFound) {
+		if notFoundExpire > 0 {
+			c.cache(ctx, key, notFoundValue, notFoundExpire)
+		}
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	data, err := encode(c.codec, val)
+	if err != nil {
+		return "", err
+	}
+	c.cache(ctx, key, string(data), expire)
+	return string(data), nil
+}
+
+// cache stores the value, the failure is only logged because the caller still gets the filled value
+func (c *Client) cache(ctx context.Context, key, data string, expire int) {
+	if _, err := c.SetEXContext(ctx, key, data, expire); err != nil {
+		log.Errorf("redis: failed to cache key %v: %v", key, err)
+	}
+}
+
+// singleFlight makes sure only one function is executed at a time for the same key
+type singleFlight struct {
+	mux   sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	doneCh chan struct{}
+	val    string
+	err    error
+}
+
+func newSingleFlight() *singleFlight {
+	return &singleFlight{
+		calls: make(map[string]*flightCall),
+	}
+}
+
+// do executes the fn in another goroutine, or joins the fn being executed for the key, and waits for its result.
+// Each of the callers, including the one which started the fn, stops waiting when its own ctx is done,
+// the fn keeps running for the other callers.
+func (s *singleFlight) do(ctx context.Context, key string, fn func() (string, error)) (string, error) {
+	s.mux.Lock()
+	call, ok := s.calls[key]
+	if !ok {
+		call = &flightCall{doneCh: make(chan struct{})}
+		s.calls[key] = call
+		go s.run(key, call, fn)
+	}
+	s.mux.Unlock()
+
+	select {
+	case <-call.doneCh:
+		return call.val, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// run executes the fn and wakes up the waiting callers.
+// The panic of the fn is recovered, the callers get it as error.
+func (s *singleFlight) run(key string, call *flightCall, fn func() (string, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("redis: filler of key %v panicked: %v\n%s", key, r, debug.Stack())
+			call.val, call.err = "", fmt.Errorf("%w: %v", ErrFillerPanic, r)
+		}
+
+		s.mux.Lock()
+		delete(s.calls, key)
+		s.mux.Unlock()
+		close(call.doneCh)
+	}()
+
+	call.val, call.err = fn()
+}
@@ -0,0 +1,214 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+func TestGetWithSingleFiller(t *testing.T) {
+	cli, mr := newTestClient(t, engine.Redigo)
+	defer mr.Close()
+
+	type user struct {
+		ID   int
+		Name string
+	}
+
+	var calls int32
+	filler := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return user{ID: 1, Name: "foo"}, nil
+	}
+
+	var u user
+	require.NoError(t, cli.GetWithSingleFiller("user:1", &u, 10, 0, filler))
+	require.Equal(t, user{ID: 1, Name: "foo"}, u)
+	require.Equal(t, 10*time.Second, mr.TTL("user:1"))
+
+	// cached
+	u = user{}
+	require.NoError(t, cli.GetWithSingleFiller("user:1", &u, 10, 0, filler))
+	require.Equal(t, user{ID: 1, Name: "foo"}, u)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	require.Equal(t, ErrNilFiltre, cli.GetWithSingleFiller("user:1", &u, 10, 0, nil))
+
+	// the error is not cached
+	myErr := errors.New("my error")
+	require.Equal(t, myErr, cli.GetWithSingleFiller("user:2", &u, 10, 0, func(ctx context.Context) (interface{}, error) {
+		return nil, myErr
+	}))
+	require.False(t, mr.Exists("user:2"))
+}
+
+func TestGetWithSingleFillerNotFound(t *testing.T) {
+	cli, mr := newTestClient(t, engine.Redigo)
+	defer mr.Close()
+
+	var calls int32
+	filler := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, ErrNotFound
+	}
+
+	var val string
+	require.Equal(t, ErrNotFound, cli.GetWithSingleFiller("none", &val, 10, 5, filler))
+	require.Equal(t, ErrNotFound, cli.GetWithSingleFiller("none", &val, 10, 5, filler))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	require.Equal(t, 5*time.Second, mr.TTL("none"))
+
+	// not cached
+	require.Equal(t, ErrNotFound, cli.GetWithSingleFiller("none:2", &val, 10, 0, filler))
+	require.Equal(t, ErrNotFound, cli.GetWithSingleFiller("none:2", &val, 10, 0, filler))
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// the wrapped ErrNotFound is cached too
+	wrapped := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, fmt.Errorf("load user: %w", ErrNotFound)
+	}
+	require.Equal(t, ErrNotFound, cli.GetWithSingleFiller("none:3", &val, 10, 5, wrapped))
+	require.Equal(t, ErrNotFound, cli.GetWithSingleFiller("none:3", &val, 10, 5, wrapped))
+	require.Equal(t, int32(4), atomic.LoadInt32(&calls))
+}
+
+func TestGetWithSingleFillerConcurrent(t *testing.T) {
+	cli, mr := newTestClient(t, engine.Redigo)
+	defer mr.Close()
+
+	var calls int32
+	releaseCh := make(chan struct{})
+	filler := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-releaseCh
+		return "value", nil
+	}
+
+	const num = 10
+	var (
+		wg      sync.WaitGroup
+		started sync.WaitGroup
+		errs    = make([]error, num)
+		vals    = make([]string, num)
+	)
+	for i := 0; i < num; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			errs[i] = cli.GetWithSingleFiller("key", &vals[i], 10, 0, filler)
+		}(i)
+	}
+	started.Wait()
+
+	// wait until all of the callers miss the cache
+	waitFor(t, func() bool {
+		cli.fillers.mux.Lock()
+		defer cli.fillers.mux.Unlock()
+		return len(cli.fillers.calls) == 1
+	})
+	time.Sleep(20 * time.Millisecond)
+	close(releaseCh)
+	wg.Wait()
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for i := 0; i < num; i++ {
+		require.NoError(t, errs[i])
+		require.Equal(t, "value", vals[i])
+	}
+}
+
+func TestGetWithSingleFillerCallerContext(t *testing.T) {
+	cli, mr := newTestClient(t, engine.Redigo)
+	defer mr.Close()
+
+	type ctxKey struct{}
+	var (
+		startedCh = make(chan struct{})
+		releaseCh = make(chan struct{})
+		fillErr   = make(chan error, 1)
+	)
+	filler := func(ctx context.Context) (interface{}, error) {
+		close(startedCh)
+		<-releaseCh
+		fillErr <- ctx.Err()
+		return ctx.Value(ctxKey{}), nil
+	}
+
+	// the first caller gives up while the filler is running
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	firstErr := make(chan error, 1)
+	go func() {
+		var val string
+		firstErr <- cli.GetWithSingleFillerContext(ctx, "key", &val, 10, 0, filler)
+	}()
+	<-startedCh
+	cancel()
+	require.Equal(t, context.Canceled, <-firstErr)
+
+	// the filler is not canceled, its value is cached for the other callers
+	close(releaseCh)
+	require.NoError(t, <-fillErr)
+	waitFor(t, func() bool {
+		cli.fillers.mux.Lock()
+		defer cli.fillers.mux.Unlock()
+		return len(cli.fillers.calls) == 0
+	})
+	var val string
+	require.NoError(t, cli.GetWithSingleFiller("key", &val, 10, 0, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("must not be called")
+	}))
+	require.Equal(t, "value", val)
+}
+
+func TestGetWithSingleFillerPanic(t *testing.T) {
+	cli, mr := newTestClient(t, engine.Redigo)
+	defer mr.Close()
+
+	var val string
+	err := cli.GetWithSingleFiller("key", &val, 10, 0, func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	require.True(t, errors.Is(err, ErrFillerPanic), err)
+	require.False(t, mr.Exists("key"))
+
+	// the next caller calls the filler again
+	require.NoError(t, cli.GetWithSingleFiller("key", &val, 10, 0, func(ctx context.Context) (interface{}, error) {
+		return "value", nil
+	}))
+	require.Equal(t, "value", val)
+}
+
+func TestSingleFlightContext(t *testing.T) {
+	s := newSingleFlight()
+	releaseCh := make(chan struct{})
+	defer close(releaseCh)
+
+	go s.do(context.Background(), "key", func() (string, error) {
+		<-releaseCh
+		return "value", nil
+	})
+	waitFor(t, func() bool {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		return len(s.calls) == 1
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := s.do(ctx, "key", func() (string, error) {
+		t.Fatal("must not be called")
+		return "", nil
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+}
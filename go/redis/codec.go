@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+// Codec serializes the Go values stored by the object commands, e.g. `SetObject` & `GetObject`.
+//
+// The encoded value is prefixed with the codec's version byte,
+// so the value is always decoded using the codec which encoded it.
+// It allows changing the codec without corrupting the existing keys.
+type Codec interface {
+	// Version is the byte prefixed to the encoded value, it must be unique for each registered codec
+	Version() byte
+
+	// Marshal encodes the value
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes the data into the value pointed by v
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// versions of the provided codecs
+const (
+	JSONCodecVersion    byte = 1
+	MsgpackCodecVersion byte = 2
+	GobCodecVersion     byte = 3
+)
+
+var (
+	// ErrUnknownCodec returned when the value is encoded using a codec which is not registered
+	ErrUnknownCodec = errors.New("unknown codec")
+
+	// ErrEmptyObject returned when the stored value is empty, so it doesn't have the codec's version byte
+	ErrEmptyObject = errors.New("empty object")
+)
+
+var (
+	codecMux        sync.RWMutex
+	codecs          = make(map[string]Codec)
+	codecsByVersion = make(map[byte]Codec)
+)
+
+func init() {
+	RegisterCodec("json", JSONCodec{})
+	RegisterCodec("msgpack", MsgpackCodec{})
+	RegisterCodec("gob", GobCodec{})
+}
+
+// RegisterCodec registers the codec, so it could be used by setting `Config.Codec` to the name.
+// It panics if the name or the version is already registered.
+// Version 0 is reserved, it is used by `GetWithSingleFiller` to cache ErrNotFound.
+func RegisterCodec(name string, codec Codec) {
+	codecMux.Lock()
+	defer codecMux.Unlock()
+
+	if codec.Version() == 0 {
+		panic("redis: codec version 0 is reserved")
+	}
+	if _, ok := codecs[name]; ok {
+		panic(fmt.Sprintf("redis: codec %q is already registered", name))
+	}
+	if _, ok := codecsByVersion[codec.Version()]; ok {
+		panic(fmt.Sprintf("redis: codec version %d is already registered", codec.Version()))
+	}
+	codecs[name] = codec
+	codecsByVersion[codec.Version()] = codec
+}
+
+// getCodec returns the codec registered with the name
+func getCodec(name string) (Codec, error) {
+	codecMux.RLock()
+	defer codecMux.RUnlock()
+
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid codec: %v", name)
+	}
+	return codec, nil
+}
+
+// encode encodes the value using the codec, prefixed with the codec's version byte
+func encode(codec Codec, v interface{}) ([]byte, error) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{codec.Version()}, data...), nil
+}
+
+// decode decodes the data using the codec of its version byte
+func decode(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return ErrEmptyObject
+	}
+
+	codecMux.RLock()
+	codec, ok := codecsByVersion[data[0]]
+	codecMux.RUnlock()
+	if !ok {
+		return ErrUnknownCodec
+	}
+	return codec.Unmarshal(data[1:], v)
+}
+
+// JSONCodec encodes the value using encoding/json
+type JSONCodec struct{}
+
+// Version of the codec
+func (JSONCodec) Version() byte {
+	return JSONCodecVersion
+}
+
+// Marshal encodes the value
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes the data into the value pointed by v
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// MsgpackCodec encodes the value using MessagePack, which is more compact & faster than JSON
+type MsgpackCodec struct{}
+
+// Version of the codec
+func (MsgpackCodec) Version() byte {
+	return MsgpackCodecVersion
+}
+
+// Marshal encodes the value
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal decodes the data into the value pointed by v
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// GobCodec encodes the value using encoding/gob.
+// The concrete types stored in interface values must be registered using gob.Register
+type GobCodec struct{}
+
+// Version of the codec
+func (GobCodec) Version() byte {
+	return GobCodecVersion
+}
+
+// Marshal encodes the value
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the data into the value pointed by v
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
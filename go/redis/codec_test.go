@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	type object struct {
+		Name  string
+		Count int
+		Attrs map[string]string
+	}
+	obj := object{Name: "foo", Count: 2, Attrs: map[string]string{"k": "v"}}
+
+	for _, name := range []string{"json", "msgpack", "gob"} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := getCodec(name)
+			require.NoError(t, err)
+
+			data, err := encode(codec, obj)
+			require.NoError(t, err)
+			require.Equal(t, codec.Version(), data[0])
+
+			var got object
+			require.NoError(t, decode(data, &got))
+			require.Equal(t, obj, got)
+		})
+	}
+
+	_, err := getCodec("xml")
+	require.Error(t, err)
+
+	var got object
+	require.Equal(t, ErrEmptyObject, decode(nil, &got))
+	require.Equal(t, ErrUnknownCodec, decode([]byte{0, '{', '}'}, &got))
+}
+
+func TestRegisterCodec(t *testing.T) {
+	require.Panics(t, func() { RegisterCodec("json", JSONCodec{}) })
+	require.Panics(t, func() { RegisterCodec("json2", JSONCodec{}) })
+
+	_, err := New(Config{Address: "127.0.0.1:0", Codec: "xml", NoPingOnCreate: true})
+	require.EqualError(t, err, "invalid codec: xml")
+}
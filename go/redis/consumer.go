@@ -0,0 +1,401 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/defaults"
+	"github.com/boxofimagination/bxdk/go/grace"
+	"github.com/boxofimagination/bxdk/go/log"
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+var (
+	// ErrNilHandler returned when `NewConsumer` called with nil handler
+	ErrNilHandler = errors.New("empty handler")
+
+	// ErrInvalidConsumerConfig returned when the stream, group, or consumer name is empty
+	ErrInvalidConsumerConfig = errors.New("stream, group, and consumer must not be empty")
+)
+
+const (
+	// sleep duration after the failed read, so we don't flood the server when it is down
+	consumerRetryInterval = time.Second
+)
+
+// ConsumerConfig defines configuration of the stream consumer
+type ConsumerConfig struct {
+	Stream string
+	Group  string
+
+	// Consumer is name of the consumer in the group, it must be unique per process.
+	// Use stable name (e.g. the hostname), so the process reclaims its own pending messages after restart
+	Consumer string
+
+	// StartID is the ID the group starts reading from when the group is created,
+	// "$" only reads the new messages and "0" reads the whole stream
+	StartID string `default:"$"`
+
+	// Workers is number of the goroutines executing the handler
+	Workers int `default:"1"`
+
+	// BatchSize is maximum number of the messages read & reclaimed at once
+	BatchSize int64 `default:"10"`
+
+	// Block is the maximum duration of each blocking read
+	Block time.Duration `default:"5s"`
+
+	// MinIdle is the idle duration of the pending message before it is reclaimed from its consumer.
+	// It should be longer than the handler's maximum processing time
+	MinIdle time.Duration `default:"1m"`
+
+	// ReclaimInterval is the interval of checking the stale pending messages
+	ReclaimInterval time.Duration `default:"30s"`
+
+	// MaxDeliveries is maximum number of times a message is delivered.
+	// The stale pending message which is already delivered MaxDeliveries times is not reclaimed anymore,
+	// it is added to DeadLetterStream, or only logged if it is empty, then acked.
+	// Zero means no limit
+	MaxDeliveries int64
+
+	// DeadLetterStream is the stream of the messages which exceeded MaxDeliveries, with their original values
+	DeadLetterStream string
+}
+
+// MessageHandler handles a stream message.
+// The message is acked if it returns nil, otherwise it stays pending and will be reclaimed after MinIdle,
+// until it is delivered MaxDeliveries times.
+type MessageHandler func(ctx context.Context, msg engine.XMessage) error
+
+// Consumer reads the stream as a member of a consumer group and passes the messages to its workers
+type Consumer struct {
+	cli     Redis
+	cfg     ConsumerConfig
+	handler MessageHandler
+
+	msgCh  chan engine.XMessage
+	stopCh chan struct{}
+	doneCh chan struct{}
+
+	// ctx of the reads, it is cancelled on shutdown to unblock the blocking read
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	readWg    sync.WaitGroup
+	workerWg  sync.WaitGroup
+}
+
+// NewConsumer creates stream consumer from the given config
+func NewConsumer(cli Redis, cfg ConsumerConfig, handler MessageHandler) (*Consumer, error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	if cfg.Stream == "" || cfg.Group == "" || cfg.Consumer == "" {
+		return nil, ErrInvalidConsumerConfig
+	}
+	if err := defaults.SetDefault(&cfg); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Consumer{
+		cli:     cli,
+		cfg:     cfg,
+		handler: handler,
+		msgCh:   make(chan engine.XMessage),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Run starts the consumer and blocks until it is stopped,
+// either by the termination signals or by calling `Shutdown`
+func (c *Consumer) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	stoppedCh := grace.WaitTermSig(c.Shutdown)
+	select {
+	case <-stoppedCh:
+	case <-c.doneCh:
+	}
+	return nil
+}
+
+// Start creates the group if it doesn't exist, and starts the consumer in background.
+// Use `Shutdown` to stop it
+func (c *Consumer) Start() error {
+	err := c.cli.XGroupCreateContext(c.ctx, c.cfg.Stream, c.cfg.Group, c.cfg.StartID, true)
+	if err != nil && !engine.IsErrBusyGroup(err) {
+		return err
+	}
+
+	c.startOnce.Do(func() {
+		for i := 0; i < c.cfg.Workers; i++ {
+			c.workerWg.Add(1)
+			go c.work()
+		}
+
+		c.readWg.Add(2)
+		go c.read()
+		go c.reclaim()
+
+		go func() {
+			c.readWg.Wait()
+			close(c.msgCh)
+			c.workerWg.Wait()
+			close(c.doneCh)
+		}()
+	})
+	return nil
+}
+
+// Shutdown stops reading the stream and waits for the in-flight messages to be handled.
+// The messages which are not handled yet stay pending and will be reclaimed later.
+// It returns ctx.Err() if the ctx is done before the handlers finished
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	c.stopOnce.Do(func() {
+		close(c.stopCh)
+		c.cancel()
+	})
+
+	// make sure that doneCh is closed even if the consumer is never started
+	c.startOnce.Do(func() {
+		close(c.msgCh)
+		close(c.doneCh)
+	})
+
+	select {
+	case <-c.doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// read reads the new messages of the group
+func (c *Consumer) read() {
+	defer c.readWg.Done()
+
+	args := engine.XReadGroupArgs{
+		Group:    c.cfg.Group,
+		Consumer: c.cfg.Consumer,
+		Streams:  []string{c.cfg.Stream},
+		IDs:      []string{">"},
+		Count:    c.cfg.BatchSize,
+		Block:    c.cfg.Block,
+	}
+	for !c.stopped() {
+		streams, err := c.cli.XReadGroupContext(c.ctx, args)
+		if err != nil {
+			if c.stopped() {
+				return
+			}
+			log.Errorf("redis consumer: failed to read stream %v: %v", c.cfg.Stream, err)
+			c.sleep(consumerRetryInterval)
+			continue
+		}
+
+		for _, stream := range streams {
+			if !c.dispatch(stream.Messages) {
+				return
+			}
+		}
+	}
+}
+
+// reclaim periodically claims the messages which are pending too long,
+// e.g. their consumer died before acking them
+func (c *Consumer) reclaim() {
+	defer c.readWg.Done()
+
+	ticker := time.NewTicker(c.cfg.ReclaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.reclaimOnce(); err != nil && !c.stopped() {
+			log.Errorf("redis consumer: failed to reclaim stream %v: %v", c.cfg.Stream, err)
+		}
+	}
+}
+
+// reclaimOnce claims and dispatches the stale pending messages, one batch at a time,
+// until all of the pending messages are checked
+func (c *Consumer) reclaimOnce() error {
+	start := "-"
+	for !c.stopped() {
+		pendings, err := c.cli.XPendingExtContext(c.ctx, engine.XPendingExtArgs{
+			Stream: c.cfg.Stream,
+			Group:  c.cfg.Group,
+			Start:  start,
+			End:    "+",
+			Count:  c.cfg.BatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		var ids, deadIDs []string
+		for _, p := range pendings {
+			switch {
+			case p.Idle < c.cfg.MinIdle:
+			case c.cfg.MaxDeliveries > 0 && p.RetryCount >= c.cfg.MaxDeliveries:
+				deadIDs = append(deadIDs, p.ID)
+			default:
+				ids = append(ids, p.ID)
+			}
+		}
+
+		if len(deadIDs) > 0 {
+			if err = c.deadLetter(deadIDs); err != nil {
+				return err
+			}
+		}
+
+		if len(ids) > 0 {
+			messages, err := c.claim(ids)
+			if err != nil {
+				return err
+			}
+			if !c.dispatch(messages) {
+				return nil
+			}
+		}
+
+		if int64(len(pendings)) < c.cfg.BatchSize {
+			return nil
+		}
+		var ok bool
+		if start, ok = nextStreamID(pendings[len(pendings)-1].ID); !ok {
+			return fmt.Errorf("invalid stream ID %v", pendings[len(pendings)-1].ID)
+		}
+	}
+	return nil
+}
+
+// claim claims the stale pending messages to this consumer.
+// XCLAIM checks the idle time again, so the message handled by the other consumer in the meantime is not claimed
+func (c *Consumer) claim(ids []string) ([]engine.XMessage, error) {
+	return c.cli.XClaimContext(c.ctx, engine.XClaimArgs{
+		Stream:   c.cfg.Stream,
+		Group:    c.cfg.Group,
+		Consumer: c.cfg.Consumer,
+		MinIdle:  c.cfg.MinIdle,
+		IDs:      ids,
+	})
+}
+
+// deadLetter gives up the messages which exceeded MaxDeliveries,
+// they are added to DeadLetterStream if it is set, and acked
+func (c *Consumer) deadLetter(ids []string) error {
+	// claim them first, to get their values & to make sure the other consumer is not handling them
+	messages, err := c.claim(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range messages {
+		if c.cfg.DeadLetterStream != "" && msg.Values != nil {
+			values := make(map[string]interface{}, len(msg.Values))
+			for k, v := range msg.Values {
+				values[k] = v
+			}
+			_, err = c.cli.XAddContext(c.ctx, engine.XAddArgs{Stream: c.cfg.DeadLetterStream, Values: values})
+			if err != nil {
+				return err
+			}
+		}
+
+		log.Errorf("redis consumer: giving up message %v of stream %v after %v deliveries", msg.ID, c.cfg.Stream, c.cfg.MaxDeliveries)
+		if _, err = c.cli.XAckContext(c.ctx, c.cfg.Stream, c.cfg.Group, msg.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatch passes the messages to the workers, it returns false if the consumer is stopped
+func (c *Consumer) dispatch(messages []engine.XMessage) bool {
+	for _, msg := range messages {
+		select {
+		case c.msgCh <- msg:
+		case <-c.stopCh:
+			return false
+		}
+	}
+	return true
+}
+
+// work handles the messages and acks the succeed ones
+func (c *Consumer) work() {
+	defer c.workerWg.Done()
+
+	for msg := range c.msgCh {
+		// the entry is deleted from the stream while it is pending, nothing to handle
+		if msg.Values != nil {
+			if err := c.handler(context.Background(), msg); err != nil {
+				log.Errorf("redis consumer: failed to handle message %v of stream %v: %v", msg.ID, c.cfg.Stream, err)
+				continue
+			}
+		}
+
+		// don't use c.ctx, the message should be acked although the consumer is stopping
+		if _, err := c.cli.XAck(c.cfg.Stream, c.cfg.Group, msg.ID); err != nil {
+			log.Errorf("redis consumer: failed to ack message %v of stream %v: %v", msg.ID, c.cfg.Stream, err)
+		}
+	}
+}
+
+func (c *Consumer) stopped() bool {
+	select {
+	case <-c.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep sleeps for the duration or until the consumer is stopped
+func (c *Consumer) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-c.stopCh:
+	}
+}
+
+// nextStreamID returns the smallest stream ID after the given one, or false if the ID is invalid
+func nextStreamID(id string) (string, bool) {
+	idx := strings.IndexByte(id, '-')
+	if idx < 0 {
+		return "", false
+	}
+	ms, err1 := strconv.ParseUint(id[:idx], 10, 64)
+	seq, err2 := strconv.ParseUint(id[idx+1:], 10, 64)
+	if err1 != nil || err2 != nil {
+		return "", false
+	}
+	if seq == math.MaxUint64 {
+		return strconv.FormatUint(ms+1, 10) + "-0", true
+	}
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq+1, 10), true
+}
@@ -0,0 +1,312 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// fakeStream implements the stream commands used by the consumer,
+// miniredis doesn't support streams yet
+type fakeStream struct {
+	Redis
+
+	mux      sync.Mutex
+	groups   int
+	messages []engine.XMessage
+	pending  map[string]engine.XPendingExt
+	acked    []string
+	added    []engine.XAddArgs
+}
+
+func newFakeStream(messages ...engine.XMessage) *fakeStream {
+	return &fakeStream{
+		messages: messages,
+		pending:  make(map[string]engine.XPendingExt),
+	}
+}
+
+func (f *fakeStream) XGroupCreateContext(ctx context.Context, stream, group, start string, mkStream bool) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.groups++
+	if f.groups > 1 {
+		return errors.New("BUSYGROUP Consumer Group name already exists")
+	}
+	return nil
+}
+
+func (f *fakeStream) XReadGroupContext(ctx context.Context, args engine.XReadGroupArgs) ([]engine.XStream, error) {
+	f.mux.Lock()
+	if len(f.messages) > 0 {
+		defer f.mux.Unlock()
+
+		msg := f.messages[0]
+		f.messages = f.messages[1:]
+		f.pending[msg.ID] = engine.XPendingExt{ID: msg.ID, Consumer: args.Consumer}
+		return []engine.XStream{{Stream: args.Streams[0], Messages: []engine.XMessage{msg}}}, nil
+	}
+	f.mux.Unlock()
+
+	select {
+	case <-time.After(args.Block):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeStream) XAck(stream, group string, ids ...string) (int64, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for _, id := range ids {
+		delete(f.pending, id)
+		f.acked = append(f.acked, id)
+	}
+	return int64(len(ids)), nil
+}
+
+func (f *fakeStream) XAckContext(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	return f.XAck(stream, group, ids...)
+}
+
+func (f *fakeStream) XAddContext(ctx context.Context, args engine.XAddArgs) (string, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.added = append(f.added, args)
+	return "0-1", nil
+}
+
+func (f *fakeStream) XPendingExtContext(ctx context.Context, args engine.XPendingExtArgs) ([]engine.XPendingExt, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	var pendings []engine.XPendingExt
+	for _, p := range f.pending {
+		if args.Start == "-" || !lessID(p.ID, args.Start) {
+			pendings = append(pendings, p)
+		}
+	}
+	sort.Slice(pendings, func(i, j int) bool {
+		return lessID(pendings[i].ID, pendings[j].ID)
+	})
+	if int64(len(pendings)) > args.Count {
+		pendings = pendings[:args.Count]
+	}
+	return pendings, nil
+}
+
+func (f *fakeStream) XClaimContext(ctx context.Context, args engine.XClaimArgs) ([]engine.XMessage, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	var messages []engine.XMessage
+	for _, id := range args.IDs {
+		p, ok := f.pending[id]
+		if !ok || p.Idle < args.MinIdle {
+			continue
+		}
+
+		p.Consumer, p.Idle = args.Consumer, 0
+		p.RetryCount++
+		f.pending[id] = p
+		messages = append(messages, engine.XMessage{ID: id, Values: map[string]string{"reclaimed": "1"}})
+	}
+	return messages, nil
+}
+
+// lessID returns true if the stream ID a is less than b
+func lessID(a, b string) bool {
+	parse := func(id string) (ms, seq uint64) {
+		parts := strings.SplitN(id, "-", 2)
+		ms, _ = strconv.ParseUint(parts[0], 10, 64)
+		seq, _ = strconv.ParseUint(parts[1], 10, 64)
+		return ms, seq
+	}
+	aMs, aSeq := parse(a)
+	bMs, bSeq := parse(b)
+	return aMs < bMs || (aMs == bMs && aSeq < bSeq)
+}
+
+func (f *fakeStream) addPending(id, consumer string, idle time.Duration) {
+	f.addDelivered(id, consumer, idle, 1)
+}
+
+func (f *fakeStream) addDelivered(id, consumer string, idle time.Duration, deliveries int64) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.pending[id] = engine.XPendingExt{ID: id, Consumer: consumer, Idle: idle, RetryCount: deliveries}
+}
+
+func (f *fakeStream) getAcked() []string {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return append([]string{}, f.acked...)
+}
+
+func TestNewConsumerInvalid(t *testing.T) {
+	handler := func(ctx context.Context, msg engine.XMessage) error { return nil }
+
+	_, err := NewConsumer(newFakeStream(), ConsumerConfig{Stream: "s", Group: "g", Consumer: "c"}, nil)
+	require.Equal(t, ErrNilHandler, err)
+
+	_, err = NewConsumer(newFakeStream(), ConsumerConfig{Stream: "s", Group: "g"}, handler)
+	require.Equal(t, ErrInvalidConsumerConfig, err)
+}
+
+func TestConsumer(t *testing.T) {
+	fake := newFakeStream(
+		engine.XMessage{ID: "1-0", Values: map[string]string{"k": "1"}},
+		engine.XMessage{ID: "2-0", Values: map[string]string{"k": "fail"}},
+		engine.XMessage{ID: "3-0", Values: map[string]string{"k": "3"}},
+	)
+	// pending message of a dead consumer
+	fake.addPending("0-1", "dead", time.Hour)
+	// pending message which is still being handled by the other consumer
+	fake.addPending("0-2", "alive", time.Millisecond)
+
+	handled := make(chan string, 10)
+	cons, err := NewConsumer(fake, ConsumerConfig{
+		Stream:          "s",
+		Group:           "g",
+		Consumer:        "c",
+		Workers:         2,
+		Block:           10 * time.Millisecond,
+		ReclaimInterval: 10 * time.Millisecond,
+	}, func(ctx context.Context, msg engine.XMessage) error {
+		handled <- msg.ID
+		if msg.Values["k"] == "fail" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the group already exists
+	require.NoError(t, fake.XGroupCreateContext(context.Background(), "s", "g", "$", true))
+	require.NoError(t, cons.Start())
+
+	got := make(map[string]bool)
+	for len(got) < 4 {
+		select {
+		case id := <-handled:
+			got[id] = true
+		case <-time.After(time.Second):
+			t.Fatalf("messages are not handled, got: %v", got)
+		}
+	}
+	require.Equal(t, map[string]bool{"0-1": true, "1-0": true, "2-0": true, "3-0": true}, got)
+
+	require.NoError(t, cons.Shutdown(context.Background()))
+	require.ElementsMatch(t, []string{"0-1", "1-0", "3-0"}, fake.getAcked())
+
+	// shutdown twice is fine
+	require.NoError(t, cons.Shutdown(context.Background()))
+}
+
+func TestConsumerShutdownTimeout(t *testing.T) {
+	fake := newFakeStream(engine.XMessage{ID: "1-0", Values: map[string]string{"k": "1"}})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cons, err := NewConsumer(fake, ConsumerConfig{
+		Stream:   "s",
+		Group:    "g",
+		Consumer: "c",
+		Block:    10 * time.Millisecond,
+	}, func(ctx context.Context, msg engine.XMessage) error {
+		close(started)
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, cons.Start())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, cons.Shutdown(ctx))
+
+	// the in-flight message is still acked
+	close(release)
+	require.NoError(t, cons.Shutdown(context.Background()))
+	require.Equal(t, []string{"1-0"}, fake.getAcked())
+}
+
+func TestConsumerReclaim(t *testing.T) {
+	fake := newFakeStream()
+	// more pending messages than the batch, the first ones are still handled by the other consumer
+	for i := 0; i < 25; i++ {
+		idle := time.Hour
+		if i < 10 {
+			idle = time.Millisecond
+		}
+		fake.addPending(fmt.Sprintf("1-%d", i), "dead", idle)
+	}
+	// the message which keeps failing
+	fake.addDelivered("2-0", "dead", time.Hour, 3)
+
+	var (
+		mux     sync.Mutex
+		handled []string
+	)
+	cons, err := NewConsumer(fake, ConsumerConfig{
+		Stream:           "s",
+		Group:            "g",
+		Consumer:         "c",
+		BatchSize:        10,
+		Block:            10 * time.Millisecond,
+		ReclaimInterval:  time.Hour,
+		MaxDeliveries:    3,
+		DeadLetterStream: "dead-s",
+	}, func(ctx context.Context, msg engine.XMessage) error {
+		mux.Lock()
+		handled = append(handled, msg.ID)
+		mux.Unlock()
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, cons.Start())
+
+	// all of the stale messages are reclaimed at once
+	require.NoError(t, cons.reclaimOnce())
+	require.NoError(t, cons.Shutdown(context.Background()))
+
+	var want []string
+	for i := 10; i < 25; i++ {
+		want = append(want, fmt.Sprintf("1-%d", i))
+	}
+	require.ElementsMatch(t, want, handled)
+
+	// the message exceeding the max deliveries is moved to the dead letter stream
+	require.Equal(t, []engine.XAddArgs{{Stream: "dead-s", Values: map[string]interface{}{"reclaimed": "1"}}}, fake.added)
+	require.ElementsMatch(t, append(want, "2-0"), fake.getAcked())
+}
+
+func TestNextStreamID(t *testing.T) {
+	for id, want := range map[string]string{
+		"1-0":                    "1-1",
+		"1526985054069-9":        "1526985054069-10",
+		"1-18446744073709551615": "2-0",
+	} {
+		next, ok := nextStreamID(id)
+		require.True(t, ok)
+		require.Equal(t, want, next)
+	}
+
+	_, ok := nextStreamID("invalid")
+	require.False(t, ok)
+}
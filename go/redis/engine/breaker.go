@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen returned without executing the command when the circuit breaker is open,
+// e.g. so the caller could fall back to the database
+var ErrCircuitOpen = errors.New("redis: circuit breaker is open")
+
+const (
+	defaultCircuitBreakerConsecutiveFailures = 5
+	defaultCircuitBreakerErrorRate           = 0.5
+	defaultCircuitBreakerMinRequests         = 20
+	defaultCircuitBreakerWindow              = 10 * time.Second
+	defaultCircuitBreakerOpenInterval        = 5 * time.Second
+	defaultCircuitBreakerHalfOpenProbes      = 1
+)
+
+// CircuitState is state of the circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed executes the commands normally
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails the commands fast with ErrCircuitOpen
+	CircuitOpen
+
+	// CircuitHalfOpen executes a few probe commands, the other commands fail fast.
+	// The circuit is closed if all of the probes succeed, and opened again if one of them fails
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops executing the commands for a while when the server looks down,
+// so the callers don't wait for the connection until `PoolWaitMs` on every command.
+//
+// The circuit is opened when:
+//   - the commands failed `CircuitBreakerConsecutiveFailures` times in a row, or
+//   - at least `CircuitBreakerErrorRate` of the commands failed in the current window of `CircuitBreakerWindowMs`,
+//     and the window has at least `CircuitBreakerMinRequests` commands.
+//
+// After `CircuitBreakerOpenMs`, it is half-open and lets `CircuitBreakerHalfOpenProbes` commands through.
+//
+// The nil *CircuitBreaker is always closed.
+type CircuitBreaker struct {
+	consecutiveFailures int
+	errorRate           float64
+	minRequests         int
+	window              time.Duration
+	openInterval        time.Duration
+	halfOpenProbes      int
+
+	isFailure func(err error) bool // true if the err means the server is unhealthy
+	now       func() time.Time
+
+	mux         sync.Mutex
+	state       CircuitState
+	generation  uint64    // incremented on each state change, the results of the older generations are ignored
+	openedAt    time.Time // when the circuit was opened
+	consecutive int       // consecutive failures
+	windowStart time.Time // start of the current window
+	requests    int       // requests in the current window
+	failures    int       // failures in the current window
+	probes      int       // probes started in the half-open state
+	succeeded   int       // probes succeeded in the half-open state
+}
+
+// NewCircuitBreaker creates the circuit breaker of the config, it returns nil if `CircuitBreaker` is disabled.
+// The isFailure func returns true if the err means that the server is unhealthy,
+// e.g. the connection error, but not the error replied by the server.
+func NewCircuitBreaker(cfg Config, isFailure func(err error) bool) *CircuitBreaker {
+	if !cfg.CircuitBreaker {
+		return nil
+	}
+
+	b := &CircuitBreaker{
+		consecutiveFailures: cfg.CircuitBreakerConsecutiveFailures,
+		errorRate:           cfg.CircuitBreakerErrorRate,
+		minRequests:         cfg.CircuitBreakerMinRequests,
+		window:              time.Duration(cfg.CircuitBreakerWindowMs) * time.Millisecond,
+		openInterval:        time.Duration(cfg.CircuitBreakerOpenMs) * time.Millisecond,
+		halfOpenProbes:      cfg.CircuitBreakerHalfOpenProbes,
+		isFailure:           isFailure,
+		now:                 time.Now,
+	}
+	// zero means the default, the negative value disables the threshold & it is checked with `> 0`
+	if b.consecutiveFailures == 0 {
+		b.consecutiveFailures = defaultCircuitBreakerConsecutiveFailures
+	}
+	if b.errorRate == 0 {
+		b.errorRate = defaultCircuitBreakerErrorRate
+	}
+	if b.minRequests <= 0 {
+		b.minRequests = defaultCircuitBreakerMinRequests
+	}
+	if b.window <= 0 {
+		b.window = defaultCircuitBreakerWindow
+	}
+	if b.openInterval <= 0 {
+		b.openInterval = defaultCircuitBreakerOpenInterval
+	}
+	if b.halfOpenProbes <= 0 {
+		b.halfOpenProbes = defaultCircuitBreakerHalfOpenProbes
+	}
+	b.windowStart = b.now()
+	return b
+}
+
+// State returns the current state of the circuit
+func (b *CircuitBreaker) State() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.refresh(b.now())
+	return b.state
+}
+
+// Do calls fn, which executes the command(s), if the circuit allows it.
+// It returns ErrCircuitOpen without calling fn otherwise.
+func (b *CircuitBreaker) Do(fn func() error) error {
+	if b == nil {
+		return fn()
+	}
+
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	b.done(generation, err)
+	return err
+}
+
+// allow returns the current generation if the command could be executed
+func (b *CircuitBreaker) allow() (uint64, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.refresh(b.now())
+	switch b.state {
+	case CircuitOpen:
+		return 0, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probes >= b.halfOpenProbes {
+			return 0, ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return b.generation, nil
+}
+
+// done records result of the command executed in the given generation
+func (b *CircuitBreaker) done(generation uint64, err error) {
+	failed := err != nil && b.isFailure(err)
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if generation != b.generation {
+		// the state has changed while the command was executed
+		return
+	}
+
+	now := b.now()
+	if b.state == CircuitHalfOpen {
+		if failed {
+			b.setState(CircuitOpen, now)
+			return
+		}
+		b.succeeded++
+		if b.succeeded >= b.halfOpenProbes {
+			b.setState(CircuitClosed, now)
+		}
+		return
+	}
+
+	b.refresh(now)
+	b.requests++
+	if !failed {
+		b.consecutive = 0
+		return
+	}
+	b.failures++
+	b.consecutive++
+
+	if b.consecutiveFailures > 0 && b.consecutive >= b.consecutiveFailures {
+		b.setState(CircuitOpen, now)
+		return
+	}
+	if b.errorRate > 0 && b.requests >= b.minRequests &&
+		float64(b.failures) >= b.errorRate*float64(b.requests) {
+		b.setState(CircuitOpen, now)
+	}
+}
+
+// refresh moves the open circuit to half-open after the open interval,
+// and starts new window of the closed circuit after the window ends
+func (b *CircuitBreaker) refresh(now time.Time) {
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) >= b.openInterval {
+			b.setState(CircuitHalfOpen, now)
+		}
+	case CircuitClosed:
+		if now.Sub(b.windowStart) >= b.window {
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+	}
+}
+
+func (b *CircuitBreaker) setState(state CircuitState, now time.Time) {
+	b.state = state
+	b.generation++
+	b.consecutive = 0
+	b.probes, b.succeeded = 0, 0
+	b.windowStart = now
+	b.requests, b.failures = 0, 0
+	if state == CircuitOpen {
+		b.openedAt = now
+	}
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/defaults"
+)
+
+var errTestFailure = errors.New("connection refused")
+
+func newTestBreaker(cfg Config) (*CircuitBreaker, *time.Time) {
+	cfg.CircuitBreaker = true
+	b := NewCircuitBreaker(cfg, func(err error) bool {
+		return err == errTestFailure
+	})
+
+	now := time.Unix(1000, 0)
+	b.now = func() time.Time { return now }
+	b.windowStart = now
+	return b, &now
+}
+
+func succeed() error { return nil }
+func fail() error    { return errTestFailure }
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := NewCircuitBreaker(Config{}, nil)
+	require.Nil(t, b)
+	require.Equal(t, CircuitClosed, b.State())
+	require.Equal(t, errTestFailure, b.Do(fail))
+}
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	b, now := newTestBreaker(Config{
+		CircuitBreakerConsecutiveFailures: 3,
+		CircuitBreakerOpenMs:              1000,
+	})
+
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.NoError(t, b.Do(succeed)) // resets the consecutive failures
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.Equal(t, errTestFailure, b.Do(fail))
+
+	// the error which isn't failure, e.g. replied by the server, resets them too
+	require.Equal(t, ErrNotOK, b.Do(func() error { return ErrNotOK }))
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.Equal(t, CircuitClosed, b.State())
+
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.Equal(t, CircuitOpen, b.State())
+
+	called := false
+	require.Equal(t, ErrCircuitOpen, b.Do(func() error {
+		called = true
+		return nil
+	}))
+	require.False(t, called)
+
+	*now = now.Add(time.Second)
+	require.Equal(t, CircuitHalfOpen, b.State())
+
+	// the failed probe opens the circuit again
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.Equal(t, CircuitOpen, b.State())
+
+	*now = now.Add(time.Second)
+	require.NoError(t, b.Do(succeed))
+	require.Equal(t, CircuitClosed, b.State())
+}
+
+func TestCircuitBreakerErrorRate(t *testing.T) {
+	b, now := newTestBreaker(Config{
+		CircuitBreakerErrorRate:   0.5,
+		CircuitBreakerMinRequests: 4,
+		CircuitBreakerWindowMs:    1000,
+	})
+
+	// not enough requests
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.NoError(t, b.Do(succeed))
+	require.Equal(t, CircuitClosed, b.State())
+
+	// new window
+	*now = now.Add(time.Second)
+	require.NoError(t, b.Do(succeed))
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.NoError(t, b.Do(succeed))
+	require.Equal(t, CircuitClosed, b.State())
+
+	require.Equal(t, errTestFailure, b.Do(fail))
+	require.Equal(t, CircuitOpen, b.State())
+}
+
+func TestCircuitBreakerDisabledThreshold(t *testing.T) {
+	// the defaults of the config don't replace the disabled threshold
+	cfg := Config{CircuitBreakerConsecutiveFailures: -1, CircuitBreakerMinRequests: 100}
+	require.NoError(t, defaults.SetDefault(&cfg))
+	b, _ := newTestBreaker(cfg)
+	require.Equal(t, defaultCircuitBreakerErrorRate, b.errorRate)
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, errTestFailure, b.Do(fail))
+	}
+	require.Equal(t, CircuitClosed, b.State())
+
+	cfg = Config{CircuitBreakerErrorRate: -1}
+	require.NoError(t, defaults.SetDefault(&cfg))
+	b, _ = newTestBreaker(cfg)
+	require.Equal(t, defaultCircuitBreakerConsecutiveFailures, b.consecutiveFailures)
+
+	// the error rate doesn't open the circuit, only the consecutive failures do
+	for i := 0; i < 10; i++ {
+		require.Equal(t, errTestFailure, b.Do(fail))
+		require.Equal(t, errTestFailure, b.Do(fail))
+		require.NoError(t, b.Do(succeed))
+	}
+	require.Equal(t, CircuitClosed, b.State())
+
+	for i := 0; i < defaultCircuitBreakerConsecutiveFailures; i++ {
+		require.Equal(t, errTestFailure, b.Do(fail))
+	}
+	require.Equal(t, CircuitOpen, b.State())
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	b, now := newTestBreaker(Config{
+		CircuitBreakerConsecutiveFailures: 1,
+		CircuitBreakerOpenMs:              1000,
+		CircuitBreakerHalfOpenProbes:      2,
+	})
+
+	require.Equal(t, errTestFailure, b.Do(fail))
+	*now = now.Add(time.Second)
+
+	// only 2 probes are let through at once
+	err := b.Do(func() error {
+		require.Equal(t, CircuitHalfOpen, b.State())
+		return b.Do(func() error {
+			require.Equal(t, ErrCircuitOpen, b.Do(succeed))
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, CircuitClosed, b.State())
+}
@@ -0,0 +1,454 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// commandInfo describes position of the keys in the command arguments.
+// The positions are index of the arguments, excluding the command name.
+type commandInfo struct {
+	// index of the first key, negative if the command has no key
+	firstKey int
+
+	// index of the last key.
+	// negative value is counted from the last argument, -1 is the last argument.
+	lastKey int
+
+	// step between the keys, e.g. 2 for MSET
+	step int
+
+	// keysFn returns the key indexes, for the commands which keys position
+	// depends on the other arguments, e.g. EVAL
+	keysFn func(args []interface{}) []int
+}
+
+var (
+	// command which has single key in its first argument
+	singleKeyCmd = commandInfo{firstKey: 0, lastKey: 0, step: 1}
+
+	// command which has two keys in its first two arguments, e.g. the source & destination of RENAME
+	twoKeysCmd = commandInfo{firstKey: 0, lastKey: 1, step: 1}
+
+	// command which all of its arguments are keys
+	allKeysCmd = commandInfo{firstKey: 0, lastKey: -1, step: 1}
+
+	// command which has no key.
+	// it is also the default info of the unknown commands.
+	noKeyCmd = commandInfo{firstKey: -1}
+)
+
+// commands is information of the known commands, by name.
+// The unknown commands are considered as command without key, see IsKnownCommand.
+var commands = map[string]commandInfo{
+	// keyless commands
+	"PING":         noKeyCmd,
+	"ECHO":         noKeyCmd,
+	"INFO":         noKeyCmd,
+	"TIME":         noKeyCmd,
+	"DBSIZE":       noKeyCmd,
+	"FLUSHDB":      noKeyCmd,
+	"FLUSHALL":     noKeyCmd,
+	"SCAN":         noKeyCmd,
+	"KEYS":         noKeyCmd,
+	"RANDOMKEY":    noKeyCmd,
+	"CLUSTER":      noKeyCmd,
+	"SCRIPT":       noKeyCmd,
+	"FUNCTION":     noKeyCmd,
+	"CLIENT":       noKeyCmd,
+	"CONFIG":       noKeyCmd,
+	"COMMAND":      noKeyCmd,
+	"PUBLISH":      noKeyCmd,
+	"PUBSUB":       noKeyCmd,
+	"AUTH":         noKeyCmd,
+	"HELLO":        noKeyCmd,
+	"SELECT":       noKeyCmd,
+	"SWAPDB":       noKeyCmd,
+	"ASKING":       noKeyCmd,
+	"READONLY":     noKeyCmd,
+	"READWRITE":    noKeyCmd,
+	"MULTI":        noKeyCmd,
+	"EXEC":         noKeyCmd,
+	"DISCARD":      noKeyCmd,
+	"UNWATCH":      noKeyCmd,
+	"WAIT":         noKeyCmd,
+	"SLOWLOG":      noKeyCmd,
+	"LATENCY":      noKeyCmd,
+	"ROLE":         noKeyCmd,
+	"LASTSAVE":     noKeyCmd,
+	"SAVE":         noKeyCmd,
+	"BGSAVE":       noKeyCmd,
+	"BGREWRITEAOF": noKeyCmd,
+	"SENTINEL":     noKeyCmd,
+	"SUBSCRIBE":    noKeyCmd,
+	"UNSUBSCRIBE":  noKeyCmd,
+	"PSUBSCRIBE":   noKeyCmd,
+	"PUNSUBSCRIBE": noKeyCmd,
+
+	// single key commands
+	"GET":              singleKeyCmd,
+	"SET":              singleKeyCmd,
+	"SETNX":            singleKeyCmd,
+	"SETEX":            singleKeyCmd,
+	"PSETEX":           singleKeyCmd,
+	"GETSET":           singleKeyCmd,
+	"GETDEL":           singleKeyCmd,
+	"GETEX":            singleKeyCmd,
+	"APPEND":           singleKeyCmd,
+	"STRLEN":           singleKeyCmd,
+	"INCR":             singleKeyCmd,
+	"INCRBY":           singleKeyCmd,
+	"INCRBYFLOAT":      singleKeyCmd,
+	"DECR":             singleKeyCmd,
+	"DECRBY":           singleKeyCmd,
+	"GETRANGE":         singleKeyCmd,
+	"SETRANGE":         singleKeyCmd,
+	"GETBIT":           singleKeyCmd,
+	"SETBIT":           singleKeyCmd,
+	"BITCOUNT":         singleKeyCmd,
+	"BITPOS":           singleKeyCmd,
+	"BITFIELD":         singleKeyCmd,
+	"EXPIRE":           singleKeyCmd,
+	"PEXPIRE":          singleKeyCmd,
+	"EXPIREAT":         singleKeyCmd,
+	"PEXPIREAT":        singleKeyCmd,
+	"TTL":              singleKeyCmd,
+	"PTTL":             singleKeyCmd,
+	"PERSIST":          singleKeyCmd,
+	"TYPE":             singleKeyCmd,
+	"DUMP":             singleKeyCmd,
+	"RESTORE":          singleKeyCmd,
+	"HSET":             singleKeyCmd,
+	"HSETNX":           singleKeyCmd,
+	"HGET":             singleKeyCmd,
+	"HMSET":            singleKeyCmd,
+	"HMGET":            singleKeyCmd,
+	"HDEL":             singleKeyCmd,
+	"HLEN":             singleKeyCmd,
+	"HSTRLEN":          singleKeyCmd,
+	"HEXISTS":          singleKeyCmd,
+	"HKEYS":            singleKeyCmd,
+	"HVALS":            singleKeyCmd,
+	"HGETALL":          singleKeyCmd,
+	"HINCRBY":          singleKeyCmd,
+	"HINCRBYFLOAT":     singleKeyCmd,
+	"HSCAN":            singleKeyCmd,
+	"LPUSH":            singleKeyCmd,
+	"LPUSHX":           singleKeyCmd,
+	"RPUSH":            singleKeyCmd,
+	"RPUSHX":           singleKeyCmd,
+	"LPOP":             singleKeyCmd,
+	"RPOP":             singleKeyCmd,
+	"LLEN":             singleKeyCmd,
+	"LRANGE":           singleKeyCmd,
+	"LINDEX":           singleKeyCmd,
+	"LSET":             singleKeyCmd,
+	"LINSERT":          singleKeyCmd,
+	"LREM":             singleKeyCmd,
+	"LTRIM":            singleKeyCmd,
+	"LPOS":             singleKeyCmd,
+	"SADD":             singleKeyCmd,
+	"SREM":             singleKeyCmd,
+	"SCARD":            singleKeyCmd,
+	"SISMEMBER":        singleKeyCmd,
+	"SMISMEMBER":       singleKeyCmd,
+	"SMEMBERS":         singleKeyCmd,
+	"SPOP":             singleKeyCmd,
+	"SRANDMEMBER":      singleKeyCmd,
+	"SSCAN":            singleKeyCmd,
+	"ZADD":             singleKeyCmd,
+	"ZINCRBY":          singleKeyCmd,
+	"ZREM":             singleKeyCmd,
+	"ZCARD":            singleKeyCmd,
+	"ZCOUNT":           singleKeyCmd,
+	"ZLEXCOUNT":        singleKeyCmd,
+	"ZSCORE":           singleKeyCmd,
+	"ZMSCORE":          singleKeyCmd,
+	"ZRANK":            singleKeyCmd,
+	"ZREVRANK":         singleKeyCmd,
+	"ZRANGE":           singleKeyCmd,
+	"ZREVRANGE":        singleKeyCmd,
+	"ZRANGEBYSCORE":    singleKeyCmd,
+	"ZREVRANGEBYSCORE": singleKeyCmd,
+	"ZRANGEBYLEX":      singleKeyCmd,
+	"ZREVRANGEBYLEX":   singleKeyCmd,
+	"ZREMRANGEBYRANK":  singleKeyCmd,
+	"ZREMRANGEBYSCORE": singleKeyCmd,
+	"ZREMRANGEBYLEX":   singleKeyCmd,
+	"ZPOPMIN":          singleKeyCmd,
+	"ZPOPMAX":          singleKeyCmd,
+	"ZRANDMEMBER":      singleKeyCmd,
+	"ZSCAN":            singleKeyCmd,
+	"PFADD":            singleKeyCmd,
+	"GEOADD":           singleKeyCmd,
+	"GEODIST":          singleKeyCmd,
+	"GEOHASH":          singleKeyCmd,
+	"GEOPOS":           singleKeyCmd,
+	"GEOSEARCH":        singleKeyCmd,
+	"XADD":             singleKeyCmd,
+	"XLEN":             singleKeyCmd,
+	"XRANGE":           singleKeyCmd,
+	"XREVRANGE":        singleKeyCmd,
+	"XDEL":             singleKeyCmd,
+	"XTRIM":            singleKeyCmd,
+	"XACK":             singleKeyCmd,
+	"XPENDING":         singleKeyCmd,
+	"XCLAIM":           singleKeyCmd,
+	"XAUTOCLAIM":       singleKeyCmd,
+	"XSETID":           singleKeyCmd,
+	"XGROUP":           {firstKey: 1, lastKey: 1, step: 1},
+	"XINFO":            {firstKey: 1, lastKey: 1, step: 1},
+	"OBJECT":           {firstKey: 1, lastKey: 1, step: 1},
+	"MEMORY":           {keysFn: memoryKeys},
+
+	// multi keys commands
+	"DEL":         allKeysCmd,
+	"UNLINK":      allKeysCmd,
+	"EXISTS":      allKeysCmd,
+	"TOUCH":       allKeysCmd,
+	"MGET":        allKeysCmd,
+	"WATCH":       allKeysCmd,
+	"SDIFF":       allKeysCmd,
+	"SINTER":      allKeysCmd,
+	"SUNION":      allKeysCmd,
+	"SDIFFSTORE":  allKeysCmd,
+	"SINTERSTORE": allKeysCmd,
+	"SUNIONSTORE": allKeysCmd,
+	"PFCOUNT":     allKeysCmd,
+	"PFMERGE":     allKeysCmd,
+	"RENAME":      twoKeysCmd,
+	"RENAMENX":    twoKeysCmd,
+	"COPY":        twoKeysCmd,
+	"RPOPLPUSH":   twoKeysCmd,
+	"BRPOPLPUSH":  twoKeysCmd,
+	"LMOVE":       twoKeysCmd,
+	"BLMOVE":      twoKeysCmd,
+	"SMOVE":       twoKeysCmd,
+	"ZRANGESTORE": twoKeysCmd,
+	"MSET":        {firstKey: 0, lastKey: -1, step: 2},
+	"MSETNX":      {firstKey: 0, lastKey: -1, step: 2},
+	"BLPOP":       {firstKey: 0, lastKey: -2, step: 1},
+	"BRPOP":       {firstKey: 0, lastKey: -2, step: 1},
+	"BZPOPMIN":    {firstKey: 0, lastKey: -2, step: 1},
+	"BZPOPMAX":    {firstKey: 0, lastKey: -2, step: 1},
+	"BITOP":       {firstKey: 1, lastKey: -1, step: 1},
+	"ZUNIONSTORE": {keysFn: storeNumKeys},
+	"ZINTERSTORE": {keysFn: storeNumKeys},
+	"ZDIFFSTORE":  {keysFn: storeNumKeys},
+	"ZUNION":      {keysFn: numKeysAt(0)},
+	"ZINTER":      {keysFn: numKeysAt(0)},
+	"ZDIFF":       {keysFn: numKeysAt(0)},
+	"EVAL":        {keysFn: numKeysAt(1)},
+	"EVALSHA":     {keysFn: numKeysAt(1)},
+	"EVAL_RO":     {keysFn: numKeysAt(1)},
+	"EVALSHA_RO":  {keysFn: numKeysAt(1)},
+	"FCALL":       {keysFn: numKeysAt(1)},
+	"FCALL_RO":    {keysFn: numKeysAt(1)},
+	"SORT":        {keysFn: sortKeys},
+	"SORT_RO":     singleKeyCmd,
+	"XREAD":       {keysFn: streamsKeys},
+	"XREADGROUP":  {keysFn: streamsKeys},
+}
+
+// KeyIndexes returns indexes of the keys in the command arguments, excluding the command name.
+// The unknown commands are considered as command without key, see IsKnownCommand.
+func KeyIndexes(cmd string, args []interface{}) []int {
+	return getCommandInfo(cmd).keyIndexes(args)
+}
+
+// IsKnownCommand returns true if position of the command's keys is known.
+// The keys of the unknown commands are not prefixed nor routed, e.g. the module commands
+func IsKnownCommand(cmd string) bool {
+	if _, ok := commands[cmd]; ok {
+		return true
+	}
+	_, ok := commands[strings.ToUpper(cmd)]
+	return ok
+}
+
+// getCommandInfo returns information of the given command
+func getCommandInfo(cmd string) commandInfo {
+	info, ok := commands[cmd]
+	if !ok {
+		// the caller might use lowercase command name
+		info, ok = commands[strings.ToUpper(cmd)]
+	}
+	if !ok {
+		return noKeyCmd
+	}
+	return info
+}
+
+// keyIndexes returns indexes of the keys in the command arguments
+func (ci commandInfo) keyIndexes(args []interface{}) []int {
+	if ci.keysFn != nil {
+		return ci.keysFn(args)
+	}
+	if ci.firstKey < 0 || ci.firstKey >= len(args) {
+		return nil
+	}
+
+	lastKey := ci.lastKey
+	if lastKey < 0 {
+		lastKey += len(args)
+	}
+	if lastKey >= len(args) {
+		lastKey = len(args) - 1
+	}
+
+	indexes := make([]int, 0, (lastKey-ci.firstKey)/ci.step+1)
+	for i := ci.firstKey; i <= lastKey; i += ci.step {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// numKeysAt returns keysFn of the commands which number of the keys is in the argument i,
+// followed by the keys, e.g. EVAL: script numkeys key [key ...] arg [arg ...]
+func numKeysAt(i int) func(args []interface{}) []int {
+	return func(args []interface{}) []int {
+		if len(args) <= i {
+			return nil
+		}
+		numKeys, err := strconv.Atoi(argString(args[i]))
+		if err != nil {
+			return nil
+		}
+
+		indexes := make([]int, 0, numKeys)
+		for j := i + 1; j <= i+numKeys && j < len(args); j++ {
+			indexes = append(indexes, j)
+		}
+		return indexes
+	}
+}
+
+// storeNumKeys returns key indexes of ZUNIONSTORE, ZINTERSTORE & ZDIFFSTORE.
+// args: destination numkeys key [key ...] [options]
+func storeNumKeys(args []interface{}) []int {
+	if len(args) == 0 {
+		return nil
+	}
+	return append([]int{0}, numKeysAt(1)(args)...)
+}
+
+// sortKeys returns key indexes of SORT, including the destination of STORE option.
+// The keys of BY & GET patterns are not included.
+// args: key [BY pattern] [LIMIT offset count] [GET pattern ...] [ASC|DESC] [ALPHA] [STORE destination]
+func sortKeys(args []interface{}) []int {
+	if len(args) == 0 {
+		return nil
+	}
+	indexes := []int{0}
+	for i := 1; i < len(args)-1; i++ {
+		switch strings.ToUpper(argString(args[i])) {
+		case "BY", "GET":
+			i++
+		case "LIMIT":
+			i += 2
+		case "STORE":
+			indexes = append(indexes, i+1)
+			i++
+		}
+	}
+	return indexes
+}
+
+// memoryKeys returns key index of MEMORY USAGE, the other MEMORY subcommands have no key.
+// args: USAGE key [SAMPLES count]
+func memoryKeys(args []interface{}) []int {
+	if len(args) >= 2 && strings.EqualFold(argString(args[0]), "USAGE") {
+		return []int{1}
+	}
+	return nil
+}
+
+// streamsKeys returns key indexes of XREAD & XREADGROUP command.
+// args: [options] STREAMS key [key ...] id [id ...]
+func streamsKeys(args []interface{}) []int {
+	for i := 0; i < len(args); i++ {
+		// skip the options, their value might be "STREAMS" too
+		switch strings.ToUpper(argString(args[i])) {
+		case "COUNT", "BLOCK":
+			i++
+		case "GROUP":
+			i += 2
+		case "STREAMS":
+			indexes := make([]int, (len(args)-i-1)/2)
+			for j := range indexes {
+				indexes[j] = i + 1 + j
+			}
+			return indexes
+		}
+	}
+	return nil
+}
+
+// argString converts the command argument to string
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// readOnlyCommands is the commands which don't modify the data,
+// e.g. they could be sent to the replicas
+var readOnlyCommands = map[string]bool{
+	"EXISTS":           true,
+	"TYPE":             true,
+	"TTL":              true,
+	"PTTL":             true,
+	"SCAN":             true,
+	"GET":              true,
+	"MGET":             true,
+	"STRLEN":           true,
+	"GETRANGE":         true,
+	"HGET":             true,
+	"HMGET":            true,
+	"HGETALL":          true,
+	"HKEYS":            true,
+	"HVALS":            true,
+	"HLEN":             true,
+	"HEXISTS":          true,
+	"HSTRLEN":          true,
+	"HSCAN":            true,
+	"LRANGE":           true,
+	"LLEN":             true,
+	"LINDEX":           true,
+	"SMEMBERS":         true,
+	"SISMEMBER":        true,
+	"SCARD":            true,
+	"SRANDMEMBER":      true,
+	"SDIFF":            true,
+	"SINTER":           true,
+	"SUNION":           true,
+	"SSCAN":            true,
+	"ZRANGE":           true,
+	"ZRANGEBYSCORE":    true,
+	"ZREVRANGE":        true,
+	"ZREVRANGEBYSCORE": true,
+	"ZRANK":            true,
+	"ZREVRANK":         true,
+	"ZSCORE":           true,
+	"ZCARD":            true,
+	"ZCOUNT":           true,
+	"ZSCAN":            true,
+	"XRANGE":           true,
+	"XREVRANGE":        true,
+	"XREAD":            true,
+	"XLEN":             true,
+}
+
+// IsReadOnly returns true if the command doesn't modify the data
+func IsReadOnly(cmd string) bool {
+	if readOnlyCommands[cmd] {
+		return true
+	}
+	return readOnlyCommands[strings.ToUpper(cmd)]
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyIndexes(t *testing.T) {
+	testCases := []struct {
+		cmd  string
+		args []interface{}
+		keys []int
+	}{
+		{"GET", []interface{}{"k"}, []int{0}},
+		{"set", []interface{}{"k", "v"}, []int{0}},
+		{"PING", nil, nil},
+		{"DEL", []interface{}{"k1", "k2"}, []int{0, 1}},
+		{"MSET", []interface{}{"k1", "v1", "k2", "v2"}, []int{0, 2}},
+		{"BLPOP", []interface{}{"k1", "k2", 0}, []int{0, 1}},
+		{"EVAL", []interface{}{"script", 2, "k1", "k2", "arg"}, []int{2, 3}},
+		{"BITOP", []interface{}{"AND", "dst", "k1", "k2"}, []int{1, 2, 3}},
+		{"ZUNIONSTORE", []interface{}{"dst", 2, "k1", "k2", "WEIGHTS", 1, 2}, []int{0, 2, 3}},
+		{"ZINTERSTORE", []interface{}{"dst", "1", "k1", "AGGREGATE", "MAX"}, []int{0, 2}},
+		{"BRPOPLPUSH", []interface{}{"src", "dst", 0}, []int{0, 1}},
+		{"BZPOPMIN", []interface{}{"k1", "k2", 0}, []int{0, 1}},
+		{"SORT", []interface{}{"k", "BY", "w_*", "LIMIT", 0, 10, "GET", "store", "STORE", "dst"}, []int{0, 9}},
+		{"MEMORY", []interface{}{"USAGE", "k"}, []int{1}},
+		{"MEMORY", []interface{}{"STATS"}, nil},
+		{"WAIT", []interface{}{1, 0}, nil},
+		{"SLOWLOG", []interface{}{"GET", 10}, nil},
+		{"HELLO", []interface{}{3}, nil},
+		{"MODULE.CMD", []interface{}{"k"}, nil},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.keys, KeyIndexes(tc.cmd, tc.args), tc.cmd)
+	}
+}
+
+func TestIsKnownCommand(t *testing.T) {
+	require.True(t, IsKnownCommand("GET"))
+	require.True(t, IsKnownCommand("ping"))
+	require.False(t, IsKnownCommand("MODULE.CMD"))
+}
+
+func TestStreamsKeys(t *testing.T) {
+	args := XReadGroupArgs{
+		Group:    "g",
+		Consumer: "STREAMS",
+		Streams:  []string{"s1", "s2"},
+		IDs:      []string{">", ">"},
+		Count:    10,
+	}.CmdArgs()
+
+	indexes := KeyIndexes("XREADGROUP", args)
+	require.Len(t, indexes, 2)
+	require.Equal(t, "s1", args[indexes[0]])
+	require.Equal(t, "s2", args[indexes[1]])
+}
+
+func TestIsReadOnly(t *testing.T) {
+	require.True(t, IsReadOnly("GET"))
+	require.True(t, IsReadOnly("hget"))
+	require.False(t, IsReadOnly("SET"))
+	require.False(t, IsReadOnly("EVAL"))
+}
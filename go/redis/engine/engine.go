@@ -2,14 +2,17 @@
 package engine
 
 import (
+	"context"
 	"errors"
 )
 
 // Type defines engine type.
-// It currently only support `redigo` engine
+// It currently supports `redigo`, `goredis`, and `memory` engine
 type Type string
 
-// ScanAllResult struct define response for redis scan all
+// ScanAllResult struct define response for redis scan all.
+// It is a batch of the keys (or the elements for HSCAN, SSCAN & ZSCAN) returned by an iteration,
+// or the error which stops the scanning.
 type ScanAllResult struct {
 	Keys []string
 	Err  error
@@ -18,6 +21,12 @@ type ScanAllResult struct {
 const (
 	// Redigo is redigo engine
 	Redigo Type = "redigo"
+
+	// GoRedis is go-redis engine
+	GoRedis Type = "goredis"
+
+	// Memory is in-process engine for the unit tests, see package memory
+	Memory Type = "memory"
 )
 
 // ErrNotOK returned if redis not respond with OK but error is nil
@@ -26,12 +35,52 @@ var ErrNotOK = errors.New("not ok")
 // Config of redis engine
 type Config struct {
 	// EngineType defines engine's/library type.
-	// The supported values : redigo,goredis
+	// The supported values : redigo,goredis,memory
 	EngineType Type `yaml:"engine_type" default:"redigo"`
 
 	// Redis server address
 	Address string `yaml:"address"`
 
+	// Username of the redis ACL user, it requires redis 6 or later.
+	// Empty means the default user.
+	Username string `yaml:"username"`
+
+	// Password to authenticate the connection.
+	// Empty means no authentication
+	Password string `yaml:"password"`
+
+	// DB is the database selected after connecting to the server.
+	// It must be zero in cluster mode
+	DB int `yaml:"db"`
+
+	// ConnectTimeoutMs is timeout in millisecond for connecting to the server.
+	// Zero means using the default of the engine
+	ConnectTimeoutMs int `yaml:"connect_timeout_ms"`
+
+	// ReadTimeoutMs is timeout in millisecond for reading a reply from the server.
+	// Zero means using the default of the engine
+	ReadTimeoutMs int `yaml:"read_timeout_ms"`
+
+	// WriteTimeoutMs is timeout in millisecond for writing a command to the server.
+	// Zero means using the default of the engine
+	WriteTimeoutMs int `yaml:"write_timeout_ms"`
+
+	// TLS enables TLS connection to the server
+	TLS bool `yaml:"tls"`
+
+	// TLSCACertFile is path of the CA certificate file used to verify the server certificate.
+	// The system CA is used if it is empty
+	TLSCACertFile string `yaml:"tls_ca_cert_file"`
+
+	// TLSCertFile & TLSKeyFile are path of the client certificate & its private key file,
+	// for the server which requires client certificate
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+
+	// TLSSkipVerify disables verification of the server certificate.
+	// Please only use it for testing
+	TLSSkipVerify bool `yaml:"tls_skip_verify"`
+
 	// Maximum number of idle connections in the pool.
 	// Only for redigo engine
 	MaxIdle int `yaml:"maxidle"`
@@ -56,133 +105,619 @@ type Config struct {
 	// for PoolWaitMs millisecond
 	PoolWaitMs int `yaml:"pool_wait_ms" default:"1000"`
 
+	// PipelineChunkSize is maximum number of the commands sent in a pipeline execution,
+	// the bigger pipeline is split into chunks which are executed one after another.
+	// Zero means the default of 1000, negative value means no limit
+	PipelineChunkSize int `yaml:"pipeline_chunk_size" default:"1000"`
+
+	// ClusterMode enables redis cluster mode.
+	// The cluster topology is discovered from the seed nodes using CLUSTER SLOTS,
+	// and each command is sent to the node serving its key.
+	// The commands unknown by the engine (see IsKnownCommand) are sent to any node, then redirected by MOVED.
+	ClusterMode bool `yaml:"cluster_mode"`
+
+	// ClusterAddresses is list of the cluster seed nodes address.
+	// `Address` is used as the only seed node if it is empty.
+	ClusterAddresses []string `yaml:"cluster_addresses"`
+
+	// ClusterMaxRedirects is maximum number of MOVED/ASK redirections
+	// followed by a command in cluster mode.
+	ClusterMaxRedirects int `yaml:"cluster_max_redirects" default:"3"`
+
+	// SentinelAddresses is list of the redis sentinel address.
+	// If it is not empty, the master address is resolved using the sentinels
+	// instead of using `Address`, and it is resolved again on failover.
+	SentinelAddresses []string `yaml:"sentinel_addresses"`
+
+	// SentinelMasterName is name of the master monitored by the sentinels
+	SentinelMasterName string `yaml:"sentinel_master_name"`
+
+	// SentinelReadReplica routes the read-only commands to the replicas of the master.
+	// The replicas might return stale data because of the asynchronous replication.
+	// Only for redigo engine
+	SentinelReadReplica bool `yaml:"sentinel_read_replica"`
+
+	// ShardNodes enables the sharded mode: the keys are spread over these independent redis nodes
+	// using a consistent-hash ring, instead of using `Address`.
+	// Multi keys command which keys are on different nodes is split per node if it is supported (e.g. MGET, DEL, MSET),
+	// the other ones (e.g. EVAL, RENAME, or Watch) must have all of their keys on the same node,
+	// use the hash tag for that, e.g. `{user1}:name` & `{user1}:email`.
+	// The commands unknown by the engine (e.g. the module commands, see IsKnownCommand) are sent to any node.
+	// Only for redigo engine, creating the client of the other engines with it fails
+	ShardNodes []ShardNode `yaml:"shard_nodes"`
+
+	// ShardFailureLimit is number of the consecutive connection failures which marks a shard node down.
+	// The keys of the down node are rehashed to the other nodes
+	ShardFailureLimit int `yaml:"shard_failure_limit" default:"3"`
+
+	// ShardRetryMs is duration in millisecond of the down node before it is put back to the ring
+	ShardRetryMs int `yaml:"shard_retry_ms" default:"30000"`
+
+	// PubSubPingPeriodMs is period in millisecond of the PING health check of the pub/sub connection.
+	// The connection is reconnected if it doesn't receive anything in twice of the period.
+	// Only for redigo engine, go-redis uses its own period (30 seconds)
+	PubSubPingPeriodMs int `yaml:"pubsub_ping_period_ms" default:"30000"`
+
+	// Codec is name of the codec used by the object commands, e.g. `SetObject`.
+	// The supported values : json,msgpack,gob, or the name of a codec registered using `RegisterCodec`
+	Codec string `yaml:"codec" default:"json"`
+
 	// NoPingOnCreate is a flag to indicate whether it will be do ping check on `New` or not.
 	// If true: client will do redis PING on `New`, make sure that the server is up.
 	NoPingOnCreate bool `yaml:"no_ping_on_create"`
+
+	// CircuitBreaker enables the circuit breaker, which fails the commands fast with ErrCircuitOpen
+	// when the server looks down, see CircuitBreaker type.
+	// Only the connection errors & the timeouts count as failure, the errors replied by the server don't.
+	// Only for redigo & goredis engines
+	CircuitBreaker bool `yaml:"circuit_breaker"`
+
+	// CircuitBreakerConsecutiveFailures is number of the consecutive failures which opens the circuit.
+	// Zero means the default of 5, negative value disables this threshold
+	CircuitBreakerConsecutiveFailures int `yaml:"circuit_breaker_consecutive_failures"`
+
+	// CircuitBreakerErrorRate is rate of the failures (0-1) in a window which opens the circuit.
+	// Zero means the default of 0.5, negative value disables this threshold
+	CircuitBreakerErrorRate float64 `yaml:"circuit_breaker_error_rate"`
+
+	// CircuitBreakerMinRequests is minimum number of the commands in a window before its error rate is checked
+	CircuitBreakerMinRequests int `yaml:"circuit_breaker_min_requests" default:"20"`
+
+	// CircuitBreakerWindowMs is length in millisecond of the window of the error rate
+	CircuitBreakerWindowMs int `yaml:"circuit_breaker_window_ms" default:"10000"`
+
+	// CircuitBreakerOpenMs is duration in millisecond of the open circuit before it lets the probes through
+	CircuitBreakerOpenMs int `yaml:"circuit_breaker_open_ms" default:"5000"`
+
+	// CircuitBreakerHalfOpenProbes is number of the probe commands executed by the half-open circuit.
+	// The circuit is closed if all of them succeed
+	CircuitBreakerHalfOpenProbes int `yaml:"circuit_breaker_half_open_probes" default:"1"`
+
+	// NearCache enables the in-process cache of Get, HGet, and MGet replies, see redis.NearCache.
+	// The cached values might be stale until they are invalidated
+	NearCache bool `yaml:"near_cache"`
+
+	// NearCacheSize is maximum number of the cached values (a hash field is a value),
+	// the least recently used values are evicted first
+	NearCacheSize int `yaml:"near_cache_size" default:"10000"`
+
+	// NearCacheTTLMs is time to live in millisecond of the cached values,
+	// it bounds the staleness of the values changed by the other clients
+	NearCacheTTLMs int `yaml:"near_cache_ttl_ms" default:"1000"`
+
+	// NearCacheInvalidationChannel is pub/sub channel of the invalidated keys.
+	// If it is not empty, the keys written by the client are published to the channel,
+	// and the keys received from the channel are invalidated, e.g. the ones written by the other pods
+	// Each written key costs a PUBLISH, executed after the write
+	NearCacheInvalidationChannel string `yaml:"near_cache_invalidation_channel"`
+
+	// KeyPrefix is added to all of the keys, so the services sharing a server have their own namespace.
+	// The callers & the hooks use the keys without the prefix, Scan & KEYS add it to the pattern & strip it from the found keys.
+	// The pub/sub channels, the keys inside the Lua scripts, and the keys in the replies of the other commands
+	// (e.g. BLPOP) are not prefixed.
+	// The arguments of the commands unknown by the engine (e.g. the module commands, see IsKnownCommand)
+	// are not prefixed either, use the prefixed keys explicitly for them.
+	// Only for redigo engine, creating the client of the other engines with it fails
+	KeyPrefix string `yaml:"key_prefix"`
+
+	// HealthCheckTimeoutMs is timeout in millisecond of the PING sent by `HealthCheck`,
+	// the ctx deadline is used instead if it comes earlier
+	HealthCheckTimeoutMs int `yaml:"health_check_timeout_ms" default:"1000"`
+
+	// FillerTimeoutMs is timeout in millisecond of the filler called by `GetWithSingleFillerContext`.
+	// The filler is shared by the concurrent callers, so it is not bounded by their ctx
+	FillerTimeoutMs int `yaml:"filler_timeout_ms" default:"10000"`
+
+	// Hooks observe the executed commands, e.g. for logging or metrics, see Hook.
+	// More hooks could be registered later using `AddHook`
+	Hooks []Hook `yaml:"-"`
+}
+
+// ShardNode is a redis node of the sharded mode, see Config.ShardNodes
+type ShardNode struct {
+	// Address of the node
+	Address string `yaml:"address"`
+
+	// Weight is share of the keys served by the node, relative to the other nodes.
+	// Zero means 1
+	Weight int `yaml:"weight"`
 }
 
 // Redis defines interface for BXDK redis library
+//
+// Each command has its context-aware variant, with `Context` suffix.
+// The context deadline bounds the whole command execution:
+// waiting for the connection from the pool, writing the command, and reading the reply.
+// The caller will stop waiting when the context is done.
 type Redis interface {
 	// Ping command to redis
 	Ping() (string, error)
 
+	// PingContext is Ping with context
+	PingContext(ctx context.Context) (string, error)
+
 	// Do command
 	Do(cmd string, args ...interface{}) (interface{}, error)
 
+	// DoContext is Do with context
+	DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error)
+
 	// IsErrNil returns true if the err given is ErrNil value.
 	// in case of redis: it is redis.ErrNil.
 	// Please use this func instead of comparing to redis.ErrNil directly
 	// because each library has its own ErrNil definition.
 	IsErrNil(err error) bool
 
+	// AddHook registers the hook which observes the executed commands, see Hook.
+	// It is called after the hooks of the config and the previously added hooks
+	AddHook(hook Hook)
+
+	// Stats returns snapshot of the connection pool statistics, see PoolStats
+	Stats() PoolStats
+
 	// Set key and value
 	Set(key string, value interface{}) error
 
+	// SetContext is Set with context
+	SetContext(ctx context.Context, key string, value interface{}) error
+
 	// SetNX do SETNX (only set if not exist) with SET's NX & EX args.
 	// It sets the key which will expired in `expire` seconds
 	SetNX(key string, value interface{}, expire int) (string, error)
 
+	// SetNXContext is SetNX with context
+	SetNXContext(ctx context.Context, key string, value interface{}, expire int) (string, error)
+
 	// SetEX key and value
 	// It sets the key wich will expired in `expire` seconds
 	SetEX(key string, value interface{}, expire int) (string, error)
 
+	// SetEXContext is SetEX with context
+	SetEXContext(ctx context.Context, key string, value interface{}, expire int) (string, error)
+
 	// Get string value
 	Get(key string) (string, error)
 
+	// GetContext is Get with context
+	GetContext(ctx context.Context, key string) (string, error)
+
 	// Delete delete keys from the server
 	Delete(keys ...string) (int, error)
 
+	// DeleteContext is Delete with context
+	DeleteContext(ctx context.Context, keys ...string) (int, error)
+
 	// MSet keys and values
 	// please use basic types only (no struct, array, or map) for arguments
 	MSet(pairs ...interface{}) error
 
+	// MSetContext is MSet with context
+	MSetContext(ctx context.Context, pairs ...interface{}) error
+
 	// MGet keys
 	MGet(keys ...string) ([]string, error)
 
+	// MGetContext is MGet with context
+	MGetContext(ctx context.Context, keys ...string) ([]string, error)
+
 	// HSetEX key and value and sets the expiration to the given `expire` seconds
 	HSetEX(key, field string, value interface{}, expire int) (int, error)
 
+	// HSetEXContext is HSetEX with context
+	HSetEXContext(ctx context.Context, key, field string, value interface{}, expire int) (int, error)
+
 	// HGet key and value
 	HGet(key, field string) (string, error)
 
+	// HGetContext is HGet with context
+	HGetContext(ctx context.Context, key, field string) (string, error)
+
 	// HMSet function
 	// please use basic types only (no struct, array, or map) for kv value
 	HMSet(key string, kv map[string]interface{}) (string, error)
 
+	// HMSetContext is HMSet with context
+	HMSetContext(ctx context.Context, key string, kv map[string]interface{}) (string, error)
+
 	// HMGet keys and value
 	HMGet(key string, fields ...string) ([]string, error)
 
+	// HMGetContext is HMGet with context
+	HMGetContext(ctx context.Context, key string, fields ...string) ([]string, error)
+
 	// HDel fields of a key
 	HDel(key string, fields ...string) (int, error)
 
+	// HDelContext is HDel with context
+	HDelContext(ctx context.Context, key string, fields ...string) (int, error)
+
 	// Incr function
 	Incr(key string) (int64, error)
 
+	// IncrContext is Incr with context
+	IncrContext(ctx context.Context, key string) (int64, error)
+
 	// IncrBy function
 	IncrBy(key string, value int64) (int64, error)
 
+	// IncrByContext is IncrBy with context
+	IncrByContext(ctx context.Context, key string, value int64) (int64, error)
+
 	// Decr function
 	Decr(key string) (int64, error)
 
+	// DecrContext is Decr with context
+	DecrContext(ctx context.Context, key string) (int64, error)
+
 	// DecrBy function
 	DecrBy(key string, value int64) (int64, error)
 
+	// DecrByContext is DecrBy with context
+	DecrByContext(ctx context.Context, key string, value int64) (int64, error)
+
 	// Expire set expiration time for a key
 	// `expiry` is in seconds
 	Expire(key string, expiry int) (int, error)
 
+	// ExpireContext is Expire with context
+	ExpireContext(ctx context.Context, key string, expiry int) (int, error)
+
 	// TTL return remaining ttl of a key
 	// from: https://redis.io/commands/ttl
 	// The command returns -2 if the key does not exist.
 	// The command returns -1 if the key exists but has no associated expire.
 	TTL(key string) (int, error)
 
+	// TTLContext is TTL with context
+	TTLContext(ctx context.Context, key string) (int, error)
+
 	// Exists checks if a key exists.
 	// return true if exists.
 	Exists(key string) (bool, error)
 
+	// ExistsContext is Exists with context
+	ExistsContext(ctx context.Context, key string) (bool, error)
+
 	// LLen get the length of the list
 	LLen(key string) (int64, error)
 
+	// LLenContext is LLen with context
+	LLenContext(ctx context.Context, key string) (int64, error)
+
 	// LPush prepends values to the list and returns the length of the list
 	LPush(key string, values ...string) (int, error)
 
+	// LPushContext is LPush with context
+	LPushContext(ctx context.Context, key string, values ...string) (int, error)
+
 	// LPop removes and get the first element in the list
 	LPop(key string) (string, error)
 
+	// LPopContext is LPop with context
+	LPopContext(ctx context.Context, key string) (string, error)
+
 	// LRange returns the specified elements of the list stored at key
 	LRange(key string, start, stop int64) ([]string, error)
 
+	// LRangeContext is LRange with context
+	LRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error)
+
 	// RPush append values to the list and return the length of the list
 	RPush(key string, values ...string) (int, error)
 
+	// RPushContext is RPush with context
+	RPushContext(ctx context.Context, key string, values ...string) (int, error)
+
 	// RPop Removes and returns the last element of the list stored at key
 	// return redigo.ErrNil if the key is not exist
 	RPop(key string) (string, error)
 
+	// RPopContext is RPop with context
+	RPopContext(ctx context.Context, key string) (string, error)
+
 	// Scan will do SCAN command to get keys by given pattern
 	// returning keys, cursor, and error
 	Scan(pattern string, cursor uint64, count int64) ([]string, uint64, error)
 
+	// ScanContext is Scan with context
+	ScanContext(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// ScanType is Scan which only returns the keys of the given type, e.g. "string", "hash", or "zset".
+	// It requires redis 6.0 or later
+	ScanType(pattern, keyType string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// ScanTypeContext is ScanType with context
+	ScanTypeContext(ctx context.Context, pattern, keyType string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// HScan will do HSCAN command to get fields of the hash by given pattern
+	// returning field & value pairs, cursor, and error
+	HScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// HScanContext is HScan with context
+	HScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// SScan will do SSCAN command to get members of the set by given pattern
+	// returning members, cursor, and error
+	SScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// SScanContext is SScan with context
+	SScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// ZScan will do ZSCAN command to get members of the sorted set by given pattern
+	// returning member & score pairs, cursor, and error
+	ZScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// ZScanContext is ZScan with context
+	ZScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error)
+
+	// Pipeline creates new pipeline.
+	// `retry` is number of retry when a chunk of the pipeline failed, see Pipeliner for the retried commands.
+	// `numCmdHint` is hint about the number of commands on each execution.
 	Pipeline(retry, numCmdHint int) Pipeliner
 
+	// PipelineContext is Pipeline with context.
+	// The context bounds every execution of the returned pipeline.
+	PipelineContext(ctx context.Context, retry, numCmdHint int) Pipeliner
+
 	// SAdd Add the specified members to the set stored at key.
 	// Specified members that are already a member of this set are ignored.
 	// If key does not exist, a new set is created before adding the specified members.
 	// An error is returned when the value stored at key is not a set.
 	SAdd(key string, members ...interface{}) (int64, error)
 
+	// SAddContext is SAdd with context
+	SAddContext(ctx context.Context, key string, members ...interface{}) (int64, error)
+
 	// SRem Remove the specified members from the set stored at key.
 	// Specified members that are not a member of this set are ignored.
 	// If key does not exist, it is treated as an empty set and this command returns 0.
 	// An error is returned when the value stored at key is not a set.
 	SRem(key string, members ...interface{}) (int64, error)
 
+	// SRemContext is SRem with context
+	SRemContext(ctx context.Context, key string, members ...interface{}) (int64, error)
+
 	// SMembers Returns all the members of the set value stored at key.
 	SMembers(key string) ([]string, error)
 
+	// SMembersContext is SMembers with context
+	SMembersContext(ctx context.Context, key string) ([]string, error)
+
 	// Append string to existing value in the key
 	Append(key, value string) (int, error)
+
+	// AppendContext is Append with context
+	AppendContext(ctx context.Context, key, value string) (int, error)
+
+	// ZAdd adds the members to the sorted set stored at key, or updates their score if they already exist.
+	// It returns number of the added members, or number of the changed members if args.CH is true.
+	ZAdd(key string, args ZAddArgs, members ...Z) (int64, error)
+
+	// ZAddContext is ZAdd with context
+	ZAddContext(ctx context.Context, key string, args ZAddArgs, members ...Z) (int64, error)
+
+	// ZIncrBy increments score of the member by `increment` and returns the new score
+	ZIncrBy(key string, increment float64, member string) (float64, error)
+
+	// ZIncrByContext is ZIncrBy with context
+	ZIncrByContext(ctx context.Context, key string, increment float64, member string) (float64, error)
+
+	// ZRange returns the members in the index range, ordered from the lowest to the highest score
+	ZRange(key string, start, stop int64) ([]string, error)
+
+	// ZRangeContext is ZRange with context
+	ZRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error)
+
+	// ZRangeWithScores is ZRange which returns the members with their score
+	ZRangeWithScores(key string, start, stop int64) ([]Z, error)
+
+	// ZRangeWithScoresContext is ZRangeWithScores with context
+	ZRangeWithScoresContext(ctx context.Context, key string, start, stop int64) ([]Z, error)
+
+	// ZRevRange returns the members in the index range, ordered from the highest to the lowest score
+	ZRevRange(key string, start, stop int64) ([]string, error)
+
+	// ZRevRangeContext is ZRevRange with context
+	ZRevRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error)
+
+	// ZRevRangeWithScores is ZRevRange which returns the members with their score
+	ZRevRangeWithScores(key string, start, stop int64) ([]Z, error)
+
+	// ZRevRangeWithScoresContext is ZRevRangeWithScores with context
+	ZRevRangeWithScoresContext(ctx context.Context, key string, start, stop int64) ([]Z, error)
+
+	// ZRangeByScore returns the members in the score range, ordered from the lowest to the highest score
+	ZRangeByScore(key string, opt ZRangeBy) ([]string, error)
+
+	// ZRangeByScoreContext is ZRangeByScore with context
+	ZRangeByScoreContext(ctx context.Context, key string, opt ZRangeBy) ([]string, error)
+
+	// ZRangeByScoreWithScores is ZRangeByScore which returns the members with their score
+	ZRangeByScoreWithScores(key string, opt ZRangeBy) ([]Z, error)
+
+	// ZRangeByScoreWithScoresContext is ZRangeByScoreWithScores with context
+	ZRangeByScoreWithScoresContext(ctx context.Context, key string, opt ZRangeBy) ([]Z, error)
+
+	// ZRank returns rank of the member, the member with the lowest score has rank 0.
+	// It returns ErrNil if the member or the key does not exist.
+	ZRank(key, member string) (int64, error)
+
+	// ZRankContext is ZRank with context
+	ZRankContext(ctx context.Context, key, member string) (int64, error)
+
+	// ZScore returns score of the member.
+	// It returns ErrNil if the member or the key does not exist.
+	ZScore(key, member string) (float64, error)
+
+	// ZScoreContext is ZScore with context
+	ZScoreContext(ctx context.Context, key, member string) (float64, error)
+
+	// ZRem removes the members and returns number of the removed members
+	ZRem(key string, members ...string) (int64, error)
+
+	// ZRemContext is ZRem with context
+	ZRemContext(ctx context.Context, key string, members ...string) (int64, error)
+
+	// ZRemRangeByScore removes the members in the score range and returns number of the removed members.
+	// `min` & `max` format is the same as ZRangeBy's
+	ZRemRangeByScore(key, min, max string) (int64, error)
+
+	// ZRemRangeByScoreContext is ZRemRangeByScore with context
+	ZRemRangeByScoreContext(ctx context.Context, key, min, max string) (int64, error)
+
+	// ZCard returns number of the members of the sorted set
+	ZCard(key string) (int64, error)
+
+	// ZCardContext is ZCard with context
+	ZCardContext(ctx context.Context, key string) (int64, error)
+
+	// ZCount returns number of the members in the score range.
+	// `min` & `max` format is the same as ZRangeBy's
+	ZCount(key, min, max string) (int64, error)
+
+	// ZCountContext is ZCount with context
+	ZCountContext(ctx context.Context, key, min, max string) (int64, error)
+
+	// XAdd appends new entry to the stream and returns ID of the entry
+	XAdd(args XAddArgs) (string, error)
+
+	// XAddContext is XAdd with context
+	XAddContext(ctx context.Context, args XAddArgs) (string, error)
+
+	// XRead reads the messages from the streams.
+	// It returns empty result if there is no message until the block duration passed.
+	XRead(args XReadArgs) ([]XStream, error)
+
+	// XReadContext is XRead with context
+	XReadContext(ctx context.Context, args XReadArgs) ([]XStream, error)
+
+	// XReadGroup reads the messages from the streams as a consumer of the group.
+	// It returns empty result if there is no message until the block duration passed.
+	XReadGroup(args XReadGroupArgs) ([]XStream, error)
+
+	// XReadGroupContext is XReadGroup with context
+	XReadGroupContext(ctx context.Context, args XReadGroupArgs) ([]XStream, error)
+
+	// XAck removes the messages from the pending entries list of the group,
+	// and returns number of the acknowledged messages
+	XAck(stream, group string, ids ...string) (int64, error)
+
+	// XAckContext is XAck with context
+	XAckContext(ctx context.Context, stream, group string, ids ...string) (int64, error)
+
+	// XPending returns summary of the pending messages of the group
+	XPending(stream, group string) (XPending, error)
+
+	// XPendingContext is XPending with context
+	XPendingContext(ctx context.Context, stream, group string) (XPending, error)
+
+	// XPendingExt returns the pending messages of the group
+	XPendingExt(args XPendingExtArgs) ([]XPendingExt, error)
+
+	// XPendingExtContext is XPendingExt with context
+	XPendingExtContext(ctx context.Context, args XPendingExtArgs) ([]XPendingExt, error)
+
+	// XClaim changes owner of the pending messages to the consumer, and returns the claimed messages
+	XClaim(args XClaimArgs) ([]XMessage, error)
+
+	// XClaimContext is XClaim with context
+	XClaimContext(ctx context.Context, args XClaimArgs) ([]XMessage, error)
+
+	// XAutoClaim claims the pending messages idle for at least args.MinIdle, and returns the claimed messages.
+	// `next` is the start ID of the next call, it is "0-0" if all of the pending messages are scanned.
+	// It requires redis 6.2 or later
+	XAutoClaim(args XAutoClaimArgs) (messages []XMessage, next string, err error)
+
+	// XAutoClaimContext is XAutoClaim with context
+	XAutoClaimContext(ctx context.Context, args XAutoClaimArgs) (messages []XMessage, next string, err error)
+
+	// XGroupCreate creates consumer group of the stream, which starts reading from the `start` ID.
+	// It creates the stream if it doesn't exist and mkStream is true.
+	// Use IsErrBusyGroup to check if the group already exists.
+	XGroupCreate(stream, group, start string, mkStream bool) error
+
+	// XGroupCreateContext is XGroupCreate with context
+	XGroupCreateContext(ctx context.Context, stream, group, start string, mkStream bool) error
+
+	// Publish posts the message to the channel, and returns number of the clients receiving the message
+	Publish(channel string, message interface{}) (int64, error)
+
+	// PublishContext is Publish with context
+	PublishContext(ctx context.Context, channel string, message interface{}) (int64, error)
+
+	// Subscribe subscribes the channels using a dedicated connection.
+	// The caller must close the returned PubSub after using it
+	Subscribe(channels ...string) (PubSub, error)
+
+	// SubscribeContext is Subscribe with context.
+	// The ctx only bounds the connecting & subscribing, not the lifetime of the PubSub
+	SubscribeContext(ctx context.Context, channels ...string) (PubSub, error)
+
+	// PSubscribe subscribes the channels matching the patterns using a dedicated connection.
+	// The caller must close the returned PubSub after using it
+	PSubscribe(patterns ...string) (PubSub, error)
+
+	// PSubscribeContext is PSubscribe with context.
+	// The ctx only bounds the connecting & subscribing, not the lifetime of the PubSub
+	PSubscribeContext(ctx context.Context, patterns ...string) (PubSub, error)
+
+	// Eval executes the lua script.
+	// The reply is converted the same way by all of the engines:
+	// integer to int64, bulk & simple string to string, array to []interface{}, and nil to ErrNil.
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+
+	// EvalContext is Eval with context
+	EvalContext(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+
+	// EvalSha executes the lua script cached in the server by its SHA1 digest.
+	// It returns NOSCRIPT error if the script is not cached.
+	EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error)
+
+	// EvalShaContext is EvalSha with context
+	EvalShaContext(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error)
+
+	// ScriptLoad caches the lua script in the server and returns its SHA1 digest.
+	// In cluster mode, the script is cached in all of the master nodes.
+	ScriptLoad(script string) (string, error)
+
+	// ScriptLoadContext is ScriptLoad with context
+	ScriptLoadContext(ctx context.Context, script string) (string, error)
+
+	// Watch executes a transaction with optimistic locking on a single connection:
+	// it WATCHes the keys, calls the fn which reads the keys & queues the commands,
+	// then executes the queued commands atomically using MULTI & EXEC.
+	//
+	// If a watched key is changed before EXEC, the transaction is aborted by the server
+	// and the whole transaction is executed again, at most `retry` times in total.
+	// ErrTxFailed is returned if it still fails after that.
+	//
+	// It returns reply of each of the queued commands, converted the same way as Eval.
+	// The failed command's reply is its error.
+	// In cluster mode, all of the keys must be in the same slot.
+	Watch(fn TxFunc, retry int, keys ...string) ([]interface{}, error)
+
+	// WatchContext is Watch with context
+	WatchContext(ctx context.Context, fn TxFunc, retry int, keys ...string) ([]interface{}, error)
 }
 
 // CmdErr is redis command, args, and error
@@ -192,16 +727,32 @@ type CmdErr interface {
 	Err() error
 }
 
-// Pipeliner is interface for pipeline object
+// Pipeliner is interface for pipeline object.
+//
+// Each of the queued commands returns its result, which is available after the pipeline is executed.
+// If a command could not get its reply, e.g. because of network error, the error is set to its result.
+//
+// The pipeline is executed in chunks of at most `PipelineChunkSize` commands, one after another.
+// A failed chunk is retried according to when it failed:
+//   - before it was sent, e.g. no connection is available: all of its failed commands are retried.
+//   - after it was sent, e.g. the connection is broken while reading the replies: only its failed commands
+//     which are idempotent are retried, the others might have been executed and are never replayed.
+//
+// The read commands (e.g. Get & MGet) are idempotent, use AddIdempotentCmd for the other idempotent commands.
 type Pipeliner interface {
 	// AddRawCmd adds/queues raw redis command to the pipeline
-	AddRawCmd(cmd string, args ...interface{})
+	AddRawCmd(cmd string, args ...interface{}) *Result
+
+	// AddIdempotentCmd is AddRawCmd for idempotent command, e.g. SET or HGETALL.
+	// It is retried even if it failed after it was sent, see Pipeliner.
+	AddIdempotentCmd(cmd string, args ...interface{}) *Result
 
 	// Exec executes all queued commands using redis pipeline.
 	// - cmdErrs is command & error of each of the queued commands.
 	// - firstErr is first index of the errored commands.
 	// 		there is no error if firstErr < 0
-	// - err is not nil if the whole pipeline execution failed
+	// - err is *PipelineError if some of the chunks failed, after the retries.
+	//		the commands of the other chunks are executed normally
 	Exec() (cmdErrs []CmdErr, firstErr int, err error)
 
 	// Discard resets the pipeline and discards queued commands
@@ -210,12 +761,27 @@ type Pipeliner interface {
 	// Close closes the pipeline, releasing any open resources.
 	Close() error
 
-	Incr(string)
-	IncrBy(string, int64)
-	Decr(string)
-	DecrBy(string, int64)
-	Expire(string, int)
-	Delete(keys ...string)
-	HMSet(key string, kv map[string]interface{})
-	HDel(key string, fields ...string)
+	Incr(string) *IntResult
+	IncrBy(string, int64) *IntResult
+	Decr(string) *IntResult
+	DecrBy(string, int64) *IntResult
+	Expire(string, int) *IntResult
+	Delete(keys ...string) *IntResult
+	HMSet(key string, kv map[string]interface{}) *StringResult
+	HDel(key string, fields ...string) *IntResult
+	ZAdd(key string, args ZAddArgs, members ...Z) *IntResult
+	ZIncrBy(key string, increment float64, member string) *FloatResult
+	ZRem(key string, members ...string) *IntResult
+	ZRemRangeByScore(key, min, max string) *IntResult
+	Eval(script string, keys []string, args ...interface{}) *Result
+	EvalSha(sha1 string, keys []string, args ...interface{}) *Result
+
+	// the read commands
+	Get(key string) *StringResult
+	MGet(keys ...string) *StringsResult
+	HGet(key, field string) *StringResult
+	HMGet(key string, fields ...string) *StringsResult
+	TTL(key string) *IntResult
+	Exists(keys ...string) *IntResult
+	LRange(key string, start, stop int64) *StringsResult
 }
@@ -0,0 +1,305 @@
+// Package goredis provide go-redis wrapper.
+//
+// It implements the same engine.Redis interface as the redigo engine,
+// so the caller could switch between both engines only by changing the config.
+
+package goredis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// redisErrorType is type of the error replied by the redis server.
+// go-redis keeps the type internal, redis.Nil is one of its values.
+var redisErrorType = reflect.TypeOf(redis.Nil)
+
+type (
+	// GoRedis defines the go-redis wrapper
+	GoRedis struct {
+		client redis.UniversalClient
+
+		pipelineChunkSize int // maximum number of commands on each pipeline execution
+
+		hooks *engine.Hooks // observe the commands, see engine.Hook
+
+		breaker *engine.CircuitBreaker // nil if the circuit breaker is disabled
+	}
+)
+
+// New creates new GoRedis object from the given config
+func New(cfg engine.Config) *GoRedis {
+	if cfg.ClusterMode {
+		return &GoRedis{
+			client:            newClusterClient(cfg),
+			pipelineChunkSize: cfg.PipelineChunkSize,
+			hooks:             engine.NewHooks(cfg.Hooks...),
+			breaker:           engine.NewCircuitBreaker(cfg, isBreakerFailure),
+		}
+	}
+
+	if len(cfg.SentinelAddresses) > 0 {
+		return &GoRedis{
+			client:            newFailoverClient(cfg),
+			pipelineChunkSize: cfg.PipelineChunkSize,
+			hooks:             engine.NewHooks(cfg.Hooks...),
+			breaker:           engine.NewCircuitBreaker(cfg, isBreakerFailure),
+		}
+	}
+
+	return &GoRedis{
+		client:            redis.NewClient(newOptions(cfg)),
+		pipelineChunkSize: cfg.PipelineChunkSize,
+		hooks:             engine.NewHooks(cfg.Hooks...),
+		breaker:           engine.NewCircuitBreaker(cfg, isBreakerFailure),
+	}
+}
+
+// newOptions creates the go-redis client options from the config
+func newOptions(cfg engine.Config) *redis.Options {
+	opt := &redis.Options{
+		Addr:         cfg.Address,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		DialTimeout:  time.Duration(cfg.ConnectTimeoutMs) * time.Millisecond,
+		ReadTimeout:  time.Duration(cfg.ReadTimeoutMs) * time.Millisecond,
+		WriteTimeout: time.Duration(cfg.WriteTimeoutMs) * time.Millisecond,
+		PoolSize:     cfg.MaxActive,
+		IdleTimeout:  time.Duration(cfg.Timeout) * time.Second,
+		PoolTimeout:  time.Duration(cfg.PoolWaitMs) * time.Millisecond,
+	}
+
+	tlsConfig, tlsErr := cfg.LoadTLSConfig()
+	opt.TLSConfig = tlsConfig
+
+	if cfg.Username != "" || tlsErr != nil {
+		// authenticate & select the database in the OnConnect hook instead
+		opt.Password = ""
+		opt.DB = 0
+		opt.OnConnect = onConnect(cfg, tlsErr)
+	}
+	return opt
+}
+
+// onConnect returns go-redis OnConnect hook which authenticates the connection
+// and selects the database, because go-redis v6 doesn't support the ACL username.
+// The hook returns the tlsErr if the TLS config couldn't be loaded,
+// so we never send the password on the insecure connection.
+func onConnect(cfg engine.Config, tlsErr error) func(conn *redis.Conn) error {
+	return func(conn *redis.Conn) error {
+		if tlsErr != nil {
+			return tlsErr
+		}
+
+		if cfg.Password != "" {
+			args := []interface{}{"AUTH", cfg.Password}
+			if cfg.Username != "" {
+				args = []interface{}{"AUTH", cfg.Username, cfg.Password}
+			}
+			if err := conn.Process(redis.NewStatusCmd(args...)); err != nil {
+				return err
+			}
+		}
+
+		if cfg.DB != 0 {
+			return conn.Select(cfg.DB).Err()
+		}
+		return nil
+	}
+}
+
+// newClusterClient creates go-redis cluster client.
+// Notes:
+// - SCAN is executed only on one of the master nodes.
+func newClusterClient(cfg engine.Config) *redis.ClusterClient {
+	addrs := cfg.ClusterAddresses
+	if len(addrs) == 0 {
+		addrs = []string{cfg.Address}
+	}
+
+	opt := newOptions(cfg)
+	return redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:        addrs,
+		MaxRedirects: cfg.ClusterMaxRedirects,
+		OnConnect:    opt.OnConnect,
+		Password:     opt.Password,
+		DialTimeout:  opt.DialTimeout,
+		ReadTimeout:  opt.ReadTimeout,
+		WriteTimeout: opt.WriteTimeout,
+		PoolSize:     opt.PoolSize,
+		IdleTimeout:  opt.IdleTimeout,
+		PoolTimeout:  opt.PoolTimeout,
+		TLSConfig:    opt.TLSConfig,
+	})
+}
+
+// newFailoverClient creates go-redis client which resolves the master using the sentinels.
+// Notes:
+// - SentinelReadReplica is not supported, all commands are sent to the master.
+func newFailoverClient(cfg engine.Config) *redis.Client {
+	opt := newOptions(cfg)
+	return redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    cfg.SentinelMasterName,
+		SentinelAddrs: cfg.SentinelAddresses,
+		OnConnect:     opt.OnConnect,
+		Password:      opt.Password,
+		DB:            opt.DB,
+		DialTimeout:   opt.DialTimeout,
+		ReadTimeout:   opt.ReadTimeout,
+		WriteTimeout:  opt.WriteTimeout,
+		PoolSize:      opt.PoolSize,
+		IdleTimeout:   opt.IdleTimeout,
+		PoolTimeout:   opt.PoolTimeout,
+		TLSConfig:     opt.TLSConfig,
+	})
+}
+
+// process the command using the go-redis client.
+// the result of the command could be read from the given cmd if it returns nil error.
+//
+// go-redis v6 doesn't use the context for its network operations.
+// The command is executed in another goroutine and the caller stops waiting when the ctx is done,
+// while the go-redis client keeps processing it until its read or write timeout.
+//
+// The command is observed by the hooks, its redis.Nil error is reported as nil error to the hooks.
+// It returns engine.ErrCircuitOpen without executing the command if the circuit breaker is open.
+func (g *GoRedis) process(ctx context.Context, cmd redis.Cmder) error {
+	args := cmd.Args()
+	name, _ := args[0].(string)
+	return g.processFunc(ctx, name, args[1:], func() error {
+		return g.exec(ctx, cmd)
+	})
+}
+
+// processFunc calls the fn which executes the command, like process,
+// e.g. for the command executed using the go-redis method
+func (g *GoRedis) processFunc(ctx context.Context, name string, args []interface{}, fn func() error) error {
+	var err error
+	hookErr := g.hooks.Process(ctx, name, args, func() error {
+		return g.breaker.Do(func() error {
+			err = fn()
+			if err == redis.Nil {
+				return nil
+			}
+			return err
+		})
+	})
+	if hookErr == engine.ErrCircuitOpen {
+		return hookErr
+	}
+	return err
+}
+
+// isBreakerFailure returns true if the err means that the server is unhealthy.
+// The errors replied by the server and the cancellation by the caller don't count.
+func isBreakerFailure(err error) bool {
+	err, _ = unwrapNotSent(err)
+	return isFailed(err) && err != context.Canceled
+}
+
+// exec executes the command, without calling the hooks
+func (g *GoRedis) exec(ctx context.Context, cmd redis.Cmder) error {
+	if ctx.Done() == nil {
+		return g.client.Process(cmd)
+	}
+	return runContext(ctx, func() error {
+		return g.client.Process(cmd)
+	})
+}
+
+// runContext runs the fn in another goroutine and stops waiting for it when the ctx is done.
+func runContext(ctx context.Context, fn func() error) error {
+	if ctx.Done() == nil {
+		// the ctx could not be cancelled, no need to spawn goroutine
+		return fn()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fn()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ping command to redis
+func (g *GoRedis) Ping() (string, error) {
+	return g.PingContext(context.Background())
+}
+
+// PingContext is Ping with context
+func (g *GoRedis) PingContext(ctx context.Context) (string, error) {
+	val, err := g.DoContext(ctx, "PING")
+	return fmt.Sprint(val), err
+}
+
+// Do Command
+func (g *GoRedis) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return g.DoContext(context.Background(), cmd, args...)
+}
+
+// DoContext is Do with context
+func (g *GoRedis) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	c := redis.NewCmd(append([]interface{}{cmd}, args...)...)
+	if err := g.process(ctx, c); err != nil {
+		return nil, err
+	}
+	return c.Val(), nil
+}
+
+// GetClient get the underlying go-redis client.
+// It is *redis.ClusterClient in cluster mode, otherwise *redis.Client.
+// Notes:
+// - Please only use it for the features not provided by this engine.
+func (g *GoRedis) GetClient() redis.UniversalClient {
+	return g.client
+}
+
+// IsErrNil returns true if the err given is ErrNil value.
+// in case of go-redis: it is redis.Nil.
+// Please use this func instead of comparing to redis.Nil directly
+// because each library has its own ErrNil definition.
+func (g *GoRedis) IsErrNil(err error) bool {
+	return err == redis.Nil
+}
+
+// Stats returns snapshot of the connection pool statistics.
+// go-redis doesn't report the waits & the dial failures, only the wait timeouts.
+func (g *GoRedis) Stats() engine.PoolStats {
+	client, ok := g.client.(interface{ PoolStats() *redis.PoolStats })
+	if !ok {
+		return engine.PoolStats{}
+	}
+
+	stats := client.PoolStats()
+	return engine.PoolStats{
+		ActiveCount:  int(stats.TotalConns),
+		IdleCount:    int(stats.IdleConns),
+		WaitTimeouts: int64(stats.Timeouts),
+	}
+}
+
+// AddHook registers the hook which observes the executed commands, see engine.Hook
+func (g *GoRedis) AddHook(hook engine.Hook) {
+	g.hooks.Add(hook)
+}
+
+// isRedisError returns true if the err is replied by the redis server
+func isRedisError(err error) bool {
+	return err != nil && reflect.TypeOf(err) == redisErrorType
+}
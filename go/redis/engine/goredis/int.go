@@ -0,0 +1,62 @@
+package goredis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis"
+)
+
+// Incr function
+func (g *GoRedis) Incr(key string) (int64, error) {
+	return g.IncrContext(context.Background(), key)
+}
+
+// IncrContext is Incr with context
+func (g *GoRedis) IncrContext(ctx context.Context, key string) (int64, error) {
+	return g.int64(ctx, "INCR", key)
+}
+
+// IncrBy function
+func (g *GoRedis) IncrBy(key string, value int64) (int64, error) {
+	return g.IncrByContext(context.Background(), key, value)
+}
+
+// IncrByContext is IncrBy with context
+func (g *GoRedis) IncrByContext(ctx context.Context, key string, value int64) (int64, error) {
+	return g.int64(ctx, "INCRBY", key, value)
+}
+
+// Decr function
+func (g *GoRedis) Decr(key string) (int64, error) {
+	return g.DecrContext(context.Background(), key)
+}
+
+// DecrContext is Decr with context
+func (g *GoRedis) DecrContext(ctx context.Context, key string) (int64, error) {
+	return g.int64(ctx, "DECR", key)
+}
+
+// DecrBy function
+func (g *GoRedis) DecrBy(key string, value int64) (int64, error) {
+	return g.DecrByContext(context.Background(), key, value)
+}
+
+// DecrByContext is DecrBy with context
+func (g *GoRedis) DecrByContext(ctx context.Context, key string, value int64) (int64, error) {
+	return g.int64(ctx, "DECRBY", key, value)
+}
+
+// int64 process the command which replies integer
+func (g *GoRedis) int64(ctx context.Context, args ...interface{}) (int64, error) {
+	cmd := redis.NewIntCmd(args...)
+	if err := g.process(ctx, cmd); err != nil {
+		return 0, err
+	}
+	return cmd.Val(), nil
+}
+
+// int is like int64 but returns int
+func (g *GoRedis) int(ctx context.Context, args ...interface{}) (int, error) {
+	val, err := g.int64(ctx, args...)
+	return int(val), err
+}
@@ -0,0 +1,53 @@
+package goredis
+
+import "context"
+
+// Expire set expiration time for a key
+// `expiry` is in seconds
+func (g *GoRedis) Expire(key string, expiry int) (int, error) {
+	return g.ExpireContext(context.Background(), key, expiry)
+}
+
+// ExpireContext is Expire with context
+func (g *GoRedis) ExpireContext(ctx context.Context, key string, expiry int) (int, error) {
+	return g.int(ctx, "EXPIRE", key, expiry)
+}
+
+// TTL return remaining ttl of a key
+// from: https://redis.io/commands/ttl
+// The command returns -2 if the key does not exist.
+// The command returns -1 if the key exists but has no associated expire.
+func (g *GoRedis) TTL(key string) (int, error) {
+	return g.TTLContext(context.Background(), key)
+}
+
+// TTLContext is TTL with context
+func (g *GoRedis) TTLContext(ctx context.Context, key string) (int, error) {
+	return g.int(ctx, "TTL", key)
+}
+
+// Exists check key existence
+func (g *GoRedis) Exists(key string) (bool, error) {
+	return g.ExistsContext(context.Background(), key)
+}
+
+// ExistsContext is Exists with context
+func (g *GoRedis) ExistsContext(ctx context.Context, key string) (bool, error) {
+	n, err := g.int64(ctx, "EXISTS", key)
+	return n > 0, err
+}
+
+// Delete function
+func (g *GoRedis) Delete(keys ...string) (int, error) {
+	return g.DeleteContext(context.Background(), keys...)
+}
+
+// DeleteContext is Delete with context
+func (g *GoRedis) DeleteContext(ctx context.Context, keys ...string) (int, error) {
+	args := make([]interface{}, len(keys)+1)
+	args[0] = "DEL"
+	for i, key := range keys {
+		args[i+1] = key
+	}
+	return g.int(ctx, args...)
+}
@@ -0,0 +1,78 @@
+package goredis
+
+import (
+	"context"
+)
+
+// RPush append values to the key
+func (g *GoRedis) RPush(key string, values ...string) (int, error) {
+	return g.RPushContext(context.Background(), key, values...)
+}
+
+// RPushContext is RPush with context
+func (g *GoRedis) RPushContext(ctx context.Context, key string, values ...string) (int, error) {
+	return g.int(ctx, keyStrings("RPUSH", key, values)...)
+}
+
+// RPop Removes and returns the last element of the list stored at key
+// return redis.Nil if the key is not exist
+func (g *GoRedis) RPop(key string) (string, error) {
+	return g.RPopContext(context.Background(), key)
+}
+
+// RPopContext is RPop with context
+func (g *GoRedis) RPopContext(ctx context.Context, key string) (string, error) {
+	return g.string(ctx, "RPOP", key)
+}
+
+// LLen get the length of the list
+func (g *GoRedis) LLen(key string) (int64, error) {
+	return g.LLenContext(context.Background(), key)
+}
+
+// LLenContext is LLen with context
+func (g *GoRedis) LLenContext(ctx context.Context, key string) (int64, error) {
+	return g.int64(ctx, "LLEN", key)
+}
+
+// LPush prepend values to the list
+func (g *GoRedis) LPush(key string, values ...string) (int, error) {
+	return g.LPushContext(context.Background(), key, values...)
+}
+
+// LPushContext is LPush with context
+func (g *GoRedis) LPushContext(ctx context.Context, key string, values ...string) (int, error) {
+	return g.int(ctx, keyStrings("LPUSH", key, values)...)
+}
+
+// LPop removes and get the first element in the list
+// return redis.Nil if the key is not exist
+func (g *GoRedis) LPop(key string) (string, error) {
+	return g.LPopContext(context.Background(), key)
+}
+
+// LPopContext is LPop with context
+func (g *GoRedis) LPopContext(ctx context.Context, key string) (string, error) {
+	return g.string(ctx, "LPOP", key)
+}
+
+// LRange returns the specified elements of the list stored at key
+func (g *GoRedis) LRange(key string, start, stop int64) ([]string, error) {
+	return g.LRangeContext(context.Background(), key, start, stop)
+}
+
+// LRangeContext is LRange with context
+func (g *GoRedis) LRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return g.strings(ctx, "LRANGE", key, start, stop)
+}
+
+// keyStrings builds the arguments of command which receives a key followed by string values
+func keyStrings(cmd, key string, values []string) []interface{} {
+	args := make([]interface{}, len(values)+2)
+	args[0] = cmd
+	args[1] = key
+	for i, value := range values {
+		args[i+2] = value
+	}
+	return args
+}
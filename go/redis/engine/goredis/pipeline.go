@@ -0,0 +1,357 @@
+package goredis
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/go-redis/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const (
+	// default value of pipeline's numCmdHint
+	defaultPipelineNumCmdHint = 2
+)
+
+// pipelineCmdErr is pipeline command, reply, and error
+type pipelineCmdErr struct {
+	cmd   string
+	args  []interface{}
+	reply interface{}
+	err   error
+
+	// result is set after the pipeline is executed
+	result engine.ReplySetter
+
+	// idempotent command is retried even if it failed after it was sent
+	idempotent bool
+}
+
+func (pce pipelineCmdErr) Name() string {
+	return pce.cmd
+}
+
+func (pce pipelineCmdErr) Args() []interface{} {
+	return pce.args
+}
+
+func (pce pipelineCmdErr) Err() error {
+	return pce.err
+}
+
+// Pipeline creates new go-redis pipeline
+func (g *GoRedis) Pipeline(retry, cmdNumHint int) engine.Pipeliner {
+	return g.PipelineContext(context.Background(), retry, cmdNumHint)
+}
+
+// PipelineContext is Pipeline with context.
+// The ctx bounds every execution of the pipeline.
+func (g *GoRedis) PipelineContext(ctx context.Context, retry, cmdNumHint int) engine.Pipeliner {
+	if retry <= 0 {
+		retry = 1
+	}
+	if cmdNumHint == 0 {
+		cmdNumHint = defaultPipelineNumCmdHint
+	}
+
+	p := &pipeline{
+		ctx:        ctx,
+		cli:        g,
+		retry:      retry,
+		cmdNumHint: cmdNumHint,
+		chunkSize:  g.pipelineChunkSize,
+	}
+	p.resetCmdBuf()
+	return p
+}
+
+// pipeline is helper for go-redis pipelined command.
+// This pipeline could be used multiple times and from concurrent goroutines
+type pipeline struct {
+	ctx context.Context
+	cli *GoRedis
+
+	mux sync.Mutex
+
+	// cmdErrs is buffer of command sent to this pipeline
+	cmdErrs []engine.CmdErr
+
+	// number of retry we do in case of pipeline execution failed.
+	retry int
+
+	// hints about number of commands on each pipeline
+	cmdNumHint int
+
+	// maximum number of commands on each execution, zero means no limit
+	chunkSize int
+}
+
+// AddRawCmd adds raw redis command to the pipeline
+func (p *pipeline) AddRawCmd(cmd string, args ...interface{}) *engine.Result {
+	res := &engine.Result{}
+	p.add(res, cmd, args...)
+	return res
+}
+
+// AddIdempotentCmd adds raw idempotent redis command to the pipeline
+func (p *pipeline) AddIdempotentCmd(cmd string, args ...interface{}) *engine.Result {
+	res := &engine.Result{}
+	p.addIdempotent(res, cmd, args...)
+	return res
+}
+
+// add adds the command and its result to the pipeline
+func (p *pipeline) add(res engine.ReplySetter, cmd string, args ...interface{}) {
+	p.addCmd(pipelineCmdErr{cmd: cmd, args: args, result: res})
+}
+
+// addIdempotent adds the idempotent command and its result to the pipeline
+func (p *pipeline) addIdempotent(res engine.ReplySetter, cmd string, args ...interface{}) {
+	p.addCmd(pipelineCmdErr{cmd: cmd, args: args, result: res, idempotent: true})
+}
+
+func (p *pipeline) addCmd(pce pipelineCmdErr) {
+	p.mux.Lock()
+	p.cmdErrs = append(p.cmdErrs, pce)
+	p.mux.Unlock()
+}
+
+// Exec executes the pipeline, chunk by chunk
+func (p *pipeline) Exec() ([]engine.CmdErr, int, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	defer p.resetCmdBuf()
+
+	return p.cli.hooks.ProcessPipeline(p.ctx, p.cmdErrs, p.execChunks)
+}
+
+// execChunks executes the queued commands chunk by chunk, without calling the hooks
+func (p *pipeline) execChunks() ([]engine.CmdErr, int, error) {
+	var chunkErrs []*engine.ChunkError
+	for start := 0; start < len(p.cmdErrs); {
+		end := len(p.cmdErrs)
+		if p.chunkSize > 0 && end-start > p.chunkSize {
+			end = start + p.chunkSize
+		}
+		if chunkErr := p.execChunk(p.cmdErrs[start:end]); chunkErr != nil {
+			chunkErr.Start, chunkErr.End = start, end
+			chunkErrs = append(chunkErrs, chunkErr)
+		}
+		start = end
+	}
+
+	firstErr := -1
+	for i, cmd := range p.cmdErrs {
+		pce := cmd.(pipelineCmdErr)
+		if pce.err == nil && pce.reply == nil {
+			pce.result.SetReply(nil, redis.Nil)
+			continue
+		}
+		pce.result.SetReply(pce.reply, pce.err)
+		if pce.err != nil && firstErr < 0 {
+			firstErr = i
+		}
+	}
+
+	if len(chunkErrs) > 0 {
+		return p.cmdErrs, firstErr, &engine.PipelineError{Chunks: chunkErrs}
+	}
+	return p.cmdErrs, firstErr, nil
+}
+
+// execChunk executes the chunk's commands, retrying the failed commands which are safe to retry.
+// It sets the reply & the error of the commands, and returns non nil error if some of them still failed.
+func (p *pipeline) execChunk(cmdErrs []engine.CmdErr) *engine.ChunkError {
+	var (
+		pending = make([]int, len(cmdErrs)) // index of the commands to be executed
+		sent    bool
+	)
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for i := 0; i < p.retry && len(pending) > 0; i++ {
+		if i > 0 && p.ctx.Err() != nil {
+			// no need to retry, the ctx is already done
+			break
+		}
+
+		cmds := make([]engine.CmdErr, len(pending))
+		for j, idx := range pending {
+			cmds[j] = cmdErrs[idx]
+		}
+
+		ret, err := p.exec(cmds)
+
+		var retry []int
+		for j, idx := range pending {
+			pce := cmdErrs[idx].(pipelineCmdErr)
+			if err != nil {
+				// the whole execution failed
+				pce.reply, pce.err = nil, err
+			} else {
+				executed := ret[j].(pipelineCmdErr)
+				pce.reply, pce.err = executed.reply, executed.err
+			}
+
+			var notSent bool
+			pce.err, notSent = unwrapNotSent(pce.err)
+			cmdErrs[idx] = pce
+
+			if !isFailed(pce.err) {
+				continue
+			}
+			if notSent || pce.idempotent {
+				retry = append(retry, idx)
+			}
+			sent = sent || !notSent
+		}
+		pending = retry
+	}
+
+	for _, cmd := range cmdErrs {
+		if isFailed(cmd.Err()) {
+			return &engine.ChunkError{Sent: sent, Err: cmd.Err()}
+		}
+	}
+	return nil
+}
+
+// exec executes the commands once, if the circuit breaker allows it.
+// The err is returned if the whole execution failed, it is wrapped by notSentError if none of the commands was sent.
+func (p *pipeline) exec(cmdErrs []engine.CmdErr) (ret []engine.CmdErr, err error) {
+	if err = p.ctx.Err(); err != nil {
+		return nil, notSentError{err}
+	}
+
+	breakerErr := p.cli.breaker.Do(func() error {
+		ret, err = p.execOnce(cmdErrs)
+		if err != nil {
+			return err
+		}
+		return failedErr(ret)
+	})
+	if breakerErr == engine.ErrCircuitOpen {
+		return nil, notSentError{breakerErr}
+	}
+	return ret, err
+}
+
+// execOnce executes the commands once, see exec
+func (p *pipeline) execOnce(cmdErrs []engine.CmdErr) ([]engine.CmdErr, error) {
+	// copy the buffered commands to the go-redis pipeline.
+	// we don't do it earlier because the go-redis pipeline discards
+	// its commands after execution, while we need them for the retry.
+	pipe := p.cli.client.Pipeline()
+
+	cmds := make([]*redis.Cmd, len(cmdErrs))
+	for i, cmd := range cmdErrs {
+		cmds[i] = redis.NewCmd(append([]interface{}{cmd.Name()}, cmd.Args()...)...)
+		pipe.Process(cmds[i])
+	}
+
+	err := runContext(p.ctx, func() error {
+		_, err := pipe.Exec()
+		return err
+	})
+	if err != nil && err == p.ctx.Err() {
+		// the go-redis pipeline might be still running, its commands could not be read
+		return nil, err
+	}
+
+	// the error of the failed command, the error of the pipeline might be replied by the server
+	var failedErr error
+	for _, cmd := range cmds {
+		if isFailed(cmd.Err()) {
+			failedErr = cmd.Err()
+			break
+		}
+	}
+
+	notSent := isNotSentErr(failedErr)
+	ret := make([]engine.CmdErr, len(cmds))
+	for i, cmd := range cmds {
+		pce := cmdErrs[i].(pipelineCmdErr)
+		pce.reply, pce.err = cmd.Val(), cmd.Err()
+		if pce.err == redis.Nil {
+			// nil reply, it is not an error of the command
+			pce.err = nil
+		} else if pce.reply == nil && pce.err == nil {
+			// go-redis stops reading the replies after the network error
+			pce.err = failedErr
+		}
+		if notSent && isFailed(pce.err) {
+			pce.err = notSentError{pce.err}
+		}
+		ret[i] = pce
+	}
+	return ret, nil
+}
+
+// failedErr returns error of the first failed command, see isFailed
+func failedErr(cmdErrs []engine.CmdErr) error {
+	for _, cmd := range cmdErrs {
+		if err := cmd.Err(); isFailed(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// notSentError is error of the pipelined command which failed before it was sent to the server
+type notSentError struct {
+	err error
+}
+
+func (e notSentError) Error() string {
+	return e.err.Error()
+}
+
+// unwrapNotSent returns the error wrapped by notSentError, and whether it is wrapped
+func unwrapNotSent(err error) (error, bool) {
+	if nse, ok := err.(notSentError); ok {
+		return nse.err, true
+	}
+	return err, false
+}
+
+// isFailed returns true if the pipelined command failed without getting its reply,
+// the error replied by the server doesn't count
+func isFailed(err error) bool {
+	return err != nil && !isRedisError(err)
+}
+
+// isNotSentErr returns true if the go-redis pipeline failed to get the connection,
+// so none of its commands was sent
+func isNotSentErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		return opErr.Op == "dial"
+	}
+	// go-redis keeps the pool errors internal
+	msg := err.Error()
+	return msg == "redis: client is closed" || msg == "redis: connection pool timeout"
+}
+
+// Discard resets the pipeline and discards queued commands
+func (p *pipeline) Discard() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.resetCmdBuf()
+	return nil
+}
+
+// Close closes the pipeline, releasing any open resources.
+func (p *pipeline) Close() error {
+	return nil
+}
+
+func (p *pipeline) resetCmdBuf() {
+	p.cmdErrs = make([]engine.CmdErr, 0, p.cmdNumHint)
+}
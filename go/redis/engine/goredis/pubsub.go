@@ -0,0 +1,230 @@
+package goredis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// buffer size of the message channel, the same as go-redis's
+const pubSubChannelSize = 100
+
+// Publish posts the message to the channel, and returns number of the clients receiving the message
+func (g *GoRedis) Publish(channel string, message interface{}) (int64, error) {
+	return g.PublishContext(context.Background(), channel, message)
+}
+
+// PublishContext is Publish with context
+func (g *GoRedis) PublishContext(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return g.int64(ctx, "PUBLISH", channel, message)
+}
+
+// Subscribe subscribes the channels using a dedicated connection.
+// The caller must close the returned PubSub after using it
+func (g *GoRedis) Subscribe(channels ...string) (engine.PubSub, error) {
+	return g.SubscribeContext(context.Background(), channels...)
+}
+
+// SubscribeContext is Subscribe with context.
+// The ctx only bounds the connecting & subscribing, not the lifetime of the PubSub
+func (g *GoRedis) SubscribeContext(ctx context.Context, channels ...string) (engine.PubSub, error) {
+	return newPubSub(ctx, g.client.Subscribe(), channels, nil)
+}
+
+// PSubscribe subscribes the channels matching the patterns using a dedicated connection.
+// The caller must close the returned PubSub after using it
+func (g *GoRedis) PSubscribe(patterns ...string) (engine.PubSub, error) {
+	return g.PSubscribeContext(context.Background(), patterns...)
+}
+
+// PSubscribeContext is PSubscribe with context.
+// The ctx only bounds the connecting & subscribing, not the lifetime of the PubSub
+func (g *GoRedis) PSubscribeContext(ctx context.Context, patterns ...string) (engine.PubSub, error) {
+	return newPubSub(ctx, g.client.Subscribe(), nil, patterns)
+}
+
+// pubSub is engine.PubSub wrapping go-redis PubSub.
+//
+// go-redis already pings the connection periodically (every 30 seconds),
+// and resubscribes all of the channels & patterns after reconnecting.
+type pubSub struct {
+	ps     *redis.PubSub
+	msgCh  chan engine.Message
+	exitCh chan struct{}
+
+	// the subscribed channels & patterns, go-redis doesn't forget them
+	// when Unsubscribe & PUnsubscribe are called without argument
+	mux      sync.Mutex
+	channels map[string]struct{}
+	patterns map[string]struct{}
+
+	closeOnce sync.Once
+}
+
+// newPubSub subscribes the channels & patterns, and waits until all of them are subscribed
+func newPubSub(ctx context.Context, ps *redis.PubSub, channels, patterns []string) (*pubSub, error) {
+	p := &pubSub{
+		ps:       ps,
+		msgCh:    make(chan engine.Message, pubSubChannelSize),
+		exitCh:   make(chan struct{}),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+
+	err := p.Subscribe(channels...)
+	if err == nil {
+		err = p.PSubscribe(patterns...)
+	}
+	if err == nil {
+		// the duplicates are subscribed once
+		err = p.waitSubscribed(ctx, len(p.channels)+len(p.patterns))
+	}
+	if err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	go p.forward()
+	return p, nil
+}
+
+// waitSubscribed waits for the subscription confirmations.
+// The messages received in the meantime are kept in the message channel.
+func (p *pubSub) waitSubscribed(ctx context.Context, num int) error {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	for num > 0 {
+		reply, err := p.ps.ReceiveTimeout(timeout)
+		if err != nil {
+			return err
+		}
+
+		switch v := reply.(type) {
+		case *redis.Subscription:
+			num--
+		case *redis.Message:
+			select {
+			case p.msgCh <- toMessage(v):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// forward forwards the messages from go-redis channel, which is closed after the go-redis PubSub is closed
+func (p *pubSub) forward() {
+	defer close(p.msgCh)
+
+	for msg := range p.ps.Channel() {
+		select {
+		case p.msgCh <- toMessage(msg):
+		case <-p.exitCh:
+			return
+		}
+	}
+}
+
+// Subscribe subscribes the channels
+func (p *pubSub) Subscribe(channels ...string) error {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.ps.Subscribe(addUnique(p.channels, channels)...)
+}
+
+// PSubscribe subscribes the channels matching the patterns
+func (p *pubSub) PSubscribe(patterns ...string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.ps.PSubscribe(addUnique(p.patterns, patterns)...)
+}
+
+// Unsubscribe unsubscribes the channels, or all of the channels if it is called without argument
+func (p *pubSub) Unsubscribe(channels ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.ps.Unsubscribe(removeAll(p.channels, channels)...)
+}
+
+// PUnsubscribe unsubscribes the patterns, or all of the patterns if it is called without argument
+func (p *pubSub) PUnsubscribe(patterns ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.ps.PUnsubscribe(removeAll(p.patterns, patterns)...)
+}
+
+// Channel returns channel of the received messages.
+// It is closed after the PubSub is closed
+func (p *pubSub) Channel() <-chan engine.Message {
+	return p.msgCh
+}
+
+// Close unsubscribes everything and closes the connection
+func (p *pubSub) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.exitCh)
+		err = p.ps.Close()
+	})
+	return err
+}
+
+func toMessage(m *redis.Message) engine.Message {
+	return engine.Message{
+		Channel: m.Channel,
+		Pattern: m.Pattern,
+		Payload: m.Payload,
+	}
+}
+
+// removeAll removes the values from the set and returns the removed values.
+// It removes all of the values in the set if vals is empty.
+func removeAll(set map[string]struct{}, vals []string) []string {
+	if len(vals) == 0 {
+		vals = make([]string, 0, len(set))
+		for v := range set {
+			vals = append(vals, v)
+		}
+	}
+
+	for _, v := range vals {
+		delete(set, v)
+	}
+	return vals
+}
+
+// addUnique adds the values to the set, and returns the values without the duplicates.
+// The server confirms each of the subscribed values, even the duplicates
+func addUnique(set map[string]struct{}, vals []string) []string {
+	unique := make([]string, 0, len(vals))
+	seen := make(map[string]struct{}, len(vals))
+	for _, v := range vals {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		set[v] = struct{}{}
+		unique = append(unique, v)
+	}
+	return unique
+}
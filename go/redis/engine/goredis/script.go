@@ -0,0 +1,57 @@
+package goredis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Eval executes the lua script.
+// The reply is converted the same way by all of the engines:
+// integer to int64, bulk & simple string to string, array to []interface{}, and nil to ErrNil.
+func (g *GoRedis) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return g.EvalContext(context.Background(), script, keys, args...)
+}
+
+// EvalContext is Eval with context
+func (g *GoRedis) EvalContext(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return g.DoContext(ctx, "EVAL", engine.EvalArgs(script, keys, args...)...)
+}
+
+// EvalSha executes the lua script cached in the server by its SHA1 digest.
+// It returns NOSCRIPT error if the script is not cached.
+func (g *GoRedis) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return g.EvalShaContext(context.Background(), sha1, keys, args...)
+}
+
+// EvalShaContext is EvalSha with context
+func (g *GoRedis) EvalShaContext(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return g.DoContext(ctx, "EVALSHA", engine.EvalArgs(sha1, keys, args...)...)
+}
+
+// ScriptLoad caches the lua script in the server and returns its SHA1 digest.
+// In cluster mode, the script is cached in all of the master nodes.
+func (g *GoRedis) ScriptLoad(script string) (string, error) {
+	return g.ScriptLoadContext(context.Background(), script)
+}
+
+// ScriptLoadContext is ScriptLoad with context
+func (g *GoRedis) ScriptLoadContext(ctx context.Context, script string) (string, error) {
+	cc, ok := g.client.(*redis.ClusterClient)
+	if !ok {
+		return g.string(ctx, "SCRIPT", "LOAD", script)
+	}
+
+	// go-redis sends SCRIPT LOAD to a random node
+	err := runContext(ctx, func() error {
+		return cc.ForEachMaster(func(master *redis.Client) error {
+			return master.ScriptLoad(script).Err()
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return engine.ScriptHash(script), nil
+}
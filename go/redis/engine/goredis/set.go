@@ -0,0 +1,41 @@
+package goredis
+
+import "context"
+
+// SAdd Add the specified members to the set stored at key.
+// Specified members that are already a member of this set are ignored.
+// If key does not exist, a new set is created before adding the specified members.
+// An error is returned when the value stored at key is not a set.
+func (g *GoRedis) SAdd(key string, members ...interface{}) (int64, error) {
+	return g.SAddContext(context.Background(), key, members...)
+}
+
+// SAddContext is SAdd with context
+func (g *GoRedis) SAddContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	args := append([]interface{}{"SADD", key}, members...)
+	return g.int64(ctx, args...)
+}
+
+// SRem Remove the specified members from the set stored at key.
+// Specified members that are not a member of this set are ignored.
+// If key does not exist, it is treated as an empty set and this command returns 0.
+// An error is returned when the value stored at key is not a set.
+func (g *GoRedis) SRem(key string, members ...interface{}) (int64, error) {
+	return g.SRemContext(context.Background(), key, members...)
+}
+
+// SRemContext is SRem with context
+func (g *GoRedis) SRemContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	args := append([]interface{}{"SREM", key}, members...)
+	return g.int64(ctx, args...)
+}
+
+// SMembers Returns all the members of the set value stored at key.
+func (g *GoRedis) SMembers(key string) ([]string, error) {
+	return g.SMembersContext(context.Background(), key)
+}
+
+// SMembersContext is SMembers with context
+func (g *GoRedis) SMembersContext(ctx context.Context, key string) ([]string, error) {
+	return g.strings(ctx, "SMEMBERS", key)
+}
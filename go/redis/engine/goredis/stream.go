@@ -0,0 +1,253 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+var errInvalidStreamReply = errors.New("goredis: invalid stream reply")
+
+// XAdd appends new entry to the stream and returns ID of the entry
+func (g *GoRedis) XAdd(args engine.XAddArgs) (string, error) {
+	return g.XAddContext(context.Background(), args)
+}
+
+// XAddContext is XAdd with context
+func (g *GoRedis) XAddContext(ctx context.Context, args engine.XAddArgs) (string, error) {
+	return g.string(ctx, append([]interface{}{"XADD"}, args.CmdArgs()...)...)
+}
+
+// XRead reads the messages from the streams.
+// It returns empty result if there is no message until the block duration passed.
+func (g *GoRedis) XRead(args engine.XReadArgs) ([]engine.XStream, error) {
+	return g.XReadContext(context.Background(), args)
+}
+
+// XReadContext is XRead with context
+func (g *GoRedis) XReadContext(ctx context.Context, args engine.XReadArgs) ([]engine.XStream, error) {
+	// use the go-redis method, it extends the read timeout by the block duration
+	var cmd *redis.XStreamSliceCmd
+	err := g.processFunc(ctx, "XREAD", args.CmdArgs(), func() error {
+		return runContext(ctx, func() error {
+			cmd = g.client.XRead(&redis.XReadArgs{
+				Streams: append(append([]string{}, args.Streams...), args.IDs...),
+				Count:   args.Count,
+				Block:   goRedisBlock(args.Block),
+			})
+			return cmd.Err()
+		})
+	})
+	return xStreams(cmd, err)
+}
+
+// XReadGroup reads the messages from the streams as a consumer of the group.
+// It returns empty result if there is no message until the block duration passed.
+func (g *GoRedis) XReadGroup(args engine.XReadGroupArgs) ([]engine.XStream, error) {
+	return g.XReadGroupContext(context.Background(), args)
+}
+
+// XReadGroupContext is XReadGroup with context
+func (g *GoRedis) XReadGroupContext(ctx context.Context, args engine.XReadGroupArgs) ([]engine.XStream, error) {
+	// use the go-redis method, it extends the read timeout by the block duration
+	var cmd *redis.XStreamSliceCmd
+	err := g.processFunc(ctx, "XREADGROUP", args.CmdArgs(), func() error {
+		return runContext(ctx, func() error {
+			cmd = g.client.XReadGroup(&redis.XReadGroupArgs{
+				Group:    args.Group,
+				Consumer: args.Consumer,
+				Streams:  append(append([]string{}, args.Streams...), args.IDs...),
+				Count:    args.Count,
+				Block:    goRedisBlock(args.Block),
+				NoAck:    args.NoAck,
+			})
+			return cmd.Err()
+		})
+	})
+	return xStreams(cmd, err)
+}
+
+// XAck removes the messages from the pending entries list of the group,
+// and returns number of the acknowledged messages
+func (g *GoRedis) XAck(stream, group string, ids ...string) (int64, error) {
+	return g.XAckContext(context.Background(), stream, group, ids...)
+}
+
+// XAckContext is XAck with context
+func (g *GoRedis) XAckContext(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	args := make([]interface{}, 0, len(ids)+3)
+	args = append(args, "XACK", stream, group)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return g.int64(ctx, args...)
+}
+
+// XPending returns summary of the pending messages of the group
+func (g *GoRedis) XPending(stream, group string) (engine.XPending, error) {
+	return g.XPendingContext(context.Background(), stream, group)
+}
+
+// XPendingContext is XPending with context
+func (g *GoRedis) XPendingContext(ctx context.Context, stream, group string) (engine.XPending, error) {
+	cmd := redis.NewXPendingCmd("XPENDING", stream, group)
+	if err := g.process(ctx, cmd); err != nil {
+		if err == redis.Nil {
+			// go-redis can't parse the nil consumers of the empty pending list
+			return engine.XPending{}, nil
+		}
+		return engine.XPending{}, err
+	}
+
+	val := cmd.Val()
+	return engine.XPending{
+		Count:     val.Count,
+		Lower:     val.Lower,
+		Higher:    val.Higher,
+		Consumers: val.Consumers,
+	}, nil
+}
+
+// XPendingExt returns the pending messages of the group
+func (g *GoRedis) XPendingExt(args engine.XPendingExtArgs) ([]engine.XPendingExt, error) {
+	return g.XPendingExtContext(context.Background(), args)
+}
+
+// XPendingExtContext is XPendingExt with context
+func (g *GoRedis) XPendingExtContext(ctx context.Context, args engine.XPendingExtArgs) ([]engine.XPendingExt, error) {
+	cmd := redis.NewXPendingExtCmd(append([]interface{}{"XPENDING"}, args.CmdArgs()...)...)
+	if err := g.process(ctx, cmd); err != nil {
+		return nil, err
+	}
+
+	vals := cmd.Val()
+	pendings := make([]engine.XPendingExt, len(vals))
+	for i, val := range vals {
+		pendings[i] = engine.XPendingExt{
+			ID:         val.Id,
+			Consumer:   val.Consumer,
+			Idle:       val.Idle,
+			RetryCount: val.RetryCount,
+		}
+	}
+	return pendings, nil
+}
+
+// XClaim changes owner of the pending messages to the consumer, and returns the claimed messages
+func (g *GoRedis) XClaim(args engine.XClaimArgs) ([]engine.XMessage, error) {
+	return g.XClaimContext(context.Background(), args)
+}
+
+// XClaimContext is XClaim with context
+func (g *GoRedis) XClaimContext(ctx context.Context, args engine.XClaimArgs) ([]engine.XMessage, error) {
+	cmd := redis.NewXMessageSliceCmd(append([]interface{}{"XCLAIM"}, args.CmdArgs()...)...)
+	if err := g.process(ctx, cmd); err != nil {
+		return nil, err
+	}
+	return toXMessages(cmd.Val()), nil
+}
+
+// XAutoClaim claims the pending messages idle for at least args.MinIdle, and returns the claimed messages.
+// `next` is the start ID of the next call, it is "0-0" if all of the pending messages are scanned.
+func (g *GoRedis) XAutoClaim(args engine.XAutoClaimArgs) ([]engine.XMessage, string, error) {
+	return g.XAutoClaimContext(context.Background(), args)
+}
+
+// XAutoClaimContext is XAutoClaim with context
+func (g *GoRedis) XAutoClaimContext(ctx context.Context, args engine.XAutoClaimArgs) ([]engine.XMessage, string, error) {
+	// go-redis v6 doesn't have XAUTOCLAIM, parse the raw reply.
+	// reply: next ID, [[id, [field, value, ...]], ...], [deleted IDs (redis 7)]
+	values, err := g.values(ctx, append([]interface{}{"XAUTOCLAIM"}, args.CmdArgs()...)...)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(values) < 2 {
+		return nil, "", errInvalidStreamReply
+	}
+
+	entries, _ := values[1].([]interface{})
+	messages := make([]engine.XMessage, len(entries))
+	for i, entry := range entries {
+		fields, ok := entry.([]interface{})
+		if !ok || len(fields) != 2 {
+			return nil, "", errInvalidStreamReply
+		}
+
+		messages[i].ID = fmt.Sprint(fields[0])
+		kvs, _ := fields[1].([]interface{})
+		if len(kvs) > 0 {
+			messages[i].Values = make(map[string]string, len(kvs)/2)
+		}
+		for j := 0; j+1 < len(kvs); j += 2 {
+			messages[i].Values[fmt.Sprint(kvs[j])] = fmt.Sprint(kvs[j+1])
+		}
+	}
+	return messages, fmt.Sprint(values[0]), nil
+}
+
+// XGroupCreate creates consumer group of the stream, which starts reading from the `start` ID.
+// It creates the stream if it doesn't exist and mkStream is true.
+func (g *GoRedis) XGroupCreate(stream, group, start string, mkStream bool) error {
+	return g.XGroupCreateContext(context.Background(), stream, group, start, mkStream)
+}
+
+// XGroupCreateContext is XGroupCreate with context
+func (g *GoRedis) XGroupCreateContext(ctx context.Context, stream, group, start string, mkStream bool) error {
+	_, err := g.status(ctx, append([]interface{}{"XGROUP"}, engine.XGroupCreateArgs(stream, group, start, mkStream)...)...)
+	return err
+}
+
+// goRedisBlock converts the block duration to go-redis's.
+// go-redis sends BLOCK if the duration is not negative.
+func goRedisBlock(block time.Duration) time.Duration {
+	switch {
+	case block <= 0:
+		return -1
+	case block < time.Millisecond:
+		// BLOCK 0 means blocking forever
+		return time.Millisecond
+	}
+	return block
+}
+
+// xStreams converts the result of XREAD & XREADGROUP.
+// redis.Nil means there is no message.
+func xStreams(cmd *redis.XStreamSliceCmd, err error) ([]engine.XStream, error) {
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	vals := cmd.Val()
+	streams := make([]engine.XStream, len(vals))
+	for i, val := range vals {
+		streams[i] = engine.XStream{
+			Stream:   val.Stream,
+			Messages: toXMessages(val.Messages),
+		}
+	}
+	return streams, nil
+}
+
+func toXMessages(vals []redis.XMessage) []engine.XMessage {
+	messages := make([]engine.XMessage, len(vals))
+	for i, val := range vals {
+		messages[i].ID = val.ID
+		if val.Values == nil {
+			continue
+		}
+
+		messages[i].Values = make(map[string]string, len(val.Values))
+		for k, v := range val.Values {
+			messages[i].Values[k] = fmt.Sprint(v)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,183 @@
+package goredis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Set key and value
+func (g *GoRedis) Set(key string, value interface{}) error {
+	return g.SetContext(context.Background(), key, value)
+}
+
+// SetContext is Set with context
+func (g *GoRedis) SetContext(ctx context.Context, key string, value interface{}) error {
+	ok, err := g.status(ctx, "SET", key, value)
+	if ok != "OK" && err == nil {
+		return engine.ErrNotOK
+	}
+	return err
+}
+
+// SetNX do SETNX (only set if not exist) with SET's NX & EX args.
+// It sets the key which will expired in `expire` seconds
+func (g *GoRedis) SetNX(key string, value interface{}, expire int) (string, error) {
+	return g.SetNXContext(context.Background(), key, value, expire)
+}
+
+// SetNXContext is SetNX with context
+func (g *GoRedis) SetNXContext(ctx context.Context, key string, value interface{}, expire int) (string, error) {
+	resp, err := g.status(ctx, "SET", key, value, "NX", "EX", expire)
+	if err == redis.Nil {
+		// the key already exist, it is not an error
+		return "", nil
+	}
+	return resp, err
+}
+
+// SetEX key and value
+// It sets the key wich will expired in `expire` seconds
+func (g *GoRedis) SetEX(key string, value interface{}, expire int) (string, error) {
+	return g.SetEXContext(context.Background(), key, value, expire)
+}
+
+// SetEXContext is SetEX with context
+func (g *GoRedis) SetEXContext(ctx context.Context, key string, value interface{}, expire int) (string, error) {
+	return g.status(ctx, "SETEX", key, expire, value)
+}
+
+// Get string value
+func (g *GoRedis) Get(key string) (string, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext is Get with context
+func (g *GoRedis) GetContext(ctx context.Context, key string) (string, error) {
+	return g.string(ctx, "GET", key)
+}
+
+// MSet keys and values
+// please use basic types only (no struct, array, or map) for arguments
+func (g *GoRedis) MSet(pairs ...interface{}) error {
+	return g.MSetContext(context.Background(), pairs...)
+}
+
+// MSetContext is MSet with context
+func (g *GoRedis) MSetContext(ctx context.Context, pairs ...interface{}) error {
+	ok, err := g.status(ctx, append([]interface{}{"MSET"}, pairs...)...)
+	if ok != "OK" && err == nil {
+		return engine.ErrNotOK
+	}
+	return err
+}
+
+// MGet keys
+func (g *GoRedis) MGet(keys ...string) ([]string, error) {
+	return g.MGetContext(context.Background(), keys...)
+}
+
+// MGetContext is MGet with context
+func (g *GoRedis) MGetContext(ctx context.Context, keys ...string) ([]string, error) {
+	args := make([]interface{}, len(keys)+1)
+	args[0] = "MGET"
+	for i, key := range keys {
+		args[i+1] = key
+	}
+	return g.strings(ctx, args...)
+}
+
+// HSetEX key and value and sets the expiration to the given `expire` seconds
+func (g *GoRedis) HSetEX(key, field string, value interface{}, expire int) (int, error) {
+	return g.HSetEXContext(context.Background(), key, field, value, expire)
+}
+
+// HSetEXContext is HSetEX with context
+func (g *GoRedis) HSetEXContext(ctx context.Context, key, field string, value interface{}, expire int) (int, error) {
+	if _, err := g.int64(ctx, "HSET", key, field, value); err != nil {
+		return 0, err
+	}
+	return g.int(ctx, "EXPIRE", key, expire)
+}
+
+// HGet key and value
+func (g *GoRedis) HGet(key, field string) (string, error) {
+	return g.HGetContext(context.Background(), key, field)
+}
+
+// HGetContext is HGet with context
+func (g *GoRedis) HGetContext(ctx context.Context, key, field string) (string, error) {
+	return g.string(ctx, "HGET", key, field)
+}
+
+// HMSet function
+// please use basic types only (no struct, array, or map) for kv value
+func (g *GoRedis) HMSet(key string, kv map[string]interface{}) (string, error) {
+	return g.HMSetContext(context.Background(), key, kv)
+}
+
+// HMSetContext is HMSet with context
+func (g *GoRedis) HMSetContext(ctx context.Context, key string, kv map[string]interface{}) (string, error) {
+	var (
+		args = make([]interface{}, 2+(len(kv)*2))
+		idx  = 2
+	)
+	args[0] = "HMSET"
+	args[1] = key
+	for k, v := range kv {
+		args[idx] = k
+		args[idx+1] = v
+		idx += 2
+	}
+	return g.status(ctx, args...)
+}
+
+// HMGet keys and value
+func (g *GoRedis) HMGet(key string, fields ...string) ([]string, error) {
+	return g.HMGetContext(context.Background(), key, fields...)
+}
+
+// HMGetContext is HMGet with context
+func (g *GoRedis) HMGetContext(ctx context.Context, key string, fields ...string) ([]string, error) {
+	return g.strings(ctx, keyStrings("HMGET", key, fields)...)
+}
+
+// HDel fields of a key
+func (g *GoRedis) HDel(key string, fields ...string) (int, error) {
+	return g.HDelContext(context.Background(), key, fields...)
+}
+
+// HDelContext is HDel with context
+func (g *GoRedis) HDelContext(ctx context.Context, key string, fields ...string) (int, error) {
+	return g.int(ctx, keyStrings("HDEL", key, fields)...)
+}
+
+// Append string to existing value in the key
+func (g *GoRedis) Append(key, value string) (int, error) {
+	return g.AppendContext(context.Background(), key, value)
+}
+
+// AppendContext is Append with context
+func (g *GoRedis) AppendContext(ctx context.Context, key, value string) (int, error) {
+	return g.int(ctx, "APPEND", key, value)
+}
+
+// string process the command which replies bulk string
+func (g *GoRedis) string(ctx context.Context, args ...interface{}) (string, error) {
+	cmd := redis.NewStringCmd(args...)
+	if err := g.process(ctx, cmd); err != nil {
+		return "", err
+	}
+	return cmd.Val(), nil
+}
+
+// status process the command which replies simple string, like OK
+func (g *GoRedis) status(ctx context.Context, args ...interface{}) (string, error) {
+	cmd := redis.NewStatusCmd(args...)
+	if err := g.process(ctx, cmd); err != nil {
+		return "", err
+	}
+	return cmd.Val(), nil
+}
@@ -0,0 +1,152 @@
+package goredis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Watch executes a transaction with optimistic locking on a single connection:
+// it WATCHes the keys, calls the fn which reads the keys & queues the commands,
+// then executes the queued commands atomically using MULTI & EXEC.
+//
+// If a watched key is changed before EXEC, the transaction is aborted by the server
+// and the whole transaction is executed again, at most `retry` times in total.
+// ErrTxFailed is returned if it still fails after that.
+//
+// It returns reply of each of the queued commands, converted the same way as Eval.
+// The failed command's reply is its error.
+// In cluster mode, all of the keys must be in the same slot.
+func (g *GoRedis) Watch(fn engine.TxFunc, retry int, keys ...string) ([]interface{}, error) {
+	return g.WatchContext(context.Background(), fn, retry, keys...)
+}
+
+// WatchContext is Watch with context
+func (g *GoRedis) WatchContext(ctx context.Context, fn engine.TxFunc, retry int, keys ...string) ([]interface{}, error) {
+	if retry <= 0 {
+		retry = 1
+	}
+
+	var replies []interface{}
+	err := runContext(ctx, func() error {
+		for i := 0; i < retry; i++ {
+			err := g.client.Watch(func(t *redis.Tx) error {
+				var err error
+				replies, err = g.execTx(ctx, t, fn)
+				return err
+			}, keys...)
+			if err != redis.TxFailedErr {
+				return err
+			}
+		}
+		return engine.ErrTxFailed
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+// execTx calls the fn and executes the queued commands using MULTI & EXEC.
+// It returns redis.TxFailedErr if the transaction is aborted because the watched keys are changed.
+// The commands executed by the fn are observed by the hooks, and the queued ones as a pipeline.
+func (g *GoRedis) execTx(ctx context.Context, t *redis.Tx, fn engine.TxFunc) ([]interface{}, error) {
+	tx := &tx{ctx: ctx, tx: t, hooks: g.hooks}
+	if err := fn(tx); err != nil {
+		return nil, err
+	}
+	if len(tx.queued) == 0 {
+		return []interface{}{}, nil
+	}
+
+	var (
+		cmds []redis.Cmder
+		err  error
+	)
+	cmdErrs := make([]engine.CmdErr, len(tx.queued))
+	for i, cmd := range tx.queued {
+		args := cmd.Args()
+		name, _ := args[0].(string)
+		cmdErrs[i] = pipelineCmdErr{cmd: name, args: args[1:]}
+	}
+	g.hooks.ProcessPipeline(ctx, cmdErrs, func() ([]engine.CmdErr, int, error) {
+		cmds, err = t.Pipelined(func(pipe redis.Pipeliner) error {
+			for _, cmd := range tx.queued {
+				pipe.Process(cmd)
+			}
+			return nil
+		})
+		return txCmdErrs(cmdErrs, tx.queued, err)
+	})
+	// the error of a command executed by EXEC is returned as its reply
+	if err == redis.TxFailedErr || (err != nil && (!isRedisError(err) || strings.HasPrefix(err.Error(), "EXECABORT"))) {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(cmds))
+	for i, cmd := range cmds {
+		c := cmd.(*redis.Cmd)
+		switch err := c.Err(); err {
+		case nil:
+			replies[i] = c.Val()
+		case redis.Nil:
+			// nil reply, the same as redigo engine
+		default:
+			replies[i] = err
+		}
+	}
+	return replies, nil
+}
+
+// txCmdErrs sets the results of the queued commands, for the hooks.
+// The redis.Nil error is reported as nil error.
+func txCmdErrs(cmdErrs []engine.CmdErr, queued []*redis.Cmd, err error) ([]engine.CmdErr, int, error) {
+	firstErr := -1
+	for i, cmd := range queued {
+		pce := cmdErrs[i].(pipelineCmdErr)
+		pce.reply = cmd.Val()
+		if pce.err = cmd.Err(); pce.err == redis.Nil {
+			pce.err = nil
+		}
+		if pce.err != nil && firstErr < 0 {
+			firstErr = i
+		}
+		cmdErrs[i] = pce
+	}
+	return cmdErrs, firstErr, err
+}
+
+// tx is engine.Tx using go-redis Tx
+type tx struct {
+	ctx    context.Context
+	tx     *redis.Tx
+	hooks  *engine.Hooks
+	queued []*redis.Cmd
+}
+
+// Do executes the command immediately, e.g. to read the watched keys before queuing the writes.
+// The reply is converted the same way as Eval
+func (t *tx) Do(cmd string, args ...interface{}) (interface{}, error) {
+	c := redis.NewCmd(append([]interface{}{cmd}, args...)...)
+
+	var err error
+	t.hooks.Process(t.ctx, cmd, args, func() error {
+		if err = t.tx.Process(c); err == redis.Nil {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.Val(), nil
+}
+
+// Queue queues the command.
+// The queued commands are executed atomically using MULTI & EXEC after the TxFunc returns
+func (t *tx) Queue(cmd string, args ...interface{}) {
+	t.queued = append(t.queued, redis.NewCmd(append([]interface{}{cmd}, args...)...))
+}
@@ -0,0 +1,106 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis"
+)
+
+// Scan function return keys that match the pattern
+func (g *GoRedis) Scan(pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.ScanContext(context.Background(), pattern, cursor, count)
+}
+
+// ScanContext is Scan with context
+func (g *GoRedis) ScanContext(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.scan(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count)
+}
+
+// ScanType function return keys of the type that match the pattern
+func (g *GoRedis) ScanType(pattern, keyType string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.ScanTypeContext(context.Background(), pattern, keyType, cursor, count)
+}
+
+// ScanTypeContext is ScanType with context
+func (g *GoRedis) ScanTypeContext(ctx context.Context, pattern, keyType string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.scan(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count, "TYPE", keyType)
+}
+
+// HScan function return field & value pairs of the hash that match the pattern
+func (g *GoRedis) HScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.HScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// HScanContext is HScan with context
+func (g *GoRedis) HScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.scan(ctx, "HSCAN", key, cursor, "MATCH", pattern, "COUNT", count)
+}
+
+// SScan function return members of the set that match the pattern
+func (g *GoRedis) SScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.SScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// SScanContext is SScan with context
+func (g *GoRedis) SScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.scan(ctx, "SSCAN", key, cursor, "MATCH", pattern, "COUNT", count)
+}
+
+// ZScan function return member & score pairs of the sorted set that match the pattern
+func (g *GoRedis) ZScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.ZScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// ZScanContext is ZScan with context
+func (g *GoRedis) ZScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return g.scan(ctx, "ZSCAN", key, cursor, "MATCH", pattern, "COUNT", count)
+}
+
+// scan executes the SCAN family command, returning the found elements & the new cursor
+func (g *GoRedis) scan(ctx context.Context, args ...interface{}) ([]string, uint64, error) {
+	rawResult, err := g.values(ctx, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	// raw result from redis will give us two index array, 1st is new cursor, 2nd is found keys
+	if len(rawResult) < 2 {
+		return nil, 0, errors.New("fail to scan")
+	}
+
+	newCursor, _ := strconv.ParseUint(fmt.Sprint(rawResult[0]), 10, 64) // err ignored, invalid cursor will be treated as 0
+
+	rawFoundKeys, _ := rawResult[1].([]interface{})
+	return toStrings(rawFoundKeys), newCursor, nil
+}
+
+// values process the command which replies array
+func (g *GoRedis) values(ctx context.Context, args ...interface{}) ([]interface{}, error) {
+	cmd := redis.NewSliceCmd(args...)
+	if err := g.process(ctx, cmd); err != nil {
+		return nil, err
+	}
+	return cmd.Val(), nil
+}
+
+// strings process the command which replies array of string.
+// nil element is returned as empty string, the same as redigo engine does.
+func (g *GoRedis) strings(ctx context.Context, args ...interface{}) ([]string, error) {
+	vals, err := g.values(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return toStrings(vals), nil
+}
+
+func toStrings(vals []interface{}) []string {
+	result := make([]string, len(vals))
+	for i, val := range vals {
+		if val != nil {
+			result[i] = fmt.Sprint(val)
+		}
+	}
+	return result
+}
@@ -0,0 +1,177 @@
+package goredis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// ZAdd adds the members to the sorted set stored at key, or updates their score if they already exist.
+// It returns number of the added members, or number of the changed members if args.CH is true.
+func (g *GoRedis) ZAdd(key string, args engine.ZAddArgs, members ...engine.Z) (int64, error) {
+	return g.ZAddContext(context.Background(), key, args, members...)
+}
+
+// ZAddContext is ZAdd with context
+func (g *GoRedis) ZAddContext(ctx context.Context, key string, args engine.ZAddArgs, members ...engine.Z) (int64, error) {
+	return g.int64(ctx, append([]interface{}{"ZADD"}, args.CmdArgs(key, members...)...)...)
+}
+
+// ZIncrBy increments score of the member by `increment` and returns the new score
+func (g *GoRedis) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	return g.ZIncrByContext(context.Background(), key, increment, member)
+}
+
+// ZIncrByContext is ZIncrBy with context
+func (g *GoRedis) ZIncrByContext(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	return g.float64(ctx, "ZINCRBY", key, increment, member)
+}
+
+// ZRange returns the members in the index range, ordered from the lowest to the highest score
+func (g *GoRedis) ZRange(key string, start, stop int64) ([]string, error) {
+	return g.ZRangeContext(context.Background(), key, start, stop)
+}
+
+// ZRangeContext is ZRange with context
+func (g *GoRedis) ZRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return g.strings(ctx, "ZRANGE", key, start, stop)
+}
+
+// ZRangeWithScores is ZRange which returns the members with their score
+func (g *GoRedis) ZRangeWithScores(key string, start, stop int64) ([]engine.Z, error) {
+	return g.ZRangeWithScoresContext(context.Background(), key, start, stop)
+}
+
+// ZRangeWithScoresContext is ZRangeWithScores with context
+func (g *GoRedis) ZRangeWithScoresContext(ctx context.Context, key string, start, stop int64) ([]engine.Z, error) {
+	return g.zs(ctx, "ZRANGE", key, start, stop, "WITHSCORES")
+}
+
+// ZRevRange returns the members in the index range, ordered from the highest to the lowest score
+func (g *GoRedis) ZRevRange(key string, start, stop int64) ([]string, error) {
+	return g.ZRevRangeContext(context.Background(), key, start, stop)
+}
+
+// ZRevRangeContext is ZRevRange with context
+func (g *GoRedis) ZRevRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return g.strings(ctx, "ZREVRANGE", key, start, stop)
+}
+
+// ZRevRangeWithScores is ZRevRange which returns the members with their score
+func (g *GoRedis) ZRevRangeWithScores(key string, start, stop int64) ([]engine.Z, error) {
+	return g.ZRevRangeWithScoresContext(context.Background(), key, start, stop)
+}
+
+// ZRevRangeWithScoresContext is ZRevRangeWithScores with context
+func (g *GoRedis) ZRevRangeWithScoresContext(ctx context.Context, key string, start, stop int64) ([]engine.Z, error) {
+	return g.zs(ctx, "ZREVRANGE", key, start, stop, "WITHSCORES")
+}
+
+// ZRangeByScore returns the members in the score range, ordered from the lowest to the highest score
+func (g *GoRedis) ZRangeByScore(key string, opt engine.ZRangeBy) ([]string, error) {
+	return g.ZRangeByScoreContext(context.Background(), key, opt)
+}
+
+// ZRangeByScoreContext is ZRangeByScore with context
+func (g *GoRedis) ZRangeByScoreContext(ctx context.Context, key string, opt engine.ZRangeBy) ([]string, error) {
+	return g.strings(ctx, append([]interface{}{"ZRANGEBYSCORE"}, opt.CmdArgs(key, false)...)...)
+}
+
+// ZRangeByScoreWithScores is ZRangeByScore which returns the members with their score
+func (g *GoRedis) ZRangeByScoreWithScores(key string, opt engine.ZRangeBy) ([]engine.Z, error) {
+	return g.ZRangeByScoreWithScoresContext(context.Background(), key, opt)
+}
+
+// ZRangeByScoreWithScoresContext is ZRangeByScoreWithScores with context
+func (g *GoRedis) ZRangeByScoreWithScoresContext(ctx context.Context, key string, opt engine.ZRangeBy) ([]engine.Z, error) {
+	return g.zs(ctx, append([]interface{}{"ZRANGEBYSCORE"}, opt.CmdArgs(key, true)...)...)
+}
+
+// ZRank returns rank of the member, the member with the lowest score has rank 0.
+// It returns redis.Nil if the member or the key does not exist.
+func (g *GoRedis) ZRank(key, member string) (int64, error) {
+	return g.ZRankContext(context.Background(), key, member)
+}
+
+// ZRankContext is ZRank with context
+func (g *GoRedis) ZRankContext(ctx context.Context, key, member string) (int64, error) {
+	return g.int64(ctx, "ZRANK", key, member)
+}
+
+// ZScore returns score of the member.
+// It returns redis.Nil if the member or the key does not exist.
+func (g *GoRedis) ZScore(key, member string) (float64, error) {
+	return g.ZScoreContext(context.Background(), key, member)
+}
+
+// ZScoreContext is ZScore with context
+func (g *GoRedis) ZScoreContext(ctx context.Context, key, member string) (float64, error) {
+	return g.float64(ctx, "ZSCORE", key, member)
+}
+
+// ZRem removes the members and returns number of the removed members
+func (g *GoRedis) ZRem(key string, members ...string) (int64, error) {
+	return g.ZRemContext(context.Background(), key, members...)
+}
+
+// ZRemContext is ZRem with context
+func (g *GoRedis) ZRemContext(ctx context.Context, key string, members ...string) (int64, error) {
+	return g.int64(ctx, keyStrings("ZREM", key, members)...)
+}
+
+// ZRemRangeByScore removes the members in the score range and returns number of the removed members
+func (g *GoRedis) ZRemRangeByScore(key, min, max string) (int64, error) {
+	return g.ZRemRangeByScoreContext(context.Background(), key, min, max)
+}
+
+// ZRemRangeByScoreContext is ZRemRangeByScore with context
+func (g *GoRedis) ZRemRangeByScoreContext(ctx context.Context, key, min, max string) (int64, error) {
+	return g.int64(ctx, "ZREMRANGEBYSCORE", key, min, max)
+}
+
+// ZCard returns number of the members of the sorted set
+func (g *GoRedis) ZCard(key string) (int64, error) {
+	return g.ZCardContext(context.Background(), key)
+}
+
+// ZCardContext is ZCard with context
+func (g *GoRedis) ZCardContext(ctx context.Context, key string) (int64, error) {
+	return g.int64(ctx, "ZCARD", key)
+}
+
+// ZCount returns number of the members in the score range
+func (g *GoRedis) ZCount(key, min, max string) (int64, error) {
+	return g.ZCountContext(context.Background(), key, min, max)
+}
+
+// ZCountContext is ZCount with context
+func (g *GoRedis) ZCountContext(ctx context.Context, key, min, max string) (int64, error) {
+	return g.int64(ctx, "ZCOUNT", key, min, max)
+}
+
+// float64 process the command which replies float as bulk string
+func (g *GoRedis) float64(ctx context.Context, args ...interface{}) (float64, error) {
+	cmd := redis.NewFloatCmd(args...)
+	if err := g.process(ctx, cmd); err != nil {
+		return 0, err
+	}
+	return cmd.Val(), nil
+}
+
+// zs process the command which replies member & score pairs
+func (g *GoRedis) zs(ctx context.Context, args ...interface{}) ([]engine.Z, error) {
+	cmd := redis.NewZSliceCmd(args...)
+	if err := g.process(ctx, cmd); err != nil {
+		return nil, err
+	}
+
+	vals := cmd.Val()
+	zs := make([]engine.Z, len(vals))
+	for i, val := range vals {
+		zs[i] = engine.Z{Member: fmt.Sprint(val.Member), Score: val.Score}
+	}
+	return zs, nil
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Hook observes the commands executed by the engine, e.g. for logging, metrics, or tracing.
+//
+// The hooks are called for the commands executed using Do and the command helpers (e.g. Get),
+// including the blocking XRead & XReadGroup, and once for each pipeline execution.
+// HSetEX is reported as HSET & EXPIRE commands, or as pipeline of them by the memory engine.
+// The commands executed by Watch's Tx.Do are reported one by one, and the queued commands
+// are reported as a pipeline when they are executed, the WATCH, MULTI & EXEC themselves are not reported.
+// ScriptLoad in cluster & sharded mode and the pub/sub commands are not reported.
+//
+// The hooks are called synchronously in the caller's goroutine, they must be fast and safe for concurrent use.
+type Hook interface {
+	// BeforeProcess is called before the command is executed.
+	// The returned context is given to AfterProcess, e.g. to carry a tracing span.
+	BeforeProcess(ctx context.Context, cmd string, args []interface{}) context.Context
+
+	// AfterProcess is called after the command is executed.
+	// The err is nil if the command replied nil, e.g. GET of a key which doesn't exist.
+	AfterProcess(ctx context.Context, cmd string, args []interface{}, duration time.Duration, err error)
+
+	// BeforeProcessPipeline is called before the pipeline is executed.
+	// The returned context is given to AfterProcessPipeline.
+	BeforeProcessPipeline(ctx context.Context, cmds []CmdErr) context.Context
+
+	// AfterProcessPipeline is called after the pipeline is executed, with the result of `Pipeliner.Exec`.
+	// Each of the cmds reports its own error.
+	AfterProcessPipeline(ctx context.Context, cmds []CmdErr, duration time.Duration, err error)
+}
+
+// Hooks is the hooks registered to an engine, it is safe for concurrent use.
+// The nil *Hooks has no hook.
+type Hooks struct {
+	mux   sync.Mutex   // serializes Add
+	hooks atomic.Value // []Hook, it is replaced on each Add so the commands could read it without lock
+}
+
+// NewHooks creates the hooks with the given initial hooks
+func NewHooks(hooks ...Hook) *Hooks {
+	h := &Hooks{}
+	h.hooks.Store(append([]Hook(nil), hooks...))
+	return h
+}
+
+// Add registers the hook, it is called after the already registered hooks
+func (h *Hooks) Add(hook Hook) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	hooks := h.load()
+	h.hooks.Store(append(hooks[:len(hooks):len(hooks)], hook))
+}
+
+func (h *Hooks) load() []Hook {
+	if h == nil {
+		return nil
+	}
+	hooks, _ := h.hooks.Load().([]Hook)
+	return hooks
+}
+
+// Process calls fn, which executes the command, between the BeforeProcess & AfterProcess of the hooks.
+// AfterProcess is called in the reverse order of the hooks.
+func (h *Hooks) Process(ctx context.Context, cmd string, args []interface{}, fn func() error) error {
+	hooks := h.load()
+	if len(hooks) == 0 {
+		return fn()
+	}
+
+	ctxs := make([]context.Context, len(hooks))
+	for i, hook := range hooks {
+		ctx = hook.BeforeProcess(ctx, cmd, args)
+		ctxs[i] = ctx
+	}
+
+	start := time.Now()
+	err := fn()
+	duration := time.Since(start)
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].AfterProcess(ctxs[i], cmd, args, duration, err)
+	}
+	return err
+}
+
+// ProcessPipeline calls fn, which executes the pipeline,
+// between the BeforeProcessPipeline & AfterProcessPipeline of the hooks.
+// AfterProcessPipeline is called in the reverse order of the hooks.
+func (h *Hooks) ProcessPipeline(ctx context.Context, cmds []CmdErr,
+	fn func() ([]CmdErr, int, error)) ([]CmdErr, int, error) {
+	hooks := h.load()
+	if len(hooks) == 0 {
+		return fn()
+	}
+
+	ctxs := make([]context.Context, len(hooks))
+	for i, hook := range hooks {
+		ctx = hook.BeforeProcessPipeline(ctx, cmds)
+		ctxs[i] = ctx
+	}
+
+	start := time.Now()
+	cmdErrs, firstErr, err := fn()
+	duration := time.Since(start)
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].AfterProcessPipeline(ctxs[i], cmdErrs, duration, err)
+	}
+	return cmdErrs, firstErr, err
+}
@@ -0,0 +1,546 @@
+package memory
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// cmdFn executes a command, it is called with the lock held
+	cmdFn func(m *Memory, args []string) (interface{}, error)
+
+	// command is spec of a supported command
+	command struct {
+		fn cmdFn
+
+		// minArgs is minimum number of the args.
+		// If step > 0, number of the args after minArgs must be multiple of step, e.g. the field & value pairs
+		minArgs int
+		step    int
+
+		// write command marks its keys as modified, see Watch
+		write bool
+		keys  func(args []string) []string
+	}
+)
+
+// commands supported by the memory engine, by their upper case name
+var commands = map[string]command{
+	// keys
+	"PING":    {fn: cmdPing},
+	"ECHO":    {fn: cmdEcho, minArgs: 1},
+	"DEL":     {fn: cmdDel, minArgs: 1, write: true, keys: allKeys},
+	"UNLINK":  {fn: cmdDel, minArgs: 1, write: true, keys: allKeys},
+	"EXISTS":  {fn: cmdExists, minArgs: 1},
+	"EXPIRE":  {fn: cmdExpire(time.Second), minArgs: 2, write: true, keys: firstKey},
+	"PEXPIRE": {fn: cmdExpire(time.Millisecond), minArgs: 2, write: true, keys: firstKey},
+	"PERSIST": {fn: cmdPersist, minArgs: 1, write: true, keys: firstKey},
+	"TTL":     {fn: cmdTTL(time.Second), minArgs: 1},
+	"PTTL":    {fn: cmdTTL(time.Millisecond), minArgs: 1},
+	"TYPE":    {fn: cmdType, minArgs: 1},
+	"KEYS":    {fn: cmdKeys, minArgs: 1},
+	"SCAN":    {fn: cmdScan, minArgs: 1},
+	"FLUSHDB": {fn: cmdFlush},
+
+	// strings
+	"SET":    {fn: cmdSet, minArgs: 2, write: true, keys: firstKey},
+	"SETNX":  {fn: cmdSetNX, minArgs: 2, write: true, keys: firstKey},
+	"SETEX":  {fn: cmdSetEX(time.Second), minArgs: 3, write: true, keys: firstKey},
+	"PSETEX": {fn: cmdSetEX(time.Millisecond), minArgs: 3, write: true, keys: firstKey},
+	"GET":    {fn: cmdGet, minArgs: 1},
+	"MSET":   {fn: cmdMSet, minArgs: 2, step: 2, write: true, keys: pairKeys},
+	"MGET":   {fn: cmdMGet, minArgs: 1},
+	"INCR":   {fn: cmdIncrBy(1), minArgs: 1, write: true, keys: firstKey},
+	"DECR":   {fn: cmdIncrBy(-1), minArgs: 1, write: true, keys: firstKey},
+	"INCRBY": {fn: cmdIncrBy(1), minArgs: 2, write: true, keys: firstKey},
+	"DECRBY": {fn: cmdIncrBy(-1), minArgs: 2, write: true, keys: firstKey},
+	"APPEND": {fn: cmdAppend, minArgs: 2, write: true, keys: firstKey},
+	"STRLEN": {fn: cmdStrLen, minArgs: 1},
+
+	// hashes
+	"HSET":    {fn: cmdHSet, minArgs: 3, step: 2, write: true, keys: firstKey},
+	"HMSET":   {fn: cmdHMSet, minArgs: 3, step: 2, write: true, keys: firstKey},
+	"HGET":    {fn: cmdHGet, minArgs: 2},
+	"HMGET":   {fn: cmdHMGet, minArgs: 2},
+	"HDEL":    {fn: cmdHDel, minArgs: 2, write: true, keys: firstKey},
+	"HGETALL": {fn: cmdHGetAll, minArgs: 1},
+	"HEXISTS": {fn: cmdHExists, minArgs: 2},
+	"HLEN":    {fn: cmdHLen, minArgs: 1},
+	"HINCRBY": {fn: cmdHIncrBy, minArgs: 3, write: true, keys: firstKey},
+	"HSCAN":   {fn: cmdHScan, minArgs: 2},
+
+	// lists
+	"LPUSH":  {fn: cmdPush(true), minArgs: 2, write: true, keys: firstKey},
+	"RPUSH":  {fn: cmdPush(false), minArgs: 2, write: true, keys: firstKey},
+	"LPOP":   {fn: cmdPop(true), minArgs: 1, write: true, keys: firstKey},
+	"RPOP":   {fn: cmdPop(false), minArgs: 1, write: true, keys: firstKey},
+	"LLEN":   {fn: cmdLLen, minArgs: 1},
+	"LRANGE": {fn: cmdLRange, minArgs: 3},
+	"LINDEX": {fn: cmdLIndex, minArgs: 2},
+	"LTRIM":  {fn: cmdLTrim, minArgs: 3, write: true, keys: firstKey},
+
+	// sets
+	"SADD":      {fn: cmdSAdd, minArgs: 2, write: true, keys: firstKey},
+	"SREM":      {fn: cmdSRem, minArgs: 2, write: true, keys: firstKey},
+	"SMEMBERS":  {fn: cmdSMembers, minArgs: 1},
+	"SISMEMBER": {fn: cmdSIsMember, minArgs: 2},
+	"SCARD":     {fn: cmdSCard, minArgs: 1},
+	"SSCAN":     {fn: cmdSScan, minArgs: 2},
+
+	// sorted sets
+	"ZADD":             {fn: cmdZAdd, minArgs: 3, write: true, keys: firstKey},
+	"ZINCRBY":          {fn: cmdZIncrBy, minArgs: 3, write: true, keys: firstKey},
+	"ZRANGE":           {fn: cmdZRange(false), minArgs: 3},
+	"ZREVRANGE":        {fn: cmdZRange(true), minArgs: 3},
+	"ZRANGEBYSCORE":    {fn: cmdZRangeByScore, minArgs: 3},
+	"ZRANK":            {fn: cmdZRank(false), minArgs: 2},
+	"ZREVRANK":         {fn: cmdZRank(true), minArgs: 2},
+	"ZSCORE":           {fn: cmdZScore, minArgs: 2},
+	"ZREM":             {fn: cmdZRem, minArgs: 2, write: true, keys: firstKey},
+	"ZREMRANGEBYSCORE": {fn: cmdZRemRangeByScore, minArgs: 3, write: true, keys: firstKey},
+	"ZCARD":            {fn: cmdZCard, minArgs: 1},
+	"ZCOUNT":           {fn: cmdZCount, minArgs: 3},
+	"ZSCAN":            {fn: cmdZScan, minArgs: 2},
+}
+
+func firstKey(args []string) []string {
+	return args[:1]
+}
+
+func allKeys(args []string) []string {
+	return args
+}
+
+// pairKeys returns the keys of the key & value pairs, e.g. of MSET
+func pairKeys(args []string) []string {
+	keys := make([]string, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+func cmdPing(m *Memory, args []string) (interface{}, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	return "PONG", nil
+}
+
+func cmdEcho(m *Memory, args []string) (interface{}, error) {
+	return args[0], nil
+}
+
+func cmdDel(m *Memory, args []string) (interface{}, error) {
+	var n int64
+	for _, key := range args {
+		if m.lookup(key) != nil && m.del(key) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func cmdExists(m *Memory, args []string) (interface{}, error) {
+	var n int64
+	for _, key := range args {
+		if m.lookup(key) != nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func cmdExpire(unit time.Duration) cmdFn {
+	return func(m *Memory, args []string) (interface{}, error) {
+		ttl, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, errNotInt
+		}
+
+		it := m.lookup(args[0])
+		if it == nil {
+			return int64(0), nil
+		}
+		if ttl <= 0 {
+			m.del(args[0])
+			return int64(1), nil
+		}
+		it.expireAt = m.now().Add(time.Duration(ttl) * unit)
+		return int64(1), nil
+	}
+}
+
+func cmdPersist(m *Memory, args []string) (interface{}, error) {
+	it := m.lookup(args[0])
+	if it == nil || it.expireAt.IsZero() {
+		return int64(0), nil
+	}
+	it.expireAt = time.Time{}
+	return int64(1), nil
+}
+
+func cmdTTL(unit time.Duration) cmdFn {
+	return func(m *Memory, args []string) (interface{}, error) {
+		it := m.lookup(args[0])
+		switch {
+		case it == nil:
+			return int64(-2), nil
+		case it.expireAt.IsZero():
+			return int64(-1), nil
+		}
+		// rounded the same way as redis
+		ttl := it.expireAt.Sub(m.now())
+		return int64((ttl + unit/2) / unit), nil
+	}
+}
+
+func cmdType(m *Memory, args []string) (interface{}, error) {
+	return typeOf(m.lookup(args[0])), nil
+}
+
+func cmdKeys(m *Memory, args []string) (interface{}, error) {
+	var keys []interface{}
+	for _, key := range m.keys() {
+		if match(args[0], key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func cmdScan(m *Memory, args []string) (interface{}, error) {
+	opts, err := parseScanArgs(args, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, key := range m.keys() {
+		if opts.keyType == "" || strings.EqualFold(opts.keyType, typeOf(m.lookup(key))) {
+			keys = append(keys, key)
+		}
+	}
+	return scanReply(keys, 1, opts), nil
+}
+
+func cmdFlush(m *Memory, args []string) (interface{}, error) {
+	m.flush()
+	return "OK", nil
+}
+
+func cmdSet(m *Memory, args []string) (interface{}, error) {
+	var (
+		key, value = args[0], args[1]
+		ttl        time.Duration
+		nx, xx     bool
+		keepTTL    bool
+	)
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return nil, errSyntax
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return nil, errNotInt
+			}
+			if n <= 0 {
+				return nil, Error("ERR invalid expire time in set")
+			}
+			ttl = time.Duration(n) * time.Second
+			if opt == "PX" {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+		default:
+			return nil, errSyntax
+		}
+	}
+	if nx && xx {
+		return nil, errSyntax
+	}
+
+	it := m.lookup(key)
+	if (nx && it != nil) || (xx && it == nil) {
+		return nil, nil
+	}
+
+	newItem := &item{value: value}
+	if keepTTL && it != nil {
+		newItem.expireAt = it.expireAt
+	}
+	if ttl > 0 {
+		newItem.expireAt = m.now().Add(ttl)
+	}
+	m.items[key] = newItem
+	return "OK", nil
+}
+
+func cmdSetNX(m *Memory, args []string) (interface{}, error) {
+	if m.lookup(args[0]) != nil {
+		return int64(0), nil
+	}
+	m.items[args[0]] = &item{value: args[1]}
+	return int64(1), nil
+}
+
+func cmdSetEX(unit time.Duration) cmdFn {
+	return func(m *Memory, args []string) (interface{}, error) {
+		ttl, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, errNotInt
+		}
+		if ttl <= 0 {
+			return nil, Error("ERR invalid expire time in setex")
+		}
+		m.items[args[0]] = &item{
+			value:    args[2],
+			expireAt: m.now().Add(time.Duration(ttl) * unit),
+		}
+		return "OK", nil
+	}
+}
+
+func cmdGet(m *Memory, args []string) (interface{}, error) {
+	val, ok, err := m.getString(args[0])
+	if err != nil || !ok {
+		return nil, err
+	}
+	return val, nil
+}
+
+func cmdMSet(m *Memory, args []string) (interface{}, error) {
+	for i := 0; i < len(args); i += 2 {
+		m.items[args[i]] = &item{value: args[i+1]}
+	}
+	return "OK", nil
+}
+
+func cmdMGet(m *Memory, args []string) (interface{}, error) {
+	vals := make([]interface{}, len(args))
+	for i, key := range args {
+		// MGET returns nil for the key holding other type
+		if val, ok, err := m.getString(key); ok && err == nil {
+			vals[i] = val
+		}
+	}
+	return vals, nil
+}
+
+func cmdIncrBy(sign int64) cmdFn {
+	return func(m *Memory, args []string) (interface{}, error) {
+		delta := int64(1)
+		if len(args) > 1 {
+			var err error
+			if delta, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+				return nil, errNotInt
+			}
+		}
+		delta *= sign
+
+		val, ok, err := m.getString(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		var n int64
+		if ok {
+			if n, err = strconv.ParseInt(val, 10, 64); err != nil {
+				return nil, errNotInt
+			}
+		}
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return nil, Error("ERR increment or decrement would overflow")
+		}
+
+		n += delta
+		m.set(args[0], strconv.FormatInt(n, 10))
+		return n, nil
+	}
+}
+
+func cmdAppend(m *Memory, args []string) (interface{}, error) {
+	val, _, err := m.getString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	val += args[1]
+	m.set(args[0], val)
+	return int64(len(val)), nil
+}
+
+func cmdStrLen(m *Memory, args []string) (interface{}, error) {
+	val, _, err := m.getString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(val)), nil
+}
+
+// getString returns the string value of the key, `ok` is false if the key doesn't exist
+func (m *Memory) getString(key string) (val string, ok bool, err error) {
+	it := m.lookup(key)
+	if it == nil {
+		return "", false, nil
+	}
+	if val, ok = it.value.(string); !ok {
+		return "", false, errWrongType
+	}
+	return val, true, nil
+}
+
+// scanOpts is options of the SCAN family commands
+type scanOpts struct {
+	cursor  int
+	pattern string
+	count   int
+	keyType string
+}
+
+// parseScanArgs parses the args of SCAN, which are [cursor, MATCH pattern, COUNT count, TYPE type]
+func parseScanArgs(args []string, allowType bool) (scanOpts, error) {
+	opts := scanOpts{pattern: "*", count: 10}
+
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return opts, Error("ERR invalid cursor")
+	}
+	opts.cursor = int(cursor)
+
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return opts, errSyntax
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			opts.pattern = args[i+1]
+		case "COUNT":
+			if opts.count, err = strconv.Atoi(args[i+1]); err != nil || opts.count <= 0 {
+				return opts, errSyntax
+			}
+		case "TYPE":
+			if !allowType {
+				return opts, errSyntax
+			}
+			opts.keyType = args[i+1]
+		default:
+			return opts, errSyntax
+		}
+	}
+	return opts, nil
+}
+
+// scanReply returns a page of the sorted elements, which are groups of `size` strings, e.g. field & value pairs.
+// The cursor is index of the first element of the next page.
+// Only the elements whose first string matches the pattern are returned.
+func scanReply(elems []string, size int, opts scanOpts) []interface{} {
+	var (
+		start = opts.cursor * size
+		end   = start + opts.count*size
+		next  = opts.cursor + opts.count
+		found = []interface{}{}
+	)
+	if end >= len(elems) {
+		end, next = len(elems), 0
+	}
+
+	for i := start; i < end; i += size {
+		if !match(opts.pattern, elems[i]) {
+			continue
+		}
+		for _, elem := range elems[i : i+size] {
+			found = append(found, elem)
+		}
+	}
+	return []interface{}{strconv.Itoa(next), found}
+}
+
+// match returns true if the string matches the glob-style pattern of redis:
+// `*`, `?`, `[abc]`, `[^abc]`, `[a-z]`, and `\` to escape the special character
+func match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// no closing bracket, match it literally
+				if s[0] != '[' {
+					return false
+				}
+				break
+			}
+			if !matchClass(pattern[1:end+1], s[0]) {
+				return false
+			}
+			pattern = pattern[end+1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// matchClass returns true if the char is in the class, e.g. `abc`, `^abc`, or `a-z`
+func matchClass(class string, c byte) bool {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+
+	matched := false
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			matched = true
+		}
+	}
+	return matched != negate
+}
@@ -0,0 +1,434 @@
+package memory
+
+import (
+	"sort"
+	"strconv"
+)
+
+func cmdHSet(m *Memory, args []string) (interface{}, error) {
+	h, err := m.getHash(args[0], true)
+	if err != nil {
+		return nil, err
+	}
+
+	var added int64
+	for i := 1; i < len(args); i += 2 {
+		if _, ok := h[args[i]]; !ok {
+			added++
+		}
+		h[args[i]] = args[i+1]
+	}
+	return added, nil
+}
+
+func cmdHMSet(m *Memory, args []string) (interface{}, error) {
+	if _, err := cmdHSet(m, args); err != nil {
+		return nil, err
+	}
+	return "OK", nil
+}
+
+func cmdHGet(m *Memory, args []string) (interface{}, error) {
+	h, err := m.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	if val, ok := h[args[1]]; ok {
+		return val, nil
+	}
+	return nil, nil
+}
+
+func cmdHMGet(m *Memory, args []string) (interface{}, error) {
+	h, err := m.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := make([]interface{}, len(args)-1)
+	for i, field := range args[1:] {
+		if val, ok := h[field]; ok {
+			vals[i] = val
+		}
+	}
+	return vals, nil
+}
+
+func cmdHDel(m *Memory, args []string) (interface{}, error) {
+	h, err := m.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	for _, field := range args[1:] {
+		if _, ok := h[field]; ok {
+			delete(h, field)
+			n++
+		}
+	}
+	if h != nil && len(h) == 0 {
+		m.del(args[0])
+	}
+	return n, nil
+}
+
+func cmdHGetAll(m *Memory, args []string) (interface{}, error) {
+	h, err := m.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := []interface{}{}
+	for _, field := range sortedFields(h) {
+		vals = append(vals, field, h[field])
+	}
+	return vals, nil
+}
+
+func cmdHExists(m *Memory, args []string) (interface{}, error) {
+	h, err := m.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := h[args[1]]; ok {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func cmdHLen(m *Memory, args []string) (interface{}, error) {
+	h, err := m.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(h)), nil
+}
+
+func cmdHIncrBy(m *Memory, args []string) (interface{}, error) {
+	delta, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return nil, errNotInt
+	}
+
+	h, err := m.getHash(args[0], true)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	if val, ok := h[args[1]]; ok {
+		if n, err = strconv.ParseInt(val, 10, 64); err != nil {
+			return nil, Error("ERR hash value is not an integer")
+		}
+	}
+	n += delta
+	h[args[1]] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func cmdHScan(m *Memory, args []string) (interface{}, error) {
+	opts, err := parseScanArgs(args[1:], false)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := m.getHash(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]string, 0, 2*len(h))
+	for _, field := range sortedFields(h) {
+		pairs = append(pairs, field, h[field])
+	}
+	return scanReply(pairs, 2, opts), nil
+}
+
+func cmdPush(left bool) cmdFn {
+	return func(m *Memory, args []string) (interface{}, error) {
+		list, err := m.getList(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, val := range args[1:] {
+			if left {
+				list = append([]string{val}, list...)
+			} else {
+				list = append(list, val)
+			}
+		}
+		m.set(args[0], list)
+		return int64(len(list)), nil
+	}
+}
+
+func cmdPop(left bool) cmdFn {
+	return func(m *Memory, args []string) (interface{}, error) {
+		list, err := m.getList(args[0])
+		if err != nil || len(list) == 0 {
+			return nil, err
+		}
+
+		var val string
+		if left {
+			val, list = list[0], list[1:]
+		} else {
+			val, list = list[len(list)-1], list[:len(list)-1]
+		}
+		m.setList(args[0], list)
+		return val, nil
+	}
+}
+
+func cmdLLen(m *Memory, args []string) (interface{}, error) {
+	list, err := m.getList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(list)), nil
+}
+
+func cmdLRange(m *Memory, args []string) (interface{}, error) {
+	list, err := m.getList(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	start, stop, err := parseRange(args[1], args[2], len(list))
+	if err != nil {
+		return nil, err
+	}
+
+	vals := []interface{}{}
+	for _, val := range list[start:stop] {
+		vals = append(vals, val)
+	}
+	return vals, nil
+}
+
+func cmdLIndex(m *Memory, args []string) (interface{}, error) {
+	idx, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, errNotInt
+	}
+
+	list, err := m.getList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if idx < 0 {
+		idx += len(list)
+	}
+	if idx < 0 || idx >= len(list) {
+		return nil, nil
+	}
+	return list[idx], nil
+}
+
+func cmdLTrim(m *Memory, args []string) (interface{}, error) {
+	list, err := m.getList(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	start, stop, err := parseRange(args[1], args[2], len(list))
+	if err != nil {
+		return nil, err
+	}
+	if list != nil {
+		m.setList(args[0], append([]string(nil), list[start:stop]...))
+	}
+	return "OK", nil
+}
+
+func cmdSAdd(m *Memory, args []string) (interface{}, error) {
+	set, err := m.getSet(args[0], true)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	for _, member := range args[1:] {
+		if _, ok := set[member]; !ok {
+			set[member] = struct{}{}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func cmdSRem(m *Memory, args []string) (interface{}, error) {
+	set, err := m.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	for _, member := range args[1:] {
+		if _, ok := set[member]; ok {
+			delete(set, member)
+			n++
+		}
+	}
+	if set != nil && len(set) == 0 {
+		m.del(args[0])
+	}
+	return n, nil
+}
+
+func cmdSMembers(m *Memory, args []string) (interface{}, error) {
+	set, err := m.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []interface{}{}
+	for _, member := range sortedMembers(set) {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func cmdSIsMember(m *Memory, args []string) (interface{}, error) {
+	set, err := m.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := set[args[1]]; ok {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func cmdSCard(m *Memory, args []string) (interface{}, error) {
+	set, err := m.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(set)), nil
+}
+
+func cmdSScan(m *Memory, args []string) (interface{}, error) {
+	opts, err := parseScanArgs(args[1:], false)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := m.getSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return scanReply(sortedMembers(set), 1, opts), nil
+}
+
+// getHash returns the hash of the key.
+// If the key doesn't exist, it returns nil, or creates an empty hash if `create` is true
+func (m *Memory) getHash(key string, create bool) (map[string]string, error) {
+	it := m.lookup(key)
+	if it == nil {
+		if !create {
+			return nil, nil
+		}
+		h := make(map[string]string)
+		m.items[key] = &item{value: h}
+		return h, nil
+	}
+
+	h, ok := it.value.(map[string]string)
+	if !ok {
+		return nil, errWrongType
+	}
+	return h, nil
+}
+
+// getList returns the list of the key, or nil if the key doesn't exist
+func (m *Memory) getList(key string) ([]string, error) {
+	it := m.lookup(key)
+	if it == nil {
+		return nil, nil
+	}
+
+	list, ok := it.value.([]string)
+	if !ok {
+		return nil, errWrongType
+	}
+	return list, nil
+}
+
+// setList sets the list of the key, the key is removed if the list is empty
+func (m *Memory) setList(key string, list []string) {
+	if len(list) == 0 {
+		m.del(key)
+		return
+	}
+	m.set(key, list)
+}
+
+// getSet returns the set of the key.
+// If the key doesn't exist, it returns nil, or creates an empty set if `create` is true
+func (m *Memory) getSet(key string, create bool) (map[string]struct{}, error) {
+	it := m.lookup(key)
+	if it == nil {
+		if !create {
+			return nil, nil
+		}
+		set := make(map[string]struct{})
+		m.items[key] = &item{value: set}
+		return set, nil
+	}
+
+	set, ok := it.value.(map[string]struct{})
+	if !ok {
+		return nil, errWrongType
+	}
+	return set, nil
+}
+
+// parseRange converts the start & stop index, which might be negative, to the slice range [start, stop)
+func parseRange(startArg, stopArg string, length int) (int, int, error) {
+	start, err := strconv.Atoi(startArg)
+	if err != nil {
+		return 0, 0, errNotInt
+	}
+	stop, err := strconv.Atoi(stopArg)
+	if err != nil {
+		return 0, 0, errNotInt
+	}
+
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop {
+		return 0, 0, nil
+	}
+	return start, stop + 1, nil
+}
+
+func sortedFields(h map[string]string) []string {
+	fields := make([]string, 0, len(h))
+	for field := range h {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func sortedMembers(set map[string]struct{}) []string {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
@@ -0,0 +1,429 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// zset is sorted set, the member & its score
+type zset map[string]float64
+
+// sorted returns the members ordered by the score, then by the member
+func (z zset) sorted() []engine.Z {
+	members := make([]engine.Z, 0, len(z))
+	for member, score := range z {
+		members = append(members, engine.Z{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+	return members
+}
+
+// scoreBound is min or max of score range, e.g. "-inf", "(1" or "1"
+type scoreBound struct {
+	score     float64
+	exclusive bool
+}
+
+func parseScoreBound(s string) (scoreBound, error) {
+	var b scoreBound
+	if strings.HasPrefix(s, "(") {
+		b.exclusive, s = true, s[1:]
+	}
+
+	score, err := parseFloat(s)
+	if err != nil {
+		return b, Error("ERR min or max is not a float")
+	}
+	b.score = score
+	return b, nil
+}
+
+// inScoreRange returns true if the score is in the range of min & max
+func inScoreRange(score float64, min, max scoreBound) bool {
+	if score < min.score || (min.exclusive && score == min.score) {
+		return false
+	}
+	if score > max.score || (max.exclusive && score == max.score) {
+		return false
+	}
+	return true
+}
+
+func cmdZAdd(m *Memory, args []string) (interface{}, error) {
+	var (
+		opts engine.ZAddArgs
+		i    = 1
+	)
+parseOpts:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			opts.NX = true
+		case "XX":
+			opts.XX = true
+		case "GT":
+			opts.GT = true
+		case "LT":
+			opts.LT = true
+		case "CH":
+			opts.CH = true
+		default:
+			break parseOpts
+		}
+	}
+
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return nil, errSyntax
+	}
+	if (opts.NX && opts.XX) || (opts.NX && (opts.GT || opts.LT)) || (opts.GT && opts.LT) {
+		return nil, Error("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		score, err := parseFloat(pairs[2*j])
+		if err != nil {
+			return nil, errNotFloat
+		}
+		scores[j] = score
+	}
+
+	z, err := m.getZSet(args[0], !opts.XX)
+	if err != nil || z == nil {
+		return int64(0), err
+	}
+
+	var added, changed int64
+	for j, score := range scores {
+		member := pairs[2*j+1]
+		current, exists := z[member]
+		switch {
+		case !exists && opts.XX, exists && opts.NX:
+			continue
+		case !exists:
+			added++
+		case score == current, opts.GT && score < current, opts.LT && score > current:
+			continue
+		default:
+			changed++
+		}
+		z[member] = score
+	}
+	if len(z) == 0 {
+		// nothing is added to the new sorted set
+		m.del(args[0])
+	}
+
+	if opts.CH {
+		return added + changed, nil
+	}
+	return added, nil
+}
+
+func cmdZIncrBy(m *Memory, args []string) (interface{}, error) {
+	incr, err := parseFloat(args[1])
+	if err != nil {
+		return nil, errNotFloat
+	}
+
+	z, err := m.getZSet(args[0], true)
+	if err != nil {
+		return nil, err
+	}
+
+	score := z[args[2]] + incr
+	if math.IsNaN(score) {
+		return nil, Error("ERR resulting score is not a number (NaN)")
+	}
+	z[args[2]] = score
+	return formatFloat(score), nil
+}
+
+func cmdZRange(reverse bool) cmdFn {
+	return func(m *Memory, args []string) (interface{}, error) {
+		withScores := false
+		for _, arg := range args[3:] {
+			if strings.ToUpper(arg) != "WITHSCORES" {
+				return nil, errSyntax
+			}
+			withScores = true
+		}
+
+		z, err := m.getZSet(args[0], false)
+		if err != nil {
+			return nil, err
+		}
+
+		members := z.sorted()
+		if reverse {
+			reverseZ(members)
+		}
+
+		start, stop, err := parseRange(args[1], args[2], len(members))
+		if err != nil {
+			return nil, err
+		}
+		return zReply(members[start:stop], withScores), nil
+	}
+}
+
+func cmdZRangeByScore(m *Memory, args []string) (interface{}, error) {
+	min, err := parseScoreBound(args[1])
+	if err != nil {
+		return nil, err
+	}
+	max, err := parseScoreBound(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		withScores    bool
+		offset, count = 0, -1
+	)
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "WITHSCORES":
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return nil, errSyntax
+			}
+			if offset, err = strconv.Atoi(args[i+1]); err != nil {
+				return nil, errNotInt
+			}
+			if count, err = strconv.Atoi(args[i+2]); err != nil {
+				return nil, errNotInt
+			}
+			i += 2
+		default:
+			return nil, errSyntax
+		}
+	}
+
+	z, err := m.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []engine.Z
+	for _, member := range z.sorted() {
+		if inScoreRange(member.Score, min, max) {
+			members = append(members, member)
+		}
+	}
+
+	if offset < 0 || offset >= len(members) {
+		members = nil
+	} else {
+		members = members[offset:]
+	}
+	if count >= 0 && count < len(members) {
+		members = members[:count]
+	}
+	return zReply(members, withScores), nil
+}
+
+func cmdZRank(reverse bool) cmdFn {
+	return func(m *Memory, args []string) (interface{}, error) {
+		z, err := m.getZSet(args[0], false)
+		if err != nil {
+			return nil, err
+		}
+
+		members := z.sorted()
+		if reverse {
+			reverseZ(members)
+		}
+		for i, member := range members {
+			if member.Member == args[1] {
+				return int64(i), nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+func cmdZScore(m *Memory, args []string) (interface{}, error) {
+	z, err := m.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	if score, ok := z[args[1]]; ok {
+		return formatFloat(score), nil
+	}
+	return nil, nil
+}
+
+func cmdZRem(m *Memory, args []string) (interface{}, error) {
+	z, err := m.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	for _, member := range args[1:] {
+		if _, ok := z[member]; ok {
+			delete(z, member)
+			n++
+		}
+	}
+	if z != nil && len(z) == 0 {
+		m.del(args[0])
+	}
+	return n, nil
+}
+
+func cmdZRemRangeByScore(m *Memory, args []string) (interface{}, error) {
+	min, err := parseScoreBound(args[1])
+	if err != nil {
+		return nil, err
+	}
+	max, err := parseScoreBound(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := m.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	for member, score := range z {
+		if inScoreRange(score, min, max) {
+			delete(z, member)
+			n++
+		}
+	}
+	if z != nil && len(z) == 0 {
+		m.del(args[0])
+	}
+	return n, nil
+}
+
+func cmdZCard(m *Memory, args []string) (interface{}, error) {
+	z, err := m.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(z)), nil
+}
+
+func cmdZCount(m *Memory, args []string) (interface{}, error) {
+	min, err := parseScoreBound(args[1])
+	if err != nil {
+		return nil, err
+	}
+	max, err := parseScoreBound(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := m.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	for _, score := range z {
+		if inScoreRange(score, min, max) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func cmdZScan(m *Memory, args []string) (interface{}, error) {
+	opts, err := parseScanArgs(args[1:], false)
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := m.getZSet(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := z.sorted()
+	pairs := make([]string, 0, 2*len(members))
+	for _, member := range members {
+		pairs = append(pairs, member.Member, formatFloat(member.Score))
+	}
+	return scanReply(pairs, 2, opts), nil
+}
+
+// getZSet returns the sorted set of the key.
+// If the key doesn't exist, it returns nil, or creates an empty sorted set if `create` is true
+func (m *Memory) getZSet(key string, create bool) (zset, error) {
+	it := m.lookup(key)
+	if it == nil {
+		if !create {
+			return nil, nil
+		}
+		z := make(zset)
+		m.items[key] = &item{value: z}
+		return z, nil
+	}
+
+	z, ok := it.value.(zset)
+	if !ok {
+		return nil, errWrongType
+	}
+	return z, nil
+}
+
+// zReply returns reply of the ZRANGE family commands
+func zReply(members []engine.Z, withScores bool) []interface{} {
+	vals := []interface{}{}
+	for _, member := range members {
+		vals = append(vals, member.Member)
+		if withScores {
+			vals = append(vals, formatFloat(member.Score))
+		}
+	}
+	return vals
+}
+
+func reverseZ(members []engine.Z) {
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+}
+
+// parseFloat parses the score, including "-inf", "+inf" and "inf"
+func parseFloat(s string) (float64, error) {
+	switch strings.ToLower(s) {
+	case "-inf":
+		return math.Inf(-1), nil
+	case "+inf", "inf":
+		return math.Inf(1), nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, errNotFloat
+	}
+	return f, nil
+}
+
+// formatFloat formats the score the same way as redis
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'g', 17, 64)
+}
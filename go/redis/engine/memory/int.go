@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+)
+
+// Incr function
+func (m *Memory) Incr(key string) (int64, error) {
+	return m.IncrContext(context.Background(), key)
+}
+
+// IncrContext is Incr with context
+func (m *Memory) IncrContext(ctx context.Context, key string) (int64, error) {
+	return toInt64(m.do(ctx, "INCR", key))
+}
+
+// IncrBy function
+func (m *Memory) IncrBy(key string, value int64) (int64, error) {
+	return m.IncrByContext(context.Background(), key, value)
+}
+
+// IncrByContext is IncrBy with context
+func (m *Memory) IncrByContext(ctx context.Context, key string, value int64) (int64, error) {
+	return toInt64(m.do(ctx, "INCRBY", key, value))
+}
+
+// Decr function
+func (m *Memory) Decr(key string) (int64, error) {
+	return m.DecrContext(context.Background(), key)
+}
+
+// DecrContext is Decr with context
+func (m *Memory) DecrContext(ctx context.Context, key string) (int64, error) {
+	return toInt64(m.do(ctx, "DECR", key))
+}
+
+// DecrBy function
+func (m *Memory) DecrBy(key string, value int64) (int64, error) {
+	return m.DecrByContext(context.Background(), key, value)
+}
+
+// DecrByContext is DecrBy with context
+func (m *Memory) DecrByContext(ctx context.Context, key string, value int64) (int64, error) {
+	return toInt64(m.do(ctx, "DECRBY", key, value))
+}
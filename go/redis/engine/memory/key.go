@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+)
+
+// Expire set expiration time for a key
+// `expiry` is in seconds
+func (m *Memory) Expire(key string, expiry int) (int, error) {
+	return m.ExpireContext(context.Background(), key, expiry)
+}
+
+// ExpireContext is Expire with context
+func (m *Memory) ExpireContext(ctx context.Context, key string, expiry int) (int, error) {
+	return toInt(m.do(ctx, "EXPIRE", key, expiry))
+}
+
+// TTL return remaining ttl of a key, using the engine's clock.
+// The command returns -2 if the key does not exist.
+// The command returns -1 if the key exists but has no associated expire.
+func (m *Memory) TTL(key string) (int, error) {
+	return m.TTLContext(context.Background(), key)
+}
+
+// TTLContext is TTL with context
+func (m *Memory) TTLContext(ctx context.Context, key string) (int, error) {
+	return toInt(m.do(ctx, "TTL", key))
+}
+
+// Exists check key existence
+func (m *Memory) Exists(key string) (bool, error) {
+	return m.ExistsContext(context.Background(), key)
+}
+
+// ExistsContext is Exists with context
+func (m *Memory) ExistsContext(ctx context.Context, key string) (bool, error) {
+	n, err := toInt64(m.do(ctx, "EXISTS", key))
+	return n > 0, err
+}
+
+// Delete function
+func (m *Memory) Delete(keys ...string) (int, error) {
+	return m.DeleteContext(context.Background(), keys...)
+}
+
+// DeleteContext is Delete with context
+func (m *Memory) DeleteContext(ctx context.Context, keys ...string) (int, error) {
+	return toInt(m.do(ctx, "DEL", keysArgs(nil, keys)...))
+}
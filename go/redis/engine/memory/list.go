@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+)
+
+// RPush append values to the key
+func (m *Memory) RPush(key string, values ...string) (int, error) {
+	return m.RPushContext(context.Background(), key, values...)
+}
+
+// RPushContext is RPush with context
+func (m *Memory) RPushContext(ctx context.Context, key string, values ...string) (int, error) {
+	return toInt(m.do(ctx, "RPUSH", keysArgs([]interface{}{key}, values)...))
+}
+
+// RPop Removes and returns the last element of the list stored at key
+// return memory.ErrNil if the key is not exist
+func (m *Memory) RPop(key string) (string, error) {
+	return m.RPopContext(context.Background(), key)
+}
+
+// RPopContext is RPop with context
+func (m *Memory) RPopContext(ctx context.Context, key string) (string, error) {
+	return toString(m.do(ctx, "RPOP", key))
+}
+
+// LLen get the length of the list
+func (m *Memory) LLen(key string) (int64, error) {
+	return m.LLenContext(context.Background(), key)
+}
+
+// LLenContext is LLen with context
+func (m *Memory) LLenContext(ctx context.Context, key string) (int64, error) {
+	return toInt64(m.do(ctx, "LLEN", key))
+}
+
+// LPush prepend values to the list
+func (m *Memory) LPush(key string, values ...string) (int, error) {
+	return m.LPushContext(context.Background(), key, values...)
+}
+
+// LPushContext is LPush with context
+func (m *Memory) LPushContext(ctx context.Context, key string, values ...string) (int, error) {
+	return toInt(m.do(ctx, "LPUSH", keysArgs([]interface{}{key}, values)...))
+}
+
+// LPop removes and get the first element in the list
+func (m *Memory) LPop(key string) (string, error) {
+	return m.LPopContext(context.Background(), key)
+}
+
+// LPopContext is LPop with context
+func (m *Memory) LPopContext(ctx context.Context, key string) (string, error) {
+	return toString(m.do(ctx, "LPOP", key))
+}
+
+// LRange returns the specified elements of the list stored at key
+func (m *Memory) LRange(key string, start, stop int64) ([]string, error) {
+	return m.LRangeContext(context.Background(), key, start, stop)
+}
+
+// LRangeContext is LRange with context
+func (m *Memory) LRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return toStrings(m.do(ctx, "LRANGE", key, start, stop))
+}
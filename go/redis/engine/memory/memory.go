@@ -0,0 +1,309 @@
+// Package memory is in-process redis engine for the unit tests, no redis server is needed.
+//
+// It supports the strings, hashes, lists, sets, sorted sets, key expiry, SCAN family commands,
+// pipelines, transactions (Watch), and pub/sub within the process.
+// The streams & the lua scripts are not supported, they return ErrNotSupported.
+//
+// The expiry uses the engine's clock, which could be moved using FastForward or SetTime,
+// so the tests don't need to sleep. Get the engine of the client created by `redis.New` using:
+//
+//	mem := cli.Redis.(*memory.Memory)
+//	mem.FastForward(time.Minute)
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+var (
+	// ErrNil returned when the reply is nil, e.g. GET of a key which doesn't exist
+	ErrNil = errors.New("memory: nil returned")
+
+	// ErrNotSupported returned by the commands not supported by the memory engine
+	ErrNotSupported = errors.New("memory: command not supported")
+)
+
+// Error is error replied by the memory engine, the same as the one replied by the redis server
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// the errors replied by the commands
+const (
+	errWrongType = Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInt    = Error("ERR value is not an integer or out of range")
+	errNotFloat  = Error("ERR value is not a valid float")
+	errSyntax    = Error("ERR syntax error")
+)
+
+type (
+	// Memory is in-process redis engine, it is safe for concurrent use
+	Memory struct {
+		mux   sync.Mutex
+		items map[string]*item
+
+		// versions is the last modification of each key, to detect the changed keys of Watch
+		versions map[string]uint64
+		version  uint64
+
+		// the clock, see now()
+		frozen time.Time
+		offset time.Duration
+
+		pubSubs *pubSubHub
+
+		hooks *engine.Hooks // observe the commands, see engine.Hook
+	}
+
+	// item is value of a key: string, []string (list), map[string]string (hash),
+	// map[string]struct{} (set), or zset
+	item struct {
+		value    interface{}
+		expireAt time.Time // zero if the key has no expiry
+	}
+)
+
+// New creates new in-memory engine.
+// Only the hooks of the config are used, the engine is always empty when it is created
+func New(cfg engine.Config) *Memory {
+	return &Memory{
+		items:    make(map[string]*item),
+		versions: make(map[string]uint64),
+		pubSubs:  newPubSubHub(),
+		hooks:    engine.NewHooks(cfg.Hooks...),
+	}
+}
+
+// FastForward moves the clock of the engine forward, expiring the keys whose TTL is passed
+func (m *Memory) FastForward(d time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.frozen.IsZero() {
+		m.offset += d
+		return
+	}
+	m.frozen = m.frozen.Add(d)
+}
+
+// SetTime freezes the clock of the engine at the given time, it only moves by FastForward afterward
+func (m *Memory) SetTime(t time.Time) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.frozen = t
+}
+
+// Now returns the current time of the engine's clock
+func (m *Memory) Now() time.Time {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.now()
+}
+
+// Keys returns the sorted keys which exist, excluding the expired keys
+func (m *Memory) Keys() []string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.keys()
+}
+
+// Type returns type of the key's value: string, list, hash, set, zset, or none if the key doesn't exist
+func (m *Memory) Type(key string) string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return typeOf(m.lookup(key))
+}
+
+// FlushAll removes all of the keys
+func (m *Memory) FlushAll() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.flush()
+}
+
+// Ping returns PONG
+func (m *Memory) Ping() (string, error) {
+	return m.PingContext(context.Background())
+}
+
+// PingContext is Ping with context
+func (m *Memory) PingContext(ctx context.Context) (string, error) {
+	return toString(m.do(ctx, "PING"))
+}
+
+// Do executes the command.
+// The reply is converted the same way as Eval:
+// integer to int64, bulk & simple string to string, array to []interface{}, and nil to nil.
+func (m *Memory) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return m.DoContext(context.Background(), cmd, args...)
+}
+
+// DoContext is Do with context
+func (m *Memory) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	return m.do(ctx, cmd, args...)
+}
+
+// IsErrNil returns true if the err given is ErrNil value.
+// in case of memory: it is memory.ErrNil.
+// Please use this func instead of comparing to memory.ErrNil directly
+// because each library has its own ErrNil definition.
+func (m *Memory) IsErrNil(err error) bool {
+	return err == ErrNil
+}
+
+// Stats returns zero statistics, the memory engine has no connection pool
+func (m *Memory) Stats() engine.PoolStats {
+	return engine.PoolStats{}
+}
+
+// AddHook registers the hook which observes the executed commands, see engine.Hook
+func (m *Memory) AddHook(hook engine.Hook) {
+	m.hooks.Add(hook)
+}
+
+// do executes the command, it is observed by the hooks
+func (m *Memory) do(ctx context.Context, cmd string, args ...interface{}) (reply interface{}, err error) {
+	err = m.hooks.Process(ctx, cmd, args, func() error {
+		reply, err = m.exec(ctx, cmd, args...)
+		return err
+	})
+	return reply, err
+}
+
+// exec executes the command atomically, without calling the hooks
+func (m *Memory) exec(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	strArgs := argStrings(args)
+
+	name := strings.ToUpper(cmd)
+	if name == "PUBLISH" {
+		// delivering the message might block, don't hold the lock
+		if len(strArgs) != 2 {
+			return nil, errWrongArgs(name)
+		}
+		return m.pubSubs.publish(strArgs[0], strArgs[1]), nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.call(name, strArgs)
+}
+
+// call executes the command, it must be called with the lock held
+func (m *Memory) call(name string, args []string) (interface{}, error) {
+	c, ok := commands[name]
+	if !ok {
+		return nil, Error("ERR unknown command '" + strings.ToLower(name) + "'")
+	}
+	if len(args) < c.minArgs || (c.step > 0 && (len(args)-c.minArgs)%c.step != 0) {
+		return nil, errWrongArgs(name)
+	}
+
+	reply, err := c.fn(m, args)
+	if c.write && err == nil {
+		for _, key := range c.keys(args) {
+			m.touch(key)
+		}
+	}
+	return reply, err
+}
+
+// now returns the current time of the clock
+func (m *Memory) now() time.Time {
+	if m.frozen.IsZero() {
+		return time.Now().Add(m.offset)
+	}
+	return m.frozen
+}
+
+// lookup returns item of the key, or nil if the key doesn't exist or is expired
+func (m *Memory) lookup(key string) *item {
+	it, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	if !it.expireAt.IsZero() && !m.now().Before(it.expireAt) {
+		m.del(key)
+		return nil
+	}
+	return it
+}
+
+// set sets value of the key, the expiry is kept if the key exists
+func (m *Memory) set(key string, value interface{}) {
+	if it := m.lookup(key); it != nil {
+		it.value = value
+		return
+	}
+	m.items[key] = &item{value: value}
+}
+
+// del removes the key, it returns true if the key existed
+func (m *Memory) del(key string) bool {
+	if _, ok := m.items[key]; !ok {
+		return false
+	}
+	delete(m.items, key)
+	m.touch(key)
+	return true
+}
+
+// touch marks the key as modified
+func (m *Memory) touch(key string) {
+	m.version++
+	m.versions[key] = m.version
+}
+
+// keys returns the sorted keys which are not expired
+func (m *Memory) keys() []string {
+	keys := make([]string, 0, len(m.items))
+	for key := range m.items {
+		if m.lookup(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *Memory) flush() {
+	for key := range m.items {
+		m.del(key)
+	}
+}
+
+// typeOf returns type of the item's value, as returned by TYPE command
+func typeOf(it *item) string {
+	if it == nil {
+		return "none"
+	}
+	switch it.value.(type) {
+	case string:
+		return "string"
+	case []string:
+		return "list"
+	case map[string]string:
+		return "hash"
+	case map[string]struct{}:
+		return "set"
+	case zset:
+		return "zset"
+	}
+	return "none"
+}
+
+func errWrongArgs(name string) error {
+	return Error("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+}
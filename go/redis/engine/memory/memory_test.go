@@ -0,0 +1,396 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+func TestString(t *testing.T) {
+	m := New(engine.Config{})
+
+	require.NoError(t, m.Set("key", "value"))
+	val, err := m.Get("key")
+	require.NoError(t, err)
+	require.Equal(t, "value", val)
+
+	_, err = m.Get("not-exist")
+	require.True(t, m.IsErrNil(err))
+
+	ok, err := m.SetNX("key", "other", 10)
+	require.NoError(t, err)
+	require.Equal(t, "", ok)
+	ok, err = m.SetNX("new", "v", 10)
+	require.NoError(t, err)
+	require.Equal(t, "OK", ok)
+
+	require.NoError(t, m.MSet("k1", 1, "k2", 2.5))
+	vals, err := m.MGet("k1", "not-exist", "k2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "", "2.5"}, vals)
+
+	n, err := m.IncrBy("counter", 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), n)
+	n, err = m.Decr("counter")
+	require.NoError(t, err)
+	require.Equal(t, int64(9), n)
+
+	_, err = m.Incr("key")
+	require.Equal(t, errNotInt, err)
+
+	length, err := m.Append("key", "!")
+	require.NoError(t, err)
+	require.Equal(t, 6, length)
+}
+
+func TestHash(t *testing.T) {
+	m := New(engine.Config{})
+
+	ok, err := m.HMSet("hash", map[string]interface{}{"f1": "v1", "f2": 2})
+	require.NoError(t, err)
+	require.Equal(t, "OK", ok)
+
+	n, err := m.HSetEX("hash", "f3", "v3", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	val, err := m.HGet("hash", "f2")
+	require.NoError(t, err)
+	require.Equal(t, "2", val)
+
+	vals, err := m.HMGet("hash", "f1", "not-exist", "f3")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "", "v3"}, vals)
+
+	n, err = m.HDel("hash", "f1", "f2", "f3", "not-exist")
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Empty(t, m.Keys(), "the empty hash is removed")
+
+	require.NoError(t, m.Set("str", "v"))
+	_, err = m.HGet("str", "f1")
+	require.Equal(t, errWrongType, err)
+}
+
+func TestList(t *testing.T) {
+	m := New(engine.Config{})
+
+	n, err := m.RPush("list", "b", "c")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	n, err = m.LPush("list", "a", "z")
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	vals, err := m.LRange("list", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"z", "a", "b", "c"}, vals)
+
+	vals, err = m.LRange("list", -2, 100)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, vals)
+
+	val, err := m.LPop("list")
+	require.NoError(t, err)
+	require.Equal(t, "z", val)
+	val, err = m.RPop("list")
+	require.NoError(t, err)
+	require.Equal(t, "c", val)
+
+	length, err := m.LLen("list")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), length)
+
+	_, err = m.LPop("not-exist")
+	require.True(t, m.IsErrNil(err))
+}
+
+func TestSet(t *testing.T) {
+	m := New(engine.Config{})
+
+	n, err := m.SAdd("set", "a", "b", "c", "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	n, err = m.SRem("set", "b", "not-exist")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	members, err := m.SMembers("set")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "c"}, members)
+	require.Equal(t, "set", m.Type("set"))
+}
+
+func TestSortedSet(t *testing.T) {
+	m := New(engine.Config{})
+
+	n, err := m.ZAdd("zset", engine.ZAddArgs{}, engine.Z{Member: "a", Score: 1}, engine.Z{Member: "b", Score: 2})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	n, err = m.ZAdd("zset", engine.ZAddArgs{GT: true, CH: true}, engine.Z{Member: "a", Score: 0}, engine.Z{Member: "b", Score: 3})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	score, err := m.ZIncrBy("zset", 1.5, "c")
+	require.NoError(t, err)
+	require.Equal(t, 1.5, score)
+
+	zs, err := m.ZRangeWithScores("zset", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []engine.Z{{Member: "a", Score: 1}, {Member: "c", Score: 1.5}, {Member: "b", Score: 3}}, zs)
+
+	members, err := m.ZRevRange("zset", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, members)
+
+	members, err = m.ZRangeByScore("zset", engine.ZRangeBy{Min: "(1", Max: "+inf", Count: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, members)
+
+	rank, err := m.ZRank("zset", "b")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rank)
+	_, err = m.ZScore("zset", "not-exist")
+	require.True(t, m.IsErrNil(err))
+
+	count, err := m.ZCount("zset", "-inf", "(3")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	n, err = m.ZRemRangeByScore("zset", "1", "1.5")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	card, err := m.ZCard("zset")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), card)
+}
+
+func TestExpiry(t *testing.T) {
+	m := New(engine.Config{})
+	m.SetTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	_, err := m.SetEX("key", "v", 10)
+	require.NoError(t, err)
+	require.NoError(t, m.Set("persistent", "v"))
+
+	ttl, err := m.TTL("key")
+	require.NoError(t, err)
+	require.Equal(t, 10, ttl)
+
+	m.FastForward(4 * time.Second)
+	ttl, err = m.TTL("key")
+	require.NoError(t, err)
+	require.Equal(t, 6, ttl)
+
+	ttl, err = m.TTL("persistent")
+	require.NoError(t, err)
+	require.Equal(t, -1, ttl)
+
+	// updating the value keeps the expiry
+	_, err = m.Append("key", "2")
+	require.NoError(t, err)
+
+	m.FastForward(6 * time.Second)
+	exists, err := m.Exists("key")
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Equal(t, []string{"persistent"}, m.Keys())
+
+	ttl, err = m.TTL("key")
+	require.NoError(t, err)
+	require.Equal(t, -2, ttl)
+
+	n, err := m.Expire("persistent", 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Empty(t, m.Keys())
+}
+
+func TestScan(t *testing.T) {
+	m := New(engine.Config{})
+
+	for _, key := range []string{"user:1", "user:2", "user:10", "order:1"} {
+		require.NoError(t, m.Set(key, "v"))
+	}
+	_, err := m.HMSet("user:hash", map[string]interface{}{"name": "x", "age": 1})
+	require.NoError(t, err)
+
+	var keys []string
+	var cursor uint64
+	for {
+		var found []string
+		found, cursor, err = m.Scan("user:?", cursor, 2)
+		require.NoError(t, err)
+		keys = append(keys, found...)
+		if cursor == 0 {
+			break
+		}
+	}
+	sort.Strings(keys)
+	require.Equal(t, []string{"user:1", "user:2"}, keys)
+
+	keys, _, err = m.ScanType("user:*", "hash", 0, 100)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user:hash"}, keys)
+
+	pairs, cursor, err := m.HScan("user:hash", "n*", 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), cursor)
+	require.Equal(t, []string{"name", "x"}, pairs)
+}
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		pattern, s string
+		matched    bool
+	}{
+		{"*", "", true},
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"*:*:end", "a:b:end", true},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.matched, match(tc.pattern, tc.s), "%q %q", tc.pattern, tc.s)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	m := New(engine.Config{})
+	require.NoError(t, m.Set("str", "v"))
+
+	p := m.Pipeline(1, 0)
+	incr := p.Incr("counter")
+	failed := p.Incr("str")
+	get := p.Get("str")
+	getNil := p.Get("not-exist")
+	raw := p.AddRawCmd("HSET", "hash", "f1", "v1")
+
+	cmdErrs, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Len(t, cmdErrs, 5)
+	require.Equal(t, 1, firstErr)
+
+	require.Equal(t, int64(1), incr.Val())
+	require.Equal(t, errNotInt, failed.Err())
+	require.Equal(t, "v", get.Val())
+	require.True(t, m.IsErrNil(getNil.Err()))
+	require.Equal(t, int64(1), raw.Val())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p = m.PipelineContext(ctx, 1, 0)
+	incr = p.Incr("counter")
+	_, _, err = p.Exec()
+	require.Equal(t, &engine.PipelineError{Chunks: []*engine.ChunkError{{Start: 0, End: 1, Err: context.Canceled}}}, err)
+	require.Equal(t, context.Canceled, incr.Err())
+}
+
+func TestWatch(t *testing.T) {
+	m := New(engine.Config{})
+	require.NoError(t, m.Set("balance", "10"))
+
+	calls := 0
+	replies, err := m.Watch(func(tx engine.Tx) error {
+		calls++
+		balance, err := tx.Do("GET", "balance")
+		if err != nil {
+			return err
+		}
+		if calls == 1 {
+			// changed by another client, the transaction is retried
+			require.NoError(t, m.Set("balance", "20"))
+		}
+		tx.Queue("SET", "balance", balance.(string)+"0")
+		tx.Queue("INCR", "not-int-later")
+		tx.Queue("HGET", "balance", "f1")
+		return nil
+	}, 2, "balance")
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, []interface{}{"OK", int64(1), errWrongType}, replies)
+
+	val, err := m.Get("balance")
+	require.NoError(t, err)
+	require.Equal(t, "200", val)
+
+	_, err = m.Watch(func(tx engine.Tx) error {
+		return m.Set("balance", "0")
+	}, 3, "balance")
+	require.NoError(t, err)
+
+	_, err = m.Watch(func(tx engine.Tx) error {
+		tx.Queue("SET", "balance", "1")
+		return m.Set("balance", "0")
+	}, 3, "balance")
+	require.Equal(t, engine.ErrTxFailed, err)
+
+	errAbort := errors.New("abort")
+	_, err = m.Watch(func(tx engine.Tx) error {
+		return errAbort
+	}, 1, "balance")
+	require.Equal(t, errAbort, err)
+}
+
+func TestPubSub(t *testing.T) {
+	m := New(engine.Config{})
+
+	ps, err := m.Subscribe("news")
+	require.NoError(t, err)
+	require.NoError(t, ps.PSubscribe("news*"))
+
+	n, err := m.Publish("news", "hello")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	require.Equal(t, engine.Message{Channel: "news", Payload: "hello"}, <-ps.Channel())
+	require.Equal(t, engine.Message{Channel: "news", Pattern: "news*", Payload: "hello"}, <-ps.Channel())
+
+	require.NoError(t, ps.Unsubscribe())
+	n, err = m.Publish("news", "ignored")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	<-ps.Channel()
+
+	require.NoError(t, ps.Close())
+	_, ok := <-ps.Channel()
+	require.False(t, ok)
+
+	n, err = m.Publish("news", "closed")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+}
+
+func TestNotSupported(t *testing.T) {
+	m := New(engine.Config{})
+
+	_, err := m.Eval("return 1", nil)
+	require.Equal(t, ErrNotSupported, err)
+
+	_, err = m.XAdd(engine.XAddArgs{Stream: "stream"})
+	require.Equal(t, ErrNotSupported, err)
+
+	_, err = m.Do("OBJECT", "ENCODING", "key")
+	require.Error(t, err)
+}
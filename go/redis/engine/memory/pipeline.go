@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const (
+	// default value of pipeline's numCmdHint
+	defaultPipelineNumCmdHint = 2
+)
+
+// pipelineCmdErr is pipeline command, reply, and error
+type pipelineCmdErr struct {
+	cmd  string
+	args []interface{}
+	err  error
+
+	// result is set after the pipeline is executed
+	result engine.ReplySetter
+}
+
+func (pce pipelineCmdErr) Name() string {
+	return pce.cmd
+}
+
+func (pce pipelineCmdErr) Args() []interface{} {
+	return pce.args
+}
+
+func (pce pipelineCmdErr) Err() error {
+	return pce.err
+}
+
+// Pipeline creates new memory pipeline.
+// The retry is ignored, the commands never fail without getting their reply
+func (m *Memory) Pipeline(retry, cmdNumHint int) engine.Pipeliner {
+	return m.PipelineContext(context.Background(), retry, cmdNumHint)
+}
+
+// PipelineContext is Pipeline with context.
+// The ctx bounds every execution of the pipeline.
+func (m *Memory) PipelineContext(ctx context.Context, retry, cmdNumHint int) engine.Pipeliner {
+	if cmdNumHint == 0 {
+		cmdNumHint = defaultPipelineNumCmdHint
+	}
+
+	p := &pipeline{
+		ctx:        ctx,
+		mem:        m,
+		cmdNumHint: cmdNumHint,
+	}
+	p.resetCmdBuf()
+	return p
+}
+
+// pipeline queues the commands and executes them one after another.
+// Like redis pipeline, the execution is not atomic: the commands of other clients might run in between.
+// This pipeline could be used multiple times and from concurrent goroutines
+type pipeline struct {
+	ctx context.Context
+	mem *Memory
+
+	mux sync.Mutex
+
+	// cmdErrs is buffer of command sent to this pipeline
+	cmdErrs []engine.CmdErr
+
+	// hints about number of commands on each pipeline
+	cmdNumHint int
+}
+
+// AddRawCmd adds raw redis command to the pipeline
+func (p *pipeline) AddRawCmd(cmd string, args ...interface{}) *engine.Result {
+	res := &engine.Result{}
+	p.add(res, cmd, args...)
+	return res
+}
+
+// AddIdempotentCmd adds raw idempotent redis command to the pipeline
+func (p *pipeline) AddIdempotentCmd(cmd string, args ...interface{}) *engine.Result {
+	res := &engine.Result{}
+	p.addIdempotent(res, cmd, args...)
+	return res
+}
+
+// add adds the command and its result to the pipeline
+func (p *pipeline) add(res engine.ReplySetter, cmd string, args ...interface{}) {
+	p.addCmd(pipelineCmdErr{cmd: cmd, args: args, result: res})
+}
+
+// addIdempotent adds the idempotent command and its result to the pipeline.
+// It is the same as add, the commands never fail without getting their reply so they are never retried
+func (p *pipeline) addIdempotent(res engine.ReplySetter, cmd string, args ...interface{}) {
+	p.add(res, cmd, args...)
+}
+
+func (p *pipeline) addCmd(pce pipelineCmdErr) {
+	p.mux.Lock()
+	p.cmdErrs = append(p.cmdErrs, pce)
+	p.mux.Unlock()
+}
+
+// Exec executes the queued commands.
+// If the ctx is already done, none of the commands is executed and the whole pipeline fails as a single chunk
+func (p *pipeline) Exec() ([]engine.CmdErr, int, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	defer p.resetCmdBuf()
+
+	return p.mem.hooks.ProcessPipeline(p.ctx, p.cmdErrs, p.exec)
+}
+
+// exec executes the queued commands, without calling the hooks
+func (p *pipeline) exec() ([]engine.CmdErr, int, error) {
+	cmdErrs := p.cmdErrs
+	if err := p.ctx.Err(); err != nil {
+		for i, cmd := range cmdErrs {
+			pce := cmd.(pipelineCmdErr)
+			pce.err = err
+			pce.result.SetReply(nil, err)
+			cmdErrs[i] = pce
+		}
+		if len(cmdErrs) == 0 {
+			return cmdErrs, -1, nil
+		}
+		return cmdErrs, 0, &engine.PipelineError{Chunks: []*engine.ChunkError{
+			{Start: 0, End: len(cmdErrs), Err: err},
+		}}
+	}
+
+	firstErr := -1
+	for i, cmd := range cmdErrs {
+		pce := cmd.(pipelineCmdErr)
+
+		var reply interface{}
+		reply, pce.err = p.mem.exec(p.ctx, pce.cmd, pce.args...)
+		if pce.err == nil && reply == nil {
+			pce.result.SetReply(nil, ErrNil)
+		} else {
+			pce.result.SetReply(reply, pce.err)
+		}
+
+		cmdErrs[i] = pce
+		if pce.err != nil && firstErr < 0 {
+			firstErr = i
+		}
+	}
+	return cmdErrs, firstErr, nil
+}
+
+// Discard resets the pipeline and discards queued commands
+func (p *pipeline) Discard() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.resetCmdBuf()
+	return nil
+}
+
+// Close closes the pipeline, releasing any open resources.
+func (p *pipeline) Close() error {
+	return nil
+}
+
+func (p *pipeline) resetCmdBuf() {
+	p.cmdErrs = make([]engine.CmdErr, 0, p.cmdNumHint)
+}
@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// buffer size of the message channel
+const pubSubChannelSize = 100
+
+// Publish posts the message to the channel, and returns number of the subscriptions receiving the message.
+// It blocks while the message channel of a receiving PubSub is full
+func (m *Memory) Publish(channel string, message interface{}) (int64, error) {
+	return m.PublishContext(context.Background(), channel, message)
+}
+
+// PublishContext is Publish with context
+func (m *Memory) PublishContext(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return toInt64(m.do(ctx, "PUBLISH", channel, message))
+}
+
+// Subscribe subscribes the channels.
+// The caller must close the returned PubSub after using it
+func (m *Memory) Subscribe(channels ...string) (engine.PubSub, error) {
+	return m.SubscribeContext(context.Background(), channels...)
+}
+
+// SubscribeContext is Subscribe with context
+func (m *Memory) SubscribeContext(ctx context.Context, channels ...string) (engine.PubSub, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.pubSubs.subscribe(channels, nil), nil
+}
+
+// PSubscribe subscribes the channels matching the patterns.
+// The caller must close the returned PubSub after using it
+func (m *Memory) PSubscribe(patterns ...string) (engine.PubSub, error) {
+	return m.PSubscribeContext(context.Background(), patterns...)
+}
+
+// PSubscribeContext is PSubscribe with context
+func (m *Memory) PSubscribeContext(ctx context.Context, patterns ...string) (engine.PubSub, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.pubSubs.subscribe(nil, patterns), nil
+}
+
+// pubSubHub holds the open PubSubs of the engine
+type pubSubHub struct {
+	mux     sync.Mutex
+	pubSubs map[*pubSub]struct{}
+}
+
+func newPubSubHub() *pubSubHub {
+	return &pubSubHub{pubSubs: make(map[*pubSub]struct{})}
+}
+
+func (h *pubSubHub) subscribe(channels, patterns []string) *pubSub {
+	p := &pubSub{
+		hub:      h,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		msgCh:    make(chan engine.Message, pubSubChannelSize),
+		exitCh:   make(chan struct{}),
+	}
+	addAll(p.channels, channels)
+	addAll(p.patterns, patterns)
+
+	h.mux.Lock()
+	h.pubSubs[p] = struct{}{}
+	h.mux.Unlock()
+	return p
+}
+
+// publish delivers the message to the matching subscriptions, and returns number of the deliveries
+func (h *pubSubHub) publish(channel, payload string) int64 {
+	h.mux.Lock()
+	pubSubs := make([]*pubSub, 0, len(h.pubSubs))
+	for p := range h.pubSubs {
+		pubSubs = append(pubSubs, p)
+	}
+	h.mux.Unlock()
+
+	var n int64
+	for _, p := range pubSubs {
+		for _, msg := range p.matches(channel, payload) {
+			if p.deliver(msg) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (h *pubSubHub) remove(p *pubSub) {
+	h.mux.Lock()
+	delete(h.pubSubs, p)
+	h.mux.Unlock()
+}
+
+// pubSub is engine.PubSub of the memory engine
+type pubSub struct {
+	hub *pubSubHub
+
+	// mux guards the subscriptions, and the msgCh from being closed while a message is sent
+	mux      sync.RWMutex
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	closed   bool
+
+	msgCh     chan engine.Message
+	exitCh    chan struct{}
+	closeOnce sync.Once
+}
+
+// Subscribe subscribes the channels
+func (p *pubSub) Subscribe(channels ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	addAll(p.channels, channels)
+	return nil
+}
+
+// PSubscribe subscribes the channels matching the patterns
+func (p *pubSub) PSubscribe(patterns ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	addAll(p.patterns, patterns)
+	return nil
+}
+
+// Unsubscribe unsubscribes the channels, or all of the channels if it is called without argument
+func (p *pubSub) Unsubscribe(channels ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	removeAll(p.channels, channels)
+	return nil
+}
+
+// PUnsubscribe unsubscribes the patterns, or all of the patterns if it is called without argument
+func (p *pubSub) PUnsubscribe(patterns ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	removeAll(p.patterns, patterns)
+	return nil
+}
+
+// Channel returns channel of the received messages.
+// It is closed after the PubSub is closed
+func (p *pubSub) Channel() <-chan engine.Message {
+	return p.msgCh
+}
+
+// Close unsubscribes everything and closes the message channel
+func (p *pubSub) Close() error {
+	p.closeOnce.Do(func() {
+		p.hub.remove(p)
+
+		// unblock the publishers, then wait until they are done before closing the msgCh
+		close(p.exitCh)
+		p.mux.Lock()
+		p.closed = true
+		close(p.msgCh)
+		p.mux.Unlock()
+	})
+	return nil
+}
+
+// matches returns the messages received by the subscription:
+// one for the subscribed channel, and one for each of the matching patterns
+func (p *pubSub) matches(channel, payload string) []engine.Message {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	var msgs []engine.Message
+	if _, ok := p.channels[channel]; ok {
+		msgs = append(msgs, engine.Message{Channel: channel, Payload: payload})
+	}
+	for pattern := range p.patterns {
+		if match(pattern, channel) {
+			msgs = append(msgs, engine.Message{Channel: channel, Pattern: pattern, Payload: payload})
+		}
+	}
+	return msgs
+}
+
+// deliver sends the message to the msgCh, it returns false if the PubSub is closed
+func (p *pubSub) deliver(msg engine.Message) bool {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	if p.closed {
+		return false
+	}
+	select {
+	case p.msgCh <- msg:
+		return true
+	case <-p.exitCh:
+		return false
+	}
+}
+
+func addAll(set map[string]struct{}, vals []string) {
+	for _, val := range vals {
+		set[val] = struct{}{}
+	}
+}
+
+// removeAll removes the values from the set, or all of the set's values if vals is empty
+func removeAll(set map[string]struct{}, vals []string) {
+	if len(vals) == 0 {
+		for val := range set {
+			delete(set, val)
+		}
+		return
+	}
+	for _, val := range vals {
+		delete(set, val)
+	}
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// argStrings converts the command arguments to string, see argString
+func argStrings(args []interface{}) []string {
+	strArgs := make([]string, len(args))
+	for i, arg := range args {
+		strArgs[i] = argString(arg)
+	}
+	return strArgs
+}
+
+// argString converts the command argument to string, the same way as redigo
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(arg)
+}
+
+func toString(reply interface{}, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return "", ErrNil
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	}
+	return "", fmt.Errorf("memory: unexpected reply type %T for string", reply)
+}
+
+// toStrings converts the array reply, the nil element is converted to empty string
+func toStrings(reply interface{}, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNil
+	}
+
+	vals, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("memory: unexpected reply type %T for array", reply)
+	}
+
+	result := make([]string, len(vals))
+	for i, val := range vals {
+		if val == nil {
+			continue
+		}
+		if result[i], err = toString(val, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func toInt64(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return 0, ErrNil
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("memory: unexpected reply type %T for integer", reply)
+}
+
+func toInt(reply interface{}, err error) (int, error) {
+	n, err := toInt64(reply, err)
+	return int(n), err
+}
+
+func toFloat64(reply interface{}, err error) (float64, error) {
+	s, err := toString(reply, err)
+	if err != nil {
+		return 0, err
+	}
+	return parseFloat(s)
+}
+
+// toScan converts reply of the SCAN family commands to the found elements & the next cursor
+func toScan(reply interface{}, err error) ([]string, uint64, error) {
+	if err != nil {
+		return nil, 0, err
+	}
+
+	vals := reply.([]interface{})
+	cursor, err := strconv.ParseUint(vals[0].(string), 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+	found, err := toStrings(vals[1], nil)
+	return found, cursor, err
+}
+
+// zValues converts WITHSCORES reply, which is member & score pairs, to []engine.Z
+func zValues(reply interface{}, err error) ([]engine.Z, error) {
+	values, err := toStrings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	zs := make([]engine.Z, len(values)/2)
+	for i := range zs {
+		score, err := parseFloat(values[2*i+1])
+		if err != nil {
+			return nil, err
+		}
+		zs[i] = engine.Z{Member: values[2*i], Score: score}
+	}
+	return zs, nil
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+)
+
+// Eval is not supported by the memory engine, it returns ErrNotSupported
+func (m *Memory) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return m.EvalContext(context.Background(), script, keys, args...)
+}
+
+// EvalContext is Eval with context
+func (m *Memory) EvalContext(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return nil, ErrNotSupported
+}
+
+// EvalSha is not supported by the memory engine, it returns ErrNotSupported
+func (m *Memory) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return m.EvalShaContext(context.Background(), sha1, keys, args...)
+}
+
+// EvalShaContext is EvalSha with context
+func (m *Memory) EvalShaContext(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return nil, ErrNotSupported
+}
+
+// ScriptLoad is not supported by the memory engine, it returns ErrNotSupported
+func (m *Memory) ScriptLoad(script string) (string, error) {
+	return m.ScriptLoadContext(context.Background(), script)
+}
+
+// ScriptLoadContext is ScriptLoad with context
+func (m *Memory) ScriptLoadContext(ctx context.Context, script string) (string, error) {
+	return "", ErrNotSupported
+}
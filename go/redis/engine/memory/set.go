@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+)
+
+// SAdd Add the specified members to the set stored at key.
+// Specified members that are already a member of this set are ignored.
+// If key does not exist, a new set is created before adding the specified members.
+// An error is returned when the value stored at key is not a set.
+func (m *Memory) SAdd(key string, members ...interface{}) (int64, error) {
+	return m.SAddContext(context.Background(), key, members...)
+}
+
+// SAddContext is SAdd with context
+func (m *Memory) SAddContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return toInt64(m.do(ctx, "SADD", append([]interface{}{key}, members...)...))
+}
+
+// SRem Remove the specified members from the set stored at key.
+// Specified members that are not a member of this set are ignored.
+// If key does not exist, it is treated as an empty set and this command returns 0.
+// An error is returned when the value stored at key is not a set.
+func (m *Memory) SRem(key string, members ...interface{}) (int64, error) {
+	return m.SRemContext(context.Background(), key, members...)
+}
+
+// SRemContext is SRem with context
+func (m *Memory) SRemContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return toInt64(m.do(ctx, "SREM", append([]interface{}{key}, members...)...))
+}
+
+// SMembers Returns all the members of the set value stored at key, sorted.
+func (m *Memory) SMembers(key string) ([]string, error) {
+	return m.SMembersContext(context.Background(), key)
+}
+
+// SMembersContext is SMembers with context
+func (m *Memory) SMembersContext(ctx context.Context, key string) ([]string, error) {
+	return toStrings(m.do(ctx, "SMEMBERS", key))
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// The streams are not supported by the memory engine, all of the stream commands return ErrNotSupported
+
+// XAdd is not supported, it returns ErrNotSupported
+func (m *Memory) XAdd(args engine.XAddArgs) (string, error) {
+	return m.XAddContext(context.Background(), args)
+}
+
+// XAddContext is XAdd with context
+func (m *Memory) XAddContext(ctx context.Context, args engine.XAddArgs) (string, error) {
+	return "", ErrNotSupported
+}
+
+// XRead is not supported, it returns ErrNotSupported
+func (m *Memory) XRead(args engine.XReadArgs) ([]engine.XStream, error) {
+	return m.XReadContext(context.Background(), args)
+}
+
+// XReadContext is XRead with context
+func (m *Memory) XReadContext(ctx context.Context, args engine.XReadArgs) ([]engine.XStream, error) {
+	return nil, ErrNotSupported
+}
+
+// XReadGroup is not supported, it returns ErrNotSupported
+func (m *Memory) XReadGroup(args engine.XReadGroupArgs) ([]engine.XStream, error) {
+	return m.XReadGroupContext(context.Background(), args)
+}
+
+// XReadGroupContext is XReadGroup with context
+func (m *Memory) XReadGroupContext(ctx context.Context, args engine.XReadGroupArgs) ([]engine.XStream, error) {
+	return nil, ErrNotSupported
+}
+
+// XAck is not supported, it returns ErrNotSupported
+func (m *Memory) XAck(stream, group string, ids ...string) (int64, error) {
+	return m.XAckContext(context.Background(), stream, group, ids...)
+}
+
+// XAckContext is XAck with context
+func (m *Memory) XAckContext(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	return 0, ErrNotSupported
+}
+
+// XPending is not supported, it returns ErrNotSupported
+func (m *Memory) XPending(stream, group string) (engine.XPending, error) {
+	return m.XPendingContext(context.Background(), stream, group)
+}
+
+// XPendingContext is XPending with context
+func (m *Memory) XPendingContext(ctx context.Context, stream, group string) (engine.XPending, error) {
+	return engine.XPending{}, ErrNotSupported
+}
+
+// XPendingExt is not supported, it returns ErrNotSupported
+func (m *Memory) XPendingExt(args engine.XPendingExtArgs) ([]engine.XPendingExt, error) {
+	return m.XPendingExtContext(context.Background(), args)
+}
+
+// XPendingExtContext is XPendingExt with context
+func (m *Memory) XPendingExtContext(ctx context.Context, args engine.XPendingExtArgs) ([]engine.XPendingExt, error) {
+	return nil, ErrNotSupported
+}
+
+// XClaim is not supported, it returns ErrNotSupported
+func (m *Memory) XClaim(args engine.XClaimArgs) ([]engine.XMessage, error) {
+	return m.XClaimContext(context.Background(), args)
+}
+
+// XClaimContext is XClaim with context
+func (m *Memory) XClaimContext(ctx context.Context, args engine.XClaimArgs) ([]engine.XMessage, error) {
+	return nil, ErrNotSupported
+}
+
+// XAutoClaim is not supported, it returns ErrNotSupported
+func (m *Memory) XAutoClaim(args engine.XAutoClaimArgs) ([]engine.XMessage, string, error) {
+	return m.XAutoClaimContext(context.Background(), args)
+}
+
+// XAutoClaimContext is XAutoClaim with context
+func (m *Memory) XAutoClaimContext(ctx context.Context, args engine.XAutoClaimArgs) ([]engine.XMessage, string, error) {
+	return nil, "", ErrNotSupported
+}
+
+// XGroupCreate is not supported, it returns ErrNotSupported
+func (m *Memory) XGroupCreate(stream, group, start string, mkStream bool) error {
+	return m.XGroupCreateContext(context.Background(), stream, group, start, mkStream)
+}
+
+// XGroupCreateContext is XGroupCreate with context
+func (m *Memory) XGroupCreateContext(ctx context.Context, stream, group, start string, mkStream bool) error {
+	return ErrNotSupported
+}
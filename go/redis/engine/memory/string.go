@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Set key and value
+func (m *Memory) Set(key string, value interface{}) error {
+	return m.SetContext(context.Background(), key, value)
+}
+
+// SetContext is Set with context
+func (m *Memory) SetContext(ctx context.Context, key string, value interface{}) error {
+	ok, err := toString(m.do(ctx, "SET", key, value))
+	if ok != "OK" && err == nil {
+		return engine.ErrNotOK
+	}
+	return err
+}
+
+// SetNX do SETNX (only set if not exist) with SET's NX & EX args.
+// It sets the key which will expired in `expire` seconds
+func (m *Memory) SetNX(key string, value interface{}, expire int) (string, error) {
+	return m.SetNXContext(context.Background(), key, value, expire)
+}
+
+// SetNXContext is SetNX with context
+func (m *Memory) SetNXContext(ctx context.Context, key string, value interface{}, expire int) (string, error) {
+	reply, err := m.do(ctx, "SET", key, value, "NX", "EX", expire)
+	if reply == nil {
+		return "", err
+	}
+	return toString(reply, err)
+}
+
+// SetEX key and value
+// It sets the key wich will expired in `expire` seconds
+func (m *Memory) SetEX(key string, value interface{}, expire int) (string, error) {
+	return m.SetEXContext(context.Background(), key, value, expire)
+}
+
+// SetEXContext is SetEX with context
+func (m *Memory) SetEXContext(ctx context.Context, key string, value interface{}, expire int) (string, error) {
+	return toString(m.do(ctx, "SETEX", key, expire, value))
+}
+
+// Get string value
+func (m *Memory) Get(key string) (string, error) {
+	return m.GetContext(context.Background(), key)
+}
+
+// GetContext is Get with context
+func (m *Memory) GetContext(ctx context.Context, key string) (string, error) {
+	return toString(m.do(ctx, "GET", key))
+}
+
+// MSet keys and values
+// please use basic types only (no struct, array, or map) for arguments
+func (m *Memory) MSet(pairs ...interface{}) error {
+	return m.MSetContext(context.Background(), pairs...)
+}
+
+// MSetContext is MSet with context
+func (m *Memory) MSetContext(ctx context.Context, pairs ...interface{}) error {
+	ok, err := toString(m.do(ctx, "MSET", pairs...))
+	if ok != "OK" && err == nil {
+		return engine.ErrNotOK
+	}
+	return err
+}
+
+// MGet keys
+func (m *Memory) MGet(keys ...string) ([]string, error) {
+	return m.MGetContext(context.Background(), keys...)
+}
+
+// MGetContext is MGet with context
+func (m *Memory) MGetContext(ctx context.Context, keys ...string) ([]string, error) {
+	return toStrings(m.do(ctx, "MGET", keysArgs(nil, keys)...))
+}
+
+// HSetEX key and value and sets the expiration to the given `expire` seconds
+func (m *Memory) HSetEX(key, field string, value interface{}, expire int) (int, error) {
+	return m.HSetEXContext(context.Background(), key, field, value, expire)
+}
+
+// HSetEXContext is HSetEX with context.
+// The HSET & EXPIRE are executed atomically, they are observed by the hooks as a pipeline
+func (m *Memory) HSetEXContext(ctx context.Context, key, field string, value interface{}, expire int) (int, error) {
+	cmdErrs := []engine.CmdErr{
+		pipelineCmdErr{cmd: "HSET", args: []interface{}{key, field, value}},
+		pipelineCmdErr{cmd: "EXPIRE", args: []interface{}{key, expire}},
+	}
+
+	var reply interface{}
+	_, _, err := m.hooks.ProcessPipeline(ctx, cmdErrs, func() ([]engine.CmdErr, int, error) {
+		if err := ctx.Err(); err != nil {
+			return cmdErrs, -1, err
+		}
+
+		m.mux.Lock()
+		defer m.mux.Unlock()
+
+		for i, cmd := range cmdErrs {
+			pce := cmd.(pipelineCmdErr)
+			reply, pce.err = m.call(pce.cmd, argStrings(pce.args))
+			cmdErrs[i] = pce
+			if pce.err != nil {
+				// the EXPIRE is not executed if the HSET failed
+				return cmdErrs, i, pce.err
+			}
+		}
+		return cmdErrs, -1, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply, nil)
+}
+
+// HGet key and value
+func (m *Memory) HGet(key, field string) (string, error) {
+	return m.HGetContext(context.Background(), key, field)
+}
+
+// HGetContext is HGet with context
+func (m *Memory) HGetContext(ctx context.Context, key, field string) (string, error) {
+	return toString(m.do(ctx, "HGET", key, field))
+}
+
+// HMSet function
+// please use basic types only (no struct, array, or map) for kv value
+func (m *Memory) HMSet(key string, kv map[string]interface{}) (string, error) {
+	return m.HMSetContext(context.Background(), key, kv)
+}
+
+// HMSetContext is HMSet with context
+func (m *Memory) HMSetContext(ctx context.Context, key string, kv map[string]interface{}) (string, error) {
+	return toString(m.do(ctx, "HMSET", hmsetArgs(key, kv)...))
+}
+
+// HMGet keys and value
+func (m *Memory) HMGet(key string, fields ...string) ([]string, error) {
+	return m.HMGetContext(context.Background(), key, fields...)
+}
+
+// HMGetContext is HMGet with context
+func (m *Memory) HMGetContext(ctx context.Context, key string, fields ...string) ([]string, error) {
+	return toStrings(m.do(ctx, "HMGET", keysArgs([]interface{}{key}, fields)...))
+}
+
+// HDel fields of a key
+func (m *Memory) HDel(key string, fields ...string) (int, error) {
+	return m.HDelContext(context.Background(), key, fields...)
+}
+
+// HDelContext is HDel with context
+func (m *Memory) HDelContext(ctx context.Context, key string, fields ...string) (int, error) {
+	return toInt(m.do(ctx, "HDEL", keysArgs([]interface{}{key}, fields)...))
+}
+
+// Append string to existing value in the key
+func (m *Memory) Append(key, value string) (int, error) {
+	return m.AppendContext(context.Background(), key, value)
+}
+
+// AppendContext is Append with context
+func (m *Memory) AppendContext(ctx context.Context, key, value string) (int, error) {
+	return toInt(m.do(ctx, "APPEND", key, value))
+}
+
+// hmsetArgs returns arguments of HMSET command
+func hmsetArgs(key string, kv map[string]interface{}) []interface{} {
+	args := make([]interface{}, 0, 1+2*len(kv))
+	args = append(args, key)
+	for k, v := range kv {
+		args = append(args, k, v)
+	}
+	return args
+}
+
+// keysArgs appends the keys (or the fields, or the members) to the args
+func keysArgs(args []interface{}, keys []string) []interface{} {
+	if args == nil {
+		args = make([]interface{}, 0, len(keys))
+	}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	return args
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Watch executes a transaction with optimistic locking:
+// it records version of the keys, calls the fn which reads the keys & queues the commands,
+// then executes the queued commands atomically.
+//
+// If a watched key is changed before the queued commands are executed,
+// the whole transaction is executed again, at most `retry` times in total.
+// ErrTxFailed is returned if it still fails after that.
+//
+// It returns reply of each of the queued commands, converted the same way as Eval.
+// The failed command's reply is its error.
+func (m *Memory) Watch(fn engine.TxFunc, retry int, keys ...string) ([]interface{}, error) {
+	return m.WatchContext(context.Background(), fn, retry, keys...)
+}
+
+// WatchContext is Watch with context
+func (m *Memory) WatchContext(ctx context.Context, fn engine.TxFunc, retry int, keys ...string) ([]interface{}, error) {
+	if retry <= 0 {
+		retry = 1
+	}
+
+	for i := 0; i < retry; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		replies, aborted, err := m.execTx(ctx, fn, keys)
+		if !aborted {
+			return replies, err
+		}
+	}
+	return nil, engine.ErrTxFailed
+}
+
+// execTx executes the transaction once.
+// `aborted` is true if the transaction is aborted because the watched keys are changed.
+// The commands executed by the fn are observed by the hooks, and the queued ones as a pipeline.
+func (m *Memory) execTx(ctx context.Context, fn engine.TxFunc, keys []string) (replies []interface{}, aborted bool, err error) {
+	m.mux.Lock()
+	versions := make([]uint64, len(keys))
+	for i, key := range keys {
+		// expire the key first, the expiry counts as a change
+		m.lookup(key)
+		versions[i] = m.versions[key]
+	}
+	m.mux.Unlock()
+
+	t := &tx{ctx: ctx, mem: m}
+	if err = fn(t); err != nil {
+		return nil, false, err
+	}
+	if len(t.queued) == 0 {
+		return []interface{}{}, false, nil
+	}
+
+	cmdErrs := make([]engine.CmdErr, len(t.queued))
+	for i, cmd := range t.queued {
+		cmdErrs[i] = pipelineCmdErr{cmd: cmd.name, args: cmd.args}
+	}
+	m.hooks.ProcessPipeline(ctx, cmdErrs, func() ([]engine.CmdErr, int, error) {
+		replies, aborted = m.execQueued(cmdErrs, keys, versions)
+		if aborted {
+			// nothing is executed, like the nil reply of EXEC
+			return cmdErrs, -1, ErrNil
+		}
+
+		firstErr := -1
+		for i, cmd := range cmdErrs {
+			if cmd.Err() != nil && firstErr < 0 {
+				firstErr = i
+			}
+		}
+		return cmdErrs, firstErr, nil
+	})
+	return replies, aborted, nil
+}
+
+// execQueued executes the queued commands atomically if none of the watched keys is changed,
+// setting their errors to the cmdErrs.
+func (m *Memory) execQueued(cmdErrs []engine.CmdErr, keys []string, versions []uint64) (replies []interface{}, aborted bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for i, key := range keys {
+		m.lookup(key)
+		if m.versions[key] != versions[i] {
+			return nil, true
+		}
+	}
+
+	replies = make([]interface{}, len(cmdErrs))
+	for i, cmd := range cmdErrs {
+		pce := cmd.(pipelineCmdErr)
+		reply, err := m.call(strings.ToUpper(pce.cmd), argStrings(pce.args))
+		if err != nil {
+			pce.err = err
+			replies[i] = err
+		} else {
+			replies[i] = reply
+		}
+		cmdErrs[i] = pce
+	}
+	return replies, false
+}
+
+// tx is engine.Tx of the memory engine
+type tx struct {
+	ctx    context.Context
+	mem    *Memory
+	queued []txCmd
+}
+
+type txCmd struct {
+	name string
+	args []interface{}
+}
+
+// Do executes the command immediately, e.g. to read the watched keys before queuing the writes.
+// The reply is converted the same way as Eval
+func (t *tx) Do(cmd string, args ...interface{}) (interface{}, error) {
+	reply, err := t.mem.do(t.ctx, cmd, args...)
+	if err == nil && reply == nil {
+		return nil, ErrNil
+	}
+	return reply, err
+}
+
+// Queue queues the command.
+// The queued commands are executed atomically after the TxFunc returns
+func (t *tx) Queue(cmd string, args ...interface{}) {
+	t.queued = append(t.queued, txCmd{name: cmd, args: append([]interface{}(nil), args...)})
+}
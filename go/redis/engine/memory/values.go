@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+)
+
+// Scan function return keys that match the pattern.
+// The cursor is index of the sorted keys, the keys added or removed while scanning might shift it.
+func (m *Memory) Scan(pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return m.ScanContext(context.Background(), pattern, cursor, count)
+}
+
+// ScanContext is Scan with context
+func (m *Memory) ScanContext(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return toScan(m.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count))
+}
+
+// ScanType function return keys of the type that match the pattern
+func (m *Memory) ScanType(pattern, keyType string, cursor uint64, count int64) ([]string, uint64, error) {
+	return m.ScanTypeContext(context.Background(), pattern, keyType, cursor, count)
+}
+
+// ScanTypeContext is ScanType with context
+func (m *Memory) ScanTypeContext(ctx context.Context, pattern, keyType string, cursor uint64, count int64) ([]string, uint64, error) {
+	return toScan(m.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count, "TYPE", keyType))
+}
+
+// HScan function return field & value pairs of the hash that match the pattern
+func (m *Memory) HScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return m.HScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// HScanContext is HScan with context
+func (m *Memory) HScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return toScan(m.do(ctx, "HSCAN", key, cursor, "MATCH", pattern, "COUNT", count))
+}
+
+// SScan function return members of the set that match the pattern
+func (m *Memory) SScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return m.SScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// SScanContext is SScan with context
+func (m *Memory) SScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return toScan(m.do(ctx, "SSCAN", key, cursor, "MATCH", pattern, "COUNT", count))
+}
+
+// ZScan function return member & score pairs of the sorted set that match the pattern
+func (m *Memory) ZScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return m.ZScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// ZScanContext is ZScan with context
+func (m *Memory) ZScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return toScan(m.do(ctx, "ZSCAN", key, cursor, "MATCH", pattern, "COUNT", count))
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// ZAdd adds the members to the sorted set stored at key, or updates their score if they already exist.
+// It returns number of the added members, or number of the changed members if args.CH is true.
+func (m *Memory) ZAdd(key string, args engine.ZAddArgs, members ...engine.Z) (int64, error) {
+	return m.ZAddContext(context.Background(), key, args, members...)
+}
+
+// ZAddContext is ZAdd with context
+func (m *Memory) ZAddContext(ctx context.Context, key string, args engine.ZAddArgs, members ...engine.Z) (int64, error) {
+	return toInt64(m.do(ctx, "ZADD", args.CmdArgs(key, members...)...))
+}
+
+// ZIncrBy increments score of the member by `increment` and returns the new score
+func (m *Memory) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	return m.ZIncrByContext(context.Background(), key, increment, member)
+}
+
+// ZIncrByContext is ZIncrBy with context
+func (m *Memory) ZIncrByContext(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	return toFloat64(m.do(ctx, "ZINCRBY", key, increment, member))
+}
+
+// ZRange returns the members in the index range, ordered from the lowest to the highest score
+func (m *Memory) ZRange(key string, start, stop int64) ([]string, error) {
+	return m.ZRangeContext(context.Background(), key, start, stop)
+}
+
+// ZRangeContext is ZRange with context
+func (m *Memory) ZRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return toStrings(m.do(ctx, "ZRANGE", key, start, stop))
+}
+
+// ZRangeWithScores is ZRange which returns the members with their score
+func (m *Memory) ZRangeWithScores(key string, start, stop int64) ([]engine.Z, error) {
+	return m.ZRangeWithScoresContext(context.Background(), key, start, stop)
+}
+
+// ZRangeWithScoresContext is ZRangeWithScores with context
+func (m *Memory) ZRangeWithScoresContext(ctx context.Context, key string, start, stop int64) ([]engine.Z, error) {
+	return zValues(m.do(ctx, "ZRANGE", key, start, stop, "WITHSCORES"))
+}
+
+// ZRevRange returns the members in the index range, ordered from the highest to the lowest score
+func (m *Memory) ZRevRange(key string, start, stop int64) ([]string, error) {
+	return m.ZRevRangeContext(context.Background(), key, start, stop)
+}
+
+// ZRevRangeContext is ZRevRange with context
+func (m *Memory) ZRevRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return toStrings(m.do(ctx, "ZREVRANGE", key, start, stop))
+}
+
+// ZRevRangeWithScores is ZRevRange which returns the members with their score
+func (m *Memory) ZRevRangeWithScores(key string, start, stop int64) ([]engine.Z, error) {
+	return m.ZRevRangeWithScoresContext(context.Background(), key, start, stop)
+}
+
+// ZRevRangeWithScoresContext is ZRevRangeWithScores with context
+func (m *Memory) ZRevRangeWithScoresContext(ctx context.Context, key string, start, stop int64) ([]engine.Z, error) {
+	return zValues(m.do(ctx, "ZREVRANGE", key, start, stop, "WITHSCORES"))
+}
+
+// ZRangeByScore returns the members in the score range, ordered from the lowest to the highest score
+func (m *Memory) ZRangeByScore(key string, opt engine.ZRangeBy) ([]string, error) {
+	return m.ZRangeByScoreContext(context.Background(), key, opt)
+}
+
+// ZRangeByScoreContext is ZRangeByScore with context
+func (m *Memory) ZRangeByScoreContext(ctx context.Context, key string, opt engine.ZRangeBy) ([]string, error) {
+	return toStrings(m.do(ctx, "ZRANGEBYSCORE", opt.CmdArgs(key, false)...))
+}
+
+// ZRangeByScoreWithScores is ZRangeByScore which returns the members with their score
+func (m *Memory) ZRangeByScoreWithScores(key string, opt engine.ZRangeBy) ([]engine.Z, error) {
+	return m.ZRangeByScoreWithScoresContext(context.Background(), key, opt)
+}
+
+// ZRangeByScoreWithScoresContext is ZRangeByScoreWithScores with context
+func (m *Memory) ZRangeByScoreWithScoresContext(ctx context.Context, key string, opt engine.ZRangeBy) ([]engine.Z, error) {
+	return zValues(m.do(ctx, "ZRANGEBYSCORE", opt.CmdArgs(key, true)...))
+}
+
+// ZRank returns rank of the member, the member with the lowest score has rank 0.
+// It returns memory.ErrNil if the member or the key does not exist.
+func (m *Memory) ZRank(key, member string) (int64, error) {
+	return m.ZRankContext(context.Background(), key, member)
+}
+
+// ZRankContext is ZRank with context
+func (m *Memory) ZRankContext(ctx context.Context, key, member string) (int64, error) {
+	return toInt64(m.do(ctx, "ZRANK", key, member))
+}
+
+// ZScore returns score of the member.
+// It returns memory.ErrNil if the member or the key does not exist.
+func (m *Memory) ZScore(key, member string) (float64, error) {
+	return m.ZScoreContext(context.Background(), key, member)
+}
+
+// ZScoreContext is ZScore with context
+func (m *Memory) ZScoreContext(ctx context.Context, key, member string) (float64, error) {
+	return toFloat64(m.do(ctx, "ZSCORE", key, member))
+}
+
+// ZRem removes the members and returns number of the removed members
+func (m *Memory) ZRem(key string, members ...string) (int64, error) {
+	return m.ZRemContext(context.Background(), key, members...)
+}
+
+// ZRemContext is ZRem with context
+func (m *Memory) ZRemContext(ctx context.Context, key string, members ...string) (int64, error) {
+	return toInt64(m.do(ctx, "ZREM", keysArgs([]interface{}{key}, members)...))
+}
+
+// ZRemRangeByScore removes the members in the score range and returns number of the removed members
+func (m *Memory) ZRemRangeByScore(key, min, max string) (int64, error) {
+	return m.ZRemRangeByScoreContext(context.Background(), key, min, max)
+}
+
+// ZRemRangeByScoreContext is ZRemRangeByScore with context
+func (m *Memory) ZRemRangeByScoreContext(ctx context.Context, key, min, max string) (int64, error) {
+	return toInt64(m.do(ctx, "ZREMRANGEBYSCORE", key, min, max))
+}
+
+// ZCard returns number of the members of the sorted set
+func (m *Memory) ZCard(key string) (int64, error) {
+	return m.ZCardContext(context.Background(), key)
+}
+
+// ZCardContext is ZCard with context
+func (m *Memory) ZCardContext(ctx context.Context, key string) (int64, error) {
+	return toInt64(m.do(ctx, "ZCARD", key))
+}
+
+// ZCount returns number of the members in the score range
+func (m *Memory) ZCount(key, min, max string) (int64, error) {
+	return m.ZCountContext(context.Background(), key, min, max)
+}
+
+// ZCountContext is ZCount with context
+func (m *Memory) ZCountContext(ctx context.Context, key, min, max string) (int64, error) {
+	return toInt64(m.do(ctx, "ZCOUNT", key, min, max))
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"fmt"
+)
+
+// ChunkError is failure of a chunk of the pipeline, which could not get the replies of some of its commands,
+// e.g. because of network error or the context is done.
+// The commands which got the replies, including the error replies, are not considered failed.
+type ChunkError struct {
+	// Start & End are the index range [Start, End) of the chunk's commands in the pipeline
+	Start, End int
+
+	// Sent is true if the chunk failed after it was sent to the server,
+	// the failed commands which are not idempotent might have been executed.
+	// If it is false, none of the failed commands was executed.
+	Sent bool
+
+	// Err is the error of the first failed command
+	Err error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("pipeline chunk [%d, %d) failed: %v", e.Start, e.End, e.Err)
+}
+
+// Unwrap returns the error of the failed commands
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// PipelineError is returned by `Pipeliner.Exec` when some of the chunks of the pipeline failed.
+// Each command of the failed chunks reports its own error, see `Pipeliner.Exec`.
+type PipelineError struct {
+	Chunks []*ChunkError
+}
+
+func (e *PipelineError) Error() string {
+	if len(e.Chunks) == 1 {
+		return e.Chunks[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more failed chunks)", e.Chunks[0], len(e.Chunks)-1)
+}
+
+// Unwrap returns the error of the first failed chunk
+func (e *PipelineError) Unwrap() error {
+	return e.Chunks[0].Err
+}
@@ -0,0 +1,37 @@
+package engine
+
+// Message is a message received from the subscribed channel
+type Message struct {
+	Channel string
+
+	// Pattern is the matched pattern if the message is received through PSubscribe
+	Pattern string
+
+	Payload string
+}
+
+// PubSub is a subscription to redis channels, using a dedicated connection outside of the pool.
+//
+// The connection is health-checked using PING periodically.
+// When the connection is broken, it reconnects and resubscribes all of the channels & patterns.
+// The messages published while it is reconnecting are lost.
+type PubSub interface {
+	// Subscribe subscribes the channels
+	Subscribe(channels ...string) error
+
+	// PSubscribe subscribes the channels matching the patterns
+	PSubscribe(patterns ...string) error
+
+	// Unsubscribe unsubscribes the channels, or all of the channels if it is called without argument
+	Unsubscribe(channels ...string) error
+
+	// PUnsubscribe unsubscribes the patterns, or all of the patterns if it is called without argument
+	PUnsubscribe(patterns ...string) error
+
+	// Channel returns channel of the received messages.
+	// It is closed after the PubSub is closed
+	Channel() <-chan Message
+
+	// Close unsubscribes everything and closes the connection
+	Close() error
+}
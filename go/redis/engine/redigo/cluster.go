@@ -0,0 +1,769 @@
+package redigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const (
+	// default value of cluster's maximum redirections
+	defaultClusterMaxRedirects = 3
+
+	// backoff of the retry after TRYAGAIN or CLUSTERDOWN error, multiplied by the attempt
+	clusterRetryBackoff = 50 * time.Millisecond
+
+	// minimum interval of the slot map reloads triggered by the connection errors
+	clusterFailureReloadInterval = 100 * time.Millisecond
+
+	// in cluster mode, the SCAN cursor holds index of the scanned master
+	// in its upper bits, and the cursor of the master in the lower bits.
+	scanNodeShift  = 48
+	scanCursorMask = 1<<scanNodeShift - 1
+)
+
+// errNoMaster returned when the cluster has no known master node
+var errNoMaster = errors.New("redigo: no cluster master node")
+
+// cluster is redis cluster client.
+//
+// It keeps the slot map fetched using CLUSTER SLOTS and a pool for each of the nodes.
+// The slot map is updated on MOVED redirection.
+type cluster struct {
+	cfg          engine.Config
+	dial         func(address string) (redis.Conn, error)
+	seeds        []string
+	poolWaitTime time.Duration
+	stats        *poolStats
+	maxRedirects int
+
+	mux     sync.RWMutex
+	pools   map[string]*redis.Pool // pool of each node, by address
+	slots   []string               // master address of each slot
+	masters []string               // sorted master addresses
+
+	loadMux         sync.Mutex // serializes the slot map loading
+	reloading       int32      // 1 if the slot map is being reloaded in background
+	failureReloadAt int64      // unix nano of the last reload triggered by the connection error
+}
+
+// clusterCmd is command executed by the cluster, or by the shards in sharded mode
+type clusterCmd struct {
+	slot  int    // slot of the keys, or index of the shard node in sharded mode. -1 for keyless command
+	addr  string // address of the node which the command is sent to, in cluster mode
+	name  string
+	args  []interface{}
+	keys  []int // index of the original keys, for the split command
+	reply interface{}
+	err   error
+	sent  bool // true if the command might have been sent to the node
+}
+
+// cmdReply is reply of a pipelined command
+type cmdReply struct {
+	reply interface{}
+	err   error
+}
+
+// mergeFn merges replies of the split command
+type mergeFn func(cmds []*clusterCmd, numKeys int) (interface{}, error)
+
+// mergers of the multi keys commands which could be split per slot.
+// The other multi keys commands must have all of their keys in the same slot.
+var mergers = map[string]mergeFn{
+	"MGET":   mergeValues,
+	"DEL":    mergeSum,
+	"UNLINK": mergeSum,
+	"EXISTS": mergeSum,
+	"TOUCH":  mergeSum,
+	"MSET":   mergeOK,
+}
+
+func newCluster(cfg engine.Config, poolWaitTime time.Duration, stats *poolStats) *cluster {
+	seeds := cfg.ClusterAddresses
+	if len(seeds) == 0 {
+		seeds = []string{cfg.Address}
+	}
+
+	maxRedirects := cfg.ClusterMaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultClusterMaxRedirects
+	}
+
+	return &cluster{
+		cfg:          cfg,
+		dial:         dialer(cfg),
+		seeds:        seeds,
+		poolWaitTime: poolWaitTime,
+		stats:        stats,
+		maxRedirects: maxRedirects,
+		pools:        make(map[string]*redis.Pool),
+	}
+}
+
+// do executes the command on the node serving its keys.
+//
+// Multi keys command which keys are on different slots is split per slot if it is
+// supported (see mergers), and the replies are merged.
+func (c *cluster) do(ctx context.Context, name string, args []interface{}) (interface{}, error) {
+	if strings.EqualFold(name, "SCAN") {
+		return c.scan(ctx, args)
+	}
+
+	cmds, merge := splitCmd(name, args, hashSlot)
+	if len(cmds) == 1 {
+		cmd := cmds[0]
+		return c.run(ctx, cmd.slot, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			return doWithTimeout(conn, timeout, cmd.name, cmd.args...)
+		})
+	}
+
+	c.execCmds(ctx, cmds)
+	return merge(cmds, len(engine.KeyIndexes(name, args)))
+}
+
+// run runs the fn on the node serving the slot, following the MOVED & ASK redirections,
+// and retrying TRYAGAIN & CLUSTERDOWN errors.
+// Negative slot means any node.
+func (c *cluster) run(ctx context.Context, slot int, fn connFn) (interface{}, error) {
+	addr, err := c.slotAddr(ctx, slot)
+	if err != nil {
+		return nil, err
+	}
+	return c.runAt(ctx, addr, false, 0, fn)
+}
+
+// runAt runs the fn on the node with the given address.
+// `asking` is true if the node is the target of ASK redirection.
+// The retries of TRYAGAIN & CLUSTERDOWN are counted as redirections.
+func (c *cluster) runAt(ctx context.Context, addr string, asking bool, redirects int, fn connFn) (interface{}, error) {
+	for ; ; redirects++ {
+		conn, err := c.getConn(ctx, addr)
+		if err != nil {
+			c.reloadSlotsOnFailure(ctx, err)
+			return nil, err
+		}
+
+		ask := asking
+		resp, err := runConn(ctx, conn, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			if ask {
+				if _, err := doWithTimeout(conn, timeout, "ASKING"); err != nil {
+					return nil, err
+				}
+			}
+			return fn(conn, timeout)
+		})
+		c.reloadSlotsOnFailure(ctx, err)
+		if redirects >= c.maxRedirects {
+			return resp, err
+		}
+
+		if c.handleRetry(ctx, err, redirects) {
+			// retry on the same node
+			continue
+		}
+		if addr, asking = c.handleRedirect(err); addr == "" {
+			return resp, err
+		}
+	}
+}
+
+// execCmds executes the commands.
+// The commands are grouped by node and pipelined to each of the nodes concurrently.
+func (c *cluster) execCmds(ctx context.Context, cmds []*clusterCmd) {
+	byAddr := make(map[string][]*clusterCmd)
+	for _, cmd := range cmds {
+		addr, err := c.slotAddr(ctx, cmd.slot)
+		if err != nil {
+			cmd.err = err
+			continue
+		}
+		cmd.addr = addr
+		byAddr[addr] = append(byAddr[addr], cmd)
+	}
+
+	var wg sync.WaitGroup
+	for addr, nodeCmds := range byAddr {
+		wg.Add(1)
+		go func(addr string, nodeCmds []*clusterCmd) {
+			defer wg.Done()
+			c.execNode(ctx, addr, nodeCmds)
+		}(addr, nodeCmds)
+	}
+	wg.Wait()
+
+	// follow the redirections & the retries one by one, it should be rare
+	for _, cmd := range cmds {
+		addr, asking := c.handleRedirect(cmd.err)
+		if addr == "" && c.handleRetry(ctx, cmd.err, 0) {
+			addr = cmd.addr
+		}
+		if addr == "" {
+			continue
+		}
+		name, args := cmd.name, cmd.args
+		cmd.sent = true
+		cmd.reply, cmd.err = c.runAt(ctx, addr, asking, 1, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			return doWithTimeout(conn, timeout, name, args...)
+		})
+	}
+}
+
+// execNode pipelines the commands to the node with the given address
+func (c *cluster) execNode(ctx context.Context, addr string, cmds []*clusterCmd) {
+	conn, err := c.getConn(ctx, addr)
+	if err == nil {
+		for _, cmd := range cmds {
+			cmd.sent = true
+		}
+
+		var resp interface{}
+		resp, err = runConn(ctx, conn, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			return sendReceive(conn, timeout, cmds)
+		})
+		if err == nil {
+			for i, rep := range resp.([]cmdReply) {
+				cmds[i].reply, cmds[i].err = rep.reply, rep.err
+			}
+			return
+		}
+	}
+
+	c.reloadSlotsOnFailure(ctx, err)
+	for _, cmd := range cmds {
+		cmd.err = err
+	}
+}
+
+// sendReceive pipelines the commands using the given connection.
+// It doesn't modify the commands because it might still run after the caller stops waiting.
+func sendReceive(conn redis.Conn, timeout time.Duration, cmds []*clusterCmd) (interface{}, error) {
+	for _, cmd := range cmds {
+		if err := conn.Send(cmd.name, cmd.args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]cmdReply, len(cmds))
+	for i := range replies {
+		replies[i].reply, replies[i].err = receiveWithTimeout(conn, timeout)
+	}
+	return replies, nil
+}
+
+// execPipeline executes the pipelined commands in the cluster.
+// Unlike the pipeline in standalone mode, failure of a node is reported as error
+// of the commands sent to that node, the other commands might be already executed.
+func (c *cluster) execPipeline(ctx context.Context, cmdErrs []engine.CmdErr) ([]engine.CmdErr, int, error) {
+	return execSplitPipeline(ctx, cmdErrs, hashSlot, c.execCmds)
+}
+
+// execSplitPipeline splits the pipelined commands using the route (see splitCmd),
+// executes them using the execCmds, and merges the replies of each pipelined command.
+func execSplitPipeline(ctx context.Context, cmdErrs []engine.CmdErr, route func(key string) int,
+	execCmds func(ctx context.Context, cmds []*clusterCmd)) ([]engine.CmdErr, int, error) {
+	var (
+		cmds   = make([]*clusterCmd, 0, len(cmdErrs))
+		splits = make([][]*clusterCmd, len(cmdErrs)) // the split commands of each pipeline command
+		merges = make([]mergeFn, len(cmdErrs))
+	)
+	for i, cmdErr := range cmdErrs {
+		splits[i], merges[i] = splitCmd(cmdErr.Name(), cmdErr.Args(), route)
+		cmds = append(cmds, splits[i]...)
+	}
+
+	execCmds(ctx, cmds)
+
+	if err := ctx.Err(); err != nil {
+		return nil, -1, err
+	}
+
+	firstErr := -1
+	for i, split := range splits {
+		pce := cmdErrs[i].(pipelineCmdErr)
+		if len(split) == 1 {
+			pce.reply, pce.err = split[0].reply, split[0].err
+		} else {
+			pce.reply, pce.err = merges[i](split, len(engine.KeyIndexes(pce.cmd, pce.args)))
+		}
+		if isFailed(pce.err) && !isSent(split) {
+			pce.err = notSentError{pce.err}
+		}
+		cmdErrs[i] = pce
+
+		if pce.err != nil && firstErr < 0 {
+			firstErr = i
+		}
+	}
+	return cmdErrs, firstErr, nil
+}
+
+// isSent returns true if any of the split commands might have been sent
+func isSent(split []*clusterCmd) bool {
+	for _, cmd := range split {
+		if cmd.sent {
+			return true
+		}
+	}
+	return false
+}
+
+// scan executes SCAN command on all of the master nodes, one after another.
+// The index of the scanned master is stored in the upper bits of the cursor.
+func (c *cluster) scan(ctx context.Context, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("redigo: SCAN without cursor")
+	}
+
+	cursor, err := strconv.ParseUint(argString(args[0]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("redigo: invalid SCAN cursor: %v", err)
+	}
+
+	masters, err := c.getMasters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIdx := int(cursor >> scanNodeShift)
+	if nodeIdx >= len(masters) {
+		return nil, fmt.Errorf("redigo: invalid SCAN cursor: %v", cursor)
+	}
+
+	nodeArgs := append([]interface{}{cursor & scanCursorMask}, args[1:]...)
+	values, err := redis.Values(c.runAt(ctx, masters[nodeIdx], false, c.maxRedirects, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+		return doWithTimeout(conn, timeout, "SCAN", nodeArgs...)
+	}))
+	if err != nil || len(values) < 2 {
+		return values, err
+	}
+
+	nodeCursor, _ := redis.Uint64(values[0], nil)
+
+	var next uint64
+	switch {
+	case nodeCursor != 0:
+		next = uint64(nodeIdx)<<scanNodeShift | nodeCursor
+	case nodeIdx+1 < len(masters):
+		next = uint64(nodeIdx+1) << scanNodeShift
+	}
+	values[0] = []byte(strconv.FormatUint(next, 10))
+
+	return values, nil
+}
+
+// doMasters do the command on all of the master nodes, one after another, e.g. SCRIPT LOAD.
+// It returns reply of the last master, or the first error.
+func (c *cluster) doMasters(ctx context.Context, name string, args []interface{}) (interface{}, error) {
+	masters, err := c.getMasters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp interface{}
+	for _, addr := range masters {
+		resp, err = c.runAt(ctx, addr, false, c.maxRedirects, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			return doWithTimeout(conn, timeout, name, args...)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// anyConn returns connection to the first master node
+func (c *cluster) anyConn(ctx context.Context) (redis.Conn, error) {
+	addr, err := c.slotAddr(ctx, -1)
+	if err != nil {
+		return nil, err
+	}
+	return c.getConn(ctx, addr)
+}
+
+// getConn gets connection to the node with the given address
+func (c *cluster) getConn(ctx context.Context, addr string) (redis.Conn, error) {
+	return c.stats.getConn(ctx, c.getPool(addr), c.poolWaitTime)
+}
+
+// getPool returns pool of the node, creating it if needed
+func (c *cluster) getPool(addr string) *redis.Pool {
+	c.mux.RLock()
+	pool, ok := c.pools[addr]
+	c.mux.RUnlock()
+	if ok {
+		return pool
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if pool, ok = c.pools[addr]; !ok {
+		pool = newPool(c.cfg, c.stats, func() (redis.Conn, error) {
+			return c.dial(addr)
+		})
+		c.pools[addr] = pool
+	}
+	return pool
+}
+
+// allPools returns pool of all of the known nodes
+func (c *cluster) allPools() []*redis.Pool {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	pools := make([]*redis.Pool, 0, len(c.pools))
+	for _, pool := range c.pools {
+		pools = append(pools, pool)
+	}
+	return pools
+}
+
+// slotAddr returns address of the master serving the slot.
+// Negative slot means any master.
+func (c *cluster) slotAddr(ctx context.Context, slot int) (string, error) {
+	if slot < 0 {
+		masters, err := c.getMasters(ctx)
+		if err != nil {
+			return "", err
+		}
+		return masters[0], nil
+	}
+
+	if err := c.ensureSlots(ctx); err != nil {
+		return "", err
+	}
+
+	c.mux.RLock()
+	addr := c.slots[slot]
+	c.mux.RUnlock()
+
+	if addr == "" {
+		return "", fmt.Errorf("redigo: slot %v is not served by any node", slot)
+	}
+	return addr, nil
+}
+
+// getMasters returns the sorted master addresses
+func (c *cluster) getMasters(ctx context.Context) ([]string, error) {
+	if err := c.ensureSlots(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mux.RLock()
+	masters := c.masters
+	c.mux.RUnlock()
+
+	if len(masters) == 0 {
+		return nil, errNoMaster
+	}
+	return masters, nil
+}
+
+// ensureSlots loads the slot map if it is not loaded yet
+func (c *cluster) ensureSlots(ctx context.Context) error {
+	c.mux.RLock()
+	loaded := c.slots != nil
+	c.mux.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	c.loadMux.Lock()
+	defer c.loadMux.Unlock()
+
+	// other goroutine might already load it
+	c.mux.RLock()
+	loaded = c.slots != nil
+	c.mux.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	return c.loadSlots(ctx)
+}
+
+// reloadSlots reloads the slot map in background.
+// It does nothing if the other reload is still running.
+func (c *cluster) reloadSlots() {
+	if !atomic.CompareAndSwapInt32(&c.reloading, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&c.reloading, 0)
+
+		c.loadMux.Lock()
+		defer c.loadMux.Unlock()
+
+		c.loadSlots(context.Background())
+	}()
+}
+
+// reloadSlotsOnFailure reloads the slot map in background if the err is connection error,
+// e.g. the master is down and its replica is promoted, which doesn't cause MOVED until the map is reloaded.
+// It is rate limited, as the errors of a dead node come in bursts.
+func (c *cluster) reloadSlotsOnFailure(ctx context.Context, err error) {
+	if ctx.Err() != nil || !isConnError(err) {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&c.failureReloadAt)
+	if now-last < int64(clusterFailureReloadInterval) || !atomic.CompareAndSwapInt64(&c.failureReloadAt, last, now) {
+		return
+	}
+	c.reloadSlots()
+}
+
+// loadSlots loads the slot map from the known masters or the seed nodes,
+// using the first node which successfully replies.
+func (c *cluster) loadSlots(ctx context.Context) error {
+	c.mux.RLock()
+	addrs := append(append([]string{}, c.masters...), c.seeds...)
+	c.mux.RUnlock()
+
+	var lastErr error
+	for _, addr := range addrs {
+		slots, masters, err := c.fetchSlots(ctx, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.mux.Lock()
+		c.slots = slots
+		c.masters = masters
+		c.mux.Unlock()
+		return nil
+	}
+	return lastErr
+}
+
+// fetchSlots fetches the slot map from the node using CLUSTER SLOTS
+func (c *cluster) fetchSlots(ctx context.Context, addr string) ([]string, []string, error) {
+	conn, err := c.getConn(ctx, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ranges, err := redis.Values(runConn(ctx, conn, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+		return doWithTimeout(conn, timeout, "CLUSTER", "SLOTS")
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		slots     = make([]string, numSlots)
+		masterSet = make(map[string]struct{})
+	)
+
+	// each of the ranges is: start slot, end slot, master node, replica nodes...
+	// the node is: host, port, id
+	for _, r := range ranges {
+		fields, err := redis.Values(r, nil)
+		if err != nil || len(fields) < 3 {
+			return nil, nil, fmt.Errorf("redigo: invalid CLUSTER SLOTS reply from %v", addr)
+		}
+		start, _ := redis.Int(fields[0], nil)
+		end, _ := redis.Int(fields[1], nil)
+
+		node, err := redis.Values(fields[2], nil)
+		if err != nil || len(node) < 2 {
+			return nil, nil, fmt.Errorf("redigo: invalid CLUSTER SLOTS node from %v", addr)
+		}
+		host, _ := redis.String(node[0], nil)
+		port, _ := redis.Int(node[1], nil)
+		if host == "" {
+			// empty host means the same host as the replying node
+			host, _, _ = net.SplitHostPort(addr)
+		}
+
+		master := net.JoinHostPort(host, strconv.Itoa(port))
+		masterSet[master] = struct{}{}
+		for slot := start; slot <= end && slot < numSlots; slot++ {
+			slots[slot] = master
+		}
+	}
+
+	masters := make([]string, 0, len(masterSet))
+	for master := range masterSet {
+		masters = append(masters, master)
+	}
+	sort.Strings(masters)
+
+	return slots, masters, nil
+}
+
+// handleRedirect returns the redirection address if the err is MOVED or ASK error.
+// MOVED error updates the slot map.
+func (c *cluster) handleRedirect(err error) (addr string, asking bool) {
+	rerr, ok := err.(redis.Error)
+	if !ok {
+		return "", false
+	}
+
+	// the error is: MOVED|ASK slot address
+	fields := strings.Fields(string(rerr))
+	if len(fields) != 3 {
+		return "", false
+	}
+
+	switch fields[0] {
+	case "MOVED":
+		slot, err := strconv.Atoi(fields[1])
+		if err != nil || slot < 0 || slot >= numSlots {
+			return "", false
+		}
+		c.mux.Lock()
+		if c.slots != nil {
+			c.slots[slot] = fields[2]
+		}
+		c.mux.Unlock()
+
+		// the other slots are likely moved too
+		c.reloadSlots()
+		return fields[2], false
+	case "ASK":
+		return fields[2], true
+	}
+	return "", false
+}
+
+// handleRetry returns true if the err is TRYAGAIN or CLUSTERDOWN error, after waiting for the backoff of the attempt.
+// CLUSTERDOWN error also reloads the slot map, the cluster might be failing over.
+// It returns false if the ctx is done while waiting.
+func (c *cluster) handleRetry(ctx context.Context, err error, attempt int) bool {
+	rerr, ok := err.(redis.Error)
+	if !ok {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(string(rerr), "TRYAGAIN"):
+	case strings.HasPrefix(string(rerr), "CLUSTERDOWN"):
+		c.reloadSlots()
+	default:
+		return false
+	}
+
+	timer := time.NewTimer(time.Duration(attempt+1) * clusterRetryBackoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// splitCmd creates the cluster command(s) of the given command.
+// The route returns the slot (or the shard node) of the key,
+// the command is split per slot if it is supported, see mergers.
+func splitCmd(name string, args []interface{}, route func(key string) int) ([]*clusterCmd, mergeFn) {
+	keys := engine.KeyIndexes(name, args)
+	if len(keys) == 0 {
+		return []*clusterCmd{{slot: -1, name: name, args: args}}, nil
+	}
+
+	slot := route(argString(args[keys[0]]))
+	merge, ok := mergers[strings.ToUpper(name)]
+	if !ok {
+		// can't be split, redis will return CROSSSLOT error if the keys are on different slots
+		return []*clusterCmd{{slot: slot, name: name, args: args}}, nil
+	}
+
+	var (
+		cmds   []*clusterCmd
+		bySlot = make(map[int]*clusterCmd)
+	)
+	for i, idx := range keys {
+		slot = route(argString(args[idx]))
+		cmd, ok := bySlot[slot]
+		if !ok {
+			cmd = &clusterCmd{slot: slot, name: name}
+			bySlot[slot] = cmd
+			cmds = append(cmds, cmd)
+		}
+		// the key's arguments span until the next key, e.g. the value of MSET
+		end := len(args)
+		if i+1 < len(keys) {
+			end = keys[i+1]
+		}
+		cmd.args = append(cmd.args, args[idx:end]...)
+		cmd.keys = append(cmd.keys, i)
+	}
+	return cmds, merge
+}
+
+// mergeValues merges array replies, ordered by the original keys
+func mergeValues(cmds []*clusterCmd, numKeys int) (interface{}, error) {
+	result := make([]interface{}, numKeys)
+	for _, cmd := range cmds {
+		values, err := redis.Values(cmd.reply, cmd.err)
+		if err != nil {
+			return nil, err
+		}
+		for i, key := range cmd.keys {
+			if i < len(values) {
+				result[key] = values[i]
+			}
+		}
+	}
+	return result, nil
+}
+
+// mergeSum sums integer replies
+func mergeSum(cmds []*clusterCmd, _ int) (interface{}, error) {
+	var sum int64
+	for _, cmd := range cmds {
+		n, err := redis.Int64(cmd.reply, cmd.err)
+		if err != nil {
+			return nil, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
+
+// mergeOK returns OK if all the replies are OK
+func mergeOK(cmds []*clusterCmd, _ int) (interface{}, error) {
+	for _, cmd := range cmds {
+		ok, err := redis.String(cmd.reply, cmd.err)
+		if err != nil {
+			return nil, err
+		}
+		if ok != "OK" {
+			return ok, nil
+		}
+	}
+	return "OK", nil
+}
+
+// argString converts the command argument to string
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
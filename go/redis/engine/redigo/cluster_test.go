@@ -0,0 +1,455 @@
+package redigo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/server"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// fakeCluster is minimal redis cluster, supporting only the commands used in the tests
+type fakeCluster struct {
+	mux   sync.Mutex
+	nodes []*fakeNode
+	owner [numSlots]int // index of the node serving each slot
+	errs  []string      // errors replied to the next GET commands, e.g. TRYAGAIN
+}
+
+type fakeNode struct {
+	srv     *server.Server
+	addr    string
+	data    map[string]string
+	scripts map[string]string // cached lua scripts, by SHA1
+}
+
+func newFakeCluster(t *testing.T, numNodes int) *fakeCluster {
+	fc := &fakeCluster{}
+	for i := 0; i < numNodes; i++ {
+		srv, err := server.NewServer("127.0.0.1:0")
+		require.NoError(t, err)
+
+		node := &fakeNode{
+			srv:     srv,
+			addr:    srv.Addr().String(),
+			data:    make(map[string]string),
+			scripts: make(map[string]string),
+		}
+		fc.register(i, node)
+		fc.nodes = append(fc.nodes, node)
+	}
+
+	// distribute the slots evenly
+	for slot := range fc.owner {
+		fc.owner[slot] = slot * numNodes / numSlots
+	}
+	return fc
+}
+
+func (fc *fakeCluster) close() {
+	for _, node := range fc.nodes {
+		node.srv.Close()
+	}
+}
+
+// get returns value of the key stored in the node
+func (fc *fakeCluster) get(node int, key string) string {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+	return fc.nodes[node].data[key]
+}
+
+// numKeys returns number of the keys stored in the node
+func (fc *fakeCluster) numKeys(node int) int {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+	return len(fc.nodes[node].data)
+}
+
+// hasScript returns true if the script is cached in the node
+func (fc *fakeCluster) hasScript(node int, sha1 string) bool {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+	_, ok := fc.nodes[node].scripts[sha1]
+	return ok
+}
+
+// failover moves the slots & the keys of the node to the other node, and stops the node.
+// It is like promoting the node's replica, without MOVED error from the stopped node.
+func (fc *fakeCluster) failover(node, to int) {
+	fc.nodes[node].srv.Close()
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	for key, val := range fc.nodes[node].data {
+		fc.nodes[to].data[key] = val
+	}
+	for slot, owner := range fc.owner {
+		if owner == node {
+			fc.owner[slot] = to
+		}
+	}
+}
+
+// replyErrors replies the errors to the next GET commands
+func (fc *fakeCluster) replyErrors(errs ...string) {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+	fc.errs = errs
+}
+
+// moveSlot moves the slot and its keys to the other node
+func (fc *fakeCluster) moveSlot(slot, to int) {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	from := fc.nodes[fc.owner[slot]]
+	for key, val := range from.data {
+		if hashSlot(key) == slot {
+			fc.nodes[to].data[key] = val
+			delete(from.data, key)
+		}
+	}
+	fc.owner[slot] = to
+}
+
+func (fc *fakeCluster) register(idx int, node *fakeNode) {
+	// checkSlot writes MOVED error if the key is not served by this node
+	checkSlot := func(c *server.Peer, key string) bool {
+		slot := hashSlot(key)
+		if owner := fc.owner[slot]; owner != idx {
+			c.WriteError(fmt.Sprintf("MOVED %v %v", slot, fc.nodes[owner].addr))
+			return false
+		}
+		return true
+	}
+
+	node.srv.Register("PING", func(c *server.Peer, cmd string, args []string) {
+		c.WriteInline("PONG")
+	})
+	node.srv.Register("CLUSTER", func(c *server.Peer, cmd string, args []string) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+
+		type slotRange struct{ start, end, node int }
+		var ranges []slotRange
+		for slot, owner := range fc.owner {
+			if len(ranges) > 0 && ranges[len(ranges)-1].node == owner {
+				ranges[len(ranges)-1].end = slot
+				continue
+			}
+			ranges = append(ranges, slotRange{slot, slot, owner})
+		}
+
+		c.WriteLen(len(ranges))
+		for _, r := range ranges {
+			c.WriteLen(3)
+			c.WriteInt(r.start)
+			c.WriteInt(r.end)
+			c.WriteLen(2)
+			c.WriteBulk("127.0.0.1")
+			c.WriteInt(fc.nodes[r.node].srv.Addr().Port)
+		}
+	})
+	node.srv.Register("SET", func(c *server.Peer, cmd string, args []string) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+
+		if checkSlot(c, args[0]) {
+			node.data[args[0]] = args[1]
+			c.WriteOK()
+		}
+	})
+	node.srv.Register("GET", func(c *server.Peer, cmd string, args []string) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+
+		if len(fc.errs) > 0 {
+			c.WriteError(fc.errs[0])
+			fc.errs = fc.errs[1:]
+			return
+		}
+
+		if checkSlot(c, args[0]) {
+			val, ok := node.data[args[0]]
+			if !ok {
+				c.WriteNull()
+				return
+			}
+			c.WriteBulk(val)
+		}
+	})
+	node.srv.Register("INCR", func(c *server.Peer, cmd string, args []string) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+
+		if checkSlot(c, args[0]) {
+			n, _ := strconv.Atoi(node.data[args[0]])
+			node.data[args[0]] = strconv.Itoa(n + 1)
+			c.WriteInt(n + 1)
+		}
+	})
+	node.srv.Register("MGET", func(c *server.Peer, cmd string, args []string) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+
+		for _, key := range args[1:] {
+			if hashSlot(key) != hashSlot(args[0]) {
+				c.WriteError("CROSSSLOT Keys in request don't hash to the same slot")
+				return
+			}
+		}
+		if checkSlot(c, args[0]) {
+			c.WriteLen(len(args))
+			for _, key := range args {
+				val, ok := node.data[key]
+				if !ok {
+					c.WriteNull()
+					continue
+				}
+				c.WriteBulk(val)
+			}
+		}
+	})
+	node.srv.Register("DEL", func(c *server.Peer, cmd string, args []string) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+
+		for _, key := range args[1:] {
+			if hashSlot(key) != hashSlot(args[0]) {
+				c.WriteError("CROSSSLOT Keys in request don't hash to the same slot")
+				return
+			}
+		}
+		if checkSlot(c, args[0]) {
+			var n int
+			for _, key := range args {
+				if _, ok := node.data[key]; ok {
+					delete(node.data, key)
+					n++
+				}
+			}
+			c.WriteInt(n)
+		}
+	})
+	node.srv.Register("SCRIPT", func(c *server.Peer, cmd string, args []string) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+
+		sha1 := engine.ScriptHash(args[1])
+		node.scripts[sha1] = args[1]
+		c.WriteBulk(sha1)
+	})
+	node.srv.Register("SCAN", func(c *server.Peer, cmd string, args []string) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+
+		// return all the keys at once
+		c.WriteLen(2)
+		c.WriteBulk("0")
+		c.WriteLen(len(node.data))
+		for key := range node.data {
+			c.WriteBulk(key)
+		}
+	})
+}
+
+func newTestCluster(t *testing.T) (*Redigo, *fakeCluster) {
+	fc := newFakeCluster(t, 3)
+	r := New(engine.Config{
+		ClusterMode:      true,
+		ClusterAddresses: []string{fc.nodes[0].addr},
+		MaxActive:        10,
+		PoolWaitMs:       1000,
+	})
+	return r, fc
+}
+
+func TestHashSlot(t *testing.T) {
+	require.Equal(t, 12182, hashSlot("foo"))
+	require.Equal(t, 12739, hashSlot("123456789"))
+	require.Equal(t, hashSlot("user1000"), hashSlot("{user1000}.following"))
+	require.Equal(t, hashSlot("{user1000}.followers"), hashSlot("{user1000}.following"))
+
+	// empty hash tag, the whole key is hashed
+	require.Equal(t, int(crc16("foo{}{bar}")%numSlots), hashSlot("foo{}{bar}"))
+}
+
+func TestClusterCommand(t *testing.T) {
+	r, fc := newTestCluster(t)
+	defer fc.close()
+
+	pong, err := r.Ping()
+	require.NoError(t, err)
+	require.Equal(t, "PONG", pong)
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%v", i)
+		require.NoError(t, r.Set(keys[i], strconv.Itoa(i)))
+	}
+
+	// the keys must be spread to all the nodes
+	for i := range fc.nodes {
+		require.NotZero(t, fc.numKeys(i))
+	}
+
+	for i, key := range keys {
+		val, err := r.Get(key)
+		require.NoError(t, err)
+		require.Equal(t, strconv.Itoa(i), val)
+	}
+
+	vals, err := r.MGet(keys[0], "not-exist", keys[10], keys[19])
+	require.NoError(t, err)
+	require.Equal(t, []string{"0", "", "10", "19"}, vals)
+
+	// scan all the nodes
+	var (
+		scanned []string
+		cursor  uint64
+	)
+	for {
+		var found []string
+		found, cursor, err = r.Scan("*", cursor, 100)
+		require.NoError(t, err)
+		scanned = append(scanned, found...)
+		if cursor == 0 {
+			break
+		}
+	}
+	sort.Strings(scanned)
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+	require.Equal(t, sorted, scanned)
+
+	n, err := r.Delete(keys[0], keys[10], "not-exist")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestClusterMoved(t *testing.T) {
+	r, fc := newTestCluster(t)
+	defer fc.close()
+
+	require.NoError(t, r.Set("foo", "bar"))
+
+	// move the slot to the other node
+	slot := hashSlot("foo")
+	to := (fc.owner[slot] + 1) % len(fc.nodes)
+	fc.moveSlot(slot, to)
+
+	val, err := r.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, "bar", val)
+	require.Equal(t, "bar", fc.get(to, "foo"))
+
+	// the slot map is updated
+	addr, err := r.cluster.slotAddr(context.Background(), slot)
+	require.NoError(t, err)
+	require.Equal(t, fc.nodes[to].addr, addr)
+}
+
+func TestClusterPipeline(t *testing.T) {
+	r, fc := newTestCluster(t)
+	defer fc.close()
+
+	require.NoError(t, r.Set("foo", "bar"))
+	fc.moveSlot(hashSlot("foo"), (fc.owner[hashSlot("foo")]+1)%len(fc.nodes))
+
+	p := r.Pipeline(1, 0)
+	for i := 0; i < 10; i++ {
+		p.Incr(fmt.Sprintf("counter-%v", i))
+	}
+	p.AddRawCmd("SET", "foo", "baz")          // MOVED
+	p.Delete("counter-0", "counter-1")        // split per slot
+	p.AddRawCmd("RENAME", "counter-2", "foo") // not supported by the fake cluster
+
+	cmdErrs, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Len(t, cmdErrs, 13)
+	require.Equal(t, 12, firstErr)
+	require.Error(t, cmdErrs[12].Err())
+
+	for i := 2; i < 10; i++ {
+		val, err := r.Get(fmt.Sprintf("counter-%v", i))
+		require.NoError(t, err)
+		require.Equal(t, "1", val)
+	}
+
+	val, err := r.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, "baz", val)
+
+	_, err = r.Get("counter-0")
+	require.True(t, r.IsErrNil(err))
+}
+
+func TestClusterScriptLoad(t *testing.T) {
+	r, fc := newTestCluster(t)
+	defer fc.close()
+
+	sha1, err := r.ScriptLoad("return 1")
+	require.NoError(t, err)
+	require.Equal(t, engine.ScriptHash("return 1"), sha1)
+
+	// the script must be cached in all of the masters
+	for i := range fc.nodes {
+		require.True(t, fc.hasScript(i, sha1))
+	}
+}
+
+func TestClusterFailover(t *testing.T) {
+	r, fc := newTestCluster(t)
+	defer fc.close()
+
+	require.NoError(t, r.Set("foo", "bar"))
+	node := fc.owner[hashSlot("foo")]
+	to := (node + 1) % len(fc.nodes)
+	fc.failover(node, to)
+
+	// the connection error reloads the slot map, there is no MOVED from the stopped node
+	require.Eventually(t, func() bool {
+		val, err := r.Get("foo")
+		return err == nil && val == "bar"
+	}, 2*time.Second, 20*time.Millisecond)
+
+	addr, err := r.cluster.slotAddr(context.Background(), hashSlot("foo"))
+	require.NoError(t, err)
+	require.Equal(t, fc.nodes[to].addr, addr)
+}
+
+func TestClusterRetry(t *testing.T) {
+	r, fc := newTestCluster(t)
+	defer fc.close()
+
+	require.NoError(t, r.Set("foo", "bar"))
+
+	fc.replyErrors("TRYAGAIN Multiple keys request during rehashing of slot", "CLUSTERDOWN The cluster is down")
+	val, err := r.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, "bar", val)
+
+	// the retries are limited by ClusterMaxRedirects
+	fc.replyErrors("TRYAGAIN", "TRYAGAIN", "TRYAGAIN", "TRYAGAIN")
+	_, err = r.Get("foo")
+	require.Equal(t, redis.Error("TRYAGAIN"), err)
+
+	// pipelined command
+	fc.replyErrors("TRYAGAIN")
+	p := r.Pipeline(1, 0)
+	get := p.Get("foo")
+	_, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+	require.Equal(t, "bar", get.Val())
+}
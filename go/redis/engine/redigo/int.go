@@ -1,23 +1,47 @@
 package redigo
 
-import "github.com/gomodule/redigo/redis"
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
 
 // Incr function
 func (r *Redigo) Incr(key string) (int64, error) {
-	return redis.Int64(r.do("INCR", key))
+	return r.IncrContext(context.Background(), key)
+}
+
+// IncrContext is Incr with context
+func (r *Redigo) IncrContext(ctx context.Context, key string) (int64, error) {
+	return redis.Int64(r.do(ctx, "INCR", key))
 }
 
 // IncrBy function
 func (r *Redigo) IncrBy(key string, value int64) (int64, error) {
-	return redis.Int64(r.do("INCRBY", key, value))
+	return r.IncrByContext(context.Background(), key, value)
+}
+
+// IncrByContext is IncrBy with context
+func (r *Redigo) IncrByContext(ctx context.Context, key string, value int64) (int64, error) {
+	return redis.Int64(r.do(ctx, "INCRBY", key, value))
 }
 
 // Decr function
 func (r *Redigo) Decr(key string) (int64, error) {
-	return redis.Int64(r.do("DECR", key))
+	return r.DecrContext(context.Background(), key)
+}
+
+// DecrContext is Decr with context
+func (r *Redigo) DecrContext(ctx context.Context, key string) (int64, error) {
+	return redis.Int64(r.do(ctx, "DECR", key))
 }
 
 // DecrBy function
 func (r *Redigo) DecrBy(key string, value int64) (int64, error) {
-	return redis.Int64(r.do("DECRBY", key, value))
+	return r.DecrByContext(context.Background(), key, value)
+}
+
+// DecrByContext is DecrBy with context
+func (r *Redigo) DecrByContext(ctx context.Context, key string, value int64) (int64, error) {
+	return redis.Int64(r.do(ctx, "DECRBY", key, value))
 }
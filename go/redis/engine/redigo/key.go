@@ -1,13 +1,20 @@
 package redigo
 
 import (
+	"context"
+
 	"github.com/gomodule/redigo/redis"
 )
 
 // Expire set expiration time for a key
 // `expiry` is in seconds
 func (r *Redigo) Expire(key string, expiry int) (int, error) {
-	return redis.Int(r.do("EXPIRE", key, expiry))
+	return r.ExpireContext(context.Background(), key, expiry)
+}
+
+// ExpireContext is Expire with context
+func (r *Redigo) ExpireContext(ctx context.Context, key string, expiry int) (int, error) {
+	return redis.Int(r.do(ctx, "EXPIRE", key, expiry))
 }
 
 // TTL return remaining ttl of a key
@@ -15,12 +22,22 @@ func (r *Redigo) Expire(key string, expiry int) (int, error) {
 // The command returns -2 if the key does not exist.
 // The command returns -1 if the key exists but has no associated expire.
 func (r *Redigo) TTL(key string) (int, error) {
-	return redis.Int(r.do("TTL", key))
+	return r.TTLContext(context.Background(), key)
+}
+
+// TTLContext is TTL with context
+func (r *Redigo) TTLContext(ctx context.Context, key string) (int, error) {
+	return redis.Int(r.do(ctx, "TTL", key))
 }
 
 // Exists check key existence
 func (r *Redigo) Exists(key string) (bool, error) {
-	return redis.Bool(r.do("EXISTS", key))
+	return r.ExistsContext(context.Background(), key)
+}
+
+// ExistsContext is Exists with context
+func (r *Redigo) ExistsContext(ctx context.Context, key string) (bool, error) {
+	return redis.Bool(r.do(ctx, "EXISTS", key))
 }
 
 // Sort ordered the value lexicographically
@@ -47,10 +64,15 @@ func (r *Redigo) Exists(key string) (bool, error) {
 
 // Delete function
 func (r *Redigo) Delete(keys ...string) (int, error) {
+	return r.DeleteContext(context.Background(), keys...)
+}
+
+// DeleteContext is Delete with context
+func (r *Redigo) DeleteContext(ctx context.Context, keys ...string) (int, error) {
 	// copy string to array of interface
 	args := make([]interface{}, len(keys))
 	for i, key := range keys {
 		args[i] = key
 	}
-	return redis.Int(r.do("DEL", args...))
+	return redis.Int(r.do(ctx, "DEL", args...))
 }
@@ -1,6 +1,7 @@
 package redigo
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/gomodule/redigo/redis"
@@ -8,20 +9,30 @@ import (
 
 // RPush append values to the key
 func (r *Redigo) RPush(key string, values ...string) (int, error) {
+	return r.RPushContext(context.Background(), key, values...)
+}
+
+// RPushContext is RPush with context
+func (r *Redigo) RPushContext(ctx context.Context, key string, values ...string) (int, error) {
 	args := make([]interface{}, len(values)+1)
 	args[0] = key
 	for i, value := range values {
 		args[i+1] = value
 	}
-	return redis.Int(r.do("RPUSH", args...))
+	return redis.Int(r.do(ctx, "RPUSH", args...))
 }
 
 // RPop Removes and returns the last element of the list stored at key
 // return redis.ErrNil if the key is not exist
 func (r *Redigo) RPop(key string) (string, error) {
+	return r.RPopContext(context.Background(), key)
+}
+
+// RPopContext is RPop with context
+func (r *Redigo) RPopContext(ctx context.Context, key string) (string, error) {
 	var resp string
 
-	val, err := r.do("RPOP", key)
+	val, err := r.do(ctx, "RPOP", key)
 	if val != nil {
 		resp = fmt.Sprintf("%s", val)
 	} else if err == nil {
@@ -33,24 +44,39 @@ func (r *Redigo) RPop(key string) (string, error) {
 
 // LLen get the length of the list
 func (r *Redigo) LLen(key string) (int64, error) {
-	return redis.Int64(r.do("LLEN", key))
+	return r.LLenContext(context.Background(), key)
+}
+
+// LLenContext is LLen with context
+func (r *Redigo) LLenContext(ctx context.Context, key string) (int64, error) {
+	return redis.Int64(r.do(ctx, "LLEN", key))
 }
 
 // LPush prepend values to the list
 func (r *Redigo) LPush(key string, values ...string) (int, error) {
+	return r.LPushContext(context.Background(), key, values...)
+}
+
+// LPushContext is LPush with context
+func (r *Redigo) LPushContext(ctx context.Context, key string, values ...string) (int, error) {
 	args := make([]interface{}, len(values)+1)
 	args[0] = key
 	for i, value := range values {
 		args[i+1] = value
 	}
-	return redis.Int(r.do("LPUSH", args...))
+	return redis.Int(r.do(ctx, "LPUSH", args...))
 }
 
 // LPop removes and get the first element in the list
 func (r *Redigo) LPop(key string) (string, error) {
+	return r.LPopContext(context.Background(), key)
+}
+
+// LPopContext is LPop with context
+func (r *Redigo) LPopContext(ctx context.Context, key string) (string, error) {
 	var resp string
 
-	val, err := r.do("LPOP", key)
+	val, err := r.do(ctx, "LPOP", key)
 	if val != nil {
 		resp = fmt.Sprintf("%s", val)
 	} else if err == nil {
@@ -62,5 +88,10 @@ func (r *Redigo) LPop(key string) (string, error) {
 
 // LRange returns the specified elements of the list stored at key
 func (r *Redigo) LRange(key string, start, stop int64) ([]string, error) {
-	return redis.Strings(r.do("LRANGE", key, start, stop))
+	return r.LRangeContext(context.Background(), key, start, stop)
+}
+
+// LRangeContext is LRange with context
+func (r *Redigo) LRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return redis.Strings(r.do(ctx, "LRANGE", key, start, stop))
 }
@@ -1,7 +1,11 @@
 package redigo
 
 import (
+	"context"
 	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
 
 	"github.com/boxofimagination/bxdk/go/redis/engine"
 )
@@ -11,11 +15,18 @@ const (
 	defaultPipelineNumCmdHint = 2
 )
 
-// pipelineCmdErr is pipeline command and error
+// pipelineCmdErr is pipeline command, reply, and error
 type pipelineCmdErr struct {
-	cmd  string
-	args []interface{}
-	err  error
+	cmd   string
+	args  []interface{}
+	reply interface{}
+	err   error
+
+	// result is set after the pipeline is executed
+	result engine.ReplySetter
+
+	// idempotent command is retried even if it failed after it was sent
+	idempotent bool
 }
 
 func (pce pipelineCmdErr) Name() string {
@@ -32,6 +43,12 @@ func (pce pipelineCmdErr) Err() error {
 
 // Pipeline creates new redigo pipeline
 func (r *Redigo) Pipeline(retry, cmdNumHint int) engine.Pipeliner {
+	return r.PipelineContext(context.Background(), retry, cmdNumHint)
+}
+
+// PipelineContext is Pipeline with context.
+// The ctx bounds every execution of the pipeline.
+func (r *Redigo) PipelineContext(ctx context.Context, retry, cmdNumHint int) engine.Pipeliner {
 	if retry <= 0 {
 		retry = 1
 	}
@@ -40,9 +57,11 @@ func (r *Redigo) Pipeline(retry, cmdNumHint int) engine.Pipeliner {
 	}
 
 	p := &pipeline{
+		ctx:        ctx,
 		cli:        r,
 		retry:      retry,
 		cmdNumHint: cmdNumHint,
+		chunkSize:  r.pipelineChunkSize,
 	}
 	p.resetCmdBuf()
 	return p
@@ -51,6 +70,7 @@ func (r *Redigo) Pipeline(retry, cmdNumHint int) engine.Pipeliner {
 // pipeline is helper for redigo pipelined command.
 // This pipeline could be used multiple times and from concurrent goroutines
 type pipeline struct {
+	ctx context.Context
 	cli *Redigo
 
 	mux sync.Mutex
@@ -63,79 +83,286 @@ type pipeline struct {
 
 	// hints about number of commands on each pipeline
 	cmdNumHint int
+
+	// maximum number of commands on each execution, zero means no limit
+	chunkSize int
 }
 
 // AddRawCmd adds raw redis command to the pipeline
-func (p *pipeline) AddRawCmd(cmd string, args ...interface{}) {
+func (p *pipeline) AddRawCmd(cmd string, args ...interface{}) *engine.Result {
+	res := &engine.Result{}
+	p.add(res, cmd, args...)
+	return res
+}
+
+// AddIdempotentCmd adds raw idempotent redis command to the pipeline
+func (p *pipeline) AddIdempotentCmd(cmd string, args ...interface{}) *engine.Result {
+	res := &engine.Result{}
+	p.addIdempotent(res, cmd, args...)
+	return res
+}
+
+// add adds the command and its result to the pipeline
+func (p *pipeline) add(res engine.ReplySetter, cmd string, args ...interface{}) {
+	p.addCmd(pipelineCmdErr{cmd: cmd, args: args, result: res})
+}
+
+// addIdempotent adds the idempotent command and its result to the pipeline
+func (p *pipeline) addIdempotent(res engine.ReplySetter, cmd string, args ...interface{}) {
+	p.addCmd(pipelineCmdErr{cmd: cmd, args: args, result: res, idempotent: true})
+}
+
+func (p *pipeline) addCmd(pce pipelineCmdErr) {
 	p.mux.Lock()
-	p.cmdErrs = append(p.cmdErrs, pipelineCmdErr{
-		cmd:  cmd,
-		args: args,
-	})
+	p.cmdErrs = append(p.cmdErrs, pce)
 	p.mux.Unlock()
 }
 
-// Exec executes the pipeline
+// Exec executes the pipeline, chunk by chunk
 func (p *pipeline) Exec() ([]engine.CmdErr, int, error) {
 	p.mux.Lock()
 	defer p.mux.Unlock()
 
 	defer p.resetCmdBuf()
 
+	return p.cli.hooks.ProcessPipeline(p.ctx, p.cmdErrs, p.execChunks)
+}
+
+// execChunks executes the queued commands chunk by chunk, without calling the hooks
+func (p *pipeline) execChunks() ([]engine.CmdErr, int, error) {
+	var chunkErrs []*engine.ChunkError
+	for start := 0; start < len(p.cmdErrs); {
+		end := len(p.cmdErrs)
+		if p.chunkSize > 0 && end-start > p.chunkSize {
+			end = start + p.chunkSize
+		}
+		if chunkErr := p.execChunk(p.cmdErrs[start:end]); chunkErr != nil {
+			chunkErr.Start, chunkErr.End = start, end
+			chunkErrs = append(chunkErrs, chunkErr)
+		}
+		start = end
+	}
+	setResults(p.cmdErrs)
+
+	firstErr := -1
+	for i, cmd := range p.cmdErrs {
+		if cmd.Err() != nil {
+			firstErr = i
+			break
+		}
+	}
+
+	if len(chunkErrs) > 0 {
+		return p.cmdErrs, firstErr, &engine.PipelineError{Chunks: chunkErrs}
+	}
+	return p.cmdErrs, firstErr, nil
+}
+
+// execChunk executes the chunk's commands, retrying the failed commands which are safe to retry.
+// It sets the reply & the error of the commands, and returns non nil error if some of them still failed.
+func (p *pipeline) execChunk(cmdErrs []engine.CmdErr) *engine.ChunkError {
 	var (
-		ret      []engine.CmdErr
-		err      error
-		firstErr int
+		pending = make([]int, len(cmdErrs)) // index of the commands to be executed
+		sent    bool
 	)
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for i := 0; i < p.retry && len(pending) > 0; i++ {
+		if i > 0 && p.ctx.Err() != nil {
+			// no need to retry, the ctx is already done
+			break
+		}
+
+		cmds := make([]engine.CmdErr, len(pending))
+		for j, idx := range pending {
+			cmds[j] = cmdErrs[idx]
+		}
+
+		ret, _, err := p.exec(cmds)
+
+		var retry []int
+		for j, idx := range pending {
+			pce := cmdErrs[idx].(pipelineCmdErr)
+			if err != nil {
+				// the whole execution failed
+				pce.reply, pce.err = nil, err
+			} else {
+				executed := ret[j].(pipelineCmdErr)
+				pce.reply, pce.err = stripReplyPrefix(p.cli.keyPrefix, pce.cmd, executed.reply), executed.err
+			}
+
+			var notSent bool
+			pce.err, notSent = unwrapNotSent(pce.err)
+			cmdErrs[idx] = pce
+
+			if !isFailed(pce.err) {
+				continue
+			}
+			if notSent || pce.idempotent {
+				retry = append(retry, idx)
+			}
+			sent = sent || !notSent
+		}
+		pending = retry
+	}
+
+	for _, cmd := range cmdErrs {
+		if isFailed(cmd.Err()) {
+			return &engine.ChunkError{Sent: sent, Err: cmd.Err()}
+		}
+	}
+	return nil
+}
+
+// setResults sets the results of the executed commands
+func setResults(cmdErrs []engine.CmdErr) {
+	for _, cmd := range cmdErrs {
+		pce := cmd.(pipelineCmdErr)
+		if pce.err == nil && pce.reply == nil {
+			pce.result.SetReply(nil, redis.ErrNil)
+			continue
+		}
+		pce.result.SetReply(toEvalValue(pce.reply), pce.err)
+	}
+}
 
-	// execute the pipeline with retry, if needed
-	for i := 0; i < p.retry; i++ {
-		ret, firstErr, err = p.exec()
-		if err == nil {
-			return ret, firstErr, nil
+// exec executes the commands once, if the circuit breaker allows it.
+// The err is returned if the whole execution failed, it is wrapped by notSentError if none of the commands was sent.
+func (p *pipeline) exec(cmdErrs []engine.CmdErr) (ret []engine.CmdErr, firstErr int, err error) {
+	if err = p.ctx.Err(); err != nil {
+		return nil, -1, notSentError{err}
+	}
+
+	// the executed copies have the prefixed keys, only their replies are read back
+	cmdErrs = prefixCmds(p.cli.keyPrefix, cmdErrs)
+	breakerErr := p.cli.breaker.Do(func() error {
+		ret, firstErr, err = p.execOnce(cmdErrs)
+		if err != nil {
+			return err
 		}
+		return failedErr(ret)
+	})
+	if breakerErr == engine.ErrCircuitOpen {
+		return nil, -1, notSentError{breakerErr}
 	}
 	return ret, firstErr, err
 }
 
-func (p *pipeline) exec() ([]engine.CmdErr, int, error) {
-	firstErr := -1
+// execOnce executes the commands once, see exec
+func (p *pipeline) execOnce(cmdErrs []engine.CmdErr) ([]engine.CmdErr, int, error) {
+	if p.cli.cluster != nil {
+		return p.cli.cluster.execPipeline(p.ctx, cmdErrs)
+	}
+
+	if p.cli.sentinel != nil {
+		return p.cli.sentinel.execPipeline(p.ctx, cmdErrs)
+	}
+
+	if p.cli.shards != nil {
+		return p.cli.shards.execPipeline(p.ctx, cmdErrs)
+	}
+
+	conn, err := p.cli.getConn(p.ctx)
+	if err != nil {
+		return nil, -1, notSentError{err}
+	}
+	return execPipelineConn(p.ctx, conn, cmdErrs)
+}
+
+// notSentError is error of the pipelined command(s) which failed before it was sent to the server
+type notSentError struct {
+	err error
+}
+
+func (e notSentError) Error() string {
+	return e.err.Error()
+}
+
+// unwrapNotSent returns the error wrapped by notSentError, and whether it is wrapped
+func unwrapNotSent(err error) (error, bool) {
+	if nse, ok := err.(notSentError); ok {
+		return nse.err, true
+	}
+	return err, false
+}
+
+// isFailed returns true if the pipelined command failed without getting its reply,
+// the error replied by the server doesn't count
+func isFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.(redis.Error)
+	return !ok
+}
+
+// failedErr returns error of the first failed command, see isFailed
+func failedErr(cmdErrs []engine.CmdErr) error {
+	for _, cmd := range cmdErrs {
+		if err := cmd.Err(); isFailed(err) {
+			return err
+		}
+	}
+	return nil
+}
 
-	conn, err := p.cli.getConn()
+// execPipelineConn executes the pipelined commands using the given connection,
+// and closes the connection afterward.
+func execPipelineConn(ctx context.Context, conn redis.Conn, cmdErrs []engine.CmdErr) ([]engine.CmdErr, int, error) {
+	// the commands might be still accessed after the ctx is done,
+	// hold them on local variable (instead of reading them from the pipeline)
+	resp, err := runConn(ctx, conn, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+		return execConn(conn, timeout, cmdErrs)
+	})
 	if err != nil {
-		return nil, firstErr, err
+		return nil, -1, err
+	}
+
+	firstErr := -1
+	for i, rep := range resp.([]cmdReply) {
+		pce := cmdErrs[i].(pipelineCmdErr)
+		pce.reply, pce.err = rep.reply, rep.err
+		cmdErrs[i] = pce
+		if rep.err != nil && firstErr < 0 {
+			firstErr = i
+		}
 	}
-	defer conn.Close()
+	return cmdErrs, firstErr, nil
+}
 
+// execConn executes the pipelined commands using the given connection.
+// It returns reply of each of the commands.
+// It doesn't modify the commands because it might still run after the caller stops waiting.
+func execConn(conn redis.Conn, timeout time.Duration, cmdErrs []engine.CmdErr) (interface{}, error) {
 	// buffer the command to redigo connection.
 	// we don't do it earlier because if we do it earlier, we get more risk
 	// that the connection become invalid/closed when we finally execute the pipeline.
-	for _, cmd := range p.cmdErrs {
-		err = conn.Send(cmd.Name(), cmd.Args()...)
+	for i, cmd := range cmdErrs {
+		err := conn.Send(cmd.Name(), cmd.Args()...)
 		if err != nil {
-			return nil, firstErr, err
+			if i == 0 {
+				// the connection is already broken, nothing is written
+				return nil, notSentError{err}
+			}
+			// Send might have flushed the buffered commands
+			return nil, err
 		}
 	}
 
 	// flush the command
-	err = conn.Flush()
+	err := conn.Flush()
 	if err != nil {
-		return nil, firstErr, err
+		return nil, err
 	}
 
-	// receive the errors
-	for i := 0; i < len(p.cmdErrs); i++ {
-		_, err = conn.Receive()
-		if err != nil {
-			pce := p.cmdErrs[i].(pipelineCmdErr)
-			pce.err = err
-			p.cmdErrs[i] = pce
-			if firstErr < 0 {
-				firstErr = i
-			}
-		}
+	// receive the replies
+	replies := make([]cmdReply, len(cmdErrs))
+	for i := range replies {
+		replies[i].reply, replies[i].err = receiveWithTimeout(conn, timeout)
 	}
-	return p.cmdErrs, firstErr, nil
+	return replies, nil
 }
 
 // Discard resets the pipeline and discards queued commands
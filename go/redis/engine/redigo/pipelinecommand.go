@@ -1,34 +1,44 @@
 package redigo
 
-func (p *pipeline) Incr(key string) {
-	p.AddRawCmd("INCR", key)
+import "github.com/boxofimagination/bxdk/go/redis/engine"
+
+func (p *pipeline) Incr(key string) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "INCR", key)
+	return res
 }
 
-func (p *pipeline) IncrBy(key string, value int64) {
-	p.AddRawCmd("INCRBY", key, value)
+func (p *pipeline) IncrBy(key string, value int64) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "INCRBY", key, value)
+	return res
 }
 
-func (p *pipeline) Decr(key string) {
-	p.AddRawCmd("DECR", key)
+func (p *pipeline) Decr(key string) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "DECR", key)
+	return res
 }
 
-func (p *pipeline) DecrBy(key string, value int64) {
-	p.AddRawCmd("DECRBY", key, value)
+func (p *pipeline) DecrBy(key string, value int64) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "DECRBY", key, value)
+	return res
 }
 
-func (p *pipeline) Expire(key string, expiry int) {
-	p.AddRawCmd("EXPIRE", key, expiry)
+func (p *pipeline) Expire(key string, expiry int) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "EXPIRE", key, expiry)
+	return res
 }
 
-func (p *pipeline) Delete(keys ...string) {
-	args := make([]interface{}, len(keys))
-	for i, key := range keys {
-		args[i] = key
-	}
-	p.AddRawCmd("DEL", args...)
+func (p *pipeline) Delete(keys ...string) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "DEL", keysArgs(nil, keys)...)
+	return res
 }
 
-func (p *pipeline) HMSet(key string, kv map[string]interface{}) {
+func (p *pipeline) HMSet(key string, kv map[string]interface{}) *engine.StringResult {
 	var (
 		args = make([]interface{}, 1+(len(kv)*2))
 		idx  = 1
@@ -39,14 +49,103 @@ func (p *pipeline) HMSet(key string, kv map[string]interface{}) {
 		args[idx+1] = v
 		idx += 2
 	}
-	p.AddRawCmd("HMSET", args...)
+
+	res := &engine.StringResult{}
+	p.add(res, "HMSET", args...)
+	return res
 }
 
-func (p *pipeline) HDel(key string, fields ...string) {
-	args := make([]interface{}, len(fields)+1)
-	args[0] = key
-	for i, field := range fields {
-		args[i+1] = field
+func (p *pipeline) HDel(key string, fields ...string) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "HDEL", keysArgs([]interface{}{key}, fields)...)
+	return res
+}
+
+func (p *pipeline) ZAdd(key string, args engine.ZAddArgs, members ...engine.Z) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "ZADD", args.CmdArgs(key, members...)...)
+	return res
+}
+
+func (p *pipeline) ZIncrBy(key string, increment float64, member string) *engine.FloatResult {
+	res := &engine.FloatResult{}
+	p.add(res, "ZINCRBY", key, increment, member)
+	return res
+}
+
+func (p *pipeline) ZRem(key string, members ...string) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "ZREM", keysArgs([]interface{}{key}, members)...)
+	return res
+}
+
+func (p *pipeline) ZRemRangeByScore(key, min, max string) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.add(res, "ZREMRANGEBYSCORE", key, min, max)
+	return res
+}
+
+func (p *pipeline) Eval(script string, keys []string, args ...interface{}) *engine.Result {
+	res := &engine.Result{}
+	p.add(res, "EVAL", engine.EvalArgs(script, keys, args...)...)
+	return res
+}
+
+func (p *pipeline) EvalSha(sha1 string, keys []string, args ...interface{}) *engine.Result {
+	res := &engine.Result{}
+	p.add(res, "EVALSHA", engine.EvalArgs(sha1, keys, args...)...)
+	return res
+}
+
+func (p *pipeline) Get(key string) *engine.StringResult {
+	res := &engine.StringResult{}
+	p.addIdempotent(res, "GET", key)
+	return res
+}
+
+func (p *pipeline) MGet(keys ...string) *engine.StringsResult {
+	res := &engine.StringsResult{}
+	p.addIdempotent(res, "MGET", keysArgs(nil, keys)...)
+	return res
+}
+
+func (p *pipeline) HGet(key, field string) *engine.StringResult {
+	res := &engine.StringResult{}
+	p.addIdempotent(res, "HGET", key, field)
+	return res
+}
+
+func (p *pipeline) HMGet(key string, fields ...string) *engine.StringsResult {
+	res := &engine.StringsResult{}
+	p.addIdempotent(res, "HMGET", keysArgs([]interface{}{key}, fields)...)
+	return res
+}
+
+func (p *pipeline) TTL(key string) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.addIdempotent(res, "TTL", key)
+	return res
+}
+
+func (p *pipeline) Exists(keys ...string) *engine.IntResult {
+	res := &engine.IntResult{}
+	p.addIdempotent(res, "EXISTS", keysArgs(nil, keys)...)
+	return res
+}
+
+func (p *pipeline) LRange(key string, start, stop int64) *engine.StringsResult {
+	res := &engine.StringsResult{}
+	p.addIdempotent(res, "LRANGE", key, start, stop)
+	return res
+}
+
+// keysArgs appends the keys (or the fields, or the members) to the args
+func keysArgs(args []interface{}, keys []string) []interface{} {
+	if args == nil {
+		args = make([]interface{}, 0, len(keys))
+	}
+	for _, key := range keys {
+		args = append(args, key)
 	}
-	p.AddRawCmd("HDEL", args...)
+	return args
 }
@@ -0,0 +1,131 @@
+package redigo
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// prefixArgs returns copy of the command arguments with the prefix added to the keys.
+// The escaped prefix is added to the pattern of SCAN & KEYS, so the prefix is matched literally,
+// it is removed from their replies by the callers, see stripPrefix & stripReplyPrefix.
+// The args are returned as is if the prefix is empty or the command has no key.
+func prefixArgs(prefix, cmd string, args []interface{}) []interface{} {
+	if prefix == "" {
+		return args
+	}
+
+	switch strings.ToUpper(cmd) {
+	case "SCAN":
+		return prefixScanArgs(prefix, args)
+	case "KEYS":
+		if len(args) == 0 {
+			return args
+		}
+		prefixed := append([]interface{}{}, args...)
+		prefixed[0] = escapePattern(prefix) + argString(args[0])
+		return prefixed
+	}
+
+	indexes := engine.KeyIndexes(cmd, args)
+	if len(indexes) == 0 {
+		return args
+	}
+	prefixed := append([]interface{}{}, args...)
+	for _, i := range indexes {
+		prefixed[i] = prefix + argString(args[i])
+	}
+	return prefixed
+}
+
+// prefixScanArgs adds the prefix to the MATCH pattern of SCAN.
+// args: cursor [MATCH pattern] [COUNT count] [TYPE type]
+func prefixScanArgs(prefix string, args []interface{}) []interface{} {
+	prefixed := append([]interface{}{}, args...)
+	for i := 1; i+1 < len(prefixed); i += 2 {
+		if strings.EqualFold(argString(prefixed[i]), "MATCH") {
+			prefixed[i+1] = escapePattern(prefix) + argString(prefixed[i+1])
+			return prefixed
+		}
+	}
+
+	// scan the keys of the namespace only
+	return append(prefixed, "MATCH", escapePattern(prefix)+"*")
+}
+
+// prefixKeys returns copy of the keys with the prefix added
+func prefixKeys(prefix string, keys []string) []string {
+	if prefix == "" {
+		return keys
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = prefix + key
+	}
+	return prefixed
+}
+
+// stripPrefix removes the prefix from the keys, in place
+func stripPrefix(prefix string, keys []string) []string {
+	if prefix == "" {
+		return keys
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+	return keys
+}
+
+// stripReplyPrefix removes the prefix from the keys in the reply of KEYS,
+// the replies of the other commands are returned as is
+func stripReplyPrefix(prefix, cmd string, reply interface{}) interface{} {
+	if prefix == "" || !strings.EqualFold(cmd, "KEYS") {
+		return reply
+	}
+	keys, ok := reply.([]interface{})
+	if !ok {
+		return reply
+	}
+	for i, key := range keys {
+		switch k := key.(type) {
+		case []byte:
+			keys[i] = bytes.TrimPrefix(k, []byte(prefix))
+		case string:
+			keys[i] = strings.TrimPrefix(k, prefix)
+		}
+	}
+	return keys
+}
+
+// escapePattern escapes the glob-style special characters of the string,
+// so it could be used as literal part of the SCAN & KEYS pattern
+func escapePattern(s string) string {
+	if !strings.ContainsAny(s, `*?[]\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// prefixCmds returns copy of the pipelined commands with the prefix added to their keys
+func prefixCmds(prefix string, cmdErrs []engine.CmdErr) []engine.CmdErr {
+	if prefix == "" {
+		return cmdErrs
+	}
+	prefixed := make([]engine.CmdErr, len(cmdErrs))
+	for i, cmd := range cmdErrs {
+		pce := cmd.(pipelineCmdErr)
+		pce.args = prefixArgs(prefix, pce.cmd, pce.args)
+		prefixed[i] = pce
+	}
+	return prefixed
+}
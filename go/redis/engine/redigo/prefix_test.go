@@ -0,0 +1,100 @@
+package redigo
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+func TestPrefixArgs(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		args []interface{}
+		want []interface{}
+	}{
+		{"GET", []interface{}{"k"}, []interface{}{"p:k"}},
+		{"del", []interface{}{"k1", []byte("k2")}, []interface{}{"p:k1", "p:k2"}},
+		{"MSET", []interface{}{"k1", "v1", "k2", "v2"}, []interface{}{"p:k1", "v1", "p:k2", "v2"}},
+		{"EVALSHA", []interface{}{"sha", 1, "k", "arg"}, []interface{}{"sha", 1, "p:k", "arg"}},
+		{"XREAD", []interface{}{"COUNT", 1, "STREAMS", "s1", "s2", "0", "0"},
+			[]interface{}{"COUNT", 1, "STREAMS", "p:s1", "p:s2", "0", "0"}},
+		{"PING", []interface{}{}, []interface{}{}},
+		{"SCAN", []interface{}{0, "MATCH", "k*", "COUNT", 10}, []interface{}{0, "MATCH", "p:k*", "COUNT", 10}},
+		{"SCAN", []interface{}{0, "COUNT", 10}, []interface{}{0, "COUNT", 10, "MATCH", "p:*"}},
+		{"KEYS", []interface{}{"*"}, []interface{}{"p:*"}},
+	}
+	for _, tt := range tests {
+		args := append([]interface{}{}, tt.args...)
+		require.Equal(t, tt.want, prefixArgs("p:", tt.cmd, args), tt.cmd)
+		require.Equal(t, tt.args, args, "the args are not modified")
+	}
+
+	require.Equal(t, []interface{}{"k"}, prefixArgs("", "GET", []interface{}{"k"}))
+	require.Equal(t, []interface{}{0, "MATCH", `p\*\?\[\]\\:k*`}, prefixArgs(`p*?[]\:`, "SCAN", []interface{}{0, "MATCH", "k*"}))
+}
+
+func TestKeyPrefix(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	r := New(engine.Config{Address: mr.Addr(), KeyPrefix: "svc:"})
+	require.NoError(t, mr.Set("other", "x"))
+
+	require.NoError(t, r.Set("k1", "v1"))
+	require.NoError(t, r.MSet("k2", "v2", "k3", "v3"))
+	_, err = r.HSetEX("hash", "f1", "a", 100)
+	require.NoError(t, err)
+
+	keys := mr.Keys()
+	require.Equal(t, []string{"other", "svc:hash", "svc:k1", "svc:k2", "svc:k3"}, keys)
+	require.Equal(t, 100*1e9, float64(mr.TTL("svc:hash")))
+
+	values, err := r.MGet("k1", "k2", "other")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "v2", ""}, values)
+
+	found, _, err := r.Scan("k*", 0, 100)
+	require.NoError(t, err)
+	sort.Strings(found)
+	require.Equal(t, []string{"k1", "k2", "k3"}, found)
+
+	reply, err := r.Do("KEYS", "k*")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []interface{}{[]byte("k1"), []byte("k2"), []byte("k3")}, reply)
+
+	p := r.Pipeline(1, 3)
+	get := p.AddRawCmd("GET", "k3")
+	p.AddRawCmd("SET", "k4", "v4")
+	keysCmd := p.AddRawCmd("KEYS", "h*")
+	_, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+	require.Equal(t, "v3", get.Val())
+	require.Equal(t, []interface{}{"hash"}, keysCmd.Val())
+	require.True(t, mr.Exists("svc:k4"))
+
+	replies, err := r.Watch(func(tx engine.Tx) error {
+		val, err := tx.Do("GET", "k1")
+		if err != nil {
+			return err
+		}
+		tx.Queue("SET", "k5", val)
+		tx.Queue("KEYS", "h*")
+		return nil
+	}, 1, "k1")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"OK", []interface{}{"hash"}}, replies)
+	val, err := mr.Get("svc:k5")
+	require.NoError(t, err)
+	require.Equal(t, "v1", val)
+
+	n, err := r.Delete("k1", "k2", "other")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.True(t, mr.Exists("other"))
+}
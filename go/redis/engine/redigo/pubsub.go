@@ -0,0 +1,382 @@
+package redigo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const (
+	defaultPubSubPingPeriod = 30 * time.Second
+
+	// backoff of the reconnection after the pub/sub connection is broken
+	pubSubMinBackoff = 100 * time.Millisecond
+	pubSubMaxBackoff = 5 * time.Second
+
+	// buffer size of the message channel
+	pubSubChannelSize = 100
+)
+
+var errPubSubClosed = errors.New("redigo: pubsub is closed")
+
+// Publish posts the message to the channel, and returns number of the clients receiving the message
+func (r *Redigo) Publish(channel string, message interface{}) (int64, error) {
+	return r.PublishContext(context.Background(), channel, message)
+}
+
+// PublishContext is Publish with context
+func (r *Redigo) PublishContext(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return redis.Int64(r.do(ctx, "PUBLISH", channel, message))
+}
+
+// Subscribe subscribes the channels using a dedicated connection.
+// The caller must close the returned PubSub after using it
+func (r *Redigo) Subscribe(channels ...string) (engine.PubSub, error) {
+	return r.SubscribeContext(context.Background(), channels...)
+}
+
+// SubscribeContext is Subscribe with context.
+// The ctx only bounds the connecting & subscribing, not the lifetime of the PubSub
+func (r *Redigo) SubscribeContext(ctx context.Context, channels ...string) (engine.PubSub, error) {
+	return newPubSub(ctx, r.dialConn, r.pubSubPingPeriod, channels, nil)
+}
+
+// PSubscribe subscribes the channels matching the patterns using a dedicated connection.
+// The caller must close the returned PubSub after using it
+func (r *Redigo) PSubscribe(patterns ...string) (engine.PubSub, error) {
+	return r.PSubscribeContext(context.Background(), patterns...)
+}
+
+// PSubscribeContext is PSubscribe with context.
+// The ctx only bounds the connecting & subscribing, not the lifetime of the PubSub
+func (r *Redigo) PSubscribeContext(ctx context.Context, patterns ...string) (engine.PubSub, error) {
+	return newPubSub(ctx, r.dialConn, r.pubSubPingPeriod, nil, patterns)
+}
+
+// dialConn dials a new connection to the master, which is not managed by any pool.
+// In cluster mode, it dials the first master node, and in sharded mode, the first up node.
+func (r *Redigo) dialConn(ctx context.Context) (redis.Conn, error) {
+	if r.cluster != nil {
+		addr, err := r.cluster.slotAddr(ctx, -1)
+		if err != nil {
+			return nil, err
+		}
+		return r.cluster.dial(addr)
+	}
+
+	if r.sentinel != nil {
+		return r.sentinel.dialMaster()
+	}
+
+	if r.shards != nil {
+		return r.shards.dialAny()
+	}
+	return r.pool.Dial()
+}
+
+// pubSub is engine.PubSub using redigo's PubSubConn.
+//
+// A goroutine receives the messages from the connection, and another one sends PING periodically.
+// If nothing is received in twice of the ping period, the connection is considered broken.
+// The receiving goroutine then reconnects and resubscribes all of the channels & patterns.
+type pubSub struct {
+	dial       func(ctx context.Context) (redis.Conn, error)
+	pingPeriod time.Duration
+
+	mux      sync.Mutex // guards the fields below and the writes to the connection
+	conn     redis.PubSubConn
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	closed   bool
+
+	msgCh  chan engine.Message
+	exitCh chan struct{}
+}
+
+// newPubSub dials the connection and waits until all of the channels & patterns are subscribed
+func newPubSub(ctx context.Context, dial func(ctx context.Context) (redis.Conn, error), pingPeriod time.Duration,
+	channels, patterns []string) (*pubSub, error) {
+	if pingPeriod <= 0 {
+		pingPeriod = defaultPubSubPingPeriod
+	}
+
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pubSub{
+		dial:       dial,
+		pingPeriod: pingPeriod,
+		conn:       redis.PubSubConn{Conn: conn},
+		channels:   make(map[string]struct{}),
+		patterns:   make(map[string]struct{}),
+		msgCh:      make(chan engine.Message, pubSubChannelSize),
+		exitCh:     make(chan struct{}),
+	}
+	addAll(p.channels, channels)
+	addAll(p.patterns, patterns)
+
+	if err = p.resubscribe(p.conn); err == nil {
+		// the duplicates are subscribed once
+		err = p.waitSubscribed(ctx, len(p.channels)+len(p.patterns))
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go p.run(p.conn)
+	return p, nil
+}
+
+// waitSubscribed waits for the subscription confirmations.
+// The messages received in the meantime are kept in the message channel.
+func (p *pubSub) waitSubscribed(ctx context.Context, num int) error {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	for num > 0 {
+		var reply interface{}
+		if timeout > 0 {
+			reply = p.conn.ReceiveWithTimeout(timeout)
+		} else {
+			reply = p.conn.Receive()
+		}
+
+		switch v := reply.(type) {
+		case redis.Subscription:
+			num--
+		case redis.Message:
+			select {
+			case p.msgCh <- toMessage(v):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case error:
+			return v
+		}
+	}
+	return ctx.Err()
+}
+
+// Subscribe subscribes the channels
+func (p *pubSub) Subscribe(channels ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.closed {
+		return errPubSubClosed
+	}
+	addAll(p.channels, channels)
+	return p.conn.Subscribe(redis.Args{}.AddFlat(channels)...)
+}
+
+// PSubscribe subscribes the channels matching the patterns
+func (p *pubSub) PSubscribe(patterns ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.closed {
+		return errPubSubClosed
+	}
+	addAll(p.patterns, patterns)
+	return p.conn.PSubscribe(redis.Args{}.AddFlat(patterns)...)
+}
+
+// Unsubscribe unsubscribes the channels, or all of the channels if it is called without argument
+func (p *pubSub) Unsubscribe(channels ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.closed {
+		return errPubSubClosed
+	}
+	removeAll(p.channels, channels)
+	return p.conn.Unsubscribe(redis.Args{}.AddFlat(channels)...)
+}
+
+// PUnsubscribe unsubscribes the patterns, or all of the patterns if it is called without argument
+func (p *pubSub) PUnsubscribe(patterns ...string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.closed {
+		return errPubSubClosed
+	}
+	removeAll(p.patterns, patterns)
+	return p.conn.PUnsubscribe(redis.Args{}.AddFlat(patterns)...)
+}
+
+// Channel returns channel of the received messages.
+// It is closed after the PubSub is closed
+func (p *pubSub) Channel() <-chan engine.Message {
+	return p.msgCh
+}
+
+// Close unsubscribes everything and closes the connection
+func (p *pubSub) Close() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.exitCh)
+
+	// closing the connection stops the receiving goroutine.
+	// The error is ignored, it might be the error which broke the connection
+	p.conn.Close()
+	return nil
+}
+
+// run receives the messages until the PubSub is closed, reconnecting when the connection is broken
+func (p *pubSub) run(conn redis.PubSubConn) {
+	defer close(p.msgCh)
+
+	for ok := true; ok; conn, ok = p.reconnect() {
+		p.receive(conn)
+		conn.Close()
+	}
+}
+
+// receive receives the messages until the connection is broken or the PubSub is closed
+func (p *pubSub) receive(conn redis.PubSubConn) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go p.ping(conn, stopCh)
+
+	for {
+		switch v := conn.ReceiveWithTimeout(2 * p.pingPeriod).(type) {
+		case redis.Message:
+			select {
+			case p.msgCh <- toMessage(v):
+			case <-p.exitCh:
+				return
+			}
+		case error:
+			// redigo closes the connection on network error & timeout,
+			// the other errors (e.g. an error reply) don't break the connection
+			if conn.Conn.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// ping sends PING periodically, so the receiving goroutine gets something when the connection is healthy
+func (p *pubSub) ping(conn redis.PubSubConn, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(p.pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		}
+
+		p.mux.Lock()
+		err := conn.Ping("")
+		p.mux.Unlock()
+		if err != nil {
+			// make sure the receiving goroutine notices it
+			conn.Close()
+			return
+		}
+	}
+}
+
+// reconnect dials a new connection and resubscribes everything, retrying with backoff until it succeeds.
+// It returns false if the PubSub is closed.
+func (p *pubSub) reconnect() (redis.PubSubConn, bool) {
+	timer := time.NewTimer(pubSubMinBackoff)
+	defer timer.Stop()
+
+	for backoff := pubSubMinBackoff; ; {
+		select {
+		case <-timer.C:
+		case <-p.exitCh:
+			return redis.PubSubConn{}, false
+		}
+
+		if conn, err := p.dial(context.Background()); err == nil {
+			psc := redis.PubSubConn{Conn: conn}
+
+			p.mux.Lock()
+			if p.closed {
+				p.mux.Unlock()
+				conn.Close()
+				return redis.PubSubConn{}, false
+			}
+			if err = p.resubscribe(psc); err == nil {
+				p.conn = psc
+			}
+			p.mux.Unlock()
+
+			if err == nil {
+				return psc, true
+			}
+			conn.Close()
+		}
+
+		if backoff *= 2; backoff > pubSubMaxBackoff {
+			backoff = pubSubMaxBackoff
+		}
+		timer.Reset(backoff)
+	}
+}
+
+// resubscribe subscribes all of the channels & patterns using the connection
+func (p *pubSub) resubscribe(conn redis.PubSubConn) error {
+	if len(p.channels) > 0 {
+		conn.Conn.Send("SUBSCRIBE", redis.Args{}.AddFlat(keys(p.channels))...)
+	}
+	if len(p.patterns) > 0 {
+		conn.Conn.Send("PSUBSCRIBE", redis.Args{}.AddFlat(keys(p.patterns))...)
+	}
+	return conn.Conn.Flush()
+}
+
+func toMessage(m redis.Message) engine.Message {
+	return engine.Message{
+		Channel: m.Channel,
+		Pattern: m.Pattern,
+		Payload: string(m.Data),
+	}
+}
+
+func addAll(set map[string]struct{}, vals []string) {
+	for _, v := range vals {
+		set[v] = struct{}{}
+	}
+}
+
+// removeAll removes the values from the set, or clears the set if vals is empty
+func removeAll(set map[string]struct{}, vals []string) {
+	if len(vals) == 0 {
+		for v := range set {
+			delete(set, v)
+		}
+		return
+	}
+	for _, v := range vals {
+		delete(set, v)
+	}
+}
+
+func keys(set map[string]struct{}) []string {
+	vals := make([]string, 0, len(set))
+	for v := range set {
+		vals = append(vals, v)
+	}
+	return vals
+}
@@ -9,7 +9,12 @@ package redigo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -17,7 +22,6 @@ import (
 	"github.com/boxofimagination/bxdk/go/redis/engine"
 )
 
-
 const (
 	networkTCP = "tcp"
 )
@@ -25,9 +29,29 @@ const (
 type (
 	// Redigo defines the redis wrapper
 	Redigo struct {
-		pool *redis.Pool // redis pool
+		pool         *redis.Pool   // redis pool
 		poolWaitTime time.Duration // duration to wait when the pool exhausted
+
+		cluster  *cluster  // not nil if it is in cluster mode
+		sentinel *sentinel // not nil if the master is discovered using sentinel
+		shards   *shards   // not nil if it is in sharded mode
+
+		pubSubPingPeriod time.Duration // health check period of the pub/sub connection
+
+		pipelineChunkSize int // maximum number of commands on each pipeline execution
+
+		hooks *engine.Hooks // observe the commands, see engine.Hook
+
+		stats *poolStats // counters of the pools, see Stats
+
+		breaker *engine.CircuitBreaker // nil if the circuit breaker is disabled
+
+		keyPrefix string // added to the keys of the commands, see engine.Config.KeyPrefix
 	}
+
+	// connFn is function which runs redis command(s) using the given connection.
+	// `timeout` is read timeout of the command, zero means using the default read timeout.
+	connFn func(conn redis.Conn, timeout time.Duration) (interface{}, error)
 )
 
 // New creates new Redigo object from the given config
@@ -36,10 +60,136 @@ func New(cfg engine.Config) *Redigo {
 		cfg.MaxIdle = cfg.MaxActive
 	}
 
-	// creates the pool
-	pool := &redis.Pool{
-		MaxIdle: cfg.MaxIdle,
-		MaxActive: cfg.MaxActive,
+	poolWaitTime := time.Duration(cfg.PoolWaitMs) * time.Millisecond
+	pubSubPingPeriod := time.Duration(cfg.PubSubPingPeriodMs) * time.Millisecond
+	stats := &poolStats{}
+	breaker := engine.NewCircuitBreaker(cfg, isBreakerFailure)
+
+	if cfg.ClusterMode {
+		return &Redigo{
+			poolWaitTime:      poolWaitTime,
+			cluster:           newCluster(cfg, poolWaitTime, stats),
+			pubSubPingPeriod:  pubSubPingPeriod,
+			pipelineChunkSize: cfg.PipelineChunkSize,
+			hooks:             engine.NewHooks(cfg.Hooks...),
+			stats:             stats,
+			breaker:           breaker,
+			keyPrefix:         cfg.KeyPrefix,
+		}
+	}
+
+	if len(cfg.ShardNodes) > 0 {
+		return &Redigo{
+			poolWaitTime:      poolWaitTime,
+			shards:            newShards(cfg, poolWaitTime, stats),
+			pubSubPingPeriod:  pubSubPingPeriod,
+			pipelineChunkSize: cfg.PipelineChunkSize,
+			hooks:             engine.NewHooks(cfg.Hooks...),
+			stats:             stats,
+			breaker:           breaker,
+			keyPrefix:         cfg.KeyPrefix,
+		}
+	}
+
+	if len(cfg.SentinelAddresses) > 0 {
+		return &Redigo{
+			poolWaitTime:      poolWaitTime,
+			sentinel:          newSentinel(cfg, poolWaitTime, stats),
+			pubSubPingPeriod:  pubSubPingPeriod,
+			pipelineChunkSize: cfg.PipelineChunkSize,
+			hooks:             engine.NewHooks(cfg.Hooks...),
+			stats:             stats,
+			breaker:           breaker,
+			keyPrefix:         cfg.KeyPrefix,
+		}
+	}
+
+	dial := dialer(cfg)
+	return &Redigo{
+		pool: newPool(cfg, stats, func() (redis.Conn, error) {
+			return dial(cfg.Address)
+		}),
+		poolWaitTime:      poolWaitTime,
+		pubSubPingPeriod:  pubSubPingPeriod,
+		pipelineChunkSize: cfg.PipelineChunkSize,
+		hooks:             engine.NewHooks(cfg.Hooks...),
+		stats:             stats,
+		breaker:           breaker,
+		keyPrefix:         cfg.KeyPrefix,
+	}
+}
+
+// dialer returns function which dials the address using the connection options of the config
+func dialer(cfg engine.Config) func(address string) (redis.Conn, error) {
+	opts := []redis.DialOption{
+		redis.DialReadTimeout(time.Duration(cfg.ReadTimeoutMs) * time.Millisecond),
+		redis.DialWriteTimeout(time.Duration(cfg.WriteTimeoutMs) * time.Millisecond),
+	}
+
+	tlsConfig, tlsErr := cfg.LoadTLSConfig()
+	if tlsConfig != nil {
+		opts = append(opts, redis.DialUseTLS(true), redis.DialTLSConfig(tlsConfig))
+	}
+
+	netDialer := &net.Dialer{
+		Timeout:   time.Duration(cfg.ConnectTimeoutMs) * time.Millisecond,
+		KeepAlive: 5 * time.Minute,
+	}
+
+	return func(address string) (redis.Conn, error) {
+		if tlsErr != nil {
+			return nil, tlsErr
+		}
+
+		// keep the dialed connection, so it can be closed by runConn when the caller abandons it
+		var netConn net.Conn
+		dialOpt := redis.DialNetDial(func(network, addr string) (net.Conn, error) {
+			var err error
+			netConn, err = netDialer.Dial(network, addr)
+			return netConn, err
+		})
+
+		rc, err := redis.Dial(networkTCP, address, append(opts, dialOpt)...)
+		if err != nil {
+			return nil, err
+		}
+		conn := &abortableConn{Conn: rc, netConn: netConn}
+
+		if err = initConn(conn, cfg); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// initConn authenticates the new connection and selects the database.
+// redigo's DialPassword doesn't support the ACL username, so we do it by ourselves.
+func initConn(conn redis.Conn, cfg engine.Config) error {
+	if cfg.Password != "" {
+		args := []interface{}{cfg.Password}
+		if cfg.Username != "" {
+			args = []interface{}{cfg.Username, cfg.Password}
+		}
+		if _, err := conn.Do("AUTH", args...); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DB != 0 {
+		if _, err := conn.Do("SELECT", cfg.DB); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newPool creates redis pool which creates the connection using the given dial func,
+// the dial failures are counted by the stats
+func newPool(cfg engine.Config, stats *poolStats, dial func() (redis.Conn, error)) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     cfg.MaxIdle,
+		MaxActive:   cfg.MaxActive,
 		IdleTimeout: time.Duration(cfg.Timeout) * time.Second,
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
 			if time.Since(t) < time.Duration(cfg.IdlePingPeriod)*time.Second {
@@ -49,43 +199,151 @@ func New(cfg engine.Config) *Redigo {
 			_, err := c.Do("PING")
 			return err
 		},
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial(networkTCP , cfg.Address)
-		},
+		Dial: stats.dial(dial),
 		Wait: true,
 	}
+}
 
-	return &Redigo{
-		pool: pool,
-		poolWaitTime: time.Duration(cfg.PoolWaitMs) * time.Millisecond,
+// get connection from the pool with some timeout.
+// The ctx deadline is used instead if it comes earlier.
+// In cluster mode, it returns connection to the first master node,
+// and in sharded mode, connection to the first up node.
+func (r *Redigo) getConn(ctx context.Context) (redis.Conn, error) {
+	if r.cluster != nil {
+		return r.cluster.anyConn(ctx)
 	}
+	if r.shards != nil {
+		return r.shards.anyConn(ctx)
+	}
+	return r.stats.getConn(ctx, r.getPool(), r.poolWaitTime)
 }
 
-// get connection from the pool with some timeout
-func (r *Redigo) getConn() (redis.Conn, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), r.poolWaitTime)
-	defer cancel()
-	return r.pool.GetContext(ctx)
+// getPool returns pool of the master
+func (r *Redigo) getPool() *redis.Pool {
+	if r.sentinel != nil {
+		return r.sentinel.masterPool()
+	}
+	return r.pool
+}
+
+// runKey runs the fn using connection to the node which serves the key
+func (r *Redigo) runKey(ctx context.Context, key string, fn connFn) (interface{}, error) {
+	if r.cluster != nil {
+		return r.cluster.run(ctx, hashSlot(key), fn)
+	}
+
+	if r.shards != nil {
+		return r.shards.run(ctx, r.shards.nodeOf(key), fn)
+	}
+
+	if r.sentinel != nil {
+		return r.sentinel.run(ctx, fn)
+	}
+
+	conn, err := r.getConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runConn(ctx, conn, fn)
 }
 
 // Ping command to redis
 func (r *Redigo) Ping() (string, error) {
-	val, err  := r.Do("PING")
-	return fmt.Sprint(val), err
+	return r.PingContext(context.Background())
 }
 
+// PingContext is Ping with context
+func (r *Redigo) PingContext(ctx context.Context) (string, error) {
+	val, err := r.do(ctx, "PING")
+	return fmt.Sprint(val), err
+}
 
 // Do Command
 func (r *Redigo) Do(cmd string, args ...interface{}) (interface{}, error) {
-	return r.do(cmd, args...)
+	return r.do(context.Background(), cmd, args...)
+}
+
+// DoContext is Do with context
+func (r *Redigo) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	return r.do(ctx, cmd, args...)
 }
 
-func (r *Redigo) do(cmd string, args ...interface{}) (interface{}, error) {
-	conn, err := r.getConn()
+// do executes the command if the circuit breaker allows it, it is observed by the hooks
+func (r *Redigo) do(ctx context.Context, cmd string, args ...interface{}) (reply interface{}, err error) {
+	err = r.hooks.Process(ctx, cmd, args, func() error {
+		return r.breaker.Do(func() error {
+			reply, err = r.exec(ctx, cmd, args...)
+			return err
+		})
+	})
+	return reply, err
+}
+
+// isBreakerFailure returns true if the err means that the server is unhealthy.
+// The errors replied by the server and the cancellation by the caller don't count.
+func isBreakerFailure(err error) bool {
+	err, _ = unwrapNotSent(err)
+	return isFailed(err) && err != context.Canceled
+}
+
+// isConnError returns true if the err is failure of dialing or of the connection I/O,
+// which means that the node might be down.
+// The ctx errors (including the pool wait timeout), the exhausted pool,
+// and the errors replied by the server don't count.
+func isConnError(err error) bool {
+	err, _ = unwrapNotSent(err)
+	switch err {
+	case nil, context.Canceled, context.DeadlineExceeded, redis.ErrPoolExhausted:
+		return false
+	case io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+	if _, ok := err.(redis.Error); ok {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// exec executes the command, without calling the hooks.
+// The key prefix is added here, so the hooks see the keys without it.
+func (r *Redigo) exec(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if r.keyPrefix != "" {
+		reply, err := r.execConn(ctx, cmd, prefixArgs(r.keyPrefix, cmd, args)...)
+		return stripReplyPrefix(r.keyPrefix, cmd, reply), err
+	}
+	return r.execConn(ctx, cmd, args...)
+}
+
+// execConn executes the command using the connection of the current mode, the keys are already prefixed
+func (r *Redigo) execConn(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if r.cluster != nil {
+		return r.cluster.do(ctx, cmd, args)
+	}
+	if r.shards != nil {
+		return r.shards.do(ctx, cmd, args)
+	}
+	if r.sentinel != nil {
+		return r.sentinel.do(ctx, cmd, args)
+	}
+	return doPool(ctx, r.stats, r.pool, r.poolWaitTime, cmd, args...)
+}
+
+// doPool do the command using connection from the given pool
+func doPool(ctx context.Context, stats *poolStats, pool *redis.Pool, waitTime time.Duration,
+	cmd string, args ...interface{}) (interface{}, error) {
+	conn, err := stats.getConn(ctx, pool, waitTime)
 	if err != nil {
 		return nil, err
 	}
 
+	if ctx.Done() != nil {
+		return runConn(ctx, conn, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			return doWithTimeout(conn, timeout, cmd, args...)
+		})
+	}
+
 	resp, err := conn.Do(cmd, args...)
 
 	// we don't defer the Close because:
@@ -97,11 +355,168 @@ func (r *Redigo) do(cmd string, args ...interface{}) (interface{}, error) {
 	return resp, err
 }
 
+// runConn runs the fn using the given connection and closes the connection afterward.
+//
+// The fn is executed in another goroutine and the caller stops waiting when the ctx is done.
+// In that case the network connection is closed to unblock the fn, so the connection
+// is discarded by the pool instead of being returned with unread reply.
+// The ctx deadline is given to the fn as the `timeout`.
+func runConn(ctx context.Context, conn redis.Conn, fn connFn) (interface{}, error) {
+	if ctx.Done() == nil {
+		// the ctx could not be cancelled, no need to spawn goroutine
+		resp, err := fn(conn, 0)
+		conn.Close()
+		return resp, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var timeout time.Duration
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		timeout = time.Until(deadline)
+	}
+
+	type result struct {
+		resp interface{}
+		err  error
+	}
+	resultCh := make(chan result, 1)
+
+	ab := &aborter{}
+	conn.Do("", ab) // binds the aborter, it never fails
+
+	go func() {
+		resp, err := fn(conn, timeout)
+		ab.finish()
+		conn.Close()
+		resultCh <- result{resp: resp, err: err}
+	}()
+
+	select {
+	case res := <-resultCh:
+		if hasDeadline && isTimeout(res.err) && !time.Now().Before(deadline) {
+			// the read timeout is the ctx deadline, which might be reached before the ctx is done
+			return nil, context.DeadlineExceeded
+		}
+		return res.resp, res.err
+	case <-ctx.Done():
+		ab.abort()
+		return nil, ctx.Err()
+	}
+}
+
+// isTimeout returns true if the err is timeout of the connection I/O
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// errConnAborted is error of the connection closed by runConn because its caller abandoned it
+var errConnAborted = errors.New("redigo: connection aborted")
+
+// aborter closes the connection which is bound to it by abortableConn,
+// unless the connection is already finished and might be back in the pool.
+type aborter struct {
+	mu       sync.Mutex
+	conn     *abortableConn
+	finished bool
+}
+
+func (a *aborter) finish() {
+	a.mu.Lock()
+	a.finished = true
+	a.mu.Unlock()
+}
+
+func (a *aborter) abort() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conn != nil && !a.finished {
+		a.conn.abort()
+	}
+}
+
+// abortableConn is the connection created by the dialer which could be closed by another goroutine.
+//
+// The pool doesn't expose the connection it wraps, so the aborter is bound by doing
+// the empty command with the aborter as its only arg. The empty command without pending
+// reply is no-op for the other connections.
+type abortableConn struct {
+	redis.Conn
+	netConn net.Conn
+	aborted int32 // 1 after abort, so the pool discards the connection even if its last command succeeded
+}
+
+func (c *abortableConn) abort() {
+	atomic.StoreInt32(&c.aborted, 1)
+	c.netConn.Close()
+}
+
+func (c *abortableConn) Err() error {
+	if atomic.LoadInt32(&c.aborted) == 1 {
+		return errConnAborted
+	}
+	return c.Conn.Err()
+}
+
+func (c *abortableConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if c.bind(cmd, args) {
+		return nil, nil
+	}
+	return c.Conn.Do(cmd, args...)
+}
+
+func (c *abortableConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if c.bind(cmd, args) {
+		return nil, nil
+	}
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+func (c *abortableConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func (c *abortableConn) bind(cmd string, args []interface{}) bool {
+	if cmd != "" || len(args) != 1 {
+		return false
+	}
+	ab, ok := args[0].(*aborter)
+	if ok {
+		ab.conn = c
+	}
+	return ok
+}
+
+// doWithTimeout do the command with the given read timeout.
+// zero timeout means using the default read timeout of the connection.
+func doWithTimeout(conn redis.Conn, timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if timeout > 0 {
+		return redis.DoWithTimeout(conn, timeout, cmd, args...)
+	}
+	return conn.Do(cmd, args...)
+}
+
+// receiveWithTimeout receives the reply with the given read timeout.
+// zero timeout means using the default read timeout of the connection.
+func receiveWithTimeout(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+	if timeout > 0 {
+		return redis.ReceiveWithTimeout(conn, timeout)
+	}
+	return conn.Receive()
+}
+
 // GetConn get connection from the redis pool.
 // Notes:
 // - Please only use it for the pipelining feature.
+// - In cluster mode, it returns connection to the first master node.
+// - In sharded mode, it returns connection to the first up node.
 func (r *Redigo) GetConn() (redis.Conn, error) {
-	return r.getConn()
+	return r.getConn(context.Background())
 }
 
 // IsErrNil returns true if the err given is ErrNil value.
@@ -110,4 +525,24 @@ func (r *Redigo) GetConn() (redis.Conn, error) {
 // because each library has its own ErrNil definition.
 func (r *Redigo) IsErrNil(err error) bool {
 	return err == redis.ErrNil
-}
\ No newline at end of file
+}
+
+// Stats returns snapshot of the connection pool statistics.
+// In cluster, sentinel & sharded mode, it is the sum of the pools of all nodes.
+func (r *Redigo) Stats() engine.PoolStats {
+	switch {
+	case r.cluster != nil:
+		return r.stats.snapshot(r.cluster.allPools()...)
+	case r.sentinel != nil:
+		return r.stats.snapshot(r.sentinel.allPools()...)
+	case r.shards != nil:
+		return r.stats.snapshot(r.shards.allPools()...)
+	default:
+		return r.stats.snapshot(r.pool)
+	}
+}
+
+// AddHook registers the hook which observes the executed commands, see engine.Hook
+func (r *Redigo) AddHook(hook engine.Hook) {
+	r.hooks.Add(hook)
+}
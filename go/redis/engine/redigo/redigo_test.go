@@ -0,0 +1,88 @@
+package redigo
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// newSilentServer starts server which reads the commands but never replies
+func newSilentServer(t *testing.T) net.Listener {
+	ln, err := net.Listen(networkTCP, "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(ioutil.Discard, conn)
+		}
+	}()
+	return ln
+}
+
+func TestRunConnAbandoned(t *testing.T) {
+	ln := newSilentServer(t)
+	defer ln.Close()
+
+	r := New(engine.Config{Address: ln.Addr().String(), MaxActive: 1, PoolWaitMs: 100})
+
+	// the ctx has no deadline and the connection has no read timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := r.DoContext(ctx, "GET", "k")
+	require.Equal(t, context.Canceled, err)
+
+	// the abandoned connection is closed instead of held forever
+	require.Eventually(t, func() bool {
+		return r.Stats().ActiveCount == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRunConnDeadline(t *testing.T) {
+	ln := newSilentServer(t)
+	defer ln.Close()
+
+	r := New(engine.Config{Address: ln.Addr().String(), PoolWaitMs: 100})
+
+	// the read timeout of the ctx deadline is reported as the ctx error
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		_, err := r.DoContext(ctx, "GET", "k")
+		cancel()
+		require.Equal(t, context.DeadlineExceeded, err)
+	}
+}
+
+func TestAbortAfterReply(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	r := New(engine.Config{Address: mr.Addr(), MaxActive: 1, PoolWaitMs: 100})
+	conn := r.pool.Get()
+	ab := &aborter{}
+	_, err = conn.Do("", ab)
+	require.NoError(t, err)
+
+	// the ctx is done right after the reply is read, before the connection is finished
+	_, err = conn.Do("PING")
+	require.NoError(t, err)
+	ab.abort()
+	require.Equal(t, errConnAborted, conn.Err())
+	conn.Close()
+
+	// the closed connection is discarded instead of being reused
+	require.Equal(t, 0, r.Stats().IdleCount)
+	_, err = r.Ping()
+	require.NoError(t, err)
+}
@@ -0,0 +1,86 @@
+package redigo
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Eval executes the lua script.
+// The reply is converted the same way by all of the engines:
+// integer to int64, bulk & simple string to string, array to []interface{}, and nil to ErrNil.
+func (r *Redigo) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return r.EvalContext(context.Background(), script, keys, args...)
+}
+
+// EvalContext is Eval with context
+func (r *Redigo) EvalContext(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return evalReply(r.do(ctx, "EVAL", engine.EvalArgs(script, keys, args...)...))
+}
+
+// EvalSha executes the lua script cached in the server by its SHA1 digest.
+// It returns NOSCRIPT error if the script is not cached.
+func (r *Redigo) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return r.EvalShaContext(context.Background(), sha1, keys, args...)
+}
+
+// EvalShaContext is EvalSha with context
+func (r *Redigo) EvalShaContext(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return evalReply(r.do(ctx, "EVALSHA", engine.EvalArgs(sha1, keys, args...)...))
+}
+
+// ScriptLoad caches the lua script in the server and returns its SHA1 digest.
+// In cluster & sharded mode, the script is cached in all of the master / up nodes.
+func (r *Redigo) ScriptLoad(script string) (string, error) {
+	return r.ScriptLoadContext(context.Background(), script)
+}
+
+// ScriptLoadContext is ScriptLoad with context
+func (r *Redigo) ScriptLoadContext(ctx context.Context, script string) (string, error) {
+	if r.cluster == nil && r.shards == nil {
+		return redis.String(r.do(ctx, "SCRIPT", "LOAD", script))
+	}
+
+	// it is observed by the hooks like do, but executed on all of the nodes
+	var (
+		reply interface{}
+		args  = []interface{}{"LOAD", script}
+	)
+	err := r.hooks.Process(ctx, "SCRIPT", args, func() (err error) {
+		return r.breaker.Do(func() error {
+			if r.cluster != nil {
+				reply, err = r.cluster.doMasters(ctx, "SCRIPT", args)
+			} else {
+				reply, err = r.shards.doAll(ctx, "SCRIPT", args)
+			}
+			return err
+		})
+	})
+	return redis.String(reply, err)
+}
+
+// evalReply converts the reply of EVAL & EVALSHA to the same types as go-redis engine's
+func evalReply(reply interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, redis.ErrNil
+	}
+	return toEvalValue(reply), nil
+}
+
+func toEvalValue(reply interface{}) interface{} {
+	switch v := reply.(type) {
+	case []byte:
+		return string(v)
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = toEvalValue(elem)
+		}
+		return v
+	}
+	return reply
+}
@@ -0,0 +1,338 @@
+package redigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const (
+	// connect, read & write timeout of the connection to the sentinel
+	sentinelTimeout = time.Second
+)
+
+// sentinel discovers the master and its replicas using redis sentinel.
+//
+// The master pool resolves the current master address each time it dials a new connection.
+// On READONLY or dial error, the master is resolved again and the pools are rebuilt
+// if the master has changed, so the connections to the old master are not used anymore.
+type sentinel struct {
+	cfg          engine.Config
+	dial         func(address string) (redis.Conn, error)
+	masterName   string
+	poolWaitTime time.Duration
+	stats        *poolStats
+	readReplica  bool
+
+	mux        sync.RWMutex
+	addrs      []string    // sentinel addresses, the last replying sentinel first
+	masterAddr string      // the master address used by the current master pool
+	master     *redis.Pool // pool of the master
+	replica    *redis.Pool // pool of the replicas, nil if the replica read is disabled
+
+	failoverMux sync.Mutex // serializes the failover
+}
+
+func newSentinel(cfg engine.Config, poolWaitTime time.Duration, stats *poolStats) *sentinel {
+	s := &sentinel{
+		cfg:          cfg,
+		dial:         dialer(cfg),
+		masterName:   cfg.SentinelMasterName,
+		poolWaitTime: poolWaitTime,
+		stats:        stats,
+		readReplica:  cfg.SentinelReadReplica,
+		addrs:        append([]string{}, cfg.SentinelAddresses...),
+	}
+	s.master, s.replica = s.newPools()
+	return s
+}
+
+// newPools creates pool of the master and the replicas
+func (s *sentinel) newPools() (master, replica *redis.Pool) {
+	master = newPool(s.cfg, s.stats, s.dialMaster)
+	if s.readReplica {
+		replica = newPool(s.cfg, s.stats, s.dialReplica)
+	}
+	return master, replica
+}
+
+// masterPool returns the current master pool
+func (s *sentinel) masterPool() *redis.Pool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.master
+}
+
+// allPools returns the current master & replica pools, the replica pool is nil if the replica read is disabled
+func (s *sentinel) allPools() []*redis.Pool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return []*redis.Pool{s.master, s.replica}
+}
+
+// pool returns the pool to execute the command(s).
+// It is the replica pool if the commands are read-only & the replica read is enabled.
+func (s *sentinel) pool(readOnly bool) (pool *redis.Pool, isReplica bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if readOnly && s.replica != nil {
+		return s.replica, true
+	}
+	return s.master, false
+}
+
+// do the command.
+// The command rejected with READONLY error is retried once on the new master.
+func (s *sentinel) do(ctx context.Context, cmd string, args []interface{}) (interface{}, error) {
+	pool, isReplica := s.pool(engine.IsReadOnly(cmd))
+	resp, err := doPool(ctx, s.stats, pool, s.poolWaitTime, cmd, args...)
+	if isReplica {
+		return resp, err
+	}
+
+	if s.failover(ctx, pool, err) && isReadOnlyErr(err) {
+		// the command is not executed by the old master, it is safe to retry
+		return doPool(ctx, s.stats, s.masterPool(), s.poolWaitTime, cmd, args...)
+	}
+	return resp, err
+}
+
+// run runs the fn using connection to the master
+func (s *sentinel) run(ctx context.Context, fn connFn) (interface{}, error) {
+	pool := s.masterPool()
+	conn, err := s.stats.getConn(ctx, pool, s.poolWaitTime)
+	if err != nil {
+		s.failover(ctx, pool, err)
+		return nil, err
+	}
+
+	resp, err := runConn(ctx, conn, fn)
+	s.failover(ctx, pool, err)
+	return resp, err
+}
+
+// execPipeline executes the pipelined commands.
+// The pipeline is sent to the replica if all of the commands are read-only & the replica read is enabled.
+func (s *sentinel) execPipeline(ctx context.Context, cmdErrs []engine.CmdErr) ([]engine.CmdErr, int, error) {
+	readOnly := true
+	for _, cmdErr := range cmdErrs {
+		if !engine.IsReadOnly(cmdErr.Name()) {
+			readOnly = false
+			break
+		}
+	}
+
+	pool, isReplica := s.pool(readOnly)
+	conn, err := s.stats.getConn(ctx, pool, s.poolWaitTime)
+	if err != nil {
+		if !isReplica {
+			s.failover(ctx, pool, err)
+		}
+		return nil, -1, notSentError{err}
+	}
+
+	ret, firstErr, err := execPipelineConn(ctx, conn, cmdErrs)
+	if isReplica {
+		return ret, firstErr, err
+	}
+
+	switch {
+	case err != nil:
+		unwrapped, _ := unwrapNotSent(err)
+		s.failover(ctx, pool, unwrapped)
+	case firstErr >= 0:
+		s.failover(ctx, pool, ret[firstErr].Err())
+	}
+	return ret, firstErr, err
+}
+
+// failover resolves the master again if the err indicates that the pool might not connect to the master,
+// and rebuilds the pools if the master has changed.
+// It returns true if the given pool is not the master pool anymore.
+func (s *sentinel) failover(ctx context.Context, pool *redis.Pool, err error) bool {
+	if !isFailoverErr(err) {
+		return false
+	}
+
+	s.failoverMux.Lock()
+	defer s.failoverMux.Unlock()
+
+	if s.masterPool() != pool {
+		// already rebuilt by the other goroutine
+		return true
+	}
+
+	addr, err := s.resolveMaster(ctx)
+	if err != nil {
+		return false
+	}
+
+	s.mux.Lock()
+	if addr == s.masterAddr {
+		s.mux.Unlock()
+		return false
+	}
+	oldMaster, oldReplica := s.master, s.replica
+	s.masterAddr = addr
+	s.master, s.replica = s.newPools()
+	s.mux.Unlock()
+
+	// the active connections are closed when they are returned to the closed pool
+	oldMaster.Close()
+	if oldReplica != nil {
+		oldReplica.Close()
+	}
+	return true
+}
+
+// dialMaster dials the current master
+func (s *sentinel) dialMaster() (redis.Conn, error) {
+	addr, err := s.resolveMaster(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mux.Lock()
+	if s.masterAddr == "" {
+		s.masterAddr = addr
+	}
+	s.mux.Unlock()
+
+	return s.dial(addr)
+}
+
+// dialReplica dials one of the healthy replicas, chosen randomly.
+// It dials the master if there is no healthy replica.
+func (s *sentinel) dialReplica() (redis.Conn, error) {
+	var addrs []string
+	err := s.query(context.Background(), func(conn redis.Conn) error {
+		replicas, err := redis.Values(conn.Do("SENTINEL", "slaves", s.masterName))
+		if err != nil {
+			return err
+		}
+
+		addrs = addrs[:0]
+		for _, replica := range replicas {
+			fields, err := redis.StringMap(replica, nil)
+			if err != nil {
+				return err
+			}
+			if isDown(fields["flags"]) {
+				continue
+			}
+			addrs = append(addrs, net.JoinHostPort(fields["ip"], fields["port"]))
+		}
+		return nil
+	})
+	if err != nil || len(addrs) == 0 {
+		return s.dialMaster()
+	}
+	return s.dial(addrs[rand.Intn(len(addrs))])
+}
+
+// resolveMaster returns the current master address
+func (s *sentinel) resolveMaster(ctx context.Context) (string, error) {
+	var addr string
+	err := s.query(ctx, func(conn redis.Conn) error {
+		hostPort, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.masterName))
+		if err != nil {
+			return err
+		}
+		if len(hostPort) != 2 {
+			return fmt.Errorf("invalid master address: %v", hostPort)
+		}
+		addr = net.JoinHostPort(hostPort[0], hostPort[1])
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("redigo: failed to resolve master %v: %v", s.masterName, err)
+	}
+	return addr, nil
+}
+
+// query runs the fn using connection to the sentinels, until one of them succeed.
+// The succeeding sentinel is queried first on the next query.
+func (s *sentinel) query(ctx context.Context, fn func(conn redis.Conn) error) error {
+	s.mux.RLock()
+	addrs := append([]string{}, s.addrs...)
+	s.mux.RUnlock()
+
+	var lastErr error
+	for i, addr := range addrs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		lastErr = querySentinel(addr, fn)
+		if lastErr != nil {
+			continue
+		}
+
+		if i > 0 {
+			s.mux.Lock()
+			s.addrs = append([]string{addr}, append(addrs[:i], addrs[i+1:]...)...)
+			s.mux.Unlock()
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// querySentinel runs the fn using connection to the sentinel with the given address
+func querySentinel(addr string, fn func(conn redis.Conn) error) error {
+	conn, err := redis.Dial(networkTCP, addr,
+		redis.DialConnectTimeout(sentinelTimeout),
+		redis.DialReadTimeout(sentinelTimeout),
+		redis.DialWriteTimeout(sentinelTimeout),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return fn(conn)
+}
+
+// isDown returns true if the sentinel flags indicate that the node is down
+func isDown(flags string) bool {
+	for _, flag := range strings.Split(flags, ",") {
+		switch flag {
+		case "s_down", "o_down", "disconnected":
+			return true
+		}
+	}
+	return false
+}
+
+// isFailoverErr returns true if the err might be caused by failover of the master:
+// the master can't be dialed, or it is demoted to replica.
+// The timeouts of the established connection, the cancellation, and the exhausted pool don't count,
+// e.g. the slow command doesn't mean that the master has changed.
+func isFailoverErr(err error) bool {
+	if isReadOnlyErr(err) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// isReadOnlyErr returns true if the err is READONLY error, replied by a replica for write command
+func isReadOnlyErr(err error) bool {
+	rerr, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(rerr), "READONLY")
+}
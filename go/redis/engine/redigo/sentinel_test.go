@@ -0,0 +1,207 @@
+package redigo
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/alicebob/miniredis/server"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const testMasterName = "mymaster"
+
+// fakeSentinel is minimal redis sentinel which monitors a single master
+type fakeSentinel struct {
+	srv *server.Server
+
+	mux      sync.Mutex
+	master   string
+	replicas []string
+}
+
+func newFakeSentinel(t *testing.T, master string, replicas ...string) *fakeSentinel {
+	srv, err := server.NewServer("127.0.0.1:0")
+	require.NoError(t, err)
+
+	fs := &fakeSentinel{
+		srv:      srv,
+		master:   master,
+		replicas: replicas,
+	}
+	srv.Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		fs.mux.Lock()
+		defer fs.mux.Unlock()
+
+		if len(args) != 2 || args[1] != testMasterName {
+			c.WriteNull()
+			return
+		}
+
+		switch args[0] {
+		case "get-master-addr-by-name":
+			host, port, _ := net.SplitHostPort(fs.master)
+			c.WriteLen(2)
+			c.WriteBulk(host)
+			c.WriteBulk(port)
+		case "slaves":
+			c.WriteLen(len(fs.replicas))
+			for _, replica := range fs.replicas {
+				host, port, _ := net.SplitHostPort(replica)
+				c.WriteLen(6)
+				c.WriteBulk("ip")
+				c.WriteBulk(host)
+				c.WriteBulk("port")
+				c.WriteBulk(port)
+				c.WriteBulk("flags")
+				c.WriteBulk("slave")
+			}
+		default:
+			c.WriteError("ERR unknown sentinel subcommand")
+		}
+	})
+	return fs
+}
+
+func (fs *fakeSentinel) setMaster(master string) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	fs.master = master
+}
+
+// newReadOnlyServer creates server which rejects the SET command with READONLY error,
+// like a master which is demoted to replica.
+func newReadOnlyServer(t *testing.T) *server.Server {
+	srv, err := server.NewServer("127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv.Register("PING", func(c *server.Peer, cmd string, args []string) {
+		c.WriteInline("PONG")
+	})
+	srv.Register("SET", func(c *server.Peer, cmd string, args []string) {
+		c.WriteError("READONLY You can't write against a read only replica.")
+	})
+	return srv
+}
+
+func newTestSentinelRedigo(fs *fakeSentinel, readReplica bool) *Redigo {
+	return New(engine.Config{
+		SentinelAddresses: []string{
+			"127.0.0.1:1", // unreachable sentinel
+			fs.srv.Addr().String(),
+		},
+		SentinelMasterName:  testMasterName,
+		SentinelReadReplica: readReplica,
+		MaxActive:           10,
+		PoolWaitMs:          1000,
+		IdlePingPeriod:      10,
+	})
+}
+
+func TestSentinelConnectionError(t *testing.T) {
+	oldMaster, err := miniredis.Run()
+	require.NoError(t, err)
+	newMaster, err := miniredis.Run()
+	require.NoError(t, err)
+	defer newMaster.Close()
+
+	fs := newFakeSentinel(t, oldMaster.Addr())
+	defer fs.srv.Close()
+
+	r := newTestSentinelRedigo(fs, false)
+	require.NoError(t, r.Set("foo", "old"))
+
+	// failover
+	fs.setMaster(newMaster.Addr())
+	oldMaster.Close()
+
+	// the command on the broken connection fails, then the new connection is dialed to the new master
+	require.Error(t, r.Set("foo", "new"))
+	require.NoError(t, r.Set("foo", "new"))
+
+	val, err := newMaster.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, "new", val)
+}
+
+func TestSentinelReadOnly(t *testing.T) {
+	oldMaster := newReadOnlyServer(t)
+	defer oldMaster.Close()
+	newMaster, err := miniredis.Run()
+	require.NoError(t, err)
+	defer newMaster.Close()
+
+	fs := newFakeSentinel(t, oldMaster.Addr().String())
+	defer fs.srv.Close()
+
+	r := newTestSentinelRedigo(fs, false)
+	_, err = r.Ping()
+	require.NoError(t, err)
+
+	// the old master is demoted, the command is retried on the new master
+	fs.setMaster(newMaster.Addr())
+	require.NoError(t, r.Set("foo", "bar"))
+
+	val, err := newMaster.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, "bar", val)
+}
+
+func TestSentinelReadReplica(t *testing.T) {
+	master, err := miniredis.Run()
+	require.NoError(t, err)
+	defer master.Close()
+	replica, err := miniredis.Run()
+	require.NoError(t, err)
+	defer replica.Close()
+
+	fs := newFakeSentinel(t, master.Addr(), replica.Addr())
+	defer fs.srv.Close()
+
+	r := newTestSentinelRedigo(fs, true)
+	require.NoError(t, r.Set("foo", "master"))
+	require.NoError(t, replica.Set("foo", "replica"))
+
+	val, err := r.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, "replica", val)
+
+	// the pipeline with write command goes to the master
+	p := r.Pipeline(1, 0)
+	p.Incr("counter")
+	p.AddRawCmd("GET", "foo")
+	_, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+
+	counter, err := master.Get("counter")
+	require.NoError(t, err)
+	require.Equal(t, "1", counter)
+}
+
+func TestIsFailoverErr(t *testing.T) {
+	_, dialErr := redis.Dial(networkTCP, "127.0.0.1:1")
+	require.Error(t, dialErr)
+
+	require.True(t, isFailoverErr(dialErr))
+	require.True(t, isFailoverErr(redis.Error("READONLY You can't write against a read only replica.")))
+
+	require.False(t, isFailoverErr(nil))
+	require.False(t, isFailoverErr(context.Canceled))
+	require.False(t, isFailoverErr(context.DeadlineExceeded))
+	require.False(t, isFailoverErr(redis.ErrPoolExhausted))
+	require.False(t, isFailoverErr(redis.Error("ERR unknown command")))
+	require.False(t, isFailoverErr(&net.OpError{Op: "read", Net: networkTCP, Err: timeoutErr{}}))
+}
+
+// timeoutErr is net.Error of the timed out I/O
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
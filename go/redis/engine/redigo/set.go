@@ -1,6 +1,8 @@
 package redigo
 
 import (
+	"context"
+
 	"github.com/gomodule/redigo/redis"
 )
 
@@ -9,8 +11,13 @@ import (
 // If key does not exist, a new set is created before adding the specified members.
 // An error is returned when the value stored at key is not a set.
 func (r *Redigo) SAdd(key string, members ...interface{}) (int64, error) {
+	return r.SAddContext(context.Background(), key, members...)
+}
+
+// SAddContext is SAdd with context
+func (r *Redigo) SAddContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
 	args := append([]interface{}{key}, members...)
-	return redis.Int64(r.do("SADD", args...))
+	return redis.Int64(r.do(ctx, "SADD", args...))
 }
 
 // SRem Remove the specified members from the set stored at key.
@@ -18,11 +25,21 @@ func (r *Redigo) SAdd(key string, members ...interface{}) (int64, error) {
 // If key does not exist, it is treated as an empty set and this command returns 0.
 // An error is returned when the value stored at key is not a set.
 func (r *Redigo) SRem(key string, members ...interface{}) (int64, error) {
+	return r.SRemContext(context.Background(), key, members...)
+}
+
+// SRemContext is SRem with context
+func (r *Redigo) SRemContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
 	args := append([]interface{}{key}, members...)
-	return redis.Int64(r.do("SREM", args...))
+	return redis.Int64(r.do(ctx, "SREM", args...))
 }
 
 // SMembers Returns all the members of the set value stored at key.
 func (r *Redigo) SMembers(key string) ([]string, error) {
-	return redis.Strings(r.do("SMEMBERS", key))
+	return r.SMembersContext(context.Background(), key)
+}
+
+// SMembersContext is SMembers with context
+func (r *Redigo) SMembersContext(ctx context.Context, key string) ([]string, error) {
+	return redis.Strings(r.do(ctx, "SMEMBERS", key))
 }
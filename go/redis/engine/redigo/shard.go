@@ -0,0 +1,502 @@
+package redigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const (
+	// number of the points in the hash ring of each weight unit of a shard node
+	shardPointsPerWeight = 160
+
+	defaultShardFailureLimit  = 3
+	defaultShardRetryInterval = 30 * time.Second
+)
+
+var (
+	// errNoShardNode returned when all of the shard nodes are down
+	errNoShardNode = errors.New("redigo: no shard node is up")
+
+	// errNotSharded returned by MarkShardDown & MarkShardUp if the Redigo is not in sharded mode
+	errNotSharded = errors.New("redigo: not in sharded mode")
+)
+
+// shards is client of several independent redis nodes, the keys are spread over them using consistent hashing.
+//
+// Each node has `weight * shardPointsPerWeight` points in the hash ring,
+// and the key is served by the node of the first point at or after the hash of the key.
+// The node is marked down after `ShardFailureLimit` consecutive connection failures:
+// its points are removed from the ring so its keys are rehashed to the other nodes,
+// and they are put back after `ShardRetryMs`.
+type shards struct {
+	nodes         []*shardNode // in the config order
+	poolWaitTime  time.Duration
+	stats         *poolStats
+	failureLimit  int32
+	retryInterval time.Duration
+	now           func() time.Time
+
+	mux     sync.RWMutex
+	ring    []ringPoint // sorted points of the up nodes
+	retryAt int64       // unix nano of the earliest retry of the down nodes, zero if there is none. atomic
+}
+
+// shardNode is a node of the shards
+type shardNode struct {
+	addr   string
+	pool   *redis.Pool
+	points []uint32 // hash of the node's points in the ring
+
+	failures int32 // consecutive failures, atomic
+
+	// guarded by shards.mux
+	downUntil  time.Time // the node is down until this time, marked by the failures
+	markedDown bool      // the node is marked down by MarkShardDown
+}
+
+// ringPoint is a point in the hash ring
+type ringPoint struct {
+	hash uint32
+	node int // index of the node
+}
+
+func newShards(cfg engine.Config, poolWaitTime time.Duration, stats *poolStats) *shards {
+	failureLimit := cfg.ShardFailureLimit
+	if failureLimit <= 0 {
+		failureLimit = defaultShardFailureLimit
+	}
+
+	retryInterval := time.Duration(cfg.ShardRetryMs) * time.Millisecond
+	if retryInterval <= 0 {
+		retryInterval = defaultShardRetryInterval
+	}
+
+	s := &shards{
+		poolWaitTime:  poolWaitTime,
+		stats:         stats,
+		failureLimit:  int32(failureLimit),
+		retryInterval: retryInterval,
+		now:           time.Now,
+	}
+
+	dial := dialer(cfg)
+	for _, sn := range cfg.ShardNodes {
+		addr := sn.Address
+		weight := sn.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+
+		node := &shardNode{
+			addr: addr,
+			pool: newPool(cfg, stats, func() (redis.Conn, error) {
+				return dial(addr)
+			}),
+			points: make([]uint32, weight*shardPointsPerWeight),
+		}
+		for i := range node.points {
+			node.points[i] = ringHash(addr + "-" + strconv.Itoa(i))
+		}
+		s.nodes = append(s.nodes, node)
+	}
+
+	s.buildRing(s.now())
+	return s
+}
+
+// MarkShardDown marks the shard node of the address down, e.g. for its maintenance.
+// Its keys are rehashed to the other nodes until it is marked up using MarkShardUp.
+func (r *Redigo) MarkShardDown(addr string) error {
+	if r.shards == nil {
+		return errNotSharded
+	}
+	return r.shards.setMarkedDown(addr, true)
+}
+
+// MarkShardUp marks the shard node of the address up, including the node which was marked down by its failures.
+// Its keys are served by it again.
+func (r *Redigo) MarkShardUp(addr string) error {
+	if r.shards == nil {
+		return errNotSharded
+	}
+	return r.shards.setMarkedDown(addr, false)
+}
+
+// ringHash returns position of the string in the hash ring
+func ringHash(s string) uint32 {
+	return crc32.ChecksumIEEE([]byte(s))
+}
+
+// do executes the command on the node serving its keys.
+//
+// Multi keys command which keys are on different nodes is split per node if it is
+// supported (see mergers), and the replies are merged.
+func (s *shards) do(ctx context.Context, name string, args []interface{}) (interface{}, error) {
+	if strings.EqualFold(name, "SCAN") {
+		return s.scan(ctx, args)
+	}
+
+	cmds, merge := splitCmd(name, args, s.nodeOf)
+	if len(cmds) == 1 {
+		cmd := cmds[0]
+		return s.run(ctx, cmd.slot, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			return doWithTimeout(conn, timeout, cmd.name, cmd.args...)
+		})
+	}
+
+	s.execCmds(ctx, cmds)
+	return merge(cmds, len(engine.KeyIndexes(name, args)))
+}
+
+// run runs the fn on the node with the given index.
+// Negative index means any node which is up.
+func (s *shards) run(ctx context.Context, idx int, fn connFn) (interface{}, error) {
+	if idx < 0 {
+		if idx = s.anyNode(); idx < 0 {
+			return nil, errNoShardNode
+		}
+	}
+
+	conn, err := s.stats.getConn(ctx, s.nodes[idx].pool, s.poolWaitTime)
+	if err != nil {
+		s.record(ctx, idx, err)
+		return nil, err
+	}
+
+	resp, err := runConn(ctx, conn, fn)
+	s.record(ctx, idx, err)
+	return resp, err
+}
+
+// execCmds executes the commands.
+// The commands are grouped by node and pipelined to each of the nodes concurrently.
+func (s *shards) execCmds(ctx context.Context, cmds []*clusterCmd) {
+	byNode := make(map[int][]*clusterCmd)
+	for _, cmd := range cmds {
+		idx := cmd.slot
+		if idx < 0 {
+			if idx = s.anyNode(); idx < 0 {
+				cmd.err = errNoShardNode
+				continue
+			}
+		}
+		byNode[idx] = append(byNode[idx], cmd)
+	}
+
+	var wg sync.WaitGroup
+	for idx, nodeCmds := range byNode {
+		wg.Add(1)
+		go func(idx int, nodeCmds []*clusterCmd) {
+			defer wg.Done()
+			s.execNode(ctx, idx, nodeCmds)
+		}(idx, nodeCmds)
+	}
+	wg.Wait()
+}
+
+// execNode pipelines the commands to the node with the given index
+func (s *shards) execNode(ctx context.Context, idx int, cmds []*clusterCmd) {
+	conn, err := s.stats.getConn(ctx, s.nodes[idx].pool, s.poolWaitTime)
+	if err == nil {
+		for _, cmd := range cmds {
+			cmd.sent = true
+		}
+
+		var resp interface{}
+		resp, err = runConn(ctx, conn, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			return sendReceive(conn, timeout, cmds)
+		})
+		if err == nil {
+			for i, rep := range resp.([]cmdReply) {
+				cmds[i].reply, cmds[i].err = rep.reply, rep.err
+			}
+			s.record(ctx, idx, nil)
+			return
+		}
+	}
+
+	s.record(ctx, idx, err)
+	for _, cmd := range cmds {
+		cmd.err = err
+	}
+}
+
+// execPipeline executes the pipelined commands in the shards.
+// Like in cluster mode, failure of a node is reported as error
+// of the commands sent to that node, the other commands might be already executed.
+func (s *shards) execPipeline(ctx context.Context, cmdErrs []engine.CmdErr) ([]engine.CmdErr, int, error) {
+	return execSplitPipeline(ctx, cmdErrs, s.nodeOf, s.execCmds)
+}
+
+// scan executes SCAN command on all of the up nodes, one after another.
+// The index of the scanned node is stored in the upper bits of the cursor, like in cluster mode.
+func (s *shards) scan(ctx context.Context, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("redigo: SCAN without cursor")
+	}
+
+	cursor, err := strconv.ParseUint(argString(args[0]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("redigo: invalid SCAN cursor: %v", err)
+	}
+
+	nodeIdx := int(cursor >> scanNodeShift)
+	if nodeIdx >= len(s.nodes) {
+		return nil, fmt.Errorf("redigo: invalid SCAN cursor: %v", cursor)
+	}
+
+	// the node might be marked down in the middle of the scan, skip it
+	nodeCursor := cursor & scanCursorMask
+	if next := s.nextUpNode(nodeIdx); next != nodeIdx {
+		nodeIdx, nodeCursor = next, 0
+	}
+	if nodeIdx < 0 {
+		return []interface{}{[]byte("0"), []interface{}{}}, nil
+	}
+
+	nodeArgs := append([]interface{}{nodeCursor}, args[1:]...)
+	values, err := redis.Values(s.run(ctx, nodeIdx, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+		return doWithTimeout(conn, timeout, "SCAN", nodeArgs...)
+	}))
+	if err != nil || len(values) < 2 {
+		return values, err
+	}
+
+	nodeCursor, _ = redis.Uint64(values[0], nil)
+
+	var next uint64
+	if nodeCursor != 0 {
+		next = uint64(nodeIdx)<<scanNodeShift | nodeCursor
+	} else if nextIdx := s.nextUpNode(nodeIdx + 1); nextIdx >= 0 {
+		next = uint64(nextIdx) << scanNodeShift
+	}
+	values[0] = []byte(strconv.FormatUint(next, 10))
+
+	return values, nil
+}
+
+// doAll do the command on all of the up nodes, one after another, e.g. SCRIPT LOAD.
+// It returns reply of the last node, or the first error.
+func (s *shards) doAll(ctx context.Context, name string, args []interface{}) (interface{}, error) {
+	var (
+		resp interface{}
+		err  error
+		ran  bool
+	)
+	for idx := s.nextUpNode(0); idx >= 0; idx = s.nextUpNode(idx + 1) {
+		resp, err = s.run(ctx, idx, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			return doWithTimeout(conn, timeout, name, args...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		ran = true
+	}
+
+	if !ran {
+		return nil, errNoShardNode
+	}
+	return resp, nil
+}
+
+// anyConn returns connection to the first up node
+func (s *shards) anyConn(ctx context.Context) (redis.Conn, error) {
+	idx := s.anyNode()
+	if idx < 0 {
+		return nil, errNoShardNode
+	}
+	return s.stats.getConn(ctx, s.nodes[idx].pool, s.poolWaitTime)
+}
+
+// dialAny dials a new connection to the first up node, which is not managed by any pool
+func (s *shards) dialAny() (redis.Conn, error) {
+	idx := s.anyNode()
+	if idx < 0 {
+		return nil, errNoShardNode
+	}
+	return s.nodes[idx].pool.Dial()
+}
+
+// allPools returns pool of all of the nodes
+func (s *shards) allPools() []*redis.Pool {
+	pools := make([]*redis.Pool, len(s.nodes))
+	for i, node := range s.nodes {
+		pools[i] = node.pool
+	}
+	return pools
+}
+
+// nodeOf returns index of the node serving the key, or -1 if all of the nodes are down.
+// Only the hash tag is hashed if the key has it, so the keys with the same hash tag are on the same node.
+func (s *shards) nodeOf(key string) int {
+	s.retry()
+
+	hash := ringHash(hashTag(key))
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if len(s.ring) == 0 {
+		return -1
+	}
+	i := sort.Search(len(s.ring), func(i int) bool {
+		return s.ring[i].hash >= hash
+	})
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.ring[i].node
+}
+
+// anyNode returns index of the first up node, or -1 if all of the nodes are down
+func (s *shards) anyNode() int {
+	return s.nextUpNode(0)
+}
+
+// nextUpNode returns index of the first up node starting from the given index,
+// or -1 if there is none
+func (s *shards) nextUpNode(from int) int {
+	s.retry()
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	now := s.now()
+	for i := from; i < len(s.nodes); i++ {
+		if s.nodes[i].isUp(now) {
+			return i
+		}
+	}
+	return -1
+}
+
+// record counts the consecutive connection failures of the node, and marks it down when they reach the limit.
+// The failures after the caller's ctx is done don't count,
+// neither do the other errors which are not caused by the node, e.g. the pool wait timeout.
+func (s *shards) record(ctx context.Context, idx int, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	node := s.nodes[idx]
+	if !isConnError(err) {
+		if isReplied(err) && atomic.LoadInt32(&node.failures) != 0 {
+			atomic.StoreInt32(&node.failures, 0)
+		}
+		return
+	}
+	if atomic.AddInt32(&node.failures, 1) < s.failureLimit {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	now := s.now()
+	if !node.isUp(now) {
+		return
+	}
+	node.downUntil = now.Add(s.retryInterval)
+	s.buildRing(now)
+}
+
+// isReplied returns true if the command is replied by the node, including the error reply
+func isReplied(err error) bool {
+	err, _ = unwrapNotSent(err)
+	if err == nil || err == redis.ErrNil {
+		return true
+	}
+	_, ok := err.(redis.Error)
+	return ok
+}
+
+// setMarkedDown marks the node of the address down or up.
+// Marking the node up also cancels its failures.
+func (s *shards) setMarkedDown(addr string, down bool) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, node := range s.nodes {
+		if node.addr != addr {
+			continue
+		}
+
+		node.markedDown = down
+		if !down {
+			node.downUntil = time.Time{}
+			atomic.StoreInt32(&node.failures, 0)
+		}
+		s.buildRing(s.now())
+		return nil
+	}
+	return fmt.Errorf("redigo: unknown shard node %v", addr)
+}
+
+// retry puts the nodes which were marked down by the failures back to the ring after their retry time
+func (s *shards) retry() {
+	retryAt := atomic.LoadInt64(&s.retryAt)
+	if retryAt == 0 || s.now().UnixNano() < retryAt {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.buildRing(s.now())
+}
+
+// buildRing builds the hash ring of the up nodes, the mux must be locked
+func (s *shards) buildRing(now time.Time) {
+	var (
+		ring    []ringPoint
+		retryAt time.Time
+	)
+	for i, node := range s.nodes {
+		if !node.downUntil.IsZero() && !now.Before(node.downUntil) {
+			// retry the node
+			node.downUntil = time.Time{}
+			atomic.StoreInt32(&node.failures, 0)
+		}
+		if !node.downUntil.IsZero() && (retryAt.IsZero() || node.downUntil.Before(retryAt)) {
+			retryAt = node.downUntil
+		}
+		if !node.isUp(now) {
+			continue
+		}
+
+		for _, hash := range node.points {
+			ring = append(ring, ringPoint{hash: hash, node: i})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool {
+		if ring[i].hash != ring[j].hash {
+			return ring[i].hash < ring[j].hash
+		}
+		return ring[i].node < ring[j].node
+	})
+	s.ring = ring
+
+	if retryAt.IsZero() {
+		atomic.StoreInt64(&s.retryAt, 0)
+	} else {
+		atomic.StoreInt64(&s.retryAt, retryAt.UnixNano())
+	}
+}
+
+// isUp returns true if the node is not marked down, the shards.mux must be locked
+func (n *shardNode) isUp(now time.Time) bool {
+	return !n.markedDown && !now.Before(n.downUntil)
+}
@@ -0,0 +1,283 @@
+package redigo
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+func newTestShards(t *testing.T, weights ...int) (*Redigo, []*miniredis.Miniredis) {
+	var (
+		mrs   []*miniredis.Miniredis
+		nodes []engine.ShardNode
+	)
+	for _, weight := range weights {
+		mr, err := miniredis.Run()
+		require.NoError(t, err)
+		mrs = append(mrs, mr)
+		nodes = append(nodes, engine.ShardNode{Address: mr.Addr(), Weight: weight})
+	}
+
+	r := New(engine.Config{
+		ShardNodes:        nodes,
+		ShardFailureLimit: 2,
+		ShardRetryMs:      1000,
+		ConnectTimeoutMs:  100,
+	})
+	return r, mrs
+}
+
+func closeAll(mrs []*miniredis.Miniredis) {
+	for _, mr := range mrs {
+		mr.Close()
+	}
+}
+
+func TestShardRing(t *testing.T) {
+	s := newShards(engine.Config{ShardNodes: []engine.ShardNode{
+		{Address: "10.0.0.1:6379"},
+		{Address: "10.0.0.2:6379", Weight: 1},
+		{Address: "10.0.0.3:6379", Weight: 2},
+	}}, time.Second, &poolStats{})
+
+	const numKeys = 10000
+	var (
+		counts = make([]int, 3)
+		before = make([]int, numKeys)
+	)
+	for i := range before {
+		before[i] = s.nodeOf("key" + strconv.Itoa(i))
+		counts[before[i]]++
+	}
+
+	// the keys are spread by the weight
+	for i, want := range []float64{0.25, 0.25, 0.5} {
+		share := float64(counts[i]) / numKeys
+		require.InDelta(t, want, share, 0.05, "node %v", i)
+	}
+
+	// the keys with the same hash tag are on the same node
+	require.Equal(t, s.nodeOf("{user1}:name"), s.nodeOf("{user1}:email"))
+
+	// only the keys of the down node are rehashed
+	require.NoError(t, s.setMarkedDown("10.0.0.1:6379", true))
+	for i, node := range before {
+		after := s.nodeOf("key" + strconv.Itoa(i))
+		if node == 0 {
+			require.NotEqual(t, 0, after)
+		} else {
+			require.Equal(t, node, after)
+		}
+	}
+
+	require.NoError(t, s.setMarkedDown("10.0.0.1:6379", false))
+	for i, node := range before {
+		require.Equal(t, node, s.nodeOf("key"+strconv.Itoa(i)))
+	}
+
+	require.Error(t, s.setMarkedDown("10.0.0.4:6379", true))
+}
+
+func TestShardRecord(t *testing.T) {
+	s := newShards(engine.Config{
+		ShardNodes:        []engine.ShardNode{{Address: "10.0.0.1:6379"}, {Address: "10.0.0.2:6379"}},
+		ShardFailureLimit: 2,
+		ShardRetryMs:      1000,
+	}, time.Second, &poolStats{})
+	ctx := context.Background()
+
+	// the errors which are not caused by the node don't count
+	for i := 0; i < 3; i++ {
+		s.record(ctx, 0, context.DeadlineExceeded)
+		s.record(ctx, 0, redis.ErrPoolExhausted)
+		s.record(ctx, 0, redis.Error("ERR wrong number of arguments"))
+	}
+	require.True(t, s.nodes[0].isUp(s.now()))
+
+	_, dialErr := redis.Dial(networkTCP, "127.0.0.1:1")
+	require.Error(t, dialErr)
+
+	// the reply resets the failures
+	s.record(ctx, 0, dialErr)
+	s.record(ctx, 0, nil)
+	s.record(ctx, 0, dialErr)
+	require.True(t, s.nodes[0].isUp(s.now()))
+
+	// the pool wait timeout doesn't reset them either
+	s.record(ctx, 0, context.DeadlineExceeded)
+	s.record(ctx, 0, dialErr)
+	require.False(t, s.nodes[0].isUp(s.now()))
+}
+
+func TestShardCommand(t *testing.T) {
+	r, mrs := newTestShards(t, 1, 1, 1)
+	defer closeAll(mrs)
+
+	var (
+		keys  []string
+		pairs []interface{}
+	)
+	for i := 0; i < 30; i++ {
+		key := "key" + strconv.Itoa(i)
+		keys = append(keys, key)
+		pairs = append(pairs, key, "val"+strconv.Itoa(i))
+	}
+	require.NoError(t, r.MSet(pairs...))
+
+	// each of the keys is stored only in its node
+	for i, key := range keys {
+		node := r.shards.nodeOf(key)
+		for j, mr := range mrs {
+			require.Equal(t, j == node, mr.Exists(key), key)
+		}
+		val, err := r.Get(key)
+		require.NoError(t, err)
+		require.Equal(t, "val"+strconv.Itoa(i), val)
+	}
+	for _, mr := range mrs {
+		require.NotEmpty(t, mr.Keys())
+	}
+
+	values, err := r.MGet(append(keys, "missing")...)
+	require.NoError(t, err)
+	require.Len(t, values, len(keys)+1)
+	for i := range keys {
+		require.Equal(t, "val"+strconv.Itoa(i), values[i])
+	}
+	require.Equal(t, "", values[len(keys)])
+
+	var found []string
+	for cursor := uint64(0); ; {
+		var batch []string
+		batch, cursor, err = r.Scan("key*", cursor, 10)
+		require.NoError(t, err)
+		found = append(found, batch...)
+		if cursor == 0 {
+			break
+		}
+	}
+	sort.Strings(found)
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+	require.Equal(t, sorted, found)
+
+	p := r.Pipeline(1, 3)
+	del := p.AddRawCmd("DEL", "key0", "key1", "key2", "missing")
+	get := p.AddRawCmd("GET", "key3")
+	p.AddRawCmd("SET", "key0", "new")
+	_, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+	require.Equal(t, int64(3), del.Val())
+	require.Equal(t, "val3", get.Val())
+
+	n, err := r.Delete(keys...)
+	require.NoError(t, err)
+	require.Equal(t, len(keys)-2, n)
+
+	sha1, err := r.ScriptLoad("return 1")
+	require.NoError(t, err)
+	for i := range keys {
+		reply, err := r.EvalSha(sha1, []string{keys[i]})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), reply)
+	}
+}
+
+// cmdHook records the names of the processed commands
+type cmdHook struct {
+	cmds *[]string
+}
+
+func (h cmdHook) BeforeProcess(ctx context.Context, cmd string, args []interface{}) context.Context {
+	*h.cmds = append(*h.cmds, cmd)
+	return ctx
+}
+
+func (h cmdHook) AfterProcess(ctx context.Context, cmd string, args []interface{}, duration time.Duration, err error) {
+}
+
+func (h cmdHook) BeforeProcessPipeline(ctx context.Context, cmds []engine.CmdErr) context.Context {
+	return ctx
+}
+
+func (h cmdHook) AfterProcessPipeline(ctx context.Context, cmds []engine.CmdErr, duration time.Duration, err error) {
+}
+
+func TestShardScriptLoad(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	var cmds []string
+	r := New(engine.Config{
+		ShardNodes:                        []engine.ShardNode{{Address: mr.Addr()}},
+		ConnectTimeoutMs:                  100,
+		Hooks:                             []engine.Hook{cmdHook{cmds: &cmds}},
+		CircuitBreaker:                    true,
+		CircuitBreakerConsecutiveFailures: 1,
+	})
+
+	_, err = r.ScriptLoad("return 1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"SCRIPT"}, cmds)
+
+	// the failure of the fan-out opens the circuit
+	mr.Close()
+	_, err = r.ScriptLoad("return 1")
+	require.Error(t, err)
+	require.Equal(t, engine.CircuitOpen, r.breaker.State())
+	_, err = r.ScriptLoad("return 1")
+	require.Equal(t, engine.ErrCircuitOpen, err)
+	require.Equal(t, []string{"SCRIPT", "SCRIPT", "SCRIPT"}, cmds)
+}
+
+func TestShardDown(t *testing.T) {
+	r, mrs := newTestShards(t, 1, 1)
+	defer closeAll(mrs)
+
+	now := time.Now()
+	r.shards.now = func() time.Time { return now }
+
+	// find a key of the first node
+	var key string
+	for i := 0; key == ""; i++ {
+		if k := "key" + strconv.Itoa(i); r.shards.nodeOf(k) == 0 {
+			key = k
+		}
+	}
+
+	// marked down manually
+	require.NoError(t, r.MarkShardDown(mrs[0].Addr()))
+	require.NoError(t, r.Set(key, "v1"))
+	require.True(t, mrs[1].Exists(key))
+	require.NoError(t, r.MarkShardUp(mrs[0].Addr()))
+	require.NoError(t, r.Set(key, "v2"))
+	require.True(t, mrs[0].Exists(key))
+
+	// marked down after the consecutive failures
+	mrs[0].Close()
+	for i := 0; i < 2; i++ {
+		require.Error(t, r.Set(key, "v3"))
+	}
+	require.NoError(t, r.Set(key, "v3"))
+	val, err := mrs[1].Get(key)
+	require.NoError(t, err)
+	require.Equal(t, "v3", val)
+
+	// put back after the retry interval
+	require.NoError(t, mrs[0].Restart())
+	now = now.Add(time.Second)
+	val, err = r.Get(key)
+	require.NoError(t, err)
+	require.Equal(t, "v2", val)
+
+	require.Equal(t, errNotSharded, New(engine.Config{Address: mrs[1].Addr()}).MarkShardDown(mrs[0].Addr()))
+}
@@ -0,0 +1,47 @@
+package redigo
+
+import "strings"
+
+// number of hash slots in redis cluster
+const numSlots = 16384
+
+// crc16Table is table of CRC16-CCITT (XMODEM) used by redis cluster
+var crc16Table = func() [256]uint16 {
+	var table [256]uint16
+	for i := range table {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^s[i]]
+	}
+	return crc
+}
+
+// hashSlot returns the cluster hash slot of the key.
+// Only the hash tag is hashed if the key has it, see https://redis.io/topics/cluster-spec#keys-hash-tags
+func hashSlot(key string) int {
+	return int(crc16(hashTag(key))) % numSlots
+}
+
+// hashTag returns the hash tag of the key, or the whole key if it has no hash tag
+func hashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
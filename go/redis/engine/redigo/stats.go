@@ -0,0 +1,89 @@
+package redigo
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// poolStats counts the pool events which the redigo pool doesn't report.
+// It is shared by all pools of a Redigo: the cluster nodes, or the sentinel master & replica.
+type poolStats struct {
+	waitCount    int64
+	waitDuration int64 // in nanosecond
+	waitTimeouts int64
+	dialFailures int64
+}
+
+// dial wraps the dial func of the pool, counting its failures
+func (s *poolStats) dial(dial func() (redis.Conn, error)) func() (redis.Conn, error) {
+	return func() (redis.Conn, error) {
+		conn, err := dial()
+		if err != nil {
+			atomic.AddInt64(&s.dialFailures, 1)
+		}
+		return conn, err
+	}
+}
+
+// getConn gets connection from the pool, waiting at most waitTime when the pool is exhausted.
+// The ctx deadline is used instead if it comes earlier.
+//
+// The pool is considered exhausted if it has MaxActive connections & none of them is idle when we ask for a connection,
+// the wait is only timed in that case to keep the hot path cheap.
+func (s *poolStats) getConn(ctx context.Context, pool *redis.Pool, waitTime time.Duration) (redis.Conn, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, waitTime)
+	defer cancel()
+
+	if !isExhausted(pool) {
+		conn, err := pool.GetContext(waitCtx)
+		s.countTimeout(ctx, err)
+		return conn, err
+	}
+
+	start := time.Now()
+	conn, err := pool.GetContext(waitCtx)
+	atomic.AddInt64(&s.waitCount, 1)
+	atomic.AddInt64(&s.waitDuration, int64(time.Since(start)))
+	s.countTimeout(ctx, err)
+	return conn, err
+}
+
+// isExhausted returns true if the caller has to wait for a connection of the pool
+func isExhausted(pool *redis.Pool) bool {
+	if pool.MaxActive <= 0 {
+		return false
+	}
+	stats := pool.Stats()
+	return stats.ActiveCount >= pool.MaxActive && stats.IdleCount == 0
+}
+
+// countTimeout counts the err if it is caused by the waitTime instead of the caller's ctx
+func (s *poolStats) countTimeout(ctx context.Context, err error) {
+	if err == context.DeadlineExceeded && ctx.Err() == nil {
+		atomic.AddInt64(&s.waitTimeouts, 1)
+	}
+}
+
+// snapshot returns the counters & the statistics of the given pools
+func (s *poolStats) snapshot(pools ...*redis.Pool) engine.PoolStats {
+	stats := engine.PoolStats{
+		WaitCount:    atomic.LoadInt64(&s.waitCount),
+		WaitDuration: time.Duration(atomic.LoadInt64(&s.waitDuration)),
+		WaitTimeouts: atomic.LoadInt64(&s.waitTimeouts),
+		DialFailures: atomic.LoadInt64(&s.dialFailures),
+	}
+	for _, pool := range pools {
+		if pool == nil {
+			continue
+		}
+		poolStats := pool.Stats()
+		stats.ActiveCount += poolStats.ActiveCount
+		stats.IdleCount += poolStats.IdleCount
+	}
+	return stats
+}
@@ -0,0 +1,77 @@
+package redigo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+func TestStatsPoolExhausted(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	r := New(engine.Config{
+		Address:    mr.Addr(),
+		MaxActive:  1,
+		PoolWaitMs: 50,
+	})
+
+	conn, err := r.GetConn()
+	require.NoError(t, err)
+
+	_, err = r.Ping()
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	stats := r.Stats()
+	require.Equal(t, 1, stats.ActiveCount)
+	require.Equal(t, 0, stats.IdleCount)
+	require.Equal(t, int64(1), stats.WaitCount)
+	require.Equal(t, int64(1), stats.WaitTimeouts)
+	require.True(t, stats.WaitDuration >= 50*time.Millisecond, stats.WaitDuration)
+
+	// the caller's ctx is not counted as the pool wait timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = r.PingContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, int64(2), r.Stats().WaitCount)
+	require.Equal(t, int64(1), r.Stats().WaitTimeouts)
+
+	conn.Close()
+	_, err = r.Ping()
+	require.NoError(t, err)
+
+	stats = r.Stats()
+	require.Equal(t, 1, stats.ActiveCount)
+	require.Equal(t, 1, stats.IdleCount)
+	require.Equal(t, int64(2), stats.WaitCount)
+	require.Equal(t, int64(0), stats.DialFailures)
+}
+
+func TestStatsDialFailures(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	addr := mr.Addr()
+	mr.Close()
+
+	r := New(engine.Config{
+		Address:    addr,
+		MaxActive:  1,
+		PoolWaitMs: 50,
+	})
+
+	_, err = r.Ping()
+	require.Error(t, err)
+	_, err = r.Ping()
+	require.Error(t, err)
+
+	stats := r.Stats()
+	require.Equal(t, int64(2), stats.DialFailures)
+	require.Equal(t, 0, stats.ActiveCount)
+}
@@ -0,0 +1,269 @@
+package redigo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const (
+	// blocking command's read timeout is its block duration plus this margin
+	blockReadMargin = time.Second
+)
+
+var errInvalidStreamReply = errors.New("redigo: invalid stream reply")
+
+// XAdd appends new entry to the stream and returns ID of the entry
+func (r *Redigo) XAdd(args engine.XAddArgs) (string, error) {
+	return r.XAddContext(context.Background(), args)
+}
+
+// XAddContext is XAdd with context
+func (r *Redigo) XAddContext(ctx context.Context, args engine.XAddArgs) (string, error) {
+	return redis.String(r.do(ctx, "XADD", args.CmdArgs()...))
+}
+
+// XRead reads the messages from the streams.
+// It returns empty result if there is no message until the block duration passed.
+func (r *Redigo) XRead(args engine.XReadArgs) ([]engine.XStream, error) {
+	return r.XReadContext(context.Background(), args)
+}
+
+// XReadContext is XRead with context
+func (r *Redigo) XReadContext(ctx context.Context, args engine.XReadArgs) ([]engine.XStream, error) {
+	return r.xStreams(r.doBlock(ctx, firstString(args.Streams), args.Block, "XREAD", args.CmdArgs()...))
+}
+
+// XReadGroup reads the messages from the streams as a consumer of the group.
+// It returns empty result if there is no message until the block duration passed.
+func (r *Redigo) XReadGroup(args engine.XReadGroupArgs) ([]engine.XStream, error) {
+	return r.XReadGroupContext(context.Background(), args)
+}
+
+// XReadGroupContext is XReadGroup with context
+func (r *Redigo) XReadGroupContext(ctx context.Context, args engine.XReadGroupArgs) ([]engine.XStream, error) {
+	return r.xStreams(r.doBlock(ctx, firstString(args.Streams), args.Block, "XREADGROUP", args.CmdArgs()...))
+}
+
+// XAck removes the messages from the pending entries list of the group,
+// and returns number of the acknowledged messages
+func (r *Redigo) XAck(stream, group string, ids ...string) (int64, error) {
+	return r.XAckContext(context.Background(), stream, group, ids...)
+}
+
+// XAckContext is XAck with context
+func (r *Redigo) XAckContext(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	args := make([]interface{}, 0, len(ids)+2)
+	args = append(args, stream, group)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return redis.Int64(r.do(ctx, "XACK", args...))
+}
+
+// XPending returns summary of the pending messages of the group
+func (r *Redigo) XPending(stream, group string) (engine.XPending, error) {
+	return r.XPendingContext(context.Background(), stream, group)
+}
+
+// XPendingContext is XPending with context
+func (r *Redigo) XPendingContext(ctx context.Context, stream, group string) (engine.XPending, error) {
+	// reply: count, lower ID, higher ID, [[consumer, count], ...]
+	values, err := redis.Values(r.do(ctx, "XPENDING", stream, group))
+	if err != nil {
+		return engine.XPending{}, err
+	}
+	if len(values) < 4 {
+		return engine.XPending{}, errInvalidStreamReply
+	}
+
+	pending := engine.XPending{}
+	pending.Count, _ = redis.Int64(values[0], nil)
+	pending.Lower, _ = redis.String(values[1], nil)
+	pending.Higher, _ = redis.String(values[2], nil)
+
+	consumers, _ := redis.Values(values[3], nil)
+	if len(consumers) > 0 {
+		pending.Consumers = make(map[string]int64, len(consumers))
+	}
+	for _, consumer := range consumers {
+		fields, err := redis.Strings(consumer, nil)
+		if err != nil || len(fields) != 2 {
+			return engine.XPending{}, errInvalidStreamReply
+		}
+		pending.Consumers[fields[0]], _ = redis.Int64([]byte(fields[1]), nil)
+	}
+	return pending, nil
+}
+
+// XPendingExt returns the pending messages of the group
+func (r *Redigo) XPendingExt(args engine.XPendingExtArgs) ([]engine.XPendingExt, error) {
+	return r.XPendingExtContext(context.Background(), args)
+}
+
+// XPendingExtContext is XPendingExt with context
+func (r *Redigo) XPendingExtContext(ctx context.Context, args engine.XPendingExtArgs) ([]engine.XPendingExt, error) {
+	// reply: [[id, consumer, idle ms, delivery count], ...]
+	values, err := redis.Values(r.do(ctx, "XPENDING", args.CmdArgs()...))
+	if err != nil {
+		return nil, err
+	}
+
+	pendings := make([]engine.XPendingExt, len(values))
+	for i, value := range values {
+		fields, err := redis.Values(value, nil)
+		if err != nil || len(fields) != 4 {
+			return nil, errInvalidStreamReply
+		}
+
+		pendings[i].ID, _ = redis.String(fields[0], nil)
+		pendings[i].Consumer, _ = redis.String(fields[1], nil)
+		idle, _ := redis.Int64(fields[2], nil)
+		pendings[i].Idle = time.Duration(idle) * time.Millisecond
+		pendings[i].RetryCount, _ = redis.Int64(fields[3], nil)
+	}
+	return pendings, nil
+}
+
+// XClaim changes owner of the pending messages to the consumer, and returns the claimed messages
+func (r *Redigo) XClaim(args engine.XClaimArgs) ([]engine.XMessage, error) {
+	return r.XClaimContext(context.Background(), args)
+}
+
+// XClaimContext is XClaim with context
+func (r *Redigo) XClaimContext(ctx context.Context, args engine.XClaimArgs) ([]engine.XMessage, error) {
+	return xMessages(r.do(ctx, "XCLAIM", args.CmdArgs()...))
+}
+
+// XAutoClaim claims the pending messages idle for at least args.MinIdle, and returns the claimed messages.
+// `next` is the start ID of the next call, it is "0-0" if all of the pending messages are scanned.
+func (r *Redigo) XAutoClaim(args engine.XAutoClaimArgs) ([]engine.XMessage, string, error) {
+	return r.XAutoClaimContext(context.Background(), args)
+}
+
+// XAutoClaimContext is XAutoClaim with context
+func (r *Redigo) XAutoClaimContext(ctx context.Context, args engine.XAutoClaimArgs) ([]engine.XMessage, string, error) {
+	// reply: next ID, messages, [deleted IDs (redis 7)]
+	values, err := redis.Values(r.do(ctx, "XAUTOCLAIM", args.CmdArgs()...))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(values) < 2 {
+		return nil, "", errInvalidStreamReply
+	}
+
+	next, _ := redis.String(values[0], nil)
+	messages, err := xMessages(values[1], nil)
+	return messages, next, err
+}
+
+// XGroupCreate creates consumer group of the stream, which starts reading from the `start` ID.
+// It creates the stream if it doesn't exist and mkStream is true.
+func (r *Redigo) XGroupCreate(stream, group, start string, mkStream bool) error {
+	return r.XGroupCreateContext(context.Background(), stream, group, start, mkStream)
+}
+
+// XGroupCreateContext is XGroupCreate with context
+func (r *Redigo) XGroupCreateContext(ctx context.Context, stream, group, start string, mkStream bool) error {
+	_, err := r.do(ctx, "XGROUP", engine.XGroupCreateArgs(stream, group, start, mkStream)...)
+	return err
+}
+
+// doBlock do the blocking command on the node serving the key, it is observed by the hooks like do.
+// The read timeout is extended by the block duration, so the command is not timed out while it is blocked.
+func (r *Redigo) doBlock(ctx context.Context, key string, block time.Duration, cmd string, args ...interface{}) (reply interface{}, err error) {
+	if block <= 0 {
+		return r.do(ctx, cmd, args...)
+	}
+
+	err = r.hooks.Process(ctx, cmd, args, func() error {
+		return r.breaker.Do(func() error {
+			// r.do adds the key prefix in exec, add it here as we bypass it
+			prefixed := prefixArgs(r.keyPrefix, cmd, args)
+			reply, err = r.runKey(ctx, r.keyPrefix+key, func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+				if timeout <= 0 {
+					timeout = block + blockReadMargin
+				}
+				return doWithTimeout(conn, timeout, cmd, prefixed...)
+			})
+			return err
+		})
+	})
+	return reply, err
+}
+
+// xStreams converts the reply of XREAD & XREADGROUP, stripping the key prefix from the stream names
+func (r *Redigo) xStreams(reply interface{}, err error) ([]engine.XStream, error) {
+	streams, err := xStreams(reply, err)
+	for i := range streams {
+		streams[i].Stream = strings.TrimPrefix(streams[i].Stream, r.keyPrefix)
+	}
+	return streams, err
+}
+
+// xStreams converts the reply of XREAD & XREADGROUP.
+// nil reply means there is no message.
+func xStreams(reply interface{}, err error) ([]engine.XStream, error) {
+	// reply: [[stream, messages], ...]
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	streams := make([]engine.XStream, len(values))
+	for i, value := range values {
+		fields, err := redis.Values(value, nil)
+		if err != nil || len(fields) != 2 {
+			return nil, errInvalidStreamReply
+		}
+
+		streams[i].Stream, _ = redis.String(fields[0], nil)
+		if streams[i].Messages, err = xMessages(fields[1], nil); err != nil {
+			return nil, err
+		}
+	}
+	return streams, nil
+}
+
+// xMessages converts array of stream entries.
+// The values of the deleted entry is nil.
+func xMessages(reply interface{}, err error) ([]engine.XMessage, error) {
+	// reply: [[id, [field, value, ...]], ...]
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]engine.XMessage, len(values))
+	for i, value := range values {
+		fields, err := redis.Values(value, nil)
+		if err != nil || len(fields) != 2 {
+			return nil, errInvalidStreamReply
+		}
+
+		messages[i].ID, _ = redis.String(fields[0], nil)
+		if fields[1] == nil {
+			continue
+		}
+		if messages[i].Values, err = redis.StringMap(fields[1], nil); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// firstString returns the first element of the strings, or empty string if it is empty
+func firstString(strs []string) string {
+	if len(strs) == 0 {
+		return ""
+	}
+	return strs[0]
+}
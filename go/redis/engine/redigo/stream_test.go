@@ -0,0 +1,44 @@
+package redigo
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+func TestXStreams(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{
+			[]byte("s1"),
+			[]interface{}{
+				[]interface{}{[]byte("1-0"), []interface{}{[]byte("k"), []byte("v")}},
+				// deleted entry
+				[]interface{}{[]byte("2-0"), nil},
+			},
+		},
+	}
+
+	streams, err := xStreams(reply, nil)
+	require.NoError(t, err)
+	require.Equal(t, []engine.XStream{{
+		Stream: "s1",
+		Messages: []engine.XMessage{
+			{ID: "1-0", Values: map[string]string{"k": "v"}},
+			{ID: "2-0"},
+		},
+	}}, streams)
+
+	// timed out
+	streams, err = xStreams(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, streams)
+
+	_, err = xStreams([]interface{}{[]interface{}{[]byte("s1")}}, nil)
+	require.Equal(t, errInvalidStreamReply, err)
+
+	_, err = xStreams(nil, redis.Error("NOGROUP"))
+	require.Equal(t, redis.Error("NOGROUP"), err)
+}
@@ -1,6 +1,7 @@
 package redigo
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/gomodule/redigo/redis"
@@ -10,7 +11,12 @@ import (
 
 // Set key and value
 func (r *Redigo) Set(key string, value interface{}) error {
-	ok, err := redis.String(r.do("SET", key, value))
+	return r.SetContext(context.Background(), key, value)
+}
+
+// SetContext is Set with context
+func (r *Redigo) SetContext(ctx context.Context, key string, value interface{}) error {
+	ok, err := redis.String(r.do(ctx, "SET", key, value))
 	if ok != "OK" && err == nil {
 		return engine.ErrNotOK
 	}
@@ -20,9 +26,14 @@ func (r *Redigo) Set(key string, value interface{}) error {
 // SetNX do SETNX (only set if not exist) with SET's NX & EX args.
 // It sets the key which will expired in `expire` seconds
 func (r *Redigo) SetNX(key string, value interface{}, expire int) (string, error) {
+	return r.SetNXContext(context.Background(), key, value, expire)
+}
+
+// SetNXContext is SetNX with context
+func (r *Redigo) SetNXContext(ctx context.Context, key string, value interface{}, expire int) (string, error) {
 	var resp string
 
-	val, err := r.do("SET", key, value, "NX", "EX", expire)
+	val, err := r.do(ctx, "SET", key, value, "NX", "EX", expire)
 	if val != nil {
 		resp = fmt.Sprintf("%s", val)
 	}
@@ -32,18 +43,33 @@ func (r *Redigo) SetNX(key string, value interface{}, expire int) (string, error
 // SetEX key and value
 // It sets the key wich will expired in `expire` seconds
 func (r *Redigo) SetEX(key string, value interface{}, expire int) (string, error) {
-	return redis.String(r.do("SETEX", key, expire, value))
+	return r.SetEXContext(context.Background(), key, value, expire)
+}
+
+// SetEXContext is SetEX with context
+func (r *Redigo) SetEXContext(ctx context.Context, key string, value interface{}, expire int) (string, error) {
+	return redis.String(r.do(ctx, "SETEX", key, expire, value))
 }
 
 // Get string value
 func (r *Redigo) Get(key string) (string, error) {
-	return redis.String(r.do("GET", key))
+	return r.GetContext(context.Background(), key)
+}
+
+// GetContext is Get with context
+func (r *Redigo) GetContext(ctx context.Context, key string) (string, error) {
+	return redis.String(r.do(ctx, "GET", key))
 }
 
 // MSet keys and values
 // please use basic types only (no struct, array, or map) for arguments
 func (r *Redigo) MSet(pairs ...interface{}) error {
-	ok, err := redis.String(r.do("MSET", pairs...))
+	return r.MSetContext(context.Background(), pairs...)
+}
+
+// MSetContext is MSet with context
+func (r *Redigo) MSetContext(ctx context.Context, pairs ...interface{}) error {
+	ok, err := redis.String(r.do(ctx, "MSET", pairs...))
 	if ok != "OK" && err == nil {
 		return engine.ErrNotOK
 	}
@@ -52,38 +78,49 @@ func (r *Redigo) MSet(pairs ...interface{}) error {
 
 // MGet keys
 func (r *Redigo) MGet(keys ...string) ([]string, error) {
+	return r.MGetContext(context.Background(), keys...)
+}
+
+// MGetContext is MGet with context
+func (r *Redigo) MGetContext(ctx context.Context, keys ...string) ([]string, error) {
 	args := make([]interface{}, len(keys))
 	for i, key := range keys {
 		args[i] = key
 	}
-	return redis.Strings(r.do("MGET", args...))
+	return redis.Strings(r.do(ctx, "MGET", args...))
 }
 
 // HSetEX key and value and sets the expiration to the given `expire` seconds
 func (r *Redigo) HSetEX(key, field string, value interface{}, expire int) (int, error) {
-	// we don't use r.do here because we do two commands
-	conn, err := r.getConn()
-	if err != nil {
-		return 0, err
-	}
-	defer conn.Close()
+	return r.HSetEXContext(context.Background(), key, field, value, expire)
+}
 
-	_, err = redis.Int(conn.Do("HSET", key, field, value))
-	if err != nil {
+// HSetEXContext is HSetEX with context
+func (r *Redigo) HSetEXContext(ctx context.Context, key, field string, value interface{}, expire int) (int, error) {
+	if _, err := redis.Int(r.do(ctx, "HSET", key, field, value)); err != nil {
 		return 0, err
 	}
-
-	return redis.Int(conn.Do("EXPIRE", key, expire))
+	return redis.Int(r.do(ctx, "EXPIRE", key, expire))
 }
 
 // HGet key and value
 func (r *Redigo) HGet(key, field string) (string, error) {
-	return redis.String(r.do("HGET", key, field))
+	return r.HGetContext(context.Background(), key, field)
+}
+
+// HGetContext is HGet with context
+func (r *Redigo) HGetContext(ctx context.Context, key, field string) (string, error) {
+	return redis.String(r.do(ctx, "HGET", key, field))
 }
 
 // HMSet function
 // please use basic types only (no struct, array, or map) for kv value
 func (r *Redigo) HMSet(key string, kv map[string]interface{}) (string, error) {
+	return r.HMSetContext(context.Background(), key, kv)
+}
+
+// HMSetContext is HMSet with context
+func (r *Redigo) HMSetContext(ctx context.Context, key string, kv map[string]interface{}) (string, error) {
 	var (
 		args = make([]interface{}, 1+(len(kv)*2))
 		idx  = 1
@@ -94,30 +131,45 @@ func (r *Redigo) HMSet(key string, kv map[string]interface{}) (string, error) {
 		args[idx+1] = v
 		idx += 2
 	}
-	return redis.String(r.do("HMSET", args...))
+	return redis.String(r.do(ctx, "HMSET", args...))
 }
 
 // HMGet keys and value
 func (r *Redigo) HMGet(key string, fields ...string) ([]string, error) {
+	return r.HMGetContext(context.Background(), key, fields...)
+}
+
+// HMGetContext is HMGet with context
+func (r *Redigo) HMGetContext(ctx context.Context, key string, fields ...string) ([]string, error) {
 	args := make([]interface{}, len(fields)+1)
 	args[0] = key
 	for i, field := range fields {
 		args[i+1] = field
 	}
-	return redis.Strings(r.do("HMGET", args...))
+	return redis.Strings(r.do(ctx, "HMGET", args...))
 }
 
 // HDel fields of a key
 func (r *Redigo) HDel(key string, fields ...string) (int, error) {
+	return r.HDelContext(context.Background(), key, fields...)
+}
+
+// HDelContext is HDel with context
+func (r *Redigo) HDelContext(ctx context.Context, key string, fields ...string) (int, error) {
 	args := make([]interface{}, len(fields)+1)
 	args[0] = key
 	for i, field := range fields {
 		args[i+1] = field
 	}
-	return redis.Int(r.do("HDEL", args...))
+	return redis.Int(r.do(ctx, "HDEL", args...))
 }
 
 // Append string to existing value in the key
 func (r *Redigo) Append(key, value string) (int, error) {
-	return redis.Int(r.do("APPEND", key, value))
+	return r.AppendContext(context.Background(), key, value)
+}
+
+// AppendContext is Append with context
+func (r *Redigo) AppendContext(ctx context.Context, key, value string) (int, error) {
+	return redis.Int(r.do(ctx, "APPEND", key, value))
 }
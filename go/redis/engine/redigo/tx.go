@@ -0,0 +1,174 @@
+package redigo
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Watch executes a transaction with optimistic locking on a single connection:
+// it WATCHes the keys, calls the fn which reads the keys & queues the commands,
+// then executes the queued commands atomically using MULTI & EXEC.
+//
+// If a watched key is changed before EXEC, the transaction is aborted by the server
+// and the whole transaction is executed again, at most `retry` times in total.
+// ErrTxFailed is returned if it still fails after that.
+//
+// It returns reply of each of the queued commands, converted the same way as Eval.
+// The failed command's reply is its error.
+// In cluster mode, all of the keys must be in the same slot.
+func (r *Redigo) Watch(fn engine.TxFunc, retry int, keys ...string) ([]interface{}, error) {
+	return r.WatchContext(context.Background(), fn, retry, keys...)
+}
+
+// WatchContext is Watch with context
+func (r *Redigo) WatchContext(ctx context.Context, fn engine.TxFunc, retry int, keys ...string) ([]interface{}, error) {
+	if retry <= 0 {
+		retry = 1
+	}
+
+	var (
+		reply interface{}
+		err   error
+	)
+	prefixed := prefixKeys(r.keyPrefix, keys)
+	breakerErr := r.breaker.Do(func() error {
+		reply, err = r.runKey(ctx, firstString(prefixed), func(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+			for i := 0; i < retry; i++ {
+				replies, aborted, err := r.execTx(ctx, conn, timeout, fn, prefixed)
+				if !aborted {
+					return replies, err
+				}
+			}
+			return nil, engine.ErrTxFailed
+		})
+
+		// the server is healthy if the transaction is aborted or failed by the fn
+		if _, ok := err.(txFuncError); ok || err == engine.ErrTxFailed {
+			return nil
+		}
+		return err
+	})
+	if breakerErr == engine.ErrCircuitOpen {
+		return nil, breakerErr
+	}
+	if fnErr, ok := err.(txFuncError); ok {
+		return nil, fnErr.error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reply.([]interface{}), nil
+}
+
+// txFuncError is the error returned by the TxFunc while the connection is healthy,
+// it doesn't count as failure of the server
+type txFuncError struct {
+	error
+}
+
+// execTx executes the transaction once, the keys are already prefixed.
+// `aborted` is true if the transaction is aborted because the watched keys are changed.
+// The commands executed by the fn are observed by the hooks, and the queued ones as a pipeline.
+func (r *Redigo) execTx(ctx context.Context, conn redis.Conn, timeout time.Duration, fn engine.TxFunc,
+	keys []string) (replies []interface{}, aborted bool, err error) {
+	if len(keys) > 0 {
+		if _, err = doWithTimeout(conn, timeout, "WATCH", redis.Args{}.AddFlat(keys)...); err != nil {
+			return nil, false, err
+		}
+	}
+
+	t := &tx{ctx: ctx, conn: conn, timeout: timeout, keyPrefix: r.keyPrefix, hooks: r.hooks}
+	if err = fn(t); err != nil {
+		// don't return the connection to the pool with the watched keys
+		doWithTimeout(conn, timeout, "UNWATCH")
+		if conn.Err() == nil {
+			err = txFuncError{err}
+		}
+		return nil, false, err
+	}
+
+	if len(t.queued) == 0 {
+		_, err = doWithTimeout(conn, timeout, "UNWATCH")
+		return []interface{}{}, false, err
+	}
+
+	cmdErrs := make([]engine.CmdErr, len(t.queued))
+	for i, cmd := range t.queued {
+		cmdErrs[i] = pipelineCmdErr{cmd: cmd.name, args: cmd.args}
+	}
+	_, _, err = r.hooks.ProcessPipeline(ctx, cmdErrs, func() ([]engine.CmdErr, int, error) {
+		conn.Send("MULTI")
+		for _, cmd := range t.queued {
+			conn.Send(cmd.name, prefixArgs(t.keyPrefix, cmd.name, cmd.args)...)
+		}
+
+		// the error of MULTI & the queued commands is returned, e.g. EXECABORT
+		replies, err = redis.Values(doWithTimeout(conn, timeout, "EXEC"))
+		return txCmdErrs(cmdErrs, replies, err)
+	})
+	if err != nil {
+		return nil, err == redis.ErrNil, err
+	}
+	for i, reply := range replies {
+		replies[i] = toEvalValue(stripReplyPrefix(t.keyPrefix, t.queued[i].name, reply))
+	}
+	return replies, false, nil
+}
+
+// txCmdErrs sets the replies of EXEC to the queued commands, for the hooks.
+// Each of the commands has the err if the whole transaction failed.
+func txCmdErrs(cmdErrs []engine.CmdErr, replies []interface{}, err error) ([]engine.CmdErr, int, error) {
+	firstErr := -1
+	for i, cmd := range cmdErrs {
+		pce := cmd.(pipelineCmdErr)
+		if err != nil {
+			pce.err = err
+		} else if i < len(replies) {
+			pce.reply = replies[i]
+			if rerr, ok := replies[i].(redis.Error); ok {
+				pce.err = rerr
+			}
+		}
+		if pce.err != nil && firstErr < 0 {
+			firstErr = i
+		}
+		cmdErrs[i] = pce
+	}
+	return cmdErrs, firstErr, err
+}
+
+// tx is engine.Tx using redigo connection
+type tx struct {
+	ctx       context.Context
+	conn      redis.Conn
+	timeout   time.Duration
+	keyPrefix string // added to the keys of the commands
+	hooks     *engine.Hooks
+	queued    []txCmd
+}
+
+// txCmd is the queued command, with the keys without the prefix
+type txCmd struct {
+	name string
+	args []interface{}
+}
+
+// Do executes the command immediately, e.g. to read the watched keys before queuing the writes.
+// The reply is converted the same way as Eval
+func (t *tx) Do(cmd string, args ...interface{}) (reply interface{}, err error) {
+	err = t.hooks.Process(t.ctx, cmd, args, func() error {
+		reply, err = doWithTimeout(t.conn, t.timeout, cmd, prefixArgs(t.keyPrefix, cmd, args)...)
+		return err
+	})
+	return evalReply(stripReplyPrefix(t.keyPrefix, cmd, reply), err)
+}
+
+// Queue queues the command.
+// The queued commands are executed atomically using MULTI & EXEC after the TxFunc returns
+func (t *tx) Queue(cmd string, args ...interface{}) {
+	t.queued = append(t.queued, txCmd{name: cmd, args: args})
+}
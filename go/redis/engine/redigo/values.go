@@ -1,6 +1,7 @@
 package redigo
 
 import (
+	"context"
 	"errors"
 
 	"github.com/gomodule/redigo/redis"
@@ -8,6 +9,56 @@ import (
 
 // Scan function return keys that match the pattern
 func (r *Redigo) Scan(pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.ScanContext(context.Background(), pattern, cursor, count)
+}
+
+// ScanContext is Scan with context
+func (r *Redigo) ScanContext(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.scan(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count)
+}
+
+// ScanType function return keys of the type that match the pattern
+func (r *Redigo) ScanType(pattern, keyType string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.ScanTypeContext(context.Background(), pattern, keyType, cursor, count)
+}
+
+// ScanTypeContext is ScanType with context
+func (r *Redigo) ScanTypeContext(ctx context.Context, pattern, keyType string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.scan(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count, "TYPE", keyType)
+}
+
+// HScan function return field & value pairs of the hash that match the pattern
+func (r *Redigo) HScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.HScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// HScanContext is HScan with context
+func (r *Redigo) HScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.scan(ctx, "HSCAN", key, cursor, "MATCH", pattern, "COUNT", count)
+}
+
+// SScan function return members of the set that match the pattern
+func (r *Redigo) SScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.SScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// SScanContext is SScan with context
+func (r *Redigo) SScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.scan(ctx, "SSCAN", key, cursor, "MATCH", pattern, "COUNT", count)
+}
+
+// ZScan function return member & score pairs of the sorted set that match the pattern
+func (r *Redigo) ZScan(key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.ZScanContext(context.Background(), key, pattern, cursor, count)
+}
+
+// ZScanContext is ZScan with context
+func (r *Redigo) ZScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return r.scan(ctx, "ZSCAN", key, cursor, "MATCH", pattern, "COUNT", count)
+}
+
+// scan executes the SCAN family command, returning the found elements & the new cursor
+func (r *Redigo) scan(ctx context.Context, cmd string, args ...interface{}) ([]string, uint64, error) {
 	var (
 		result       []string
 		err          error
@@ -16,7 +67,7 @@ func (r *Redigo) Scan(pattern string, cursor uint64, count int64) ([]string, uin
 		rawFoundKeys []string
 	)
 
-	rawResult, err = redis.Values(r.do("SCAN", cursor, "MATCH", pattern, "COUNT", count))
+	rawResult, err = redis.Values(r.do(ctx, cmd, args...))
 	if err != nil {
 		return result, newCursor, err
 	}
@@ -27,6 +78,9 @@ func (r *Redigo) Scan(pattern string, cursor uint64, count int64) ([]string, uin
 	newCursor, _ = redis.Uint64(rawResult[0], nil)     // err ignored since redis.Uint64() function already handle it and will return 0
 	rawFoundKeys, _ = redis.Strings(rawResult[1], nil) // err ignored since redis.Strings() function already handle it and will return ""
 
+	if cmd == "SCAN" {
+		rawFoundKeys = stripPrefix(r.keyPrefix, rawFoundKeys)
+	}
 	result = append(result, rawFoundKeys...)
 
 	return result, newCursor, err
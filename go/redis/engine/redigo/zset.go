@@ -0,0 +1,179 @@
+package redigo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// ZAdd adds the members to the sorted set stored at key, or updates their score if they already exist.
+// It returns number of the added members, or number of the changed members if args.CH is true.
+func (r *Redigo) ZAdd(key string, args engine.ZAddArgs, members ...engine.Z) (int64, error) {
+	return r.ZAddContext(context.Background(), key, args, members...)
+}
+
+// ZAddContext is ZAdd with context
+func (r *Redigo) ZAddContext(ctx context.Context, key string, args engine.ZAddArgs, members ...engine.Z) (int64, error) {
+	return redis.Int64(r.do(ctx, "ZADD", args.CmdArgs(key, members...)...))
+}
+
+// ZIncrBy increments score of the member by `increment` and returns the new score
+func (r *Redigo) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	return r.ZIncrByContext(context.Background(), key, increment, member)
+}
+
+// ZIncrByContext is ZIncrBy with context
+func (r *Redigo) ZIncrByContext(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	return redis.Float64(r.do(ctx, "ZINCRBY", key, increment, member))
+}
+
+// ZRange returns the members in the index range, ordered from the lowest to the highest score
+func (r *Redigo) ZRange(key string, start, stop int64) ([]string, error) {
+	return r.ZRangeContext(context.Background(), key, start, stop)
+}
+
+// ZRangeContext is ZRange with context
+func (r *Redigo) ZRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return redis.Strings(r.do(ctx, "ZRANGE", key, start, stop))
+}
+
+// ZRangeWithScores is ZRange which returns the members with their score
+func (r *Redigo) ZRangeWithScores(key string, start, stop int64) ([]engine.Z, error) {
+	return r.ZRangeWithScoresContext(context.Background(), key, start, stop)
+}
+
+// ZRangeWithScoresContext is ZRangeWithScores with context
+func (r *Redigo) ZRangeWithScoresContext(ctx context.Context, key string, start, stop int64) ([]engine.Z, error) {
+	return zValues(r.do(ctx, "ZRANGE", key, start, stop, "WITHSCORES"))
+}
+
+// ZRevRange returns the members in the index range, ordered from the highest to the lowest score
+func (r *Redigo) ZRevRange(key string, start, stop int64) ([]string, error) {
+	return r.ZRevRangeContext(context.Background(), key, start, stop)
+}
+
+// ZRevRangeContext is ZRevRange with context
+func (r *Redigo) ZRevRangeContext(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return redis.Strings(r.do(ctx, "ZREVRANGE", key, start, stop))
+}
+
+// ZRevRangeWithScores is ZRevRange which returns the members with their score
+func (r *Redigo) ZRevRangeWithScores(key string, start, stop int64) ([]engine.Z, error) {
+	return r.ZRevRangeWithScoresContext(context.Background(), key, start, stop)
+}
+
+// ZRevRangeWithScoresContext is ZRevRangeWithScores with context
+func (r *Redigo) ZRevRangeWithScoresContext(ctx context.Context, key string, start, stop int64) ([]engine.Z, error) {
+	return zValues(r.do(ctx, "ZREVRANGE", key, start, stop, "WITHSCORES"))
+}
+
+// ZRangeByScore returns the members in the score range, ordered from the lowest to the highest score
+func (r *Redigo) ZRangeByScore(key string, opt engine.ZRangeBy) ([]string, error) {
+	return r.ZRangeByScoreContext(context.Background(), key, opt)
+}
+
+// ZRangeByScoreContext is ZRangeByScore with context
+func (r *Redigo) ZRangeByScoreContext(ctx context.Context, key string, opt engine.ZRangeBy) ([]string, error) {
+	return redis.Strings(r.do(ctx, "ZRANGEBYSCORE", opt.CmdArgs(key, false)...))
+}
+
+// ZRangeByScoreWithScores is ZRangeByScore which returns the members with their score
+func (r *Redigo) ZRangeByScoreWithScores(key string, opt engine.ZRangeBy) ([]engine.Z, error) {
+	return r.ZRangeByScoreWithScoresContext(context.Background(), key, opt)
+}
+
+// ZRangeByScoreWithScoresContext is ZRangeByScoreWithScores with context
+func (r *Redigo) ZRangeByScoreWithScoresContext(ctx context.Context, key string, opt engine.ZRangeBy) ([]engine.Z, error) {
+	return zValues(r.do(ctx, "ZRANGEBYSCORE", opt.CmdArgs(key, true)...))
+}
+
+// ZRank returns rank of the member, the member with the lowest score has rank 0.
+// It returns redigo.ErrNil if the member or the key does not exist.
+func (r *Redigo) ZRank(key, member string) (int64, error) {
+	return r.ZRankContext(context.Background(), key, member)
+}
+
+// ZRankContext is ZRank with context
+func (r *Redigo) ZRankContext(ctx context.Context, key, member string) (int64, error) {
+	return redis.Int64(r.do(ctx, "ZRANK", key, member))
+}
+
+// ZScore returns score of the member.
+// It returns redigo.ErrNil if the member or the key does not exist.
+func (r *Redigo) ZScore(key, member string) (float64, error) {
+	return r.ZScoreContext(context.Background(), key, member)
+}
+
+// ZScoreContext is ZScore with context
+func (r *Redigo) ZScoreContext(ctx context.Context, key, member string) (float64, error) {
+	return redis.Float64(r.do(ctx, "ZSCORE", key, member))
+}
+
+// ZRem removes the members and returns number of the removed members
+func (r *Redigo) ZRem(key string, members ...string) (int64, error) {
+	return r.ZRemContext(context.Background(), key, members...)
+}
+
+// ZRemContext is ZRem with context
+func (r *Redigo) ZRemContext(ctx context.Context, key string, members ...string) (int64, error) {
+	args := make([]interface{}, 0, len(members)+1)
+	args = append(args, key)
+	for _, member := range members {
+		args = append(args, member)
+	}
+	return redis.Int64(r.do(ctx, "ZREM", args...))
+}
+
+// ZRemRangeByScore removes the members in the score range and returns number of the removed members
+func (r *Redigo) ZRemRangeByScore(key, min, max string) (int64, error) {
+	return r.ZRemRangeByScoreContext(context.Background(), key, min, max)
+}
+
+// ZRemRangeByScoreContext is ZRemRangeByScore with context
+func (r *Redigo) ZRemRangeByScoreContext(ctx context.Context, key, min, max string) (int64, error) {
+	return redis.Int64(r.do(ctx, "ZREMRANGEBYSCORE", key, min, max))
+}
+
+// ZCard returns number of the members of the sorted set
+func (r *Redigo) ZCard(key string) (int64, error) {
+	return r.ZCardContext(context.Background(), key)
+}
+
+// ZCardContext is ZCard with context
+func (r *Redigo) ZCardContext(ctx context.Context, key string) (int64, error) {
+	return redis.Int64(r.do(ctx, "ZCARD", key))
+}
+
+// ZCount returns number of the members in the score range
+func (r *Redigo) ZCount(key, min, max string) (int64, error) {
+	return r.ZCountContext(context.Background(), key, min, max)
+}
+
+// ZCountContext is ZCount with context
+func (r *Redigo) ZCountContext(ctx context.Context, key, min, max string) (int64, error) {
+	return redis.Int64(r.do(ctx, "ZCOUNT", key, min, max))
+}
+
+// zValues converts WITHSCORES reply, which is member & score pairs, to []engine.Z
+func zValues(reply interface{}, err error) ([]engine.Z, error) {
+	values, err := redis.Strings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("redigo: invalid WITHSCORES reply length: %v", len(values))
+	}
+
+	zs := make([]engine.Z, len(values)/2)
+	for i := range zs {
+		score, err := redis.Float64([]byte(values[2*i+1]), nil)
+		if err != nil {
+			return nil, err
+		}
+		zs[i] = engine.Z{Member: values[2*i], Score: score}
+	}
+	return zs, nil
+}
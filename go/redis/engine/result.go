@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ReplySetter sets reply of a pipelined command after the pipeline is executed.
+// It is implemented by the results returned by the Pipeliner, and called by the engines.
+type ReplySetter interface {
+	// SetReply sets the reply & the error of the command.
+	// The reply is converted the same way as Eval: integer to int64, bulk & simple string to string,
+	// array to []interface{}, and nil to nil with the engine's ErrNil.
+	SetReply(reply interface{}, err error)
+}
+
+// reply is the reply & the error of a pipelined command
+type reply struct {
+	val interface{}
+	err error
+}
+
+// SetReply sets the reply & the error of the command, see ReplySetter
+func (r *reply) SetReply(val interface{}, err error) {
+	r.val, r.err = val, err
+}
+
+// Err returns error of the command.
+// It is ErrNil if the reply is nil, check it using `IsErrNil` of the engine
+func (r *reply) Err() error {
+	return r.err
+}
+
+// Result is the untyped reply of a pipelined command, it is available after the pipeline is executed.
+// Don't read it from another goroutine while the pipeline is executed.
+type Result struct {
+	reply
+}
+
+// Val returns the reply, converted the same way as Eval
+func (r *Result) Val() interface{} {
+	return r.val
+}
+
+// Result returns the reply & the error
+func (r *Result) Result() (interface{}, error) {
+	return r.val, r.err
+}
+
+// StringResult is the string reply of a pipelined command, e.g. GET
+type StringResult struct {
+	reply
+}
+
+// Val returns the reply, or empty string if the command failed
+func (r *StringResult) Val() string {
+	val, _ := r.Result()
+	return val
+}
+
+// Result returns the reply & the error
+func (r *StringResult) Result() (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	return toString(r.val)
+}
+
+// IntResult is the integer reply of a pipelined command, e.g. INCR
+type IntResult struct {
+	reply
+}
+
+// Val returns the reply, or 0 if the command failed
+func (r *IntResult) Val() int64 {
+	val, _ := r.Result()
+	return val
+}
+
+// Result returns the reply & the error
+func (r *IntResult) Result() (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	switch v := r.val.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected reply type %T for integer", r.val)
+}
+
+// FloatResult is the float reply of a pipelined command, e.g. ZINCRBY
+type FloatResult struct {
+	reply
+}
+
+// Val returns the reply, or 0 if the command failed
+func (r *FloatResult) Val() float64 {
+	val, _ := r.Result()
+	return val
+}
+
+// Result returns the reply & the error
+func (r *FloatResult) Result() (float64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	switch v := r.val.(type) {
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unexpected reply type %T for float", r.val)
+}
+
+// StringsResult is the array reply of a pipelined command, e.g. MGET.
+// The nil element is returned as empty string
+type StringsResult struct {
+	reply
+}
+
+// Val returns the reply, or nil if the command failed
+func (r *StringsResult) Val() []string {
+	val, _ := r.Result()
+	return val
+}
+
+// Result returns the reply & the error
+func (r *StringsResult) Result() ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	vals, ok := r.val.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply type %T for array", r.val)
+	}
+
+	result := make([]string, len(vals))
+	for i, val := range vals {
+		if val == nil {
+			continue
+		}
+		s, err := toString(val)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = s
+	}
+	return result, nil
+}
+
+func toString(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	}
+	return "", fmt.Errorf("unexpected reply type %T for string", val)
+}
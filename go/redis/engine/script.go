@@ -0,0 +1,28 @@
+package engine
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// EvalArgs returns arguments of EVAL & EVALSHA command, excluding the command name
+func EvalArgs(scriptOrSha string, keys []string, args ...interface{}) []interface{} {
+	cmdArgs := make([]interface{}, 0, 2+len(keys)+len(args))
+	cmdArgs = append(cmdArgs, scriptOrSha, len(keys))
+	for _, key := range keys {
+		cmdArgs = append(cmdArgs, key)
+	}
+	return append(cmdArgs, args...)
+}
+
+// ScriptHash returns SHA1 digest of the lua script, which is used by EVALSHA
+func ScriptHash(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsErrNoScript returns true if the err is returned by EVALSHA because the script is not cached
+func IsErrNoScript(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT")
+}
@@ -0,0 +1,27 @@
+package engine
+
+import "time"
+
+// PoolStats is snapshot of the connection pool statistics.
+// In cluster & sentinel mode, it is the sum of the pools of all nodes.
+//
+// go-redis doesn't report the waits & the dial failures, they are always zero for goredis engine.
+type PoolStats struct {
+	// ActiveCount is number of the connections in the pool, including the idle connections
+	ActiveCount int
+
+	// IdleCount is number of the idle connections in the pool
+	IdleCount int
+
+	// WaitCount is number of times the caller waited for a connection because the pool was exhausted
+	WaitCount int64
+
+	// WaitDuration is the total time spent waiting for a connection because the pool was exhausted
+	WaitDuration time.Duration
+
+	// WaitTimeouts is number of times the caller gave up waiting for a connection after `PoolWaitMs`
+	WaitTimeouts int64
+
+	// DialFailures is number of times a new connection failed to be dialed or initialized
+	DialFailures int64
+}
@@ -0,0 +1,261 @@
+package engine
+
+import (
+	"strings"
+	"time"
+)
+
+// XMessage is an entry of a stream
+type XMessage struct {
+	ID     string
+	Values map[string]string
+}
+
+// XStream is the messages read from a stream
+type XStream struct {
+	Stream   string
+	Messages []XMessage
+}
+
+// XAddArgs is the arguments of XADD command
+type XAddArgs struct {
+	Stream string
+
+	// ID of the new entry, it is generated by the server if empty
+	ID string
+
+	// MaxLen trims the stream to its latest MaxLen entries.
+	// Zero means no trimming
+	MaxLen int64
+
+	// Approx makes the trimming approximate (MAXLEN ~), which is much more efficient.
+	// The stream might have a bit more than MaxLen entries
+	Approx bool
+
+	// Values of the entry.
+	// please use basic types only (no struct, array, or map)
+	Values map[string]interface{}
+}
+
+// CmdArgs returns arguments of XADD command, excluding the command name
+func (a XAddArgs) CmdArgs() []interface{} {
+	args := make([]interface{}, 0, 5+2*len(a.Values))
+	args = append(args, a.Stream)
+	if a.MaxLen > 0 {
+		args = append(args, "MAXLEN")
+		if a.Approx {
+			args = append(args, "~")
+		}
+		args = append(args, a.MaxLen)
+	}
+
+	if a.ID != "" {
+		args = append(args, a.ID)
+	} else {
+		args = append(args, "*")
+	}
+
+	for k, v := range a.Values {
+		args = append(args, k, v)
+	}
+	return args
+}
+
+// XReadArgs is the arguments of XREAD command
+type XReadArgs struct {
+	// Streams to read & the last read ID of each of the streams,
+	// only the messages with greater ID are returned.
+	// The ID could be "$" to read only the messages added after the command is executed.
+	Streams []string
+	IDs     []string
+
+	// Count is maximum number of the messages returned per stream, zero means no limit
+	Count int64
+
+	// Block is the maximum duration to wait when there is no message.
+	// Zero means it doesn't block
+	Block time.Duration
+}
+
+// CmdArgs returns arguments of XREAD command, excluding the command name
+func (a XReadArgs) CmdArgs() []interface{} {
+	args := make([]interface{}, 0, 5+len(a.Streams)+len(a.IDs))
+	return appendStreams(appendReadOpts(args, a.Count, a.Block), a.Streams, a.IDs)
+}
+
+// XReadGroupArgs is the arguments of XREADGROUP command
+type XReadGroupArgs struct {
+	Group    string
+	Consumer string
+
+	// Streams to read & the ID of each of the streams.
+	// The ID ">" reads the messages never delivered to the other consumers,
+	// the other ID reads the pending messages of the consumer
+	Streams []string
+	IDs     []string
+
+	// Count is maximum number of the messages returned per stream, zero means no limit
+	Count int64
+
+	// Block is the maximum duration to wait when there is no message.
+	// Zero means it doesn't block
+	Block time.Duration
+
+	// NoAck doesn't add the messages to the pending entries list,
+	// there is no need to ack them
+	NoAck bool
+}
+
+// CmdArgs returns arguments of XREADGROUP command, excluding the command name
+func (a XReadGroupArgs) CmdArgs() []interface{} {
+	args := make([]interface{}, 0, 9+len(a.Streams)+len(a.IDs))
+	args = append(args, "GROUP", a.Group, a.Consumer)
+	args = appendReadOpts(args, a.Count, a.Block)
+	if a.NoAck {
+		args = append(args, "NOACK")
+	}
+	return appendStreams(args, a.Streams, a.IDs)
+}
+
+// XPending is the summary of the pending messages of a group
+type XPending struct {
+	// Count is number of the pending messages
+	Count int64
+
+	// Lower & Higher are the smallest & the greatest ID of the pending messages
+	Lower  string
+	Higher string
+
+	// Consumers is number of the pending messages of each of the consumers
+	Consumers map[string]int64
+}
+
+// XPendingExtArgs is the arguments of the extended form of XPENDING command
+type XPendingExtArgs struct {
+	Stream string
+	Group  string
+
+	// Start & End are the ID range, "-" and "+" means the smallest & the greatest ID
+	Start string
+	End   string
+
+	// Count is maximum number of the returned pending messages
+	Count int64
+
+	// Consumer filters the pending messages of the consumer, optional
+	Consumer string
+}
+
+// CmdArgs returns arguments of XPENDING command, excluding the command name
+func (a XPendingExtArgs) CmdArgs() []interface{} {
+	args := []interface{}{a.Stream, a.Group, a.Start, a.End, a.Count}
+	if a.Consumer != "" {
+		args = append(args, a.Consumer)
+	}
+	return args
+}
+
+// XPendingExt is a pending message
+type XPendingExt struct {
+	ID       string
+	Consumer string
+
+	// Idle is the duration since the message is delivered to the consumer
+	Idle time.Duration
+
+	// RetryCount is number of times the message has been delivered
+	RetryCount int64
+}
+
+// XClaimArgs is the arguments of XCLAIM command
+type XClaimArgs struct {
+	Stream   string
+	Group    string
+	Consumer string
+
+	// MinIdle only claims the messages idle for at least MinIdle
+	MinIdle time.Duration
+
+	// IDs of the claimed messages
+	IDs []string
+}
+
+// CmdArgs returns arguments of XCLAIM command, excluding the command name
+func (a XClaimArgs) CmdArgs() []interface{} {
+	args := make([]interface{}, 0, 4+len(a.IDs))
+	args = append(args, a.Stream, a.Group, a.Consumer, int64(a.MinIdle/time.Millisecond))
+	for _, id := range a.IDs {
+		args = append(args, id)
+	}
+	return args
+}
+
+// XAutoClaimArgs is the arguments of XAUTOCLAIM command, it requires redis 6.2 or later
+type XAutoClaimArgs struct {
+	Stream   string
+	Group    string
+	Consumer string
+
+	// MinIdle only claims the messages idle for at least MinIdle
+	MinIdle time.Duration
+
+	// Start is the smallest ID of the claimed messages, "0-0" to start from the beginning
+	Start string
+
+	// Count is maximum number of the claimed messages, zero means the server default (100)
+	Count int64
+}
+
+// CmdArgs returns arguments of XAUTOCLAIM command, excluding the command name
+func (a XAutoClaimArgs) CmdArgs() []interface{} {
+	start := a.Start
+	if start == "" {
+		start = "0-0"
+	}
+
+	args := []interface{}{a.Stream, a.Group, a.Consumer, int64(a.MinIdle / time.Millisecond), start}
+	if a.Count > 0 {
+		args = append(args, "COUNT", a.Count)
+	}
+	return args
+}
+
+// XGroupCreateArgs returns arguments of XGROUP CREATE command, excluding the command name
+func XGroupCreateArgs(stream, group, start string, mkStream bool) []interface{} {
+	args := []interface{}{"CREATE", stream, group, start}
+	if mkStream {
+		args = append(args, "MKSTREAM")
+	}
+	return args
+}
+
+// IsErrBusyGroup returns true if the err is returned by XGROUP CREATE because the group already exists
+func IsErrBusyGroup(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP")
+}
+
+func appendReadOpts(args []interface{}, count int64, block time.Duration) []interface{} {
+	if count > 0 {
+		args = append(args, "COUNT", count)
+	}
+	if block > 0 {
+		// BLOCK 0 means blocking forever, make sure that we never send it
+		ms := int64(block / time.Millisecond)
+		if ms == 0 {
+			ms = 1
+		}
+		args = append(args, "BLOCK", ms)
+	}
+	return args
+}
+
+func appendStreams(args []interface{}, streams, ids []string) []interface{} {
+	args = append(args, "STREAMS")
+	for _, stream := range streams {
+		args = append(args, stream)
+	}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return args
+}
@@ -0,0 +1,22 @@
+package engine
+
+import "errors"
+
+// ErrTxFailed returned by Watch when the watched keys are still changed after all of the retries
+var ErrTxFailed = errors.New("transaction failed: the watched keys are changed")
+
+// Tx is a transaction running on a single connection, see `Watch`
+type Tx interface {
+	// Do executes the command immediately, e.g. to read the watched keys before queuing the writes.
+	// The reply is converted the same way as Eval
+	Do(cmd string, args ...interface{}) (interface{}, error)
+
+	// Queue queues the command.
+	// The queued commands are executed atomically using MULTI & EXEC after the TxFunc returns
+	Queue(cmd string, args ...interface{})
+}
+
+// TxFunc reads the watched keys and queues the commands of the transaction.
+// The transaction is aborted if it returns error.
+// It might be executed more than once, so it must not have any side effect other than the queued commands.
+type TxFunc func(tx Tx) error
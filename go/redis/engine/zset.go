@@ -0,0 +1,75 @@
+package engine
+
+// Z is member of a sorted set and its score
+type Z struct {
+	Member string
+	Score  float64
+}
+
+// ZAddArgs is the options of ZADD command
+type ZAddArgs struct {
+	// NX only adds new members, it never updates the existing members
+	NX bool
+
+	// XX only updates the existing members, it never adds new members
+	XX bool
+
+	// GT only updates the existing members if the new score is greater than the current score.
+	// It requires redis 6.2 or later
+	GT bool
+
+	// LT only updates the existing members if the new score is less than the current score.
+	// It requires redis 6.2 or later
+	LT bool
+
+	// CH makes ZADD return number of the changed members (added & updated)
+	// instead of number of the added members
+	CH bool
+}
+
+// CmdArgs returns arguments of ZADD command, excluding the command name
+func (a ZAddArgs) CmdArgs(key string, members ...Z) []interface{} {
+	args := make([]interface{}, 0, 6+2*len(members))
+	args = append(args, key)
+
+	for _, opt := range []struct {
+		enabled bool
+		name    string
+	}{
+		{a.NX, "NX"},
+		{a.XX, "XX"},
+		{a.GT, "GT"},
+		{a.LT, "LT"},
+		{a.CH, "CH"},
+	} {
+		if opt.enabled {
+			args = append(args, opt.name)
+		}
+	}
+
+	for _, m := range members {
+		args = append(args, m.Score, m.Member)
+	}
+	return args
+}
+
+// ZRangeBy is the score range of ZRANGEBYSCORE command
+type ZRangeBy struct {
+	// Min & Max are the score range, e.g. "-inf", "+inf", "(1" (exclusive) or "1" (inclusive)
+	Min, Max string
+
+	// Offset & Count limit the returned members, zero Count means no limit
+	Offset, Count int64
+}
+
+// CmdArgs returns arguments of ZRANGEBYSCORE command, excluding the command name
+func (r ZRangeBy) CmdArgs(key string, withScores bool) []interface{} {
+	args := []interface{}{key, r.Min, r.Max}
+	if withScores {
+		args = append(args, "WITHSCORES")
+	}
+	if r.Count != 0 {
+		args = append(args, "LIMIT", r.Offset, r.Count)
+	}
+	return args
+}
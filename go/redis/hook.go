@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/log"
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Hook alias of engine.Hook, the caller don't have to import engine
+type Hook = engine.Hook
+
+// PipelineMetricsName is the name of the pipeline executions in the metrics of MetricsHook
+const PipelineMetricsName = "PIPELINE"
+
+// SlowLogHook logs the commands & the pipelines which are slower than the threshold, using the bxdk log package
+type SlowLogHook struct {
+	threshold time.Duration
+}
+
+// NewSlowLogHook creates hook which logs the commands & the pipelines which take at least `threshold`.
+// Only the command name & its first argument (usually the key) are logged, the other arguments might be big or sensitive.
+func NewSlowLogHook(threshold time.Duration) *SlowLogHook {
+	return &SlowLogHook{threshold: threshold}
+}
+
+// BeforeProcess implements Hook
+func (h *SlowLogHook) BeforeProcess(ctx context.Context, cmd string, args []interface{}) context.Context {
+	return ctx
+}
+
+// AfterProcess logs the command if it is slow
+func (h *SlowLogHook) AfterProcess(ctx context.Context, cmd string, args []interface{}, duration time.Duration, err error) {
+	if duration < h.threshold {
+		return
+	}
+
+	fields := log.KV{
+		"cmd":      cmd,
+		"duration": duration.String(),
+	}
+	if len(args) > 0 {
+		fields["key"] = fmt.Sprint(args[0])
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	log.WarnWithFields("redis: slow command", fields)
+}
+
+// BeforeProcessPipeline implements Hook
+func (h *SlowLogHook) BeforeProcessPipeline(ctx context.Context, cmds []engine.CmdErr) context.Context {
+	return ctx
+}
+
+// AfterProcessPipeline logs the pipeline if it is slow
+func (h *SlowLogHook) AfterProcessPipeline(ctx context.Context, cmds []engine.CmdErr, duration time.Duration, err error) {
+	if duration < h.threshold {
+		return
+	}
+
+	fields := log.KV{
+		"cmds":     len(cmds),
+		"duration": duration.String(),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	log.WarnWithFields("redis: slow pipeline", fields)
+}
+
+// CommandMetrics is the counters of a command
+type CommandMetrics struct {
+	// Count is number of the executions, including the failed ones
+	Count int64
+
+	// Errors is number of the failed executions, including the error replied by the server, e.g. WRONGTYPE.
+	// The nil reply is not an error
+	Errors int64
+
+	// TotalDuration & MaxDuration are the total & the maximum latency of the executions
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+}
+
+// AvgDuration returns the average latency of the executions
+func (m CommandMetrics) AvgDuration() time.Duration {
+	if m.Count == 0 {
+		return 0
+	}
+	return m.TotalDuration / time.Duration(m.Count)
+}
+
+func (m *CommandMetrics) add(duration time.Duration, failed bool) {
+	m.Count++
+	if failed {
+		m.Errors++
+	}
+	m.TotalDuration += duration
+	if duration > m.MaxDuration {
+		m.MaxDuration = duration
+	}
+}
+
+// MetricsHook counts the executions, the errors, and the latency of each command, by its upper case name.
+// The pipeline executions are counted as PipelineMetricsName, the execution fails if any of its commands failed.
+type MetricsHook struct {
+	mux     sync.Mutex
+	metrics map[string]*CommandMetrics
+}
+
+// NewMetricsHook creates new MetricsHook
+func NewMetricsHook() *MetricsHook {
+	return &MetricsHook{metrics: make(map[string]*CommandMetrics)}
+}
+
+// Metrics returns snapshot of the metrics, by the command name
+func (h *MetricsHook) Metrics() map[string]CommandMetrics {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	metrics := make(map[string]CommandMetrics, len(h.metrics))
+	for name, m := range h.metrics {
+		metrics[name] = *m
+	}
+	return metrics
+}
+
+// Reset clears the metrics, e.g. after they are exported
+func (h *MetricsHook) Reset() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.metrics = make(map[string]*CommandMetrics)
+}
+
+// BeforeProcess implements Hook
+func (h *MetricsHook) BeforeProcess(ctx context.Context, cmd string, args []interface{}) context.Context {
+	return ctx
+}
+
+// AfterProcess counts the command
+func (h *MetricsHook) AfterProcess(ctx context.Context, cmd string, args []interface{}, duration time.Duration, err error) {
+	h.add(strings.ToUpper(cmd), duration, err != nil)
+}
+
+// BeforeProcessPipeline implements Hook
+func (h *MetricsHook) BeforeProcessPipeline(ctx context.Context, cmds []engine.CmdErr) context.Context {
+	return ctx
+}
+
+// AfterProcessPipeline counts the pipeline execution
+func (h *MetricsHook) AfterProcessPipeline(ctx context.Context, cmds []engine.CmdErr, duration time.Duration, err error) {
+	failed := err != nil
+	for _, cmd := range cmds {
+		if failed {
+			break
+		}
+		failed = cmd.Err() != nil
+	}
+	h.add(PipelineMetricsName, duration, failed)
+}
+
+func (h *MetricsHook) add(name string, duration time.Duration, failed bool) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	m, ok := h.metrics[name]
+	if !ok {
+		m = &CommandMetrics{}
+		h.metrics[name] = m
+	}
+	m.add(duration, failed)
+}
@@ -0,0 +1,185 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+type hookCtxKey struct{}
+
+// recordHook records the calls of the hook
+type recordHook struct {
+	name  string
+	calls *[]string
+}
+
+func (h recordHook) BeforeProcess(ctx context.Context, cmd string, args []interface{}) context.Context {
+	*h.calls = append(*h.calls, fmt.Sprintf("%s before %s %v", h.name, cmd, args))
+	return context.WithValue(ctx, hookCtxKey{}, h.name)
+}
+
+func (h recordHook) AfterProcess(ctx context.Context, cmd string, args []interface{}, duration time.Duration, err error) {
+	*h.calls = append(*h.calls, fmt.Sprintf("%s after %s %v ctx=%v err=%v", h.name, cmd, args, ctx.Value(hookCtxKey{}), err))
+}
+
+func (h recordHook) BeforeProcessPipeline(ctx context.Context, cmds []engine.CmdErr) context.Context {
+	*h.calls = append(*h.calls, fmt.Sprintf("%s before pipeline %d", h.name, len(cmds)))
+	return context.WithValue(ctx, hookCtxKey{}, h.name)
+}
+
+func (h recordHook) AfterProcessPipeline(ctx context.Context, cmds []engine.CmdErr, duration time.Duration, err error) {
+	*h.calls = append(*h.calls, fmt.Sprintf("%s after pipeline %d ctx=%v err=%v", h.name, len(cmds), ctx.Value(hookCtxKey{}), err))
+}
+
+func TestHook(t *testing.T) {
+	for _, engineType := range []engine.Type{engine.Redigo, engine.GoRedis, engine.Memory} {
+		t.Run(string(engineType), func(t *testing.T) {
+			var calls []string
+			cfg := Config{
+				EngineType: engineType,
+				Hooks:      []Hook{recordHook{name: "first", calls: &calls}},
+			}
+			if engineType != engine.Memory {
+				mr, err := miniredis.Run()
+				require.NoError(t, err)
+				defer mr.Close()
+				cfg.Address = mr.Addr()
+			}
+			cli, err := New(cfg)
+			require.NoError(t, err)
+			cli.AddHook(recordHook{name: "second", calls: &calls})
+			require.Equal(t, []string{"first before PING []", "first after PING [] ctx=first err=<nil>"}, calls)
+			calls = nil
+
+			err = cli.Set("key", "value")
+			require.NoError(t, err)
+			_, err = cli.Get("missing")
+			require.True(t, cli.IsErrNil(err))
+
+			p := cli.Pipeline(1, 2)
+			p.Get("key")
+			incr := p.Incr("key")
+			_, _, err = p.Exec()
+			require.NoError(t, err)
+			require.Error(t, incr.Err())
+
+			require.Equal(t, []string{
+				"first before SET [key value]",
+				"second before SET [key value]",
+				"second after SET [key value] ctx=second err=<nil>",
+				"first after SET [key value] ctx=first err=<nil>",
+				"first before GET [missing]",
+				"second before GET [missing]",
+				"second after GET [missing] ctx=second err=<nil>",
+				"first after GET [missing] ctx=first err=<nil>",
+				"first before pipeline 2",
+				"second before pipeline 2",
+				"second after pipeline 2 ctx=second err=<nil>",
+				"first after pipeline 2 ctx=first err=<nil>",
+			}, calls)
+		})
+	}
+}
+
+func TestHookHSetEXAndWatch(t *testing.T) {
+	for _, engineType := range []engine.Type{engine.Redigo, engine.GoRedis, engine.Memory} {
+		t.Run(string(engineType), func(t *testing.T) {
+			var calls []string
+			cfg := Config{
+				EngineType:     engineType,
+				Hooks:          []Hook{recordHook{name: "first", calls: &calls}},
+				NoPingOnCreate: true,
+			}
+			if engineType != engine.Memory {
+				mr, err := miniredis.Run()
+				require.NoError(t, err)
+				defer mr.Close()
+				cfg.Address = mr.Addr()
+			}
+			cli, err := New(cfg)
+			require.NoError(t, err)
+
+			_, err = cli.HSetEX("hash", "f", "v", 10)
+			require.NoError(t, err)
+			_, err = cli.Watch(func(tx Tx) error {
+				if _, err := tx.Do("GET", "key"); err != nil && !cli.IsErrNil(err) {
+					return err
+				}
+				tx.Queue("SET", "key", "value")
+				return nil
+			}, 1, "key")
+			require.NoError(t, err)
+
+			want := []string{
+				"first before HSET [hash f v]",
+				"first after HSET [hash f v] ctx=first err=<nil>",
+				"first before EXPIRE [hash 10]",
+				"first after EXPIRE [hash 10] ctx=first err=<nil>",
+			}
+			if engineType == engine.Memory {
+				// executed atomically
+				want = []string{"first before pipeline 2", "first after pipeline 2 ctx=first err=<nil>"}
+			}
+			want = append(want,
+				"first before GET [key]",
+				"first after GET [key] ctx=first err=<nil>",
+				"first before pipeline 1",
+				"first after pipeline 1 ctx=first err=<nil>",
+			)
+			require.Equal(t, want, calls)
+		})
+	}
+}
+
+func TestMetricsHook(t *testing.T) {
+	hook := NewMetricsHook()
+	cli, err := New(Config{
+		EngineType: engine.Memory,
+		Hooks:      []Hook{hook, NewSlowLogHook(0)},
+	})
+	require.NoError(t, err)
+	hook.Reset() // New pings the server
+
+	err = cli.Set("key", "value")
+	require.NoError(t, err)
+	_, err = cli.Get("key")
+	require.NoError(t, err)
+	_, err = cli.Get("missing")
+	require.True(t, cli.IsErrNil(err))
+	_, err = cli.Incr("key")
+	require.Error(t, err)
+
+	p := cli.Pipeline(1, 2)
+	p.Incr("counter")
+	_, _, err = p.Exec()
+	require.NoError(t, err)
+
+	incr := p.Incr("key")
+	_, _, err = p.Exec()
+	require.NoError(t, err)
+	require.Error(t, incr.Err())
+
+	metrics := hook.Metrics()
+	require.Len(t, metrics, 4)
+	for name, count := range map[string][2]int64{
+		"SET":               {1, 0},
+		"GET":               {2, 0},
+		"INCR":              {1, 1},
+		PipelineMetricsName: {2, 1},
+	} {
+		m := metrics[name]
+		require.Equal(t, count, [2]int64{m.Count, m.Errors}, name)
+		require.True(t, m.MaxDuration <= m.TotalDuration, name)
+		require.True(t, m.AvgDuration() <= m.MaxDuration, name)
+	}
+
+	hook.Reset()
+	require.Empty(t, hook.Metrics())
+}
@@ -0,0 +1,291 @@
+// Package lock provides distributed lock on top of redis.
+//
+// The lock is a key holding a random token of its owner, with expiry in millisecond.
+// Only the owner could renew or release the lock, both are done atomically using lua script,
+// so a lock which is expired & acquired by another owner is never extended or removed.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	mrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/defaults"
+	"github.com/boxofimagination/bxdk/go/redis"
+)
+
+var (
+	// ErrNotObtained returned when the lock is held by another owner
+	ErrNotObtained = errors.New("lock not obtained")
+
+	// ErrLockLost returned when the lock is no longer held by the owner, e.g. it is expired
+	ErrLockLost = errors.New("lock lost")
+
+	// ErrInvalidExpiry returned when the expiry is less than a millisecond,
+	// or the renew interval is not between a millisecond and the expiry
+	ErrInvalidExpiry = errors.New("lock expiry must be at least 1ms, and the renew interval between 1ms and the expiry")
+)
+
+var (
+	acquireScript = redis.NewScript(`
+		if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+			return 1
+		end
+		return 0`)
+
+	// compare-and-delete
+	releaseScript = redis.NewScript(`
+		if redis.call('GET', KEYS[1]) == ARGV[1] then
+			return redis.call('DEL', KEYS[1])
+		end
+		return 0`)
+
+	// compare-and-extend
+	renewScript = redis.NewScript(`
+		if redis.call('GET', KEYS[1]) == ARGV[1] then
+			return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+		end
+		return 0`)
+)
+
+// Config defines configuration of the lock
+type Config struct {
+	// Expiry of the lock, it is released automatically after that if it is not renewed.
+	// It must be at least a millisecond, and it is rounded down to millisecond
+	Expiry time.Duration `default:"10s"`
+
+	// MaxRetries is maximum number of the retries of `Acquire` when the lock is held by another owner,
+	// 0 means retrying until the ctx is done
+	MaxRetries int
+
+	// RetryDelay is the initial delay between the retries, it is doubled after each retry
+	RetryDelay time.Duration `default:"50ms"`
+
+	// MaxRetryDelay is the maximum delay between the retries
+	MaxRetryDelay time.Duration `default:"1s"`
+
+	// AutoRenew renews the lock periodically in background until it is released.
+	// Use `Lock.Lost` to get notified when the lock could not be renewed
+	AutoRenew bool
+
+	// RenewInterval is the interval of the auto renewal, it is a third of the Expiry if it is empty.
+	// It must be between a millisecond and the Expiry
+	RenewInterval time.Duration
+}
+
+// Lock is an acquired lock
+type Lock struct {
+	cli    redis.Redis
+	key    string
+	token  string
+	expiry time.Duration
+
+	lostCh   chan struct{}
+	lostOnce sync.Once
+
+	// stopCh stops the auto renewal
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	renewWg  sync.WaitGroup
+}
+
+// Acquire acquires the lock of the key.
+// If the lock is held by another owner, it retries with exponential backoff
+// until the lock is acquired, the ctx is done, or `MaxRetries` is reached.
+// It returns ErrNotObtained if the retries are exhausted, or ctx.Err() if the ctx is done.
+func Acquire(ctx context.Context, cli redis.Redis, key string, cfg Config) (*Lock, error) {
+	if err := setDefault(&cfg); err != nil {
+		return nil, err
+	}
+
+	delay := cfg.RetryDelay
+	for retry := 0; ; retry++ {
+		l, err := acquire(ctx, cli, key, cfg)
+		if err != ErrNotObtained {
+			return l, err
+		}
+		if cfg.MaxRetries > 0 && retry >= cfg.MaxRetries {
+			return nil, ErrNotObtained
+		}
+
+		timer := time.NewTimer(jitter(delay))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		if delay *= 2; delay > cfg.MaxRetryDelay {
+			delay = cfg.MaxRetryDelay
+		}
+	}
+}
+
+// TryAcquire acquires the lock of the key without retrying.
+// It returns ErrNotObtained if the lock is held by another owner.
+func TryAcquire(ctx context.Context, cli redis.Redis, key string, cfg Config) (*Lock, error) {
+	if err := setDefault(&cfg); err != nil {
+		return nil, err
+	}
+	return acquire(ctx, cli, key, cfg)
+}
+
+// setDefault sets the default values of the config, and validates the expiry & the renew interval
+func setDefault(cfg *Config) error {
+	if err := defaults.SetDefault(cfg); err != nil {
+		return err
+	}
+	if cfg.RenewInterval <= 0 {
+		cfg.RenewInterval = cfg.Expiry / 3
+	}
+	if cfg.Expiry < time.Millisecond {
+		return ErrInvalidExpiry
+	}
+	if cfg.AutoRenew && (cfg.RenewInterval < time.Millisecond || cfg.RenewInterval >= cfg.Expiry) {
+		return ErrInvalidExpiry
+	}
+	return nil
+}
+
+func acquire(ctx context.Context, cli redis.Redis, key string, cfg Config) (*Lock, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := acquireScript.RunContext(ctx, cli, []string{key}, token, milliseconds(cfg.Expiry))
+	if err != nil {
+		return nil, err
+	}
+	if reply != int64(1) {
+		return nil, ErrNotObtained
+	}
+
+	l := &Lock{
+		cli:    cli,
+		key:    key,
+		token:  token,
+		expiry: cfg.Expiry,
+		lostCh: make(chan struct{}),
+		stopCh: make(chan struct{}),
+	}
+
+	if cfg.AutoRenew {
+		l.renewWg.Add(1)
+		go l.autoRenew(cfg.RenewInterval)
+	}
+	return l, nil
+}
+
+// Key of the lock
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token is the random token identifying the owner of the lock
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Renew resets the expiry of the lock.
+// It returns ErrLockLost if the lock is no longer held by the owner.
+func (l *Lock) Renew(ctx context.Context) error {
+	reply, err := renewScript.RunContext(ctx, l.cli, []string{l.key}, l.token, milliseconds(l.expiry))
+	if err != nil {
+		return err
+	}
+	if reply != int64(1) {
+		l.lose()
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release releases the lock and stops the auto renewal.
+// It returns ErrLockLost if the lock is no longer held by the owner, e.g. it is already expired.
+func (l *Lock) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		close(l.stopCh)
+	})
+	l.renewWg.Wait()
+
+	reply, err := releaseScript.RunContext(ctx, l.cli, []string{l.key}, l.token)
+	if err != nil {
+		return err
+	}
+	if reply != int64(1) {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Lost returns channel which is closed when the lock is found no longer held by the owner,
+// either by `Renew` or by the auto renewal.
+// The auto renewal also considers the lock lost if it fails to renew the lock before it is expired.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lostCh
+}
+
+func (l *Lock) lose() {
+	l.lostOnce.Do(func() {
+		close(l.lostCh)
+	})
+}
+
+// autoRenew renews the lock periodically until it is released or lost
+func (l *Lock) autoRenew(interval time.Duration) {
+	defer l.renewWg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-l.stopCh:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := l.Renew(ctx)
+		cancel()
+
+		switch {
+		case err == nil:
+			renewed = time.Now()
+		case err == ErrLockLost:
+			return
+		case time.Since(renewed) >= l.expiry:
+			// the lock might be already acquired by another owner
+			l.lose()
+			return
+		}
+	}
+}
+
+// newToken generates random token of the lock owner
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// jitter randomizes the delay to [d/2, d), so the contending owners don't retry at the same time
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(mrand.Int63n(int64(half)))
+}
@@ -0,0 +1,200 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis"
+)
+
+func newTestClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	cli, err := redis.New(redis.Config{Address: mr.Addr()})
+	require.NoError(t, err)
+	return cli, mr
+}
+
+// closeTestServer closes the server after the scripts abandoned by the timed out callers are finished.
+// miniredis holds its lock while it is closed, the running script would wait for it forever.
+func closeTestServer(t *testing.T, cli *redis.Client, mr *miniredis.Miniredis) {
+	// the connection of the abandoned script is discarded from the pool, but the server keeps it until the script ends
+	require.Eventually(t, func() bool {
+		return mr.CurrentConnectionCount() <= cli.Stats().ActiveCount
+	}, time.Second, time.Millisecond)
+	mr.Close()
+}
+
+func TestAcquireRelease(t *testing.T) {
+	cli, mr := newTestClient(t)
+	defer closeTestServer(t, cli, mr)
+
+	ctx := context.Background()
+	l, err := TryAcquire(ctx, cli, "lock", Config{Expiry: 1500 * time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, "lock", l.Key())
+	require.Equal(t, 1500*time.Millisecond, mr.TTL("lock"))
+
+	val, err := mr.Get("lock")
+	require.NoError(t, err)
+	require.Equal(t, l.Token(), val)
+
+	_, err = TryAcquire(ctx, cli, "lock", Config{})
+	require.Equal(t, ErrNotObtained, err)
+
+	_, err = Acquire(ctx, cli, "lock", Config{MaxRetries: 2, RetryDelay: time.Millisecond})
+	require.Equal(t, ErrNotObtained, err)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = Acquire(timeoutCtx, cli, "lock", Config{RetryDelay: time.Millisecond})
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	require.NoError(t, l.Renew(ctx))
+	require.NoError(t, l.Release(ctx))
+	require.False(t, mr.Exists("lock"))
+	require.Equal(t, ErrLockLost, l.Release(ctx))
+
+	l2, err := TryAcquire(ctx, cli, "lock", Config{})
+	require.NoError(t, err)
+	require.NotEqual(t, l.Token(), l2.Token())
+}
+
+func TestAcquireRetry(t *testing.T) {
+	cli, mr := newTestClient(t)
+	defer closeTestServer(t, cli, mr)
+
+	ctx := context.Background()
+	l, err := TryAcquire(ctx, cli, "lock", Config{})
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		l.Release(ctx)
+	}()
+
+	l2, err := Acquire(ctx, cli, "lock", Config{RetryDelay: 5 * time.Millisecond})
+	require.NoError(t, err)
+	val, err := mr.Get("lock")
+	require.NoError(t, err)
+	require.Equal(t, l2.Token(), val)
+}
+
+func TestAcquireConcurrent(t *testing.T) {
+	cli, mr := newTestClient(t)
+	defer closeTestServer(t, cli, mr)
+
+	var (
+		ctx     = context.Background()
+		holders int32
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				// the abandoned acquisitions don't break the others
+				timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(j%3)*time.Millisecond)
+				if l, err := TryAcquire(timeoutCtx, cli, "other", Config{}); err == nil {
+					l.Release(ctx)
+				}
+				cancel()
+
+				l, err := Acquire(ctx, cli, "lock", Config{RetryDelay: time.Millisecond, MaxRetryDelay: 5 * time.Millisecond})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if n := atomic.AddInt32(&holders, 1); n != 1 {
+					t.Errorf("the lock is held by %d owners", n)
+				}
+				atomic.AddInt32(&holders, -1)
+				if err := l.Release(ctx); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestInvalidExpiry(t *testing.T) {
+	cli, mr := newTestClient(t)
+	defer closeTestServer(t, cli, mr)
+
+	ctx := context.Background()
+	_, err := TryAcquire(ctx, cli, "lock", Config{Expiry: time.Microsecond})
+	require.Equal(t, ErrInvalidExpiry, err)
+	_, err = Acquire(ctx, cli, "lock", Config{Expiry: time.Second, AutoRenew: true, RenewInterval: time.Second})
+	require.Equal(t, ErrInvalidExpiry, err)
+	require.False(t, mr.Exists("lock"))
+
+	l, err := TryAcquire(ctx, cli, "lock", Config{Expiry: time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, time.Millisecond, mr.TTL("lock"))
+	require.NoError(t, l.Release(ctx))
+}
+
+func TestLockLost(t *testing.T) {
+	cli, mr := newTestClient(t)
+	defer closeTestServer(t, cli, mr)
+
+	ctx := context.Background()
+	l, err := TryAcquire(ctx, cli, "lock", Config{})
+	require.NoError(t, err)
+
+	// expired & acquired by another owner
+	mr.FastForward(10 * time.Second)
+	l2, err := TryAcquire(ctx, cli, "lock", Config{})
+	require.NoError(t, err)
+
+	// the other owner's lock is not renewed nor released
+	require.Equal(t, ErrLockLost, l.Renew(ctx))
+	require.Equal(t, ErrLockLost, l.Release(ctx))
+	val, err := mr.Get("lock")
+	require.NoError(t, err)
+	require.Equal(t, l2.Token(), val)
+
+	select {
+	case <-l.Lost():
+	default:
+		t.Fatal("lost channel is not closed")
+	}
+}
+
+func TestAutoRenew(t *testing.T) {
+	cli, mr := newTestClient(t)
+	defer closeTestServer(t, cli, mr)
+
+	ctx := context.Background()
+	l, err := TryAcquire(ctx, cli, "lock", Config{
+		AutoRenew:     true,
+		RenewInterval: 5 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// the TTL is reset by the renewal
+	mr.SetTTL("lock", time.Second)
+	deadline := time.Now().Add(time.Second)
+	for mr.TTL("lock") != 10*time.Second {
+		require.True(t, time.Now().Before(deadline), "lock is not renewed after a second")
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mr.Del("lock")
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lost channel is not closed after a second")
+	}
+	require.Equal(t, ErrLockLost, l.Release(ctx))
+}
@@ -0,0 +1,420 @@
+package redis
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/log"
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+const (
+	defaultNearCacheSize = 10000
+	defaultNearCacheTTL  = time.Second
+)
+
+// NearCache is in-process LRU cache of the hot keys, in front of the engine.
+//
+// Get, HGet, and MGet replies (including the nil reply) are cached for `NearCacheTTLMs`,
+// at most `NearCacheSize` values. The other commands are executed directly.
+//
+// The keys are invalidated locally when the client writes them, including in pipelines & transactions.
+// The keys written by the other clients are invalidated only when:
+//   - the cached value expires, or
+//   - the key is published to `NearCacheInvalidationChannel`, as done by the other clients with the same channel.
+//     The messages published while the subscription is reconnecting are lost, the TTL still bounds the staleness.
+//
+// Redis server-assisted client tracking is not used, it has to be enabled on each pooled connection
+// which the engines don't expose.
+type NearCache struct {
+	Redis
+
+	size    int
+	ttl     time.Duration
+	channel string
+	pubSub  engine.PubSub // nil if there is no invalidation channel
+	now     func() time.Time
+
+	mux     sync.Mutex
+	entries map[nearCacheKey]*list.Element
+	fields  map[string]map[string]struct{} // the cached fields of each hash
+	lru     *list.List                     // front is the most recently used
+	epoch   uint64                         // incremented on each invalidation, see set
+	stats   NearCacheStats
+}
+
+// NearCacheStats is the counters of the near cache
+type NearCacheStats struct {
+	Hits          int64
+	Misses        int64
+	Evictions     int64 // number of the values evicted because the cache is full
+	Invalidations int64 // number of the invalidated keys
+	Size          int   // number of the cached values
+}
+
+// HitRatio returns the hits per lookup, or zero if there is no lookup yet
+func (s NearCacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type nearCacheKey struct {
+	key   string
+	field string
+	hash  bool // true for the hash field
+}
+
+type nearCacheEntry struct {
+	nearCacheKey
+	value    string
+	err      error // the nil reply error of the engine
+	expireAt time.Time
+}
+
+// newNearCache creates the near cache of the engine, and subscribes the invalidation channel of the config
+func newNearCache(eng Redis, cfg Config) (*NearCache, error) {
+	n := &NearCache{
+		Redis:   eng,
+		size:    cfg.NearCacheSize,
+		ttl:     time.Duration(cfg.NearCacheTTLMs) * time.Millisecond,
+		channel: cfg.NearCacheInvalidationChannel,
+		now:     time.Now,
+		entries: make(map[nearCacheKey]*list.Element),
+		fields:  make(map[string]map[string]struct{}),
+		lru:     list.New(),
+	}
+	if n.size <= 0 {
+		n.size = defaultNearCacheSize
+	}
+	if n.ttl <= 0 {
+		n.ttl = defaultNearCacheTTL
+	}
+
+	if n.channel != "" {
+		pubSub, err := eng.Subscribe(n.channel)
+		if err != nil {
+			return nil, err
+		}
+		n.pubSub = pubSub
+		go n.receive(pubSub.Channel())
+	}
+
+	eng.AddHook(nearCacheHook{n})
+	return n, nil
+}
+
+// receive invalidates the keys published to the invalidation channel
+func (n *NearCache) receive(msgCh <-chan engine.Message) {
+	for msg := range msgCh {
+		n.Invalidate(msg.Payload)
+	}
+}
+
+// Close stops subscribing the invalidation channel
+func (n *NearCache) Close() error {
+	if n.pubSub == nil {
+		return nil
+	}
+	return n.pubSub.Close()
+}
+
+// CacheStats returns snapshot of the near cache counters
+func (n *NearCache) CacheStats() NearCacheStats {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	stats := n.stats
+	stats.Size = n.lru.Len()
+	return stats
+}
+
+// Get gets the cached value of the key, or gets it from redis if it is not cached
+func (n *NearCache) Get(key string) (string, error) {
+	return n.GetContext(context.Background(), key)
+}
+
+// GetContext is Get with context
+func (n *NearCache) GetContext(ctx context.Context, key string) (string, error) {
+	ck := nearCacheKey{key: key}
+	if value, err, ok := n.get(ck); ok {
+		return value, err
+	}
+
+	epoch := n.currentEpoch()
+	value, err := n.Redis.GetContext(ctx, key)
+	n.set(epoch, ck, value, err)
+	return value, err
+}
+
+// HGet gets the cached value of the hash field, or gets it from redis if it is not cached
+func (n *NearCache) HGet(key, field string) (string, error) {
+	return n.HGetContext(context.Background(), key, field)
+}
+
+// HGetContext is HGet with context
+func (n *NearCache) HGetContext(ctx context.Context, key, field string) (string, error) {
+	ck := nearCacheKey{key: key, field: field, hash: true}
+	if value, err, ok := n.get(ck); ok {
+		return value, err
+	}
+
+	epoch := n.currentEpoch()
+	value, err := n.Redis.HGetContext(ctx, key, field)
+	n.set(epoch, ck, value, err)
+	return value, err
+}
+
+// MGet gets the cached values of the keys, the keys which are not cached are got from redis in a single MGET
+func (n *NearCache) MGet(keys ...string) ([]string, error) {
+	return n.MGetContext(context.Background(), keys...)
+}
+
+// MGetContext is MGet with context
+func (n *NearCache) MGetContext(ctx context.Context, keys ...string) ([]string, error) {
+	var (
+		values = make([]string, len(keys))
+		missed []int // index of the keys which are not cached
+	)
+	for i, key := range keys {
+		// the nil reply is cached as empty value with the nil error, MGET replies it as empty string too
+		value, _, ok := n.get(nearCacheKey{key: key})
+		if !ok {
+			missed = append(missed, i)
+			continue
+		}
+		values[i] = value
+	}
+	if len(missed) == 0 {
+		return values, nil
+	}
+
+	missedKeys := make([]string, len(missed))
+	for i, idx := range missed {
+		missedKeys[i] = keys[idx]
+	}
+
+	epoch := n.currentEpoch()
+	missedValues, err := n.Redis.MGetContext(ctx, missedKeys...)
+	if err != nil {
+		return nil, err
+	}
+	for i, idx := range missed {
+		if i < len(missedValues) {
+			values[idx] = missedValues[i]
+			if missedValues[i] != "" {
+				// the empty string might be the nil reply, it is not cached
+				n.set(epoch, nearCacheKey{key: keys[idx]}, missedValues[i], nil)
+			}
+		}
+	}
+	return values, nil
+}
+
+// Invalidate removes the keys from the cache, including all of the cached fields of the hashes
+func (n *NearCache) Invalidate(keys ...string) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	n.epoch++
+	for _, key := range keys {
+		n.stats.Invalidations++
+		n.remove(nearCacheKey{key: key})
+		for field := range n.fields[key] {
+			n.remove(nearCacheKey{key: key, field: field, hash: true})
+		}
+	}
+}
+
+// Purge removes all of the cached values
+func (n *NearCache) Purge() {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	n.epoch++
+	n.stats.Invalidations += int64(len(n.entries))
+	n.entries = make(map[nearCacheKey]*list.Element)
+	n.fields = make(map[string]map[string]struct{})
+	n.lru.Init()
+}
+
+// invalidateWritten invalidates the keys written by the client, and publishes them to the invalidation channel
+func (n *NearCache) invalidateWritten(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	n.Invalidate(keys...)
+	if n.channel == "" {
+		return
+	}
+
+	for _, key := range keys {
+		if _, err := n.Redis.PublishContext(ctx, n.channel, key); err != nil {
+			log.WarnWithFields("redis: failed to publish the near cache invalidation", log.KV{
+				"channel": n.channel,
+				"key":     key,
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+}
+
+// get returns the cached value, ok is false if it is not cached or expired
+func (n *NearCache) get(ck nearCacheKey) (value string, err error, ok bool) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	elem, found := n.entries[ck]
+	if found {
+		entry := elem.Value.(*nearCacheEntry)
+		if n.now().Before(entry.expireAt) {
+			n.stats.Hits++
+			n.lru.MoveToFront(elem)
+			return entry.value, entry.err, true
+		}
+		n.remove(ck)
+	}
+
+	n.stats.Misses++
+	return "", nil, false
+}
+
+// currentEpoch returns the epoch before getting the value from redis, it must be given to set
+func (n *NearCache) currentEpoch() uint64 {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	return n.epoch
+}
+
+// set caches the value got from redis, only the value & the nil reply are cached.
+// The value is not cached if any key is invalidated since the given epoch,
+// because it might be got before the invalidating write.
+func (n *NearCache) set(epoch uint64, ck nearCacheKey, value string, err error) {
+	if err != nil && !n.IsErrNil(err) {
+		return
+	}
+
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	if n.epoch != epoch {
+		return
+	}
+
+	entry := &nearCacheEntry{nearCacheKey: ck, value: value, err: err, expireAt: n.now().Add(n.ttl)}
+	if elem, ok := n.entries[ck]; ok {
+		elem.Value = entry
+		n.lru.MoveToFront(elem)
+		return
+	}
+
+	n.entries[ck] = n.lru.PushFront(entry)
+	if ck.hash {
+		fields, ok := n.fields[ck.key]
+		if !ok {
+			fields = make(map[string]struct{})
+			n.fields[ck.key] = fields
+		}
+		fields[ck.field] = struct{}{}
+	}
+
+	for n.lru.Len() > n.size {
+		n.stats.Evictions++
+		n.remove(n.lru.Back().Value.(*nearCacheEntry).nearCacheKey)
+	}
+}
+
+// remove removes the cached value, the caller must hold the lock
+func (n *NearCache) remove(ck nearCacheKey) {
+	elem, ok := n.entries[ck]
+	if !ok {
+		return
+	}
+
+	n.lru.Remove(elem)
+	delete(n.entries, ck)
+	if ck.hash {
+		delete(n.fields[ck.key], ck.field)
+		if len(n.fields[ck.key]) == 0 {
+			delete(n.fields, ck.key)
+		}
+	}
+}
+
+// writtenKeys returns the keys written by the command, or nil if it is read-only
+func writtenKeys(cmd string, args []interface{}) []string {
+	if engine.IsReadOnly(cmd) {
+		return nil
+	}
+
+	indexes := engine.KeyIndexes(cmd, args)
+	if len(indexes) == 0 {
+		return nil
+	}
+	keys := make([]string, len(indexes))
+	for i, idx := range indexes {
+		keys[i] = keyString(args[idx])
+	}
+	return keys
+}
+
+// keyString converts the key argument to string
+func keyString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// purgesAll returns true if the command removes all of the keys,
+// or it is unknown command which written keys can't be found, e.g. the module commands
+func purgesAll(cmd string) bool {
+	if strings.EqualFold(cmd, "FLUSHDB") || strings.EqualFold(cmd, "FLUSHALL") {
+		return true
+	}
+	return !engine.IsKnownCommand(cmd) && !engine.IsReadOnly(cmd)
+}
+
+// nearCacheHook invalidates the keys written by the commands executed through the engine
+type nearCacheHook struct {
+	n *NearCache
+}
+
+func (h nearCacheHook) BeforeProcess(ctx context.Context, cmd string, args []interface{}) context.Context {
+	return ctx
+}
+
+func (h nearCacheHook) AfterProcess(ctx context.Context, cmd string, args []interface{}, _ time.Duration, _ error) {
+	if purgesAll(cmd) {
+		h.n.Purge()
+		return
+	}
+	h.n.invalidateWritten(ctx, writtenKeys(cmd, args))
+}
+
+func (h nearCacheHook) BeforeProcessPipeline(ctx context.Context, cmds []engine.CmdErr) context.Context {
+	return ctx
+}
+
+func (h nearCacheHook) AfterProcessPipeline(ctx context.Context, cmds []engine.CmdErr, _ time.Duration, _ error) {
+	var keys []string
+	for _, cmd := range cmds {
+		if purgesAll(cmd.Name()) {
+			h.n.Purge()
+			continue
+		}
+		keys = append(keys, writtenKeys(cmd.Name(), cmd.Args())...)
+	}
+	h.n.invalidateWritten(ctx, keys)
+}
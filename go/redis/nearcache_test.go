@@ -0,0 +1,217 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+func newNearCacheClient(t *testing.T, engineType engine.Type, mr *miniredis.Miniredis, cfg Config) (*Client, *time.Time) {
+	cfg.EngineType = engineType
+	cfg.Address = mr.Addr()
+	cfg.NearCache = true
+	cli, err := New(cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	cli.NearCache().now = func() time.Time { return now }
+	return cli, &now
+}
+
+func TestNearCache(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			mr, err := miniredis.Run()
+			require.NoError(t, err)
+			defer mr.Close()
+
+			cli, now := newNearCacheClient(t, engineType, mr, Config{NearCacheTTLMs: 1000})
+			nc := cli.NearCache()
+
+			require.NoError(t, cli.Set("key", "v1"))
+			val, err := cli.Get("key")
+			require.NoError(t, err)
+			require.Equal(t, "v1", val)
+
+			// written by the other client, the cached value is stale until it expires
+			require.NoError(t, mr.Set("key", "v2"))
+			val, err = cli.Get("key")
+			require.NoError(t, err)
+			require.Equal(t, "v1", val)
+
+			*now = now.Add(time.Second)
+			val, err = cli.Get("key")
+			require.NoError(t, err)
+			require.Equal(t, "v2", val)
+
+			// the nil reply is cached too
+			_, err = cli.Get("missing")
+			require.True(t, cli.IsErrNil(err))
+			require.NoError(t, mr.Set("missing", "found"))
+			_, err = cli.Get("missing")
+			require.True(t, cli.IsErrNil(err))
+
+			values, err := cli.MGet("key", "missing", "other")
+			require.NoError(t, err)
+			require.Equal(t, []string{"v2", "", ""}, values)
+
+			mr.HSet("hash", "f1", "a")
+			val, err = cli.HGet("hash", "f1")
+			require.NoError(t, err)
+			require.Equal(t, "a", val)
+
+			require.Equal(t, NearCacheStats{Hits: 4, Misses: 5, Invalidations: 1, Size: 3}, nc.CacheStats())
+			require.Equal(t, 4.0/9.0, nc.CacheStats().HitRatio())
+		})
+	}
+}
+
+func TestNearCacheLocalWrite(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	cli, _ := newNearCacheClient(t, engine.Redigo, mr, Config{})
+
+	// read the keys & fields into the cache, then write them behind the cache
+	cache := func() {
+		cli.NearCache().Purge()
+		for _, key := range []string{"k1", "k2"} {
+			require.NoError(t, mr.Set(key, "old"))
+			_, err := cli.Get(key)
+			require.NoError(t, err)
+			require.NoError(t, mr.Set(key, "new"))
+		}
+		mr.HSet("hash", "f1", "old")
+		_, err := cli.HGet("hash", "f1")
+		require.NoError(t, err)
+		mr.HSet("hash", "f1", "new")
+	}
+	requireFresh := func(keys ...string) {
+		for _, key := range keys {
+			val, err := cli.Get(key)
+			require.NoError(t, err)
+			require.Equal(t, "new", val, key)
+		}
+	}
+
+	cache()
+	_, err = cli.Delete("k1", "k2")
+	require.NoError(t, err)
+	values, err := cli.MGet("k1", "k2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"", ""}, values)
+
+	cache()
+	require.NoError(t, cli.MSet("k1", "new", "k2", "new"))
+	requireFresh("k1", "k2")
+
+	cache()
+	_, err = cli.HMSet("hash", map[string]interface{}{"f2": "x"})
+	require.NoError(t, err)
+	val, err := cli.HGet("hash", "f1")
+	require.NoError(t, err)
+	require.Equal(t, "new", val)
+
+	cache()
+	_, err = cli.HSetEX("hash", "f2", "x", 10)
+	require.NoError(t, err)
+	val, err = cli.HGet("hash", "f1")
+	require.NoError(t, err)
+	require.Equal(t, "new", val)
+
+	cache()
+	p := cli.Pipeline(1, 2)
+	p.Expire("k1", 100)
+	_, _, err = p.Exec()
+	require.NoError(t, err)
+	requireFresh("k1")
+
+	cache()
+	_, err = cli.Watch(func(tx Tx) error {
+		tx.Queue("EXPIRE", "k2", 100)
+		return nil
+	}, 1, "k2")
+	require.NoError(t, err)
+	requireFresh("k2")
+
+	cache()
+	_, err = cli.Do("FLUSHDB")
+	require.NoError(t, err)
+	require.Equal(t, 0, cli.NearCache().CacheStats().Size)
+
+	// the written keys of the unknown commands can't be found
+	cache()
+	_, _ = cli.Do("JSON.SET", "k1", "$", `"new"`)
+	require.Equal(t, 0, cli.NearCache().CacheStats().Size)
+}
+
+func TestNearCacheEviction(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	cli, _ := newNearCacheClient(t, engine.Redigo, mr, Config{NearCacheSize: 2})
+	for _, key := range []string{"k1", "k2", "k1", "k3"} {
+		_, err = cli.Get(key)
+		require.True(t, cli.IsErrNil(err))
+	}
+
+	// k2 is the least recently used
+	stats := cli.NearCache().CacheStats()
+	require.Equal(t, NearCacheStats{Hits: 1, Misses: 3, Evictions: 1, Size: 2}, stats)
+
+	_, err = cli.Get("k1")
+	require.True(t, cli.IsErrNil(err))
+	_, err = cli.Get("k2")
+	require.True(t, cli.IsErrNil(err))
+	stats = cli.NearCache().CacheStats()
+	require.Equal(t, int64(2), stats.Hits)
+	require.Equal(t, int64(4), stats.Misses)
+}
+
+func TestNearCacheInvalidationChannel(t *testing.T) {
+	// miniredis doesn't support pub/sub, use the memory engine
+	cli, err := New(Config{
+		EngineType:                   engine.Memory,
+		NearCache:                    true,
+		NearCacheInvalidationChannel: "invalidate",
+	})
+	require.NoError(t, err)
+	nc := cli.NearCache()
+	defer nc.Close()
+
+	sub, err := cli.Subscribe("invalidate")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// the written keys are published
+	require.NoError(t, cli.MSet("k1", "v1", "k2", "v2"))
+	for _, key := range []string{"k1", "k2"} {
+		select {
+		case msg := <-sub.Channel():
+			require.Equal(t, key, msg.Payload)
+		case <-time.After(time.Second):
+			t.Fatal("the written key is not published")
+		}
+	}
+
+	// the published keys are invalidated
+	_, err = cli.Get("k1")
+	require.NoError(t, err)
+	_, err = cli.HGet("hash", "f1")
+	require.True(t, cli.IsErrNil(err))
+	require.Equal(t, 2, nc.CacheStats().Size)
+
+	_, err = nc.Redis.Publish("invalidate", "k1")
+	require.NoError(t, err)
+	_, err = nc.Redis.Publish("invalidate", "hash")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return nc.CacheStats().Size == 0
+	}, time.Second, 10*time.Millisecond)
+}
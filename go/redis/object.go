@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+)
+
+// SetObject encodes the value using the client's codec and stores it in the key
+func (c *Client) SetObject(key string, v interface{}) error {
+	return c.SetObjectContext(context.Background(), key, v)
+}
+
+// SetObjectContext is SetObject with context
+func (c *Client) SetObjectContext(ctx context.Context, key string, v interface{}) error {
+	data, err := encode(c.codec, v)
+	if err != nil {
+		return err
+	}
+	return c.SetContext(ctx, key, data)
+}
+
+// SetObjectEX encodes the value using the client's codec and stores it in the key
+// which is expired after `expire` seconds
+func (c *Client) SetObjectEX(key string, v interface{}, expire int) error {
+	return c.SetObjectEXContext(context.Background(), key, v, expire)
+}
+
+// SetObjectEXContext is SetObjectEX with context
+func (c *Client) SetObjectEXContext(ctx context.Context, key string, v interface{}, expire int) error {
+	data, err := encode(c.codec, v)
+	if err != nil {
+		return err
+	}
+	_, err = c.SetEXContext(ctx, key, data, expire)
+	return err
+}
+
+// GetObject decodes the value stored in the key into the value pointed by v.
+// The value is decoded using the codec which encoded it, not necessarily the client's codec.
+// It returns ErrNil if the key doesn't exist, check it using `IsErrNil`
+func (c *Client) GetObject(key string, v interface{}) error {
+	return c.GetObjectContext(context.Background(), key, v)
+}
+
+// GetObjectContext is GetObject with context
+func (c *Client) GetObjectContext(ctx context.Context, key string, v interface{}) error {
+	data, err := c.GetContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	return decode([]byte(data), v)
+}
+
+// HSetObject encodes the value using the client's codec and stores it in the hash field
+func (c *Client) HSetObject(key, field string, v interface{}) error {
+	return c.HSetObjectContext(context.Background(), key, field, v)
+}
+
+// HSetObjectContext is HSetObject with context
+func (c *Client) HSetObjectContext(ctx context.Context, key, field string, v interface{}) error {
+	data, err := encode(c.codec, v)
+	if err != nil {
+		return err
+	}
+	_, err = c.HMSetContext(ctx, key, map[string]interface{}{field: data})
+	return err
+}
+
+// HGetObject decodes the value stored in the hash field into the value pointed by v.
+// The value is decoded using the codec which encoded it, not necessarily the client's codec.
+// It returns ErrNil if the key or the field doesn't exist, check it using `IsErrNil`
+func (c *Client) HGetObject(key, field string, v interface{}) error {
+	return c.HGetObjectContext(context.Background(), key, field, v)
+}
+
+// HGetObjectContext is HGetObject with context
+func (c *Client) HGetObjectContext(ctx context.Context, key, field string, v interface{}) error {
+	data, err := c.HGetContext(ctx, key, field)
+	if err != nil {
+		return err
+	}
+	return decode([]byte(data), v)
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineRetry(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			fake := newFakeTx(t)
+			defer fake.Close()
+
+			cli, err := New(Config{
+				EngineType:        engineType,
+				Address:           fake.Addr(),
+				PipelineChunkSize: 2,
+			})
+			require.NoError(t, err)
+
+			p := cli.Pipeline(3, 0)
+			defer p.Close()
+
+			// the connection is dropped after the first INCR is executed
+			fake.setDrops(1)
+			incr := p.Incr("counter")
+			get := p.Get("counter")
+			set := p.AddIdempotentCmd("SET", "key", "v")
+			raw := p.AddRawCmd("INCR", "other")
+
+			cmdErrs, firstErr, err := p.Exec()
+			require.Len(t, cmdErrs, 4)
+			require.Equal(t, 0, firstErr)
+
+			// only the first chunk failed, and only the INCR is not retried
+			pipeErr, ok := err.(*PipelineError)
+			require.True(t, ok, "%v", err)
+			require.Len(t, pipeErr.Chunks, 1)
+			require.Equal(t, 0, pipeErr.Chunks[0].Start)
+			require.Equal(t, 2, pipeErr.Chunks[0].End)
+			require.True(t, pipeErr.Chunks[0].Sent)
+
+			require.Error(t, incr.Err())
+			require.Equal(t, "1", get.Val())
+			require.Equal(t, "OK", set.Val())
+			require.Equal(t, int64(1), raw.Val())
+			require.Equal(t, "1", fake.get("counter"))
+		})
+	}
+}
+
+func TestPipelineNoChunkLimit(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			fake := newFakeTx(t)
+			defer fake.Close()
+
+			cli, err := New(Config{
+				EngineType:        engineType,
+				Address:           fake.Addr(),
+				PipelineChunkSize: -1,
+			})
+			require.NoError(t, err)
+
+			p := cli.Pipeline(3, 0)
+			defer p.Close()
+
+			fake.setDrops(1)
+			p.Incr("counter")
+			p.Get("counter")
+			p.AddRawCmd("INCR", "other")
+
+			// the whole pipeline is a single chunk
+			_, _, err = p.Exec()
+			pipeErr, ok := err.(*PipelineError)
+			require.True(t, ok, "%v", err)
+			require.Len(t, pipeErr.Chunks, 1)
+			require.Equal(t, 0, pipeErr.Chunks[0].Start)
+			require.Equal(t, 3, pipeErr.Chunks[0].End)
+		})
+	}
+}
+
+func TestPipelineRetryNotSent(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			mr, err := miniredis.Run()
+			require.NoError(t, err)
+
+			// no idle connection, the pipeline fails to dial
+			cli, err := New(Config{
+				EngineType:     engineType,
+				Address:        mr.Addr(),
+				NoPingOnCreate: true,
+			})
+			require.NoError(t, err)
+			mr.Close()
+
+			p := cli.Pipeline(2, 0)
+			defer p.Close()
+
+			incr := p.Incr("counter")
+			_, firstErr, err := p.Exec()
+			require.Equal(t, 0, firstErr)
+			require.Error(t, incr.Err())
+
+			// nothing is sent, the commands could be safely retried by the caller
+			pipeErr, ok := err.(*PipelineError)
+			require.True(t, ok, "%v", err)
+			require.Len(t, pipeErr.Chunks, 1)
+			require.False(t, pipeErr.Chunks[0].Sent)
+			require.Equal(t, incr.Err(), pipeErr.Chunks[0].Err)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// PubSub alias of engine.PubSub, the caller don't have to import engine
+type PubSub = engine.PubSub
+
+// Message alias of engine.Message, the caller don't have to import engine
+type Message = engine.Message
+
+// Listen calls the handler for each of the messages received by the PubSub,
+// until the ctx is done or the PubSub is closed.
+// It returns ctx.Err() if the ctx is done, the PubSub is not closed in that case.
+func Listen(ctx context.Context, ps PubSub, handler func(msg Message)) error {
+	msgCh := ps.Channel()
+	for {
+		select {
+		case msg, ok := <-msgCh:
+			if !ok {
+				return nil
+			}
+			handler(msg)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// SubscribeFunc subscribes the channels and calls the handler for each of the received messages,
+// until the ctx is done. The subscription is closed before it returns.
+func SubscribeFunc(ctx context.Context, cli Redis, handler func(msg Message), channels ...string) error {
+	ps, err := cli.SubscribeContext(ctx, channels...)
+	if err != nil {
+		return err
+	}
+	defer ps.Close()
+
+	return Listen(ctx, ps, handler)
+}
+
+// PSubscribeFunc subscribes the channels matching the patterns and calls the handler
+// for each of the received messages, until the ctx is done. The subscription is closed before it returns.
+func PSubscribeFunc(ctx context.Context, cli Redis, handler func(msg Message), patterns ...string) error {
+	ps, err := cli.PSubscribeContext(ctx, patterns...)
+	if err != nil {
+		return err
+	}
+	defer ps.Close()
+
+	return Listen(ctx, ps, handler)
+}
@@ -0,0 +1,411 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakePubSub is minimal redis server supporting only PING & the pub/sub commands,
+// miniredis doesn't support pub/sub yet
+type fakePubSub struct {
+	lis net.Listener
+
+	mux        sync.Mutex
+	conns      map[*fakePubSubConn]struct{}
+	totalConns int
+	mute       bool // doesn't reply PING if true
+}
+
+type fakePubSubConn struct {
+	conn     net.Conn
+	w        *bufio.Writer
+	channels map[string]struct{}
+	patterns map[string]struct{}
+}
+
+func newFakePubSub(t *testing.T) *fakePubSub {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakePubSub{
+		lis:   lis,
+		conns: make(map[*fakePubSubConn]struct{}),
+	}
+	go f.serve()
+	return f
+}
+
+func (f *fakePubSub) Addr() string {
+	return f.lis.Addr().String()
+}
+
+func (f *fakePubSub) Close() {
+	f.lis.Close()
+	f.dropConns()
+}
+
+func (f *fakePubSub) serve() {
+	for {
+		conn, err := f.lis.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &fakePubSubConn{
+			conn:     conn,
+			w:        bufio.NewWriter(conn),
+			channels: make(map[string]struct{}),
+			patterns: make(map[string]struct{}),
+		}
+		f.mux.Lock()
+		f.conns[c] = struct{}{}
+		f.totalConns++
+		f.mux.Unlock()
+
+		go f.serveConn(c)
+	}
+}
+
+func (f *fakePubSub) serveConn(c *fakePubSubConn) {
+	defer func() {
+		f.mux.Lock()
+		delete(f.conns, c)
+		f.mux.Unlock()
+		c.conn.Close()
+	}()
+
+	r := bufio.NewReader(c.conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		f.mux.Lock()
+		f.handle(c, strings.ToUpper(args[0]), args[1:])
+		c.w.Flush()
+		f.mux.Unlock()
+	}
+}
+
+// handle handles the command, it must be called with the lock held
+func (f *fakePubSub) handle(c *fakePubSubConn, cmd string, args []string) {
+	subscribed := len(c.channels)+len(c.patterns) > 0
+
+	switch cmd {
+	case "PING":
+		switch {
+		case f.mute:
+		case subscribed:
+			payload := ""
+			if len(args) > 0 {
+				payload = args[0]
+			}
+			writeArray(c.w, "pong", payload)
+		default:
+			fmt.Fprint(c.w, "+PONG\r\n")
+		}
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		set := c.channels
+		if cmd == "PSUBSCRIBE" {
+			set = c.patterns
+		}
+		for _, arg := range args {
+			set[arg] = struct{}{}
+			writeArray(c.w, strings.ToLower(cmd), arg, len(c.channels)+len(c.patterns))
+		}
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		set := c.channels
+		if cmd == "PUNSUBSCRIBE" {
+			set = c.patterns
+		}
+		if len(args) == 0 {
+			for arg := range set {
+				args = append(args, arg)
+			}
+		}
+		for _, arg := range args {
+			delete(set, arg)
+			writeArray(c.w, strings.ToLower(cmd), arg, len(c.channels)+len(c.patterns))
+		}
+	case "PUBLISH":
+		fmt.Fprintf(c.w, ":%d\r\n", f.publish(args[0], args[1]))
+	default:
+		fmt.Fprintf(c.w, "-ERR unknown command '%s'\r\n", cmd)
+	}
+}
+
+// publish sends the message to the subscribers, it must be called with the lock held
+func (f *fakePubSub) publish(channel, payload string) int {
+	var n int
+	for c := range f.conns {
+		if _, ok := c.channels[channel]; ok {
+			writeArray(c.w, "message", channel, payload)
+			n++
+		}
+		for pattern := range c.patterns {
+			if ok, _ := path.Match(pattern, channel); ok {
+				writeArray(c.w, "pmessage", pattern, channel, payload)
+				n++
+			}
+		}
+		c.w.Flush()
+	}
+	return n
+}
+
+// numSubscribers returns number of the connections subscribing the channel or pattern
+func (f *fakePubSub) numSubscribers(channelOrPattern string) int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	var n int
+	for c := range f.conns {
+		_, ok1 := c.channels[channelOrPattern]
+		_, ok2 := c.patterns[channelOrPattern]
+		if ok1 || ok2 {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakePubSub) dropConns() {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for c := range f.conns {
+		c.conn.Close()
+		delete(f.conns, c)
+	}
+}
+
+func (f *fakePubSub) setMute(mute bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.mute = mute
+}
+
+func (f *fakePubSub) getTotalConns() int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.totalConns
+}
+
+// readCommand reads a command sent as array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[0] != '*' {
+		return nil, fmt.Errorf("invalid command: %q", line)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// writeArray writes array of bulk strings & integers
+func writeArray(w io.Writer, vals ...interface{}) {
+	fmt.Fprintf(w, "*%d\r\n", len(vals))
+	for _, val := range vals {
+		switch v := val.(type) {
+		case int:
+			fmt.Fprintf(w, ":%d\r\n", v)
+		default:
+			s := fmt.Sprint(v)
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+		}
+	}
+}
+
+// waitFor waits until the condition is true, or fails the test after a second
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met after a second")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func receiveMessage(t *testing.T, ps PubSub) Message {
+	select {
+	case msg, ok := <-ps.Channel():
+		require.True(t, ok, "channel is closed")
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message received after a second")
+	}
+	return Message{}
+}
+
+func TestPubSub(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			fake := newFakePubSub(t)
+			defer fake.Close()
+
+			cli, err := New(Config{
+				EngineType: engineType,
+				Address:    fake.Addr(),
+			})
+			require.NoError(t, err)
+
+			ps, err := cli.Subscribe("foo")
+			require.NoError(t, err)
+			defer ps.Close()
+
+			require.NoError(t, ps.PSubscribe("ba*"))
+			waitFor(t, func() bool { return fake.numSubscribers("ba*") == 1 })
+
+			n, err := cli.Publish("foo", "1")
+			require.NoError(t, err)
+			require.Equal(t, int64(1), n)
+			require.Equal(t, Message{Channel: "foo", Payload: "1"}, receiveMessage(t, ps))
+
+			_, err = cli.Publish("bar", 2)
+			require.NoError(t, err)
+			require.Equal(t, Message{Channel: "bar", Pattern: "ba*", Payload: "2"}, receiveMessage(t, ps))
+
+			// resubscribe after the connection is dropped
+			fake.dropConns()
+			waitFor(t, func() bool {
+				return fake.numSubscribers("foo") == 1 && fake.numSubscribers("ba*") == 1
+			})
+
+			fake.mux.Lock()
+			fake.publish("foo", "3")
+			fake.mux.Unlock()
+			require.Equal(t, Message{Channel: "foo", Payload: "3"}, receiveMessage(t, ps))
+
+			require.NoError(t, ps.Unsubscribe())
+			require.NoError(t, ps.PUnsubscribe("ba*"))
+			waitFor(t, func() bool {
+				return fake.numSubscribers("foo") == 0 && fake.numSubscribers("ba*") == 0
+			})
+
+			require.NoError(t, ps.Close())
+			select {
+			case _, ok := <-ps.Channel():
+				require.False(t, ok)
+			case <-time.After(time.Second):
+				t.Fatal("channel is not closed after a second")
+			}
+		})
+	}
+}
+
+func TestPubSubDuplicates(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			fake := newFakePubSub(t)
+			defer fake.Close()
+
+			cli, err := New(Config{
+				EngineType: engineType,
+				Address:    fake.Addr(),
+			})
+			require.NoError(t, err)
+
+			// it would wait for the confirmations which never come if the duplicates were counted
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			ps, err := cli.SubscribeContext(ctx, "foo", "foo")
+			require.NoError(t, err)
+			defer ps.Close()
+			require.Equal(t, 1, fake.numSubscribers("foo"))
+
+			ps, err = cli.PSubscribeContext(ctx, "ba*", "ba*")
+			require.NoError(t, err)
+			defer ps.Close()
+			require.Equal(t, 1, fake.numSubscribers("ba*"))
+		})
+	}
+}
+
+func TestPubSubHealthCheck(t *testing.T) {
+	fake := newFakePubSub(t)
+	defer fake.Close()
+
+	cli, err := New(Config{
+		Address:            fake.Addr(),
+		PubSubPingPeriodMs: 10,
+	})
+	require.NoError(t, err)
+
+	ps, err := cli.Subscribe("foo")
+	require.NoError(t, err)
+	defer ps.Close()
+
+	// the server is not responding, the connection must be replaced
+	conns := fake.getTotalConns()
+	fake.setMute(true)
+	waitFor(t, func() bool { return fake.getTotalConns() > conns })
+	fake.setMute(false)
+
+	waitFor(t, func() bool { return fake.numSubscribers("foo") == 1 })
+	fake.mux.Lock()
+	fake.publish("foo", "1")
+	fake.mux.Unlock()
+	require.Equal(t, Message{Channel: "foo", Payload: "1"}, receiveMessage(t, ps))
+}
+
+func TestSubscribeFunc(t *testing.T) {
+	fake := newFakePubSub(t)
+	defer fake.Close()
+
+	cli, err := New(Config{Address: fake.Addr()})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgCh := make(chan Message, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- SubscribeFunc(ctx, cli, func(msg Message) {
+			msgCh <- msg
+		}, "foo")
+	}()
+
+	waitFor(t, func() bool { return fake.numSubscribers("foo") == 1 })
+	_, err = cli.Publish("foo", "1")
+	require.NoError(t, err)
+	require.Equal(t, Message{Channel: "foo", Payload: "1"}, <-msgCh)
+
+	cancel()
+	require.Equal(t, context.Canceled, <-errCh)
+	waitFor(t, func() bool { return fake.numSubscribers("foo") == 0 })
+}
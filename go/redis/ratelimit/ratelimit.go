@@ -0,0 +1,387 @@
+// Package ratelimit provides rate limiter on top of redis.
+//
+// Each limit check is a single lua script execution, so the check & the update of the counter
+// are atomic even if the limited key is shared by many processes.
+//
+// The supported algorithms:
+//   - fixed_window: counts the requests in fixed windows (e.g. each minute), cheapest but allows
+//     twice of the rate at the window boundary.
+//   - sliding_window: keeps log of the requests in the last period using sorted set, exact
+//     but the memory usage grows with the rate.
+//   - gcra: generic cell rate algorithm (token bucket), smooths the requests over the period
+//     and allows burst, using a single timestamp per key.
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/defaults"
+	"github.com/boxofimagination/bxdk/go/log"
+	"github.com/boxofimagination/bxdk/go/redis"
+)
+
+// Algorithm of the rate limiter
+type Algorithm string
+
+// supported algorithms
+const (
+	FixedWindow   Algorithm = "fixed_window"
+	SlidingWindow Algorithm = "sliding_window"
+	GCRA          Algorithm = "gcra"
+)
+
+// FailurePolicy decides the result of the limit check when redis is unavailable
+type FailurePolicy string
+
+// supported failure policies
+const (
+	// FailError returns the redis error to the caller
+	FailError FailurePolicy = "error"
+
+	// FailOpen allows the request, the limit is not enforced while redis is unavailable
+	FailOpen FailurePolicy = "open"
+
+	// FailClosed denies the request, nothing is allowed while redis is unavailable
+	FailClosed FailurePolicy = "closed"
+)
+
+var (
+	// ErrInvalidLimit returned when the rate or the period of the limit is not positive
+	ErrInvalidLimit = errors.New("rate and period of the limit must be positive")
+
+	// ErrInvalidN returned when the number of the requests is not positive
+	ErrInvalidN = errors.New("number of the requests must be positive")
+
+	// ErrInvalidAlgorithm returned when the algorithm is not supported
+	ErrInvalidAlgorithm = errors.New("invalid rate limit algorithm")
+
+	// ErrInvalidFailurePolicy returned when the failure policy is not supported
+	ErrInvalidFailurePolicy = errors.New("invalid rate limit failure policy")
+)
+
+var (
+	fixedWindowScript = redis.NewScript(`
+		local count = redis.call('INCRBY', KEYS[1], ARGV[1])
+		if count == tonumber(ARGV[1]) then
+			redis.call('PEXPIRE', KEYS[1], ARGV[2])
+		end
+		return count`)
+
+	// the times are in microsecond
+	slidingWindowScript = redis.NewScript(`
+		local rate = tonumber(ARGV[1])
+		local period = tonumber(ARGV[2])
+		local n = tonumber(ARGV[3])
+		local now = tonumber(ARGV[4])
+
+		redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - period)
+		local count = redis.call('ZCARD', KEYS[1])
+		if count + n > rate then
+			local retry_after = period
+			local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+			if oldest[2] and n <= rate then
+				retry_after = tonumber(oldest[2]) + period - now
+			end
+			return {0, rate - count, retry_after}
+		end
+
+		for i = 1, n do
+			redis.call('ZADD', KEYS[1], now, ARGV[5] .. ':' .. i)
+		end
+		redis.call('PEXPIRE', KEYS[1], math.ceil(period / 1000))
+		return {1, rate - count - n, 0}`)
+
+	// the times are in microsecond
+	gcraScript = redis.NewScript(`
+		local interval = tonumber(ARGV[1])
+		local burst = tonumber(ARGV[2])
+		local n = tonumber(ARGV[3])
+		local now = tonumber(ARGV[4])
+		local tolerance = interval * burst
+
+		local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+		if tat < now then
+			tat = now
+		end
+
+		local new_tat = tat + interval * n
+		local allow_at = new_tat - tolerance
+		if allow_at > now then
+			return {0, math.floor((now - tat + tolerance) / interval), allow_at - now, tat - now}
+		end
+
+		redis.call('SET', KEYS[1], string.format('%d', new_tat), 'PX', math.max(1, math.ceil((new_tat - now) / 1000)))
+		return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}`)
+)
+
+// Limit is maximum number of the requests allowed in the period
+type Limit struct {
+	Rate   int
+	Period time.Duration
+
+	// Burst is maximum number of the requests allowed at once, only for gcra.
+	// It is the same as the Rate if it is empty
+	Burst int
+}
+
+// PerSecond returns limit of the rate per second
+func PerSecond(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Second}
+}
+
+// PerMinute returns limit of the rate per minute
+func PerMinute(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Minute}
+}
+
+// PerHour returns limit of the rate per hour
+func PerHour(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Hour}
+}
+
+// Result of the limit check
+type Result struct {
+	// Allowed is true if the request is allowed
+	Allowed bool
+
+	// Remaining is number of the requests which are still allowed right now
+	Remaining int
+
+	// RetryAfter is the duration until the request would be allowed, it is 0 if the request is allowed
+	RetryAfter time.Duration
+
+	// ResetAfter is the duration until the whole limit is available again
+	ResetAfter time.Duration
+}
+
+// Config defines configuration of the rate limiter
+type Config struct {
+	// Algorithm of the limiter.
+	// The supported values : fixed_window,sliding_window,gcra
+	Algorithm Algorithm `yaml:"algorithm" default:"gcra"`
+
+	// Prefix of the redis keys
+	Prefix string `yaml:"prefix" default:"ratelimit:"`
+
+	// FailurePolicy decides the result when redis is unavailable.
+	// The supported values : error,open,closed
+	FailurePolicy FailurePolicy `yaml:"failure_policy" default:"error"`
+}
+
+// Limiter is rate limiter of the keys, e.g. the user IDs or the client IPs
+type Limiter struct {
+	cli redis.Redis
+	cfg Config
+
+	// now returns the current time, it is replaced in the tests
+	now func() time.Time
+}
+
+// New creates rate limiter from the given config
+func New(cli redis.Redis, cfg Config) (*Limiter, error) {
+	if err := defaults.SetDefault(&cfg); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Algorithm {
+	case FixedWindow, SlidingWindow, GCRA:
+	default:
+		return nil, ErrInvalidAlgorithm
+	}
+
+	switch cfg.FailurePolicy {
+	case FailError, FailOpen, FailClosed:
+	default:
+		return nil, ErrInvalidFailurePolicy
+	}
+
+	return &Limiter{
+		cli: cli,
+		cfg: cfg,
+		now: time.Now,
+	}, nil
+}
+
+// Allow checks whether a request of the key is allowed by the limit
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	return l.AllowN(ctx, key, limit, 1)
+}
+
+// AllowN checks whether n requests of the key are allowed by the limit, n must be positive.
+// The denied requests are not counted, except by fixed_window.
+//
+// If redis is unavailable, the result depends on the failure policy:
+// the error is returned as is by `error` policy, otherwise it is only logged.
+func (l *Limiter) AllowN(ctx context.Context, key string, limit Limit, n int) (*Result, error) {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return nil, ErrInvalidLimit
+	}
+	if n <= 0 {
+		return nil, ErrInvalidN
+	}
+
+	var (
+		res *Result
+		err error
+	)
+	switch l.cfg.Algorithm {
+	case FixedWindow:
+		res, err = l.fixedWindow(ctx, key, limit, n)
+	case SlidingWindow:
+		res, err = l.slidingWindow(ctx, key, limit, n)
+	default:
+		res, err = l.gcra(ctx, key, limit, n)
+	}
+	if err == nil {
+		return res, nil
+	}
+
+	switch l.cfg.FailurePolicy {
+	case FailOpen:
+		log.Errorf("ratelimit: failed to check limit of %v, allowing the request: %v", key, err)
+		res = &Result{Allowed: true, Remaining: limit.Rate - n}
+		if res.Remaining < 0 {
+			res.Remaining = 0
+		}
+		return res, nil
+	case FailClosed:
+		log.Errorf("ratelimit: failed to check limit of %v, denying the request: %v", key, err)
+		return &Result{RetryAfter: limit.Period, ResetAfter: limit.Period}, nil
+	}
+	return nil, err
+}
+
+// Reset removes the counter of the key, so the whole limit is available again.
+// The limit is only used by fixed_window, to find the current window
+func (l *Limiter) Reset(ctx context.Context, key string, limit Limit) error {
+	redisKey := l.cfg.Prefix + key
+	if l.cfg.Algorithm == FixedWindow {
+		redisKey, _ = l.window(key, limit)
+	}
+	_, err := l.cli.DeleteContext(ctx, redisKey)
+	return err
+}
+
+// window returns key of the current fixed window, and the duration until the window ends
+func (l *Limiter) window(key string, limit Limit) (string, time.Duration) {
+	now := l.now().UnixNano()
+	window := now / int64(limit.Period)
+	return fmt.Sprintf("%v%v:%d", l.cfg.Prefix, key, window), time.Duration((window+1)*int64(limit.Period) - now)
+}
+
+func (l *Limiter) fixedWindow(ctx context.Context, key string, limit Limit, n int) (*Result, error) {
+	windowKey, resetAfter := l.window(key, limit)
+	reply, err := fixedWindowScript.RunContext(ctx, l.cli, []string{windowKey}, n, milliseconds(limit.Period))
+	if err != nil {
+		return nil, err
+	}
+
+	count, ok := reply.(int64)
+	if !ok {
+		return nil, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+	}
+
+	res := &Result{
+		Allowed:    int(count) <= limit.Rate,
+		Remaining:  limit.Rate - int(count),
+		ResetAfter: resetAfter,
+	}
+	if !res.Allowed {
+		res.RetryAfter = resetAfter
+	}
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res, nil
+}
+
+func (l *Limiter) slidingWindow(ctx context.Context, key string, limit Limit, n int) (*Result, error) {
+	member, err := newMember()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := slidingWindowScript.RunContext(ctx, l.cli, []string{l.cfg.Prefix + key},
+		limit.Rate, microseconds(limit.Period), n, l.now().UnixNano()/1000, member)
+	if err != nil {
+		return nil, err
+	}
+
+	vals, err := toInts(reply, 3)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Allowed:    vals[0] == 1,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Microsecond,
+		ResetAfter: limit.Period,
+	}, nil
+}
+
+func (l *Limiter) gcra(ctx context.Context, key string, limit Limit, n int) (*Result, error) {
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Rate
+	}
+	interval := microseconds(limit.Period) / int64(limit.Rate)
+	if interval <= 0 {
+		interval = 1
+	}
+
+	reply, err := gcraScript.RunContext(ctx, l.cli, []string{l.cfg.Prefix + key},
+		interval, burst, n, l.now().UnixNano()/1000)
+	if err != nil {
+		return nil, err
+	}
+
+	vals, err := toInts(reply, 4)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Allowed:    vals[0] == 1,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Microsecond,
+		ResetAfter: time.Duration(vals[3]) * time.Microsecond,
+	}, nil
+}
+
+// toInts converts the script's reply to the integers
+func toInts(reply interface{}, num int) ([]int64, error) {
+	vals, ok := reply.([]interface{})
+	if !ok || len(vals) != num {
+		return nil, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+	}
+
+	ints := make([]int64, num)
+	for i, val := range vals {
+		if ints[i], ok = val.(int64); !ok {
+			return nil, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+		}
+	}
+	return ints, nil
+}
+
+// newMember generates random member of the sliding window log,
+// so the concurrent requests at the same time are not deduplicated by the sorted set
+func newMember() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func microseconds(d time.Duration) int64 {
+	return int64(d / time.Microsecond)
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis"
+)
+
+// clock is fake clock of the limiter
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(t *testing.T, cfg Config) (*Limiter, *miniredis.Miniredis, *clock) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	cli, err := redis.New(redis.Config{Address: mr.Addr()})
+	require.NoError(t, err)
+
+	l, err := New(cli, cfg)
+	require.NoError(t, err)
+
+	c := &clock{now: time.Unix(1599999960, 0)}
+	l.now = c.Now
+	return l, mr, c
+}
+
+func TestFixedWindow(t *testing.T) {
+	l, mr, c := newTestLimiter(t, Config{Algorithm: FixedWindow})
+	defer mr.Close()
+
+	ctx := context.Background()
+	limit := PerMinute(2)
+	c.Add(15 * time.Second)
+
+	for i := 1; i >= 0; i-- {
+		res, err := l.Allow(ctx, "user", limit)
+		require.NoError(t, err)
+		require.Equal(t, &Result{Allowed: true, Remaining: i, ResetAfter: 45 * time.Second}, res)
+	}
+
+	res, err := l.Allow(ctx, "user", limit)
+	require.NoError(t, err)
+	require.Equal(t, &Result{RetryAfter: 45 * time.Second, ResetAfter: 45 * time.Second}, res)
+
+	// the next window
+	c.Add(45 * time.Second)
+	res, err = l.Allow(ctx, "user", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+
+	require.NoError(t, l.Reset(ctx, "user", limit))
+	res, err = l.AllowN(ctx, "user", limit, 2)
+	require.NoError(t, err)
+	require.Equal(t, 0, res.Remaining)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	l, mr, c := newTestLimiter(t, Config{Algorithm: SlidingWindow})
+	defer mr.Close()
+
+	ctx := context.Background()
+	limit := PerMinute(3)
+
+	res, err := l.AllowN(ctx, "user", limit, 2)
+	require.NoError(t, err)
+	require.Equal(t, &Result{Allowed: true, Remaining: 1, ResetAfter: time.Minute}, res)
+
+	c.Add(20 * time.Second)
+	res, err = l.Allow(ctx, "user", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+
+	// the denied request is not counted
+	res, err = l.Allow(ctx, "user", limit)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, 40*time.Second, res.RetryAfter)
+
+	// the first two requests are out of the window
+	c.Add(40 * time.Second)
+	res, err = l.AllowN(ctx, "user", limit, 2)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+
+	require.NoError(t, l.Reset(ctx, "user", limit))
+	require.False(t, mr.Exists("ratelimit:user"))
+}
+
+func TestGCRA(t *testing.T) {
+	l, mr, c := newTestLimiter(t, Config{})
+	defer mr.Close()
+
+	ctx := context.Background()
+	// a request per second, with burst of 2
+	limit := Limit{Rate: 10, Period: 10 * time.Second, Burst: 2}
+
+	res, err := l.Allow(ctx, "user", limit)
+	require.NoError(t, err)
+	require.Equal(t, &Result{Allowed: true, Remaining: 1, ResetAfter: time.Second}, res)
+
+	res, err = l.Allow(ctx, "user", limit)
+	require.NoError(t, err)
+	require.Equal(t, &Result{Allowed: true, Remaining: 0, ResetAfter: 2 * time.Second}, res)
+
+	res, err = l.Allow(ctx, "user", limit)
+	require.NoError(t, err)
+	require.Equal(t, &Result{RetryAfter: time.Second, ResetAfter: 2 * time.Second}, res)
+
+	// a request is allowed after the emission interval
+	c.Add(time.Second)
+	res, err = l.Allow(ctx, "user", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+
+	c.Add(10 * time.Second)
+	res, err = l.AllowN(ctx, "user", limit, 3)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, 2, res.Remaining)
+}
+
+func TestFailurePolicy(t *testing.T) {
+	ctx := context.Background()
+	for policy, allowed := range map[FailurePolicy]bool{FailOpen: true, FailClosed: false} {
+		l, mr, _ := newTestLimiter(t, Config{FailurePolicy: policy})
+		mr.Close()
+
+		res, err := l.Allow(ctx, "user", PerSecond(1))
+		require.NoError(t, err)
+		require.Equal(t, allowed, res.Allowed)
+	}
+
+	// the remaining of the failed open check is not negative
+	l, mr, _ := newTestLimiter(t, Config{FailurePolicy: FailOpen})
+	mr.Close()
+	res, err := l.AllowN(ctx, "user", PerSecond(1), 3)
+	require.NoError(t, err)
+	require.Equal(t, &Result{Allowed: true}, res)
+
+	l, mr, _ = newTestLimiter(t, Config{})
+	mr.Close()
+	_, err = l.Allow(ctx, "user", PerSecond(1))
+	require.Error(t, err)
+}
+
+func TestAllowInvalidN(t *testing.T) {
+	for _, algorithm := range []Algorithm{FixedWindow, SlidingWindow, GCRA} {
+		l, mr, _ := newTestLimiter(t, Config{Algorithm: algorithm})
+
+		for _, n := range []int{0, -1} {
+			_, err := l.AllowN(context.Background(), "user", PerSecond(10), n)
+			require.Equal(t, ErrInvalidN, err, algorithm)
+		}
+		require.Empty(t, mr.Keys())
+		mr.Close()
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	_, err := New(nil, Config{Algorithm: "leaky_bucket"})
+	require.Equal(t, ErrInvalidAlgorithm, err)
+
+	_, err = New(nil, Config{FailurePolicy: "ignore"})
+	require.Equal(t, ErrInvalidFailurePolicy, err)
+}
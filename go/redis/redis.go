@@ -1,11 +1,15 @@
 package redis
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/boxofimagination/bxdk/go/defaults"
 	"github.com/boxofimagination/bxdk/go/redis/engine"
+	"github.com/boxofimagination/bxdk/go/redis/engine/goredis"
+	"github.com/boxofimagination/bxdk/go/redis/engine/memory"
 	"github.com/boxofimagination/bxdk/go/redis/engine/redigo"
 )
 
@@ -15,6 +19,12 @@ type Config = engine.Config
 var (
 	// ErrNilFiller returned when `GetWithSingleFiller` called with nil filler
 	ErrNilFiltre = errors.New("empty filter")
+
+	// ErrTxFailed returned by `Watch` when the watched keys are still changed after all of the retries
+	ErrTxFailed = engine.ErrTxFailed
+
+	// ErrCircuitOpen returned without executing the command when the circuit breaker is open
+	ErrCircuitOpen = engine.ErrCircuitOpen
 )
 
 
@@ -24,10 +34,58 @@ type Redis = engine.Redis
 // Pipeliner alias of engine.Pipeliner, the caller don't have to import engine
 type Pipeliner = engine.Pipeliner
 
+// Tx alias of engine.Tx, the caller don't have to import engine
+type Tx = engine.Tx
+
+// TxFunc alias of engine.TxFunc, the caller don't have to import engine
+type TxFunc = engine.TxFunc
+
+// Result alias of engine.Result, the caller don't have to import engine
+type Result = engine.Result
+
+// StringResult alias of engine.StringResult, the caller don't have to import engine
+type StringResult = engine.StringResult
+
+// IntResult alias of engine.IntResult, the caller don't have to import engine
+type IntResult = engine.IntResult
+
+// FloatResult alias of engine.FloatResult, the caller don't have to import engine
+type FloatResult = engine.FloatResult
+
+// StringsResult alias of engine.StringsResult, the caller don't have to import engine
+type StringsResult = engine.StringsResult
+
+// PipelineError alias of engine.PipelineError, the caller don't have to import engine
+type PipelineError = engine.PipelineError
+
+// ChunkError alias of engine.ChunkError, the caller don't have to import engine
+type ChunkError = engine.ChunkError
+
+// PoolStats alias of engine.PoolStats, the caller don't have to import engine
+type PoolStats = engine.PoolStats
+
+// ShardNode alias of engine.ShardNode, the caller don't have to import engine
+type ShardNode = engine.ShardNode
+
 
 // Client defines a redis client
 type Client struct {
 	Redis
+
+	// codec encodes the values of the object commands
+	codec Codec
+
+	// fillers of `GetWithSingleFiller`
+	fillers *singleFlight
+
+	// fillerTimeout is timeout of the filler of `GetWithSingleFillerContext`
+	fillerTimeout time.Duration
+
+	// healthCheckTimeout is timeout of the PING sent by `HealthCheck`
+	healthCheckTimeout time.Duration
+
+	// nearCache is the embedded Redis if `NearCache` is enabled
+	nearCache *NearCache
 }
 
 // New creates new redis bxdk library from the given config.
@@ -38,22 +96,91 @@ func New(cfg Config) (*Client, error ) {
 		return nil, err
 	}
 
+	// make sure the TLS config is valid before creating the engine,
+	// the engine could only return the error when it dials the server.
+	if _, err = cfg.LoadTLSConfig(); err != nil {
+		return nil, err
+	}
+
+	codec, err := getCodec(cfg.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkRedigoOnly(cfg); err != nil {
+		return nil, err
+	}
+
 	var eng Redis
 
 	switch cfg.EngineType {
 	case engine.Redigo:
 		eng = redigo.New(cfg)
+	case engine.GoRedis:
+		eng = goredis.New(cfg)
+	case engine.Memory:
+		eng = memory.New(cfg)
 	default:
 		return nil, fmt.Errorf("invalid engine type: %v", cfg.EngineType)
 	}
 
+	eng.AddHook(scriptHook{})
+
 	if !cfg.NoPingOnCreate {
 		if _, err = eng.Ping(); err != nil {
 			return nil, err
 		}
 	}
 
+	var nearCache *NearCache
+	if cfg.NearCache {
+		if nearCache, err = newNearCache(eng, cfg); err != nil {
+			return nil, err
+		}
+		eng = nearCache
+	}
+
 	return &Client{
-		Redis: eng,
+		Redis:              eng,
+		codec:              codec,
+		fillers:            newSingleFlight(),
+		healthCheckTimeout: time.Duration(cfg.HealthCheckTimeoutMs) * time.Millisecond,
+		fillerTimeout:      time.Duration(cfg.FillerTimeoutMs) * time.Millisecond,
+		nearCache:          nearCache,
 	}, nil
+}
+
+// checkRedigoOnly returns error if the config has the options supported only by redigo engine,
+// which would be silently ignored by the other engines, e.g. writing the keys outside of the namespace
+func checkRedigoOnly(cfg Config) error {
+	if cfg.EngineType != engine.GoRedis && cfg.EngineType != engine.Memory {
+		return nil
+	}
+	if cfg.KeyPrefix != "" {
+		return fmt.Errorf("key prefix is not supported by %v engine", cfg.EngineType)
+	}
+	if len(cfg.ShardNodes) > 0 {
+		return fmt.Errorf("shard nodes are not supported by %v engine", cfg.EngineType)
+	}
+	return nil
+}
+
+// NearCache returns the near cache, e.g. to get its stats, or nil if `NearCache` is disabled
+func (c *Client) NearCache() *NearCache {
+	return c.nearCache
+}
+
+// HealthCheck pings the server, e.g. for the readiness probe, and returns latency of the PING.
+// The PING times out after `HealthCheckTimeoutMs`, or the ctx deadline if it comes earlier.
+// Use `Stats` to check whether the connection pool is exhausted.
+func (c *Client) HealthCheck(ctx context.Context) (time.Duration, error) {
+	if c.healthCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.healthCheckTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	_, err := c.PingContext(ctx)
+	return time.Since(start), err
 }
\ No newline at end of file
@@ -0,0 +1,855 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+	"github.com/boxofimagination/bxdk/go/redis/engine/memory"
+)
+
+// engineTypes are the engines which must pass the conformance tests
+var engineTypes = []engine.Type{
+	engine.Redigo,
+	engine.GoRedis,
+}
+
+func newTestClient(t testing.TB, engineType engine.Type) (*Client, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	cli, err := New(Config{
+		EngineType: engineType,
+		Address:    mr.Addr(),
+	})
+	require.NoError(t, err)
+
+	return cli, mr
+}
+
+// TestConformance runs the same test cases against all the engines,
+// making sure that the engines are interchangeable.
+func TestConformance(t *testing.T) {
+	testCases := []struct {
+		name string
+		fn   func(t *testing.T, cli *Client, mr *miniredis.Miniredis)
+	}{
+		{"string", testString},
+		{"hash", testHash},
+		{"int", testInt},
+		{"key", testKey},
+		{"list", testList},
+		{"set", testSet},
+		{"sorted set", testSortedSet},
+		{"scan", testScan},
+		{"pipeline", testPipeline},
+		{"pipeline_read", testPipelineRead},
+		{"script", testScript},
+		{"transaction", testTx},
+		{"object", testObject},
+		{"context", testContext},
+	}
+
+	for _, engineType := range engineTypes {
+		for _, tc := range testCases {
+			t.Run(string(engineType)+"/"+tc.name, func(t *testing.T) {
+				cli, mr := newTestClient(t, engineType)
+				defer mr.Close()
+
+				tc.fn(t, cli, mr)
+			})
+		}
+	}
+}
+
+func TestNewInvalidEngine(t *testing.T) {
+	_, err := New(Config{EngineType: "unknown"})
+	require.Error(t, err)
+}
+
+func TestNewRedigoOnlyConfig(t *testing.T) {
+	for _, engineType := range []engine.Type{engine.GoRedis, engine.Memory} {
+		_, err := New(Config{EngineType: engineType, KeyPrefix: "svc:", NoPingOnCreate: true})
+		require.Error(t, err, engineType)
+
+		_, err = New(Config{
+			EngineType:     engineType,
+			ShardNodes:     []ShardNode{{Address: "localhost:6379"}},
+			NoPingOnCreate: true,
+		})
+		require.Error(t, err, engineType)
+	}
+}
+
+func TestNewMemoryEngine(t *testing.T) {
+	// no server is needed
+	cli, err := New(Config{EngineType: engine.Memory})
+	require.NoError(t, err)
+
+	require.NoError(t, cli.SetObject("obj", map[string]int{"a": 1}))
+	var obj map[string]int
+	require.NoError(t, cli.GetObject("obj", &obj))
+	require.Equal(t, map[string]int{"a": 1}, obj)
+
+	mem := cli.Redis.(*memory.Memory)
+	require.Equal(t, []string{"obj"}, mem.Keys())
+}
+
+func TestStatsAndHealthCheck(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			cli, mr := newTestClient(t, engineType)
+			defer mr.Close()
+
+			latency, err := cli.HealthCheck(context.Background())
+			require.NoError(t, err)
+			require.True(t, latency > 0)
+
+			stats := cli.Stats()
+			require.Equal(t, 1, stats.ActiveCount)
+			require.Equal(t, 1, stats.IdleCount)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = cli.HealthCheck(ctx)
+			require.Equal(t, context.Canceled, err)
+
+			mr.Close()
+			_, err = cli.HealthCheck(context.Background())
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			mr, err := miniredis.Run()
+			require.NoError(t, err)
+			defer mr.Close()
+
+			cli, err := New(Config{
+				EngineType:                        engineType,
+				Address:                           mr.Addr(),
+				CircuitBreaker:                    true,
+				CircuitBreakerConsecutiveFailures: 2,
+				CircuitBreakerOpenMs:              50,
+			})
+			require.NoError(t, err)
+
+			// the errors replied by the server don't count
+			require.NoError(t, cli.Set("key", "value"))
+			_, err = cli.Incr("key")
+			require.Error(t, err)
+			_, err = cli.Incr("key")
+			require.Error(t, err)
+			_, err = cli.Get("missing")
+			require.True(t, cli.IsErrNil(err))
+
+			mr.Close()
+			for i := 0; i < 2; i++ {
+				_, err = cli.Get("key")
+				require.Error(t, err)
+				require.NotEqual(t, ErrCircuitOpen, err)
+			}
+
+			_, err = cli.Get("key")
+			require.Equal(t, ErrCircuitOpen, err)
+
+			p := cli.Pipeline(1, 2)
+			get := p.Get("key")
+			_, _, err = p.Exec()
+			require.True(t, errors.Is(err, ErrCircuitOpen))
+			require.Equal(t, ErrCircuitOpen, get.Err())
+
+			require.NoError(t, mr.Restart())
+			time.Sleep(60 * time.Millisecond)
+
+			// the probe closes the circuit
+			_, err = cli.Get("key")
+			require.NoError(t, err)
+			_, err = cli.Get("key")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNewInvalidTLSConfig(t *testing.T) {
+	_, err := New(Config{
+		TLS:           true,
+		TLSCACertFile: "/not/exist/ca.pem",
+	})
+	require.Error(t, err)
+}
+
+func TestAuthAndSelectDB(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			mr, err := miniredis.Run()
+			require.NoError(t, err)
+			defer mr.Close()
+
+			mr.RequireAuth("secret")
+
+			_, err = New(Config{
+				EngineType: engineType,
+				Address:    mr.Addr(),
+				Password:   "wrong",
+			})
+			require.Error(t, err)
+
+			cli, err := New(Config{
+				EngineType:     engineType,
+				Address:        mr.Addr(),
+				Password:       "secret",
+				DB:             2,
+				ReadTimeoutMs:  1000,
+				WriteTimeoutMs: 1000,
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, cli.Set("foo", "bar"))
+
+			val, err := mr.DB(2).Get("foo")
+			require.NoError(t, err)
+			require.Equal(t, "bar", val)
+			require.False(t, mr.DB(0).Exists("foo"))
+		})
+	}
+}
+
+func testString(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	_, err := cli.Get("str")
+	require.True(t, cli.IsErrNil(err))
+
+	require.NoError(t, cli.Set("str", "value"))
+	val, err := cli.Get("str")
+	require.NoError(t, err)
+	require.Equal(t, "value", val)
+
+	n, err := cli.Append("str", "-appended")
+	require.NoError(t, err)
+	require.Equal(t, len("value-appended"), n)
+
+	resp, err := cli.SetNX("nx", "first", 10)
+	require.NoError(t, err)
+	require.Equal(t, "OK", resp)
+
+	resp, err = cli.SetNX("nx", "second", 10)
+	require.NoError(t, err)
+	require.Equal(t, "", resp)
+
+	resp, err = cli.SetEX("ex", "value", 10)
+	require.NoError(t, err)
+	require.Equal(t, "OK", resp)
+
+	ttl, err := cli.TTL("ex")
+	require.NoError(t, err)
+	require.Equal(t, 10, ttl)
+
+	require.NoError(t, cli.MSet("m1", "v1", "m2", 2))
+	vals, err := cli.MGet("m1", "m2", "m3")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "2", ""}, vals)
+}
+
+func testHash(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	_, err := cli.HGet("hash", "f1")
+	require.True(t, cli.IsErrNil(err))
+
+	resp, err := cli.HMSet("hash", map[string]interface{}{
+		"f1": "v1",
+		"f2": 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "OK", resp)
+
+	val, err := cli.HGet("hash", "f1")
+	require.NoError(t, err)
+	require.Equal(t, "v1", val)
+
+	vals, err := cli.HMGet("hash", "f1", "f2", "f3")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "2", ""}, vals)
+
+	n, err := cli.HSetEX("hash", "f3", "v3", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, "v3", mr.HGet("hash", "f3"))
+
+	n, err = cli.HDel("hash", "f1", "f4")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func testInt(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	n, err := cli.Incr("counter")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	n, err = cli.IncrBy("counter", 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), n)
+
+	n, err = cli.Decr("counter")
+	require.NoError(t, err)
+	require.Equal(t, int64(10), n)
+
+	n, err = cli.DecrBy("counter", 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), n)
+}
+
+func testKey(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	require.NoError(t, mr.Set("k1", "v1"))
+	require.NoError(t, mr.Set("k2", "v2"))
+
+	exists, err := cli.Exists("k1")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	ttl, err := cli.TTL("k1")
+	require.NoError(t, err)
+	require.Equal(t, -1, ttl)
+
+	ttl, err = cli.TTL("not-exist")
+	require.NoError(t, err)
+	require.Equal(t, -2, ttl)
+
+	n, err := cli.Expire("k1", 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = cli.Delete("k1", "k2", "k3")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	exists, err = cli.Exists("k1")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func testList(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	_, err := cli.LPop("list")
+	require.True(t, cli.IsErrNil(err))
+
+	_, err = cli.RPop("list")
+	require.True(t, cli.IsErrNil(err))
+
+	n, err := cli.RPush("list", "b", "c")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	n, err = cli.LPush("list", "a")
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	length, err := cli.LLen("list")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), length)
+
+	vals, err := cli.LRange("list", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, vals)
+
+	val, err := cli.LPop("list")
+	require.NoError(t, err)
+	require.Equal(t, "a", val)
+
+	val, err = cli.RPop("list")
+	require.NoError(t, err)
+	require.Equal(t, "c", val)
+}
+
+func testSet(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	n, err := cli.SAdd("set", "a", "b", "c", "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	n, err = cli.SRem("set", "c", "d")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	members, err := cli.SMembers("set")
+	require.NoError(t, err)
+	sort.Strings(members)
+	require.Equal(t, []string{"a", "b"}, members)
+}
+
+func testSortedSet(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	n, err := cli.ZAdd("zset", engine.ZAddArgs{}, engine.Z{Member: "a", Score: 1}, engine.Z{Member: "b", Score: 2})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	// NX never updates the existing member
+	n, err = cli.ZAdd("zset", engine.ZAddArgs{NX: true, CH: true}, engine.Z{Member: "a", Score: 10}, engine.Z{Member: "c", Score: 3})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// XX never adds new member
+	n, err = cli.ZAdd("zset", engine.ZAddArgs{XX: true, CH: true}, engine.Z{Member: "b", Score: 2.5}, engine.Z{Member: "d", Score: 4})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	score, err := cli.ZIncrBy("zset", 0.5, "c")
+	require.NoError(t, err)
+	require.Equal(t, 3.5, score)
+
+	members, err := cli.ZRange("zset", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, members)
+
+	members, err = cli.ZRevRange("zset", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, members)
+
+	zs, err := cli.ZRangeWithScores("zset", 0, 1)
+	require.NoError(t, err)
+	require.Equal(t, []engine.Z{{Member: "a", Score: 1}, {Member: "b", Score: 2.5}}, zs)
+
+	zs, err = cli.ZRevRangeWithScores("zset", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []engine.Z{{Member: "c", Score: 3.5}}, zs)
+
+	members, err = cli.ZRangeByScore("zset", engine.ZRangeBy{Min: "(1", Max: "+inf"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, members)
+
+	zs, err = cli.ZRangeByScoreWithScores("zset", engine.ZRangeBy{Min: "-inf", Max: "+inf", Offset: 1, Count: 1})
+	require.NoError(t, err)
+	require.Equal(t, []engine.Z{{Member: "b", Score: 2.5}}, zs)
+
+	rank, err := cli.ZRank("zset", "c")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rank)
+
+	_, err = cli.ZRank("zset", "not-exist")
+	require.True(t, cli.IsErrNil(err))
+
+	score, err = cli.ZScore("zset", "b")
+	require.NoError(t, err)
+	require.Equal(t, 2.5, score)
+
+	_, err = cli.ZScore("zset", "not-exist")
+	require.True(t, cli.IsErrNil(err))
+
+	count, err := cli.ZCount("zset", "2", "3.5")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	n, err = cli.ZRem("zset", "a", "not-exist")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	n, err = cli.ZRemRangeByScore("zset", "-inf", "(3")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	card, err := cli.ZCard("zset")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), card)
+
+	// pipeline
+	p := cli.Pipeline(1, 0)
+	p.ZAdd("zset", engine.ZAddArgs{}, engine.Z{Member: "x", Score: 1}, engine.Z{Member: "y", Score: 2})
+	p.ZIncrBy("zset", 1, "x")
+	p.ZRem("zset", "y")
+	p.ZRemRangeByScore("zset", "3", "+inf")
+	_, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+
+	zs, err = cli.ZRangeWithScores("zset", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []engine.Z{{Member: "x", Score: 2}}, zs)
+}
+
+func testScan(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	require.NoError(t, mr.Set("scan:1", "v"))
+	require.NoError(t, mr.Set("scan:2", "v"))
+	require.NoError(t, mr.Set("other", "v"))
+
+	keys, cursor, err := cli.Scan("scan:*", 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), cursor)
+	sort.Strings(keys)
+	require.Equal(t, []string{"scan:1", "scan:2"}, keys)
+
+	var all []string
+	for res := range cli.ScanAll(context.Background(), "scan:*", 10) {
+		require.NoError(t, res.Err)
+		all = append(all, res.Keys...)
+	}
+	sort.Strings(all)
+	require.Equal(t, []string{"scan:1", "scan:2"}, all)
+
+	mr.HSet("hash", "f1", "v1")
+	mr.HSet("hash", "other", "v2")
+	elems, cursor, err := cli.HScan("hash", "f*", 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), cursor)
+	require.Equal(t, []string{"f1", "v1"}, elems)
+
+	_, err = mr.SetAdd("set", "m1", "m2")
+	require.NoError(t, err)
+	elems, _, err = cli.SScan("set", "*", 0, 10)
+	require.NoError(t, err)
+	sort.Strings(elems)
+	require.Equal(t, []string{"m1", "m2"}, elems)
+
+	_, err = mr.ZAdd("zset", 1.5, "m1")
+	require.NoError(t, err)
+	elems, _, err = cli.ZScan("zset", "*", 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"m1", "1.5"}, elems)
+}
+
+func testPipeline(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	require.NoError(t, mr.Set("str", "v"))
+
+	p := cli.Pipeline(1, 0)
+	defer p.Close()
+
+	incr := p.Incr("counter")
+	incrBy := p.IncrBy("counter", 10)
+	failed := p.Incr("str") // must be failed, str is not an integer
+	hmset := p.HMSet("hash", map[string]interface{}{"f1": "v1", "f2": "v2"})
+	p.HDel("hash", "f2")
+	p.Expire("hash", 100)
+	raw := p.AddRawCmd("GET", "not-exist")
+
+	cmdErrs, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Len(t, cmdErrs, 7)
+	require.Equal(t, 2, firstErr)
+	for i, cmdErr := range cmdErrs {
+		if i == firstErr {
+			require.Error(t, cmdErr.Err())
+			continue
+		}
+		require.NoError(t, cmdErr.Err(), cmdErr.Name())
+	}
+
+	require.Equal(t, int64(1), incr.Val())
+	n, err := incrBy.Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(11), n)
+	require.Error(t, failed.Err())
+	require.Equal(t, "OK", hmset.Val())
+	require.True(t, cli.IsErrNil(raw.Err()))
+	require.Nil(t, raw.Val())
+
+	val, err := mr.Get("counter")
+	require.NoError(t, err)
+	require.Equal(t, "11", val)
+	fields, err := mr.HKeys("hash")
+	require.NoError(t, err)
+	require.Equal(t, []string{"f1"}, fields)
+
+	// the pipeline could be reused after execution
+	p.Delete("counter", "hash")
+	_, firstErr, err = p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+	require.False(t, mr.Exists("counter"))
+}
+
+func testPipelineRead(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	require.NoError(t, mr.Set("str", "v"))
+	require.NoError(t, mr.Set("num", "10"))
+	mr.SetTTL("num", 100*time.Second)
+	mr.HSet("hash", "f1", "v1")
+	_, err := mr.Push("list", "a", "b", "c")
+	require.NoError(t, err)
+	_, err = mr.ZAdd("zset", 1, "m1")
+	require.NoError(t, err)
+
+	p := cli.Pipeline(1, 0)
+	defer p.Close()
+
+	get := p.Get("str")
+	getNil := p.Get("not-exist")
+	mget := p.MGet("str", "not-exist", "num")
+	hget := p.HGet("hash", "f1")
+	hgetNil := p.HGet("hash", "not-exist")
+	hmget := p.HMGet("hash", "f1", "not-exist")
+	lrange := p.LRange("list", 0, -1)
+	ttl := p.TTL("num")
+	exists := p.Exists("str", "num", "not-exist")
+	zincr := p.ZIncrBy("zset", 1.5, "m1")
+	eval := p.Eval("return {1, ARGV[1]}", nil, "arg")
+
+	_, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+
+	val, err := get.Result()
+	require.NoError(t, err)
+	require.Equal(t, "v", val)
+
+	_, err = getNil.Result()
+	require.True(t, cli.IsErrNil(err))
+
+	require.Equal(t, []string{"v", "", "10"}, mget.Val())
+	require.Equal(t, "v1", hget.Val())
+	require.True(t, cli.IsErrNil(hgetNil.Err()))
+	require.Equal(t, []string{"v1", ""}, hmget.Val())
+	require.Equal(t, []string{"a", "b", "c"}, lrange.Val())
+	require.Equal(t, int64(100), ttl.Val())
+	require.Equal(t, int64(2), exists.Val())
+	require.Equal(t, 2.5, zincr.Val())
+	require.Equal(t, []interface{}{int64(1), "arg"}, eval.Val())
+}
+
+func testScript(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	require.NoError(t, mr.Set("str", "v"))
+	reply, err := cli.Eval("return {1, 'two', redis.call('GET', KEYS[1]), ARGV[1]}", []string{"str"}, "arg")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), "two", "v", "arg"}, reply)
+
+	_, err = cli.Eval("return nil", nil)
+	require.True(t, cli.IsErrNil(err))
+
+	_, err = cli.Eval("return redis.error_reply('MYERR failed')", nil)
+	require.EqualError(t, err, "MYERR failed")
+
+	// compare-and-delete
+	script := NewScript(`
+		if redis.call('GET', KEYS[1]) == ARGV[1] then
+			return redis.call('DEL', KEYS[1])
+		end
+		return 0`)
+
+	_, err = cli.EvalSha(script.Hash(), []string{"str"}, "v")
+	require.True(t, IsErrNoScript(err))
+
+	// falls back to EVAL
+	reply, err = script.Run(cli, []string{"str"}, "other")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), reply)
+	reply, err = script.Run(cli, []string{"str"}, "v")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), reply)
+	require.False(t, mr.Exists("str"))
+
+	sha1, err := cli.ScriptLoad("return ARGV[1]")
+	require.NoError(t, err)
+	reply, err = cli.EvalSha(sha1, nil, "v")
+	require.NoError(t, err)
+	require.Equal(t, "v", reply)
+
+	// pipelined script uses EVALSHA only after it is loaded
+	p := cli.Pipeline(1, 0)
+	defer p.Close()
+
+	require.NoError(t, mr.Set("str", "v"))
+	script = NewScript("return redis.call('DEL', KEYS[1])")
+	res := script.Pipe(p, []string{"str"})
+	cmdErrs, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+	require.Equal(t, "EVAL", strings.ToUpper(cmdErrs[0].Name()))
+	require.Equal(t, int64(1), res.Val())
+	require.False(t, mr.Exists("str"))
+
+	require.NoError(t, script.Load(cli))
+	res = script.Pipe(p, []string{"str"})
+	cmdErrs, firstErr, err = p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+	require.Equal(t, "EVALSHA", strings.ToUpper(cmdErrs[0].Name()))
+	require.Equal(t, int64(0), res.Val())
+
+	// the server lost the cache, the script falls back to EVAL until it is loaded again
+	_, err = cli.Do("SCRIPT", "FLUSH")
+	require.NoError(t, err)
+	res = script.Pipe(p, []string{"str"})
+	_, firstErr, err = p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, 0, firstErr)
+	require.True(t, IsErrNoScript(res.Err()))
+
+	res = script.Pipe(p, []string{"str"})
+	cmdErrs, _, err = p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, "EVAL", strings.ToUpper(cmdErrs[0].Name()))
+	require.Equal(t, int64(0), res.Val())
+
+	// all of the registered scripts are loaded
+	_, err = cli.Do("SCRIPT", "FLUSH")
+	require.NoError(t, err)
+	require.NoError(t, LoadScripts(context.Background(), cli))
+	res = script.Pipe(p, []string{"str"})
+	cmdErrs, _, err = p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, "EVALSHA", strings.ToUpper(cmdErrs[0].Name()))
+	require.Equal(t, int64(0), res.Val())
+}
+
+func testTx(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	incr := func(tx Tx) error {
+		val, err := tx.Do("GET", "counter")
+		if err != nil && !cli.IsErrNil(err) {
+			return err
+		}
+		n, _ := strconv.Atoi(fmt.Sprint(val))
+		tx.Queue("SET", "counter", n+1)
+		tx.Queue("GET", "counter")
+		return nil
+	}
+
+	replies, err := cli.Watch(incr, 1, "counter")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"OK", "1"}, replies)
+
+	myErr := errors.New("my error")
+	_, err = cli.Watch(func(tx Tx) error {
+		tx.Queue("INCR", "counter")
+		return myErr
+	}, 1, "counter")
+	require.Equal(t, myErr, err)
+
+	val, err := mr.Get("counter")
+	require.NoError(t, err)
+	require.Equal(t, "1", val)
+
+	// the failed command doesn't fail the others
+	require.NoError(t, mr.Set("str", "v"))
+	replies, err = cli.Watch(func(tx Tx) error {
+		tx.Queue("INCR", "str")
+		tx.Queue("INCR", "counter")
+		return nil
+	}, 1, "str", "counter")
+	require.NoError(t, err)
+	require.Len(t, replies, 2)
+	require.Error(t, replies[0].(error))
+	require.Equal(t, int64(2), replies[1])
+
+	replies, err = cli.Watch(func(tx Tx) error { return nil }, 1, "counter")
+	require.NoError(t, err)
+	require.Empty(t, replies)
+}
+
+func testObject(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	type object struct {
+		Name  string
+		Count int
+		Tags  []string
+	}
+	obj := object{Name: "foo", Count: 2, Tags: []string{"a", "b"}}
+
+	require.NoError(t, cli.SetObject("obj", obj))
+	var got object
+	require.NoError(t, cli.GetObject("obj", &got))
+	require.Equal(t, obj, got)
+
+	val, err := mr.Get("obj")
+	require.NoError(t, err)
+	require.Equal(t, JSONCodecVersion, val[0])
+
+	require.NoError(t, cli.SetObjectEX("obj:ex", obj, 10))
+	require.Equal(t, 10*time.Second, mr.TTL("obj:ex"))
+
+	require.NoError(t, cli.HSetObject("hash", "obj", obj))
+	got = object{}
+	require.NoError(t, cli.HGetObject("hash", "obj", &got))
+	require.Equal(t, obj, got)
+
+	require.True(t, cli.IsErrNil(cli.GetObject("none", &got)))
+	require.True(t, cli.IsErrNil(cli.HGetObject("hash", "none", &got)))
+
+	// the existing value is decoded using its codec after the codec is changed
+	cli.codec = MsgpackCodec{}
+	got = object{}
+	require.NoError(t, cli.GetObject("obj", &got))
+	require.Equal(t, obj, got)
+
+	require.NoError(t, cli.SetObject("obj", obj))
+	val, err = mr.Get("obj")
+	require.NoError(t, err)
+	require.Equal(t, MsgpackCodecVersion, val[0])
+
+	require.NoError(t, mr.Set("str", "v"))
+	require.Equal(t, ErrUnknownCodec, cli.GetObject("str", &got))
+}
+
+func testContext(t *testing.T, cli *Client, mr *miniredis.Miniredis) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, cli.SetContext(ctx, "key", "value"))
+	val, err := cli.GetContext(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", val)
+
+	n, err := cli.HSetEXContext(ctx, "hash", "f1", "v1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	p := cli.PipelineContext(ctx, 1, 0)
+	p.Incr("counter")
+	_, firstErr, err := p.Exec()
+	require.NoError(t, err)
+	require.Equal(t, -1, firstErr)
+
+	// cancelled context
+	cancel()
+
+	_, err = cli.GetContext(ctx, "key")
+	require.Equal(t, context.Canceled, err)
+
+	incr := p.Incr("counter")
+	_, _, err = p.Exec()
+	require.Equal(t, &PipelineError{Chunks: []*ChunkError{{Start: 0, End: 1, Err: context.Canceled}}}, err)
+	require.Equal(t, context.Canceled, incr.Err())
+
+	val, err = mr.Get("counter")
+	require.NoError(t, err)
+	require.Equal(t, "1", val)
+}
+
+func BenchmarkGet(b *testing.B) {
+	for _, engineType := range engineTypes {
+		b.Run(string(engineType), func(b *testing.B) {
+			cli, mr := newTestClient(b, engineType)
+			defer mr.Close()
+
+			require.NoError(b, mr.Set("key", "value"))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cli.Get("key")
+			}
+		})
+	}
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, engineType := range engineTypes {
+		b.Run(string(engineType), func(b *testing.B) {
+			cli, mr := newTestClient(b, engineType)
+			defer mr.Close()
+
+			p := cli.Pipeline(1, 10)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < 10; j++ {
+					p.IncrBy("counter", int64(j))
+				}
+				p.Exec()
+			}
+		})
+	}
+}
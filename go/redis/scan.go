@@ -0,0 +1,110 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// ScanAllResult alias of engine.ScanAllResult, the caller don't have to import engine
+type ScanAllResult = engine.ScanAllResult
+
+// scanFunc executes an iteration of the SCAN family command
+type scanFunc func(ctx context.Context, cursor uint64) ([]string, uint64, error)
+
+// ScanAll iterates the whole keyspace using SCAN, and sends the keys matching the pattern
+// to the returned channel, a batch per iteration. `count` is the hint of the batch size.
+//
+// The channel is closed after the cursor returns to 0, or after the error is sent.
+// The iteration is stopped when the ctx is done, ctx.Err() is always sent in that case
+// (a batch fetched before the cancellation might still be sent before it, or dropped).
+// The caller must either read the channel until it is closed, or cancel the ctx.
+//
+// As SCAN guarantees, a key existing during the whole iteration is returned at least once,
+// the caller must handle the duplicate keys.
+func (c *Client) ScanAll(ctx context.Context, pattern string, count int64) <-chan ScanAllResult {
+	return scanAll(ctx, func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		return c.ScanContext(ctx, pattern, cursor, count)
+	})
+}
+
+// ScanAllType is ScanAll which only returns the keys of the given type, e.g. "string", "hash", or "zset".
+// It requires redis 6.0 or later
+func (c *Client) ScanAllType(ctx context.Context, pattern, keyType string, count int64) <-chan ScanAllResult {
+	return scanAll(ctx, func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		return c.ScanTypeContext(ctx, pattern, keyType, cursor, count)
+	})
+}
+
+// HScanAll iterates the hash using HSCAN, the same way as ScanAll.
+// The batches contain field & value pairs, e.g. [field1, value1, field2, value2]
+func (c *Client) HScanAll(ctx context.Context, key, pattern string, count int64) <-chan ScanAllResult {
+	return scanAll(ctx, func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		return c.HScanContext(ctx, key, pattern, cursor, count)
+	})
+}
+
+// SScanAll iterates the set using SSCAN, the same way as ScanAll
+func (c *Client) SScanAll(ctx context.Context, key, pattern string, count int64) <-chan ScanAllResult {
+	return scanAll(ctx, func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		return c.SScanContext(ctx, key, pattern, cursor, count)
+	})
+}
+
+// ZScanAll iterates the sorted set using ZSCAN, the same way as ScanAll.
+// The batches contain member & score pairs, e.g. [member1, score1, member2, score2]
+func (c *Client) ZScanAll(ctx context.Context, key, pattern string, count int64) <-chan ScanAllResult {
+	return scanAll(ctx, func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		return c.ZScanContext(ctx, key, pattern, cursor, count)
+	})
+}
+
+// scanAll calls the scan in a goroutine until the cursor returns to 0, sending the non-empty batches.
+//
+// The channel has a slot, so the final ctx.Err() is always delivered without blocking the goroutine,
+// even if the caller doesn't read anymore after the cancellation.
+func scanAll(ctx context.Context, scan scanFunc) <-chan ScanAllResult {
+	resultCh := make(chan ScanAllResult, 1)
+
+	go func() {
+		defer close(resultCh)
+
+		// send returns false if the ctx is done
+		send := func(res ScanAllResult) bool {
+			select {
+			case resultCh <- res:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var cursor uint64
+		for ctx.Err() == nil {
+			keys, next, err := scan(ctx, cursor)
+			if err != nil {
+				if !send(ScanAllResult{Err: err}) {
+					break
+				}
+				return
+			}
+			if len(keys) > 0 && !send(ScanAllResult{Keys: keys}) {
+				break
+			}
+			if next == 0 {
+				return
+			}
+			cursor = next
+		}
+
+		// the ctx is done, drop the unread batch (if any) so the slot is free.
+		// this goroutine is the only sender, the send below never blocks
+		select {
+		case <-resultCh:
+		default:
+		}
+		resultCh <- ScanAllResult{Err: ctx.Err()}
+	}()
+
+	return resultCh
+}
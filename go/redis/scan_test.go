@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScanner returns a page of the keys per SCAN, the cursor is index of the next page
+type fakeScanner struct {
+	Redis
+
+	pages [][]string
+	err   error // returned after the pages
+	args  []interface{}
+}
+
+func (f *fakeScanner) ScanContext(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	f.args = []interface{}{pattern, count}
+	return f.page(cursor)
+}
+
+func (f *fakeScanner) HScanContext(ctx context.Context, key, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	f.args = []interface{}{key, pattern, count}
+	return f.page(cursor)
+}
+
+func (f *fakeScanner) page(cursor uint64) ([]string, uint64, error) {
+	if int(cursor) >= len(f.pages) {
+		return nil, 0, f.err
+	}
+
+	next := cursor + 1
+	if int(next) == len(f.pages) && f.err == nil {
+		next = 0
+	}
+	return f.pages[cursor], next, nil
+}
+
+func collectScan(t *testing.T, resultCh <-chan ScanAllResult) ([]string, error) {
+	var (
+		keys []string
+		err  error
+	)
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case res, ok := <-resultCh:
+			if !ok {
+				return keys, err
+			}
+			keys = append(keys, res.Keys...)
+			if res.Err != nil {
+				err = res.Err
+			}
+		case <-timeout:
+			t.Fatal("channel is not closed after a second")
+		}
+	}
+}
+
+func TestScanAll(t *testing.T) {
+	fake := &fakeScanner{pages: [][]string{{"k1", "k2"}, {}, {"k3"}}}
+	cli := &Client{Redis: fake}
+
+	keys, err := collectScan(t, cli.ScanAll(context.Background(), "k*", 2))
+	require.NoError(t, err)
+	require.Equal(t, []string{"k1", "k2", "k3"}, keys)
+	require.Equal(t, []interface{}{"k*", int64(2)}, fake.args)
+
+	keys, err = collectScan(t, cli.HScanAll(context.Background(), "hash", "*", 10))
+	require.NoError(t, err)
+	require.Equal(t, []string{"k1", "k2", "k3"}, keys)
+	require.Equal(t, []interface{}{"hash", "*", int64(10)}, fake.args)
+
+	// stopped by the error
+	fake.err = errors.New("scan failed")
+	keys, err = collectScan(t, cli.ScanAll(context.Background(), "*", 10))
+	require.Equal(t, fake.err, err)
+	require.Equal(t, []string{"k1", "k2", "k3"}, keys)
+}
+
+func TestScanAllCancel(t *testing.T) {
+	fake := &fakeScanner{pages: [][]string{{"k1"}, {"k2"}, {"k3"}, {"k4"}}}
+	cli := &Client{Redis: fake}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resultCh := cli.ScanAll(ctx, "*", 10)
+	res := <-resultCh
+	require.Equal(t, []string{"k1"}, res.Keys)
+
+	// k2 & k3 are already fetched, the scan stops before k4
+	cancel()
+	keys, err := collectScan(t, resultCh)
+	require.Equal(t, context.Canceled, err)
+	require.NotContains(t, keys, "k4")
+
+	// the caller stops reading after the cancellation, the goroutine must not be blocked
+	ctx, cancel = context.WithCancel(context.Background())
+	resultCh = cli.ScanAll(ctx, "*", 10)
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	_, err = collectScan(t, resultCh)
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestScanAllCancelNotReading(t *testing.T) {
+	// the scan blocks until the ctx is cancelled, k1 is still unread in the channel at that time
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		scanned  = make(chan struct{})
+		returned = make(chan struct{})
+	)
+	resultCh := scanAll(ctx, func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		if cursor == 0 {
+			return []string{"k1"}, 1, nil
+		}
+		defer close(returned)
+		close(scanned)
+		<-ctx.Done()
+		return nil, 0, ctx.Err()
+	})
+
+	<-scanned
+	cancel()
+	<-returned
+
+	// the error is delivered although nobody received while it was sent
+	keys, err := collectScan(t, resultCh)
+	require.Equal(t, context.Canceled, err)
+	require.True(t, len(keys) <= 1)
+}
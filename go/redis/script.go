@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/boxofimagination/bxdk/go/redis/engine"
+)
+
+// Script is a lua script which is executed using EVALSHA, sending only the SHA1 digest of the script.
+// It falls back to EVAL if the script is not cached in the server yet.
+//
+// The script is safe to be used from concurrent goroutines,
+// please create it once (e.g. as a package variable) instead of for each execution.
+// The created scripts are registered, so they could be loaded at once using `LoadScripts`.
+type Script struct {
+	src  string
+	hash string
+
+	// loaded is 1 after the script is successfully loaded using Load,
+	// it is reset to 0 when EVALSHA of the script fails with NOSCRIPT error, see scriptHook
+	loaded int32
+}
+
+// scriptRegistry is all of the scripts created by NewScript, by their hash
+var scriptRegistry = struct {
+	mux    sync.Mutex
+	byHash map[string][]*Script
+}{byHash: make(map[string][]*Script)}
+
+// NewScript creates new lua script, and registers it
+func NewScript(src string) *Script {
+	s := &Script{
+		src:  src,
+		hash: engine.ScriptHash(src),
+	}
+
+	scriptRegistry.mux.Lock()
+	scriptRegistry.byHash[s.hash] = append(scriptRegistry.byHash[s.hash], s)
+	scriptRegistry.mux.Unlock()
+	return s
+}
+
+// LoadScripts caches the given scripts in the server using SCRIPT LOAD, or all of the registered scripts if none is given.
+// Call it at startup, so the pipelined scripts could use EVALSHA.
+func LoadScripts(ctx context.Context, cli Redis, scripts ...*Script) error {
+	if len(scripts) == 0 {
+		scriptRegistry.mux.Lock()
+		for _, byHash := range scriptRegistry.byHash {
+			scripts = append(scripts, byHash...)
+		}
+		scriptRegistry.mux.Unlock()
+	}
+
+	for _, s := range scripts {
+		if err := s.LoadContext(ctx, cli); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unloadScript resets the loaded flag of the registered scripts with the given hash
+func unloadScript(hash string) {
+	scriptRegistry.mux.Lock()
+	defer scriptRegistry.mux.Unlock()
+	for _, s := range scriptRegistry.byHash[hash] {
+		atomic.StoreInt32(&s.loaded, 0)
+	}
+}
+
+// Hash returns SHA1 digest of the script
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+// Load caches the script in the server using SCRIPT LOAD.
+// Call it at startup, so the pipelined script could use EVALSHA.
+func (s *Script) Load(cli Redis) error {
+	return s.LoadContext(context.Background(), cli)
+}
+
+// LoadContext is Load with context
+func (s *Script) LoadContext(ctx context.Context, cli Redis) error {
+	if _, err := cli.ScriptLoadContext(ctx, s.src); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&s.loaded, 1)
+	return nil
+}
+
+// Run executes the script using EVALSHA, and falls back to EVAL on NOSCRIPT error.
+// The reply is converted the same way as `Eval`.
+func (s *Script) Run(cli Redis, keys []string, args ...interface{}) (interface{}, error) {
+	return s.RunContext(context.Background(), cli, keys, args...)
+}
+
+// RunContext is Run with context
+func (s *Script) RunContext(ctx context.Context, cli Redis, keys []string, args ...interface{}) (interface{}, error) {
+	reply, err := cli.EvalShaContext(ctx, s.hash, keys, args...)
+	if engine.IsErrNoScript(err) {
+		// EVAL also caches the script, the next EVALSHA won't fail
+		return cli.EvalContext(ctx, s.src, keys, args...)
+	}
+	return reply, err
+}
+
+// Pipe queues the script to the pipeline, and returns its result which is set after the pipeline is executed.
+//
+// The pipeline couldn't fall back to EVAL, so it only uses EVALSHA after the script is loaded using `Load`,
+// otherwise it uses EVAL which sends the whole script.
+// If the server loses the cache (e.g. it is restarted or failed over), the pipelined EVALSHA fails with NOSCRIPT error,
+// and the script uses EVAL again until it is loaded again.
+func (s *Script) Pipe(p Pipeliner, keys []string, args ...interface{}) *Result {
+	if atomic.LoadInt32(&s.loaded) == 1 {
+		return p.EvalSha(s.hash, keys, args...)
+	}
+	return p.Eval(s.src, keys, args...)
+}
+
+// IsErrNoScript returns true if the err is returned by EVALSHA because the script is not cached in the server
+func IsErrNoScript(err error) bool {
+	return engine.IsErrNoScript(err)
+}
+
+// scriptHook resets the loaded flag of the script when its EVALSHA fails with NOSCRIPT error,
+// so the next `Script.Pipe` uses EVAL instead of failing until the script is loaded again.
+// It is registered to each Client.
+type scriptHook struct{}
+
+// BeforeProcess implements Hook
+func (scriptHook) BeforeProcess(ctx context.Context, cmd string, args []interface{}) context.Context {
+	return ctx
+}
+
+// AfterProcess implements Hook
+func (scriptHook) AfterProcess(ctx context.Context, cmd string, args []interface{}, duration time.Duration, err error) {
+	checkNoScript(cmd, args, err)
+}
+
+// BeforeProcessPipeline implements Hook
+func (scriptHook) BeforeProcessPipeline(ctx context.Context, cmds []engine.CmdErr) context.Context {
+	return ctx
+}
+
+// AfterProcessPipeline implements Hook
+func (scriptHook) AfterProcessPipeline(ctx context.Context, cmds []engine.CmdErr, duration time.Duration, err error) {
+	for _, cmd := range cmds {
+		checkNoScript(cmd.Name(), cmd.Args(), cmd.Err())
+	}
+}
+
+func checkNoScript(cmd string, args []interface{}, err error) {
+	if !engine.IsErrNoScript(err) || !strings.EqualFold(cmd, "EVALSHA") || len(args) == 0 {
+		return
+	}
+	if hash, ok := args[0].(string); ok {
+		unloadScript(hash)
+	}
+}
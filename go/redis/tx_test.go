@@ -0,0 +1,201 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTx is minimal redis server supporting only the string commands & transactions.
+// miniredis replies empty array instead of nil when the transaction is aborted,
+// which is not what the clients expect.
+type fakeTx struct {
+	lis net.Listener
+
+	mux       sync.Mutex
+	vals      map[string]string
+	conflicts int // number of the next EXECs to be aborted
+	drops     int // number of the next INCRs whose connection is dropped before replying
+}
+
+func newFakeTx(t *testing.T) *fakeTx {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakeTx{
+		lis:  lis,
+		vals: make(map[string]string),
+	}
+	go f.serve()
+	return f
+}
+
+func (f *fakeTx) Addr() string {
+	return f.lis.Addr().String()
+}
+
+func (f *fakeTx) Close() {
+	f.lis.Close()
+}
+
+func (f *fakeTx) setConflicts(n int) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.conflicts = n
+}
+
+func (f *fakeTx) setDrops(n int) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.drops = n
+}
+
+func (f *fakeTx) get(key string) string {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.vals[key]
+}
+
+func (f *fakeTx) serve() {
+	for {
+		conn, err := f.lis.Accept()
+		if err != nil {
+			return
+		}
+		go f.serveConn(conn)
+	}
+}
+
+func (f *fakeTx) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	var (
+		r      = bufio.NewReader(conn)
+		w      = bufio.NewWriter(conn)
+		multi  bool
+		queued [][]string
+	)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "MULTI":
+			multi = true
+			fmt.Fprint(w, "+OK\r\n")
+		case cmd == "EXEC":
+			f.exec(w, queued)
+			multi, queued = false, nil
+		case multi:
+			queued = append(queued, args)
+			fmt.Fprint(w, "+QUEUED\r\n")
+		default:
+			f.mux.Lock()
+			f.handle(w, cmd, args[1:])
+			drop := cmd == "INCR" && f.drops > 0
+			if drop {
+				f.drops--
+			}
+			f.mux.Unlock()
+			if drop {
+				// the command is executed, but its reply is never sent
+				return
+			}
+		}
+		w.Flush()
+	}
+}
+
+func (f *fakeTx) exec(w *bufio.Writer, queued [][]string) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.conflicts > 0 {
+		f.conflicts--
+		fmt.Fprint(w, "*-1\r\n")
+		return
+	}
+
+	fmt.Fprintf(w, "*%d\r\n", len(queued))
+	for _, args := range queued {
+		f.handle(w, strings.ToUpper(args[0]), args[1:])
+	}
+}
+
+// handle handles the command, it must be called with the lock held
+func (f *fakeTx) handle(w *bufio.Writer, cmd string, args []string) {
+	switch cmd {
+	case "PING":
+		fmt.Fprint(w, "+PONG\r\n")
+	case "WATCH", "UNWATCH":
+		fmt.Fprint(w, "+OK\r\n")
+	case "GET":
+		val, ok := f.vals[args[0]]
+		if !ok {
+			fmt.Fprint(w, "$-1\r\n")
+			return
+		}
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(val), val)
+	case "SET":
+		f.vals[args[0]] = args[1]
+		fmt.Fprint(w, "+OK\r\n")
+	case "INCR":
+		n, _ := strconv.Atoi(f.vals[args[0]])
+		f.vals[args[0]] = strconv.Itoa(n + 1)
+		fmt.Fprintf(w, ":%d\r\n", n+1)
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", cmd)
+	}
+}
+
+func TestWatchRetry(t *testing.T) {
+	for _, engineType := range engineTypes {
+		t.Run(string(engineType), func(t *testing.T) {
+			fake := newFakeTx(t)
+			defer fake.Close()
+
+			cli, err := New(Config{
+				EngineType: engineType,
+				Address:    fake.Addr(),
+			})
+			require.NoError(t, err)
+
+			var attempts int
+			incr := func(tx Tx) error {
+				attempts++
+				tx.Queue("INCR", "counter")
+				return nil
+			}
+
+			// aborted on the first attempt
+			fake.setConflicts(1)
+			replies, err := cli.Watch(incr, 3, "counter")
+			require.NoError(t, err)
+			require.Equal(t, 2, attempts)
+			require.Equal(t, []interface{}{int64(1)}, replies)
+
+			// aborted on all of the attempts
+			attempts = 0
+			fake.setConflicts(5)
+			_, err = cli.Watch(incr, 3, "counter")
+			require.Equal(t, ErrTxFailed, err)
+			require.Equal(t, 3, attempts)
+			require.Equal(t, "1", fake.get("counter"))
+
+			// the connection is still usable
+			fake.setConflicts(0)
+			_, err = cli.Watch(incr, 1, "counter")
+			require.NoError(t, err)
+			require.Equal(t, "2", fake.get("counter"))
+		})
+	}
+}